@@ -0,0 +1,133 @@
+package groupstore
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// CacheInterface is the storage backend used by the cached store
+//
+// Implementations must be safe for concurrent use. Backends, which
+// can fail (i.e. remote caches), should report failed reads as misses
+// and ignore failed writes, the cached store will fall back to the
+// database.
+type CacheInterface interface {
+	// Get returns the value stored under the key, and whether it was found
+	Get(key string) (value string, found bool)
+
+	// Set stores the value under the key, a zero ttl means the value does not expire
+	Set(key string, value string, ttl time.Duration)
+
+	// Delete removes the value stored under the key
+	Delete(key string)
+}
+
+// == TYPE ====================================================================
+
+// memoryCache is an in-memory least recently used cache with expiring entries
+type memoryCache struct {
+	// capacity is the maximum number of entries
+	capacity int
+
+	// entries is the recency list, the most recently used entry is at the front
+	entries *list.List
+
+	// index maps the keys to their recency list elements
+	index map[string]*list.Element
+
+	mutex sync.Mutex
+}
+
+type memoryCacheEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+var _ CacheInterface = (*memoryCache)(nil) // verify it extends the interface
+
+// == CONSTRUCTOR =============================================================
+
+// NewMemoryCache creates an in-memory LRU cache holding at most capacity
+// entries, when full the least recently used entry is evicted
+func NewMemoryCache(capacity int) CacheInterface {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &memoryCache{
+		capacity: capacity,
+		entries:  list.New(),
+		index:    map[string]*list.Element{},
+	}
+}
+
+// == PUBLIC METHODS ==========================================================
+
+func (c *memoryCache) Get(key string) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, exists := c.index[key]
+
+	if !exists {
+		return "", false
+	}
+
+	entry := element.Value.(*memoryCacheEntry)
+
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.removeElement(element)
+		return "", false
+	}
+
+	c.entries.MoveToFront(element)
+
+	return entry.value, true
+}
+
+func (c *memoryCache) Set(key string, value string, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	expiresAt := time.Time{}
+
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	if element, exists := c.index[key]; exists {
+		entry := element.Value.(*memoryCacheEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.entries.MoveToFront(element)
+		return
+	}
+
+	c.index[key] = c.entries.PushFront(&memoryCacheEntry{
+		key:       key,
+		value:     value,
+		expiresAt: expiresAt,
+	})
+
+	for c.entries.Len() > c.capacity {
+		c.removeElement(c.entries.Back())
+	}
+}
+
+func (c *memoryCache) Delete(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, exists := c.index[key]; exists {
+		c.removeElement(element)
+	}
+}
+
+// == PRIVATE METHODS =========================================================
+
+func (c *memoryCache) removeElement(element *list.Element) {
+	c.entries.Remove(element)
+	delete(c.index, element.Value.(*memoryCacheEntry).key)
+}
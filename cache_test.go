@@ -0,0 +1,71 @@
+package groupstore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheGetSetDelete(t *testing.T) {
+	cache := NewMemoryCache(10)
+
+	if _, found := cache.Get("KEY"); found {
+		t.Fatal("KEY MUST NOT be found")
+	}
+
+	cache.Set("KEY", "VALUE", 0)
+
+	value, found := cache.Get("KEY")
+
+	if !found || value != "VALUE" {
+		t.Fatal("unexpected value:", value, found)
+	}
+
+	cache.Set("KEY", "VALUE_2", 0)
+
+	value, _ = cache.Get("KEY")
+
+	if value != "VALUE_2" {
+		t.Fatal("unexpected value:", value)
+	}
+
+	cache.Delete("KEY")
+
+	if _, found := cache.Get("KEY"); found {
+		t.Fatal("KEY MUST NOT be found after delete")
+	}
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewMemoryCache(2)
+
+	cache.Set("KEY_1", "VALUE_1", 0)
+	cache.Set("KEY_2", "VALUE_2", 0)
+
+	cache.Get("KEY_1") // KEY_2 is now the least recently used
+
+	cache.Set("KEY_3", "VALUE_3", 0)
+
+	if _, found := cache.Get("KEY_2"); found {
+		t.Fatal("KEY_2 MUST be evicted")
+	}
+
+	if _, found := cache.Get("KEY_1"); !found {
+		t.Fatal("KEY_1 MUST be found")
+	}
+
+	if _, found := cache.Get("KEY_3"); !found {
+		t.Fatal("KEY_3 MUST be found")
+	}
+}
+
+func TestMemoryCacheExpires(t *testing.T) {
+	cache := NewMemoryCache(10)
+
+	cache.Set("KEY", "VALUE", time.Millisecond)
+
+	time.Sleep(5 * time.Millisecond)
+
+	if _, found := cache.Get("KEY"); found {
+		t.Fatal("KEY MUST be expired")
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"strings"
+
+	"github.com/gouniverse/groupstore"
+	"github.com/gouniverse/sb"
+)
+
+// metaFlags collects the repeatable -meta key=value flags
+type metaFlags map[string]string
+
+func (m metaFlags) String() string {
+	return ""
+}
+
+func (m metaFlags) Set(value string) error {
+	key, metaValue, found := strings.Cut(value, "=")
+
+	if !found || key == "" {
+		return errors.New("meta must be in the key=value format")
+	}
+
+	m[key] = metaValue
+
+	return nil
+}
+
+func groupCommand(ctx context.Context, c *cli, args []string) error {
+	return subcommand(ctx, c, "group", args, map[string]command{
+		"create": groupCreateCommand,
+		"list":   groupListCommand,
+		"show":   groupShowCommand,
+		"update": groupUpdateCommand,
+		"delete": groupDeleteCommand,
+	})
+}
+
+func groupCreateCommand(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("group create")
+	title := flags.String("title", "", "title (required)")
+	handle := flags.String("handle", "", "handle")
+	status := flags.String("status", groupstore.GROUP_STATUS_ACTIVE, "status")
+	memo := flags.String("memo", "", "memo")
+	metas := metaFlags{}
+	flags.Var(metas, "meta", "meta as key=value, repeatable")
+
+	positional, err := parseFlags(flags, args)
+
+	if err != nil {
+		return err
+	}
+
+	if len(positional) > 0 {
+		return errors.New("group create: unexpected arguments: " + strings.Join(positional, " "))
+	}
+
+	if *title == "" {
+		return errors.New("group create: -title is required")
+	}
+
+	group := groupstore.NewGroup().
+		SetTitle(*title).
+		SetHandle(*handle).
+		SetStatus(*status).
+		SetMemo(*memo)
+
+	if err := group.SetMetas(metas); err != nil {
+		return err
+	}
+
+	if err := c.store.GroupCreate(ctx, group); err != nil {
+		return err
+	}
+
+	return c.printGroup(group)
+}
+
+func groupListCommand(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("group list")
+	status := flags.String("status", "", "only groups with the status")
+	search := flags.String("search", "", "only groups with the text in the title")
+	limit := flags.Int("limit", 100, "maximum number of groups")
+	offset := flags.Int("offset", 0, "number of groups to skip")
+	softDeleted := flags.Bool("deleted", false, "include the soft deleted groups")
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	query := groupstore.NewGroupQuery().
+		SetOrderBy(groupstore.COLUMN_TITLE).
+		SetSortDirection(sb.ASC).
+		SetLimit(*limit).
+		SetOffset(*offset).
+		SetSoftDeletedIncluded(*softDeleted)
+
+	if *status != "" {
+		query.SetStatus(*status)
+	}
+
+	if *search != "" {
+		query.SetTitleLike(*search)
+	}
+
+	groups, err := c.store.GroupList(ctx, query)
+
+	if err != nil {
+		return err
+	}
+
+	return c.printGroups(groups)
+}
+
+func groupShowCommand(ctx context.Context, c *cli, args []string) error {
+	positional, err := parseFlags(newFlagSet("group show"), args)
+
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		return errors.New("group show: expected <group>")
+	}
+
+	group, err := c.groupFind(ctx, positional[0])
+
+	if err != nil {
+		return err
+	}
+
+	return c.printGroup(group)
+}
+
+func groupUpdateCommand(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("group update")
+	title := flags.String("title", "", "title")
+	handle := flags.String("handle", "", "handle")
+	status := flags.String("status", "", "status")
+	memo := flags.String("memo", "", "memo")
+	metas := metaFlags{}
+	flags.Var(metas, "meta", "meta as key=value, repeatable, an empty value removes the meta")
+
+	positional, err := parseFlags(flags, args)
+
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		return errors.New("group update: expected <group>")
+	}
+
+	group, err := c.groupFind(ctx, positional[0])
+
+	if err != nil {
+		return err
+	}
+
+	// only the flags given are applied, so values can be set to empty
+	var errSet error
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "title":
+			group.SetTitle(*title)
+		case "handle":
+			group.SetHandle(*handle)
+		case "status":
+			group.SetStatus(*status)
+		case "memo":
+			group.SetMemo(*memo)
+		case "meta":
+			errSet = groupMetasMerge(group, metas)
+		}
+	})
+
+	if errSet != nil {
+		return errSet
+	}
+
+	if err := c.store.GroupUpdate(ctx, group); err != nil {
+		return err
+	}
+
+	return c.printGroup(group)
+}
+
+func groupDeleteCommand(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("group delete")
+	hard := flags.Bool("hard", false, "delete permanently, instead of soft deleting")
+
+	positional, err := parseFlags(flags, args)
+
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		return errors.New("group delete: expected <group>")
+	}
+
+	group, err := c.groupFind(ctx, positional[0])
+
+	if err != nil {
+		return err
+	}
+
+	if *hard {
+		err = c.store.GroupDelete(ctx, group)
+	} else {
+		err = c.store.GroupSoftDelete(ctx, group)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return c.printMessage("deleted group " + group.ID())
+}
+
+// groupFind returns the group with the ID or handle, or an error when not found
+func (c *cli) groupFind(ctx context.Context, idOrHandle string) (groupstore.GroupInterface, error) {
+	group, err := c.store.GroupFindByID(ctx, idOrHandle)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if group != nil {
+		return group, nil
+	}
+
+	group, err = c.store.GroupFindByHandle(ctx, idOrHandle)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if group == nil {
+		return nil, errors.New("group not found: " + idOrHandle)
+	}
+
+	return group, nil
+}
+
+// groupMetasMerge sets the metas on the group, removing the ones with empty values
+func groupMetasMerge(group groupstore.GroupInterface, metas map[string]string) error {
+	current, err := group.Metas()
+
+	if err != nil {
+		return err
+	}
+
+	for key, value := range metas {
+		if value == "" {
+			delete(current, key)
+		} else {
+			current[key] = value
+		}
+	}
+
+	return group.SetMetas(current)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/gouniverse/groupstore"
+	"github.com/gouniverse/sb"
+)
+
+func memberCommand(ctx context.Context, c *cli, args []string) error {
+	return subcommand(ctx, c, "member", args, map[string]command{
+		"add":    memberAddCommand,
+		"remove": memberRemoveCommand,
+		"list":   memberListCommand,
+	})
+}
+
+func memberAddCommand(ctx context.Context, c *cli, args []string) error {
+	positional, err := parseFlags(newFlagSet("member add"), args)
+
+	if err != nil {
+		return err
+	}
+
+	if len(positional) < 3 {
+		return errors.New("member add: expected <group> <entity-type> <entity-id>...")
+	}
+
+	group, err := c.groupFind(ctx, positional[0])
+
+	if err != nil {
+		return err
+	}
+
+	relations := []groupstore.RelationInterface{}
+
+	for _, entityID := range positional[2:] {
+		relation, err := c.store.RelationEnsure(ctx, positional[1], entityID, group.ID())
+
+		if err != nil {
+			return err
+		}
+
+		relations = append(relations, relation)
+	}
+
+	return c.printRelations(relations)
+}
+
+func memberRemoveCommand(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("member remove")
+	hard := flags.Bool("hard", false, "delete permanently, instead of soft deleting")
+
+	positional, err := parseFlags(flags, args)
+
+	if err != nil {
+		return err
+	}
+
+	if len(positional) < 3 {
+		return errors.New("member remove: expected <group> <entity-type> <entity-id>...")
+	}
+
+	group, err := c.groupFind(ctx, positional[0])
+
+	if err != nil {
+		return err
+	}
+
+	removed := 0
+
+	for _, entityID := range positional[2:] {
+		relation, err := c.store.RelationFindByEntityAndGroup(ctx, positional[1], entityID, group.ID())
+
+		if err != nil {
+			return err
+		}
+
+		if relation == nil {
+			continue
+		}
+
+		if *hard {
+			err = c.store.RelationDelete(ctx, relation)
+		} else {
+			err = c.store.RelationSoftDelete(ctx, relation)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		removed++
+	}
+
+	return c.printMessage("removed " + strconv.Itoa(removed) + " member(s)")
+}
+
+func memberListCommand(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("member list")
+	entityType := flags.String("entity-type", "", "only members of the entity type")
+	limit := flags.Int("limit", 100, "maximum number of members")
+	offset := flags.Int("offset", 0, "number of members to skip")
+
+	positional, err := parseFlags(flags, args)
+
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		return errors.New("member list: expected <group>")
+	}
+
+	group, err := c.groupFind(ctx, positional[0])
+
+	if err != nil {
+		return err
+	}
+
+	query := groupstore.NewRelationQuery().
+		SetGroupID(group.ID()).
+		SetOrderBy(groupstore.COLUMN_ENTITY_ID).
+		SetSortDirection(sb.ASC).
+		SetLimit(*limit).
+		SetOffset(*offset)
+
+	if *entityType != "" {
+		query.SetEntityType(*entityType)
+	}
+
+	relations, err := c.store.RelationList(ctx, query)
+
+	if err != nil {
+		return err
+	}
+
+	return c.printRelations(relations)
+}
+
+func checkCommand(ctx context.Context, c *cli, args []string) error {
+	positional, err := parseFlags(newFlagSet("check"), args)
+
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 3 {
+		return errors.New("check: expected <entity-type> <entity-id> <group>")
+	}
+
+	isMember, err := c.store.IsMember(ctx, positional[0], positional[1], positional[2])
+
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		err = c.printJSON(map[string]bool{"is_member": isMember})
+	} else {
+		err = c.printMessage(strconv.FormatBool(isMember))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !isMember {
+		return errNotMember
+	}
+
+	return nil
+}
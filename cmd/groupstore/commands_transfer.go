@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/gouniverse/groupstore"
+)
+
+func exportCommand(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("export")
+	output := flags.String("output", "", "file to write, defaults to the standard output")
+	softDeleted := flags.Bool("deleted", false, "include the soft deleted groups and relations")
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	options := groupstore.ExportOptions{
+		SoftDeletedIncluded: *softDeleted,
+	}
+
+	if *output == "" {
+		return c.store.Export(ctx, c.stdout, options)
+	}
+
+	file, err := os.Create(*output)
+
+	if err != nil {
+		return err
+	}
+
+	// the close error is returned, as a failed close may leave the file
+	// truncated
+	return errors.Join(c.store.Export(ctx, file, options), file.Close())
+}
+
+func importCommand(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("import")
+	input := flags.String("input", "", "file to read, defaults to the standard input")
+	matchBy := flags.String("match-by", groupstore.IMPORT_MATCH_BY_ID, "match the groups by id or handle")
+	onConflict := flags.String("on-conflict", groupstore.IMPORT_CONFLICT_FAIL, "on existing records: fail, skip or overwrite")
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	var reader io.Reader = c.stdin
+
+	if *input != "" {
+		file, err := os.Open(*input)
+
+		if err != nil {
+			return err
+		}
+
+		defer file.Close()
+
+		reader = file
+	}
+
+	result, err := c.store.Import(ctx, reader, groupstore.ImportOptions{
+		MatchBy:    *matchBy,
+		OnConflict: *onConflict,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return c.printJSON(result)
+	}
+
+	return c.printTable([]string{"", "CREATED", "UPDATED", "SKIPPED"}, [][]string{
+		{"groups", strconv.Itoa(result.GroupsCreated), strconv.Itoa(result.GroupsUpdated), strconv.Itoa(result.GroupsSkipped)},
+		{"relations", strconv.Itoa(result.RelationsCreated), strconv.Itoa(result.RelationsUpdated), strconv.Itoa(result.RelationsSkipped)},
+	})
+}
+
+func reconcileCommand(ctx context.Context, c *cli, args []string) error {
+	flags := newFlagSet("reconcile")
+	input := flags.String("input", "", "manifest file to read, defaults to the standard input")
+	prune := flags.Bool("prune", false, "soft delete the groups, which are not in the manifest")
+	dryRun := flags.Bool("dry-run", false, "only print the plan, without any change")
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	var reader io.Reader = c.stdin
+
+	if *input != "" {
+		file, err := os.Open(*input)
+
+		if err != nil {
+			return err
+		}
+
+		defer file.Close()
+
+		reader = file
+	}
+
+	manifest, err := groupstore.ParseManifest(reader)
+
+	if err != nil {
+		return err
+	}
+
+	plan, err := c.store.Reconcile(ctx, manifest, groupstore.ReconcileOptions{
+		PruneGroups: *prune,
+		DryRun:      *dryRun,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return c.printJSON(plan)
+	}
+
+	if plan.IsEmpty() {
+		return c.printMessage("no changes")
+	}
+
+	return c.printMessage(plan.String())
+}
@@ -0,0 +1,232 @@
+// Command groupstore administers the groups and memberships of a groupstore
+// database from the command line
+//
+// Usage:
+//
+//	groupstore [global flags] <command> [flags] [arguments]
+//
+// Global flags:
+//
+//	-driver          database driver: sqlite, mysql or postgres ($GROUPSTORE_DRIVER, default sqlite)
+//	-dsn             database connection string ($GROUPSTORE_DSN)
+//	-group-table     group table name ($GROUPSTORE_GROUP_TABLE, default groups_group)
+//	-relation-table  relation table name ($GROUPSTORE_RELATION_TABLE, default groups_group_entity_relation)
+//	-json            print JSON instead of tables
+//
+// Commands:
+//
+//	migrate                                           creates the tables and indexes
+//	group create -title T [-handle H] [-status S] [-memo M] [-meta k=v]...
+//	group list [-status S] [-search Q] [-limit N] [-offset N] [-deleted]
+//	group show <group>
+//	group update <group> [-title T] [-handle H] [-status S] [-memo M] [-meta k=v]...
+//	group delete <group> [-hard]
+//	member add <group> <entity-type> <entity-id>...
+//	member remove <group> <entity-type> <entity-id>... [-hard]
+//	member list <group> [-entity-type T] [-limit N] [-offset N]
+//	check <entity-type> <entity-id> <group>           exits 0 for members, 1 otherwise
+//	export [-output FILE] [-deleted]                  writes a JSON snapshot of the groups and relations
+//	import [-input FILE] [-match-by id|handle] [-on-conflict fail|skip|overwrite]
+//	reconcile [-input FILE] [-prune] [-dry-run]      applies a YAML or JSON manifest, printing the plan
+//
+// Groups are given by ID or handle. Errors exit with status 2.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/gouniverse/groupstore"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+// exit statuses
+const (
+	exitOK        = 0
+	exitNotMember = 1
+	exitError     = 2
+)
+
+// errNotMember is returned by the check command for non members
+var errNotMember = errors.New("not a member")
+
+// cli holds the state shared by the commands
+type cli struct {
+	store  groupstore.StoreInterface
+	stdin  io.Reader
+	stdout io.Writer
+	json   bool
+}
+
+// command is a command or subcommand, called with the remaining arguments
+type command func(ctx context.Context, c *cli, args []string) error
+
+// commands maps the command names to the commands
+var commands = map[string]command{
+	"migrate":   migrateCommand,
+	"group":     groupCommand,
+	"member":    memberCommand,
+	"check":     checkCommand,
+	"export":    exportCommand,
+	"import":    importCommand,
+	"reconcile": reconcileCommand,
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run runs the command line, and returns the exit status
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("groupstore", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	driver := flags.String("driver", envOrDefault("GROUPSTORE_DRIVER", "sqlite"), "database driver: sqlite, mysql or postgres")
+	dsn := flags.String("dsn", os.Getenv("GROUPSTORE_DSN"), "database connection string")
+	groupTable := flags.String("group-table", envOrDefault("GROUPSTORE_GROUP_TABLE", "groups_group"), "group table name")
+	relationTable := flags.String("relation-table", envOrDefault("GROUPSTORE_RELATION_TABLE", "groups_group_entity_relation"), "relation table name")
+	jsonOutput := flags.Bool("json", false, "print JSON instead of tables")
+
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: groupstore [global flags] <migrate|group|member|check|export|import|reconcile> [flags] [arguments]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitError
+	}
+
+	cmd, exists := commands[flags.Arg(0)]
+
+	if !exists {
+		fmt.Fprintln(stderr, "groupstore: unknown command:", flags.Arg(0))
+		flags.Usage()
+		return exitError
+	}
+
+	if *dsn == "" {
+		fmt.Fprintln(stderr, "groupstore: -dsn or $GROUPSTORE_DSN is required")
+		return exitError
+	}
+
+	db, err := sql.Open(*driver, *dsn)
+
+	if err != nil {
+		fmt.Fprintln(stderr, "groupstore:", err)
+		return exitError
+	}
+
+	defer db.Close()
+
+	store, err := groupstore.NewStore(groupstore.NewStoreOptions{
+		DB:                           db,
+		GroupTableName:               *groupTable,
+		GroupEntityRelationTableName: *relationTable,
+	})
+
+	if err != nil {
+		fmt.Fprintln(stderr, "groupstore:", err)
+		return exitError
+	}
+
+	c := &cli{
+		store:  store,
+		stdin:  stdin,
+		stdout: stdout,
+		json:   *jsonOutput,
+	}
+
+	err = cmd(ctx, c, flags.Args()[1:])
+
+	if errors.Is(err, errNotMember) {
+		return exitNotMember
+	}
+
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(stderr, "groupstore:", err)
+		}
+
+		return exitError
+	}
+
+	return exitOK
+}
+
+// subcommand dispatches to the named subcommand
+func subcommand(ctx context.Context, c *cli, name string, args []string, subcommands map[string]command) error {
+	if len(args) == 0 {
+		return errors.New(name + ": subcommand is required")
+	}
+
+	cmd, exists := subcommands[args[0]]
+
+	if !exists {
+		return errors.New(name + ": unknown subcommand: " + args[0])
+	}
+
+	return cmd(ctx, c, args[1:])
+}
+
+// newFlagSet returns a flag set for a command, printing its errors
+// through the returned error instead of the standard error
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	return flags
+}
+
+// parseFlags parses the flags, allowing them before and after the positional arguments
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, errors.New(flags.Name() + ": " + err.Error())
+		}
+
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+func migrateCommand(_ context.Context, c *cli, args []string) error {
+	if _, err := parseFlags(newFlagSet("migrate"), args); err != nil {
+		return err
+	}
+
+	if err := c.store.AutoMigrate(); err != nil {
+		return err
+	}
+
+	return c.printMessage("migrated")
+}
+
+func envOrDefault(name string, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return defaultValue
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCLI runs the command line against the database, and returns the
+// exit status, the standard output and the standard error
+func runCLI(t *testing.T, dsn string, stdin string, args ...string) (int, string, string) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	args = append([]string{"-driver", "sqlite", "-dsn", dsn}, args...)
+	code := run(context.Background(), args, strings.NewReader(stdin), stdout, stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func initDSN(t *testing.T) string {
+	dsn := filepath.Join(t.TempDir(), "groupstore.db")
+
+	if code, _, stderr := runCLI(t, dsn, "", "migrate"); code != exitOK {
+		t.Fatal("unexpected migrate failure:", stderr)
+	}
+
+	return dsn
+}
+
+func TestRunUsage(t *testing.T) {
+	if code, _, _ := runCLI(t, "unused.db", ""); code != exitError {
+		t.Fatal("missing command must fail, exit status:", code)
+	}
+
+	if code, _, stderr := runCLI(t, "unused.db", "", "unknown"); code != exitError || !strings.Contains(stderr, "unknown command") {
+		t.Fatal("unknown command must fail:", code, stderr)
+	}
+}
+
+func TestRunGroupCommands(t *testing.T) {
+	dsn := initDSN(t)
+
+	code, stdout, stderr := runCLI(t, dsn, "", "-json", "group", "create", "-title", "Administrators", "-handle", "admins", "-meta", "color=red")
+
+	if code != exitOK {
+		t.Fatal("unexpected failure:", stderr)
+	}
+
+	created := map[string]string{}
+
+	if err := json.Unmarshal([]byte(stdout), &created); err != nil {
+		t.Fatal("unexpected error:", err, stdout)
+	}
+
+	if created["id"] == "" || created["title"] != "Administrators" {
+		t.Fatal("unexpected group:", created)
+	}
+
+	if code, _, stderr = runCLI(t, dsn, "", "group", "create", "-handle", "nameless"); code != exitError || !strings.Contains(stderr, "-title is required") {
+		t.Fatal("missing title must fail:", code, stderr)
+	}
+
+	code, stdout, stderr = runCLI(t, dsn, "", "group", "update", "admins", "-title", "Admins", "-memo", "", "-meta", "color=")
+
+	if code != exitOK {
+		t.Fatal("unexpected failure:", stderr)
+	}
+
+	if !strings.Contains(stdout, "Admins") || strings.Contains(stdout, "meta.color") {
+		t.Fatal("unexpected updated group:", stdout)
+	}
+
+	code, stdout, _ = runCLI(t, dsn, "", "group", "list")
+
+	if code != exitOK || !strings.Contains(stdout, "HANDLE") || !strings.Contains(stdout, "admins") {
+		t.Fatal("unexpected group list:", stdout)
+	}
+
+	if code, _, stderr = runCLI(t, dsn, "", "group", "delete", created["id"]); code != exitOK {
+		t.Fatal("unexpected failure:", stderr)
+	}
+
+	if code, _, stderr = runCLI(t, dsn, "", "group", "show", "admins"); code != exitError || !strings.Contains(stderr, "group not found") {
+		t.Fatal("deleted group must not be found:", code, stderr)
+	}
+}
+
+func TestRunMemberCommands(t *testing.T) {
+	dsn := initDSN(t)
+
+	if code, _, stderr := runCLI(t, dsn, "", "group", "create", "-title", "Sales", "-handle", "sales"); code != exitOK {
+		t.Fatal("unexpected failure:", stderr)
+	}
+
+	if code, _, stderr := runCLI(t, dsn, "", "member", "add", "sales", "user", "USER_01", "USER_02"); code != exitOK {
+		t.Fatal("unexpected failure:", stderr)
+	}
+
+	code, stdout, _ := runCLI(t, dsn, "", "member", "list", "sales")
+
+	if code != exitOK || !strings.Contains(stdout, "USER_01") || !strings.Contains(stdout, "USER_02") {
+		t.Fatal("unexpected member list:", stdout)
+	}
+
+	if code, stdout, _ = runCLI(t, dsn, "", "check", "user", "USER_01", "sales"); code != exitOK || strings.TrimSpace(stdout) != "true" {
+		t.Fatal("USER_01 must be a member:", code, stdout)
+	}
+
+	if code, _, stderr := runCLI(t, dsn, "", "member", "remove", "sales", "user", "USER_01"); code != exitOK {
+		t.Fatal("unexpected failure:", stderr)
+	}
+
+	if code, stdout, _ = runCLI(t, dsn, "", "check", "user", "USER_01", "sales"); code != exitNotMember || strings.TrimSpace(stdout) != "false" {
+		t.Fatal("USER_01 must not be a member:", code, stdout)
+	}
+}
+
+func TestRunExportImport(t *testing.T) {
+	source := initDSN(t)
+
+	runCLI(t, source, "", "group", "create", "-title", "Sales", "-handle", "sales", "-meta", "region=eu")
+	runCLI(t, source, "", "member", "add", "sales", "user", "USER_01")
+
+	code, exported, stderr := runCLI(t, source, "", "export")
+
+	if code != exitOK {
+		t.Fatal("unexpected failure:", stderr)
+	}
+
+	output := filepath.Join(t.TempDir(), "export.json")
+
+	if code, _, stderr = runCLI(t, source, "", "export", "-output", output); code != exitOK {
+		t.Fatal("unexpected failure:", stderr)
+	}
+
+	if written, err := os.ReadFile(output); err != nil || len(written) != len(exported) {
+		t.Fatal("export file must have the exported snapshot:", err, string(written))
+	}
+
+	target := initDSN(t)
+
+	if code, _, stderr = runCLI(t, target, exported, "import"); code != exitOK {
+		t.Fatal("unexpected failure:", stderr)
+	}
+
+	if code, _, stderr = runCLI(t, target, exported, "import"); code != exitError || !strings.Contains(stderr, "conflicts") {
+		t.Fatal("conflicting import must fail by default:", code, stderr)
+	}
+
+	if code, _, stderr = runCLI(t, target, exported, "import", "-on-conflict", "skip"); code != exitOK {
+		t.Fatal("unexpected failure:", stderr)
+	}
+
+	code, stdout, _ := runCLI(t, target, "", "-json", "member", "list", "sales")
+
+	members := []map[string]string{}
+
+	if err := json.Unmarshal([]byte(stdout), &members); err != nil {
+		t.Fatal("unexpected error:", err, stdout)
+	}
+
+	if code != exitOK || len(members) != 1 || members[0]["entity_id"] != "USER_01" {
+		t.Fatal("unexpected members:", stdout)
+	}
+
+	if _, stdout, _ = runCLI(t, target, "", "group", "show", "sales"); !strings.Contains(stdout, "meta.region") {
+		t.Fatal("metas must be imported:", stdout)
+	}
+
+	if code, _, stderr = runCLI(t, target, "{not json", "import"); code != exitError || !strings.Contains(stderr, "invalid document") {
+		t.Fatal("invalid document must fail:", code, stderr)
+	}
+}
+
+func TestRunReconcile(t *testing.T) {
+	dsn := initDSN(t)
+
+	manifest := "groups:\n  - handle: admins\n    title: Administrators\n    members:\n      user: [USER_01]\n"
+
+	code, stdout, stderr := runCLI(t, dsn, manifest, "reconcile", "-dry-run")
+
+	if code != exitOK || stdout != "+ group admins\n+ member admins user USER_01\n" {
+		t.Fatal("unexpected plan:", code, stdout, stderr)
+	}
+
+	if code, _, stderr = runCLI(t, dsn, manifest, "reconcile"); code != exitOK {
+		t.Fatal("unexpected failure:", stderr)
+	}
+
+	if code, _, _ = runCLI(t, dsn, "", "check", "user", "USER_01", "admins"); code != exitOK {
+		t.Fatal("USER_01 must be a member of admins, exit status:", code)
+	}
+
+	if _, stdout, _ = runCLI(t, dsn, manifest, "reconcile"); stdout != "no changes\n" {
+		t.Fatal("unexpected plan:", stdout)
+	}
+}
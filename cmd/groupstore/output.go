@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gouniverse/groupstore"
+	"github.com/samber/lo"
+)
+
+// printJSON prints the value as indented JSON
+func (c *cli) printJSON(value any) error {
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(value)
+}
+
+// printMessage prints a confirmation message
+func (c *cli) printMessage(message string) error {
+	if c.json {
+		return c.printJSON(map[string]string{"message": message})
+	}
+
+	_, err := fmt.Fprintln(c.stdout, message)
+
+	return err
+}
+
+// printTable prints the rows aligned in columns under the header
+func (c *cli) printTable(header []string, rows [][]string) error {
+	writer := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(writer, strings.Join(header, "\t"))
+
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+
+	return writer.Flush()
+}
+
+// printGroups prints the groups as a table or JSON
+func (c *cli) printGroups(groups []groupstore.GroupInterface) error {
+	if c.json {
+		return c.printJSON(lo.Map(groups, func(group groupstore.GroupInterface, _ int) map[string]string {
+			return group.Data()
+		}))
+	}
+
+	return c.printTable([]string{"ID", "HANDLE", "TITLE", "STATUS", "UPDATED"}, lo.Map(groups, func(group groupstore.GroupInterface, _ int) []string {
+		return []string{group.ID(), group.Handle(), group.Title(), group.Status(), group.UpdatedAt()}
+	}))
+}
+
+// printGroup prints the group and its metas as a list or JSON
+func (c *cli) printGroup(group groupstore.GroupInterface) error {
+	if c.json {
+		return c.printJSON(group.Data())
+	}
+
+	metas, err := group.Metas()
+
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{
+		{"id", group.ID()},
+		{"handle", group.Handle()},
+		{"title", group.Title()},
+		{"status", group.Status()},
+		{"memo", group.Memo()},
+		{"created_at", group.CreatedAt()},
+		{"updated_at", group.UpdatedAt()},
+	}
+
+	if group.IsSoftDeleted() {
+		rows = append(rows, []string{"soft_deleted_at", group.SoftDeletedAt()})
+	}
+
+	keys := lo.Keys(metas)
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		rows = append(rows, []string{"meta." + key, metas[key]})
+	}
+
+	return c.printTable([]string{"FIELD", "VALUE"}, rows)
+}
+
+// printRelations prints the relations as a table or JSON
+func (c *cli) printRelations(relations []groupstore.RelationInterface) error {
+	if c.json {
+		return c.printJSON(lo.Map(relations, func(relation groupstore.RelationInterface, _ int) map[string]string {
+			return relation.Data()
+		}))
+	}
+
+	return c.printTable([]string{"ID", "ENTITY TYPE", "ENTITY ID", "CREATED"}, lo.Map(relations, func(relation groupstore.RelationInterface, _ int) []string {
+		return []string{relation.ID(), relation.EntityType(), relation.EntityID(), relation.CreatedAt()}
+	}))
+}
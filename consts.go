@@ -4,19 +4,119 @@ const ERROR_EMPTY_ARRAY = "array cannot be empty"
 const ERROR_EMPTY_STRING = "string cannot be empty"
 const ERROR_NEGATIVE_NUMBER = "number cannot be negative"
 
+const COLUMN_ACTION = "action"
+const COLUMN_ACTIVATE_AT = "activate_at"
+const COLUMN_ACTOR = "actor"
+const COLUMN_CAPACITY = "capacity"
 const COLUMN_CREATED_AT = "created_at"
+const COLUMN_DEACTIVATE_AT = "deactivate_at"
+const COLUMN_DETAILS = "details"
 const COLUMN_ENTITY_ID = "entity_id"
 const COLUMN_ENTITY_TYPE = "entity_type"
+const COLUMN_EXCLUSIVE_SET = "exclusive_set"
+const COLUMN_EXTERNAL_ID = "external_id"
 const COLUMN_HANDLE = "handle"
 const COLUMN_ID = "id"
 const COLUMN_MEMO = "memo"
 const COLUMN_METAS = "metas"
+const COLUMN_RULE = "rule"
 const COLUMN_GROUP_ID = "group_id"
+const COLUMN_POSITION = "position"
 const COLUMN_STATUS = "status"
+const COLUMN_SUBJECT_ID = "subject_id"
+const COLUMN_SUBJECT_TYPE = "subject_type"
 const COLUMN_SOFT_DELETED_AT = "soft_deleted_at"
 const COLUMN_TITLE = "title"
 const COLUMN_UPDATED_AT = "updated_at"
 
+// ENTITY_TYPE_GROUP is the entity type of the relations nesting a group,
+// the entity ID being the ID of the nested group
+const ENTITY_TYPE_GROUP = "group"
+
+// ENTITY_TYPE_USER is the entity type of the users, as related by the
+// user membership facade
+const ENTITY_TYPE_USER = "user"
+
 const GROUP_STATUS_ACTIVE = "active"
+const GROUP_STATUS_ARCHIVED = "archived"
 const GROUP_STATUS_INACTIVE = "inactive"
+
+// Deprecated: GROUP_STATUS_DELETED overlaps with the soft deletion, use
+// GroupSoftDelete, or GROUP_STATUS_ARCHIVED. It is kept valid in the
+// default status transitions, for the groups stored with it
 const GROUP_STATUS_DELETED = "deleted"
+
+const GROUP_CAPACITY_UNLIMITED = -1
+
+// The metas recording the merges and the splits of the groups
+const GROUP_META_MERGED_AT = "merged_at"
+const GROUP_META_MERGED_INTO = "merged_into"
+const GROUP_META_SPLIT_AT = "split_at"
+const GROUP_META_SPLIT_FROM = "split_from"
+
+// The strategies of GroupMerge for the metas, which a source has with
+// another value than the target
+const GROUP_MERGE_METAS_FAIL = "fail"
+const GROUP_MERGE_METAS_KEEP_SOURCE = "keep_source"
+const GROUP_MERGE_METAS_KEEP_TARGET = "keep_target"
+
+// The statuses of the relations, only the active relations are members,
+// the relations stored before the statuses (i.e. without one) are active
+const RELATION_STATUS_ACTIVE = "active"
+const RELATION_STATUS_BANNED = "banned"
+const RELATION_STATUS_INVITED = "invited"
+const RELATION_STATUS_PENDING = "pending"
+const RELATION_STATUS_REJECTED = "rejected"
+
+// RELATION_STATUSES are all the statuses of the relations
+var RELATION_STATUSES = []string{
+	RELATION_STATUS_ACTIVE,
+	RELATION_STATUS_BANNED,
+	RELATION_STATUS_INVITED,
+	RELATION_STATUS_PENDING,
+	RELATION_STATUS_REJECTED,
+}
+
+// The actions recorded in the audit trail
+const AUDIT_ACTION_CREATED = "created"
+const AUDIT_ACTION_DELETED = "deleted"
+const AUDIT_ACTION_MERGED = "merged"
+const AUDIT_ACTION_RESTORED = "restored"
+const AUDIT_ACTION_SOFT_DELETED = "soft_deleted"
+const AUDIT_ACTION_SPLIT = "split"
+const AUDIT_ACTION_UPDATED = "updated"
+
+// The types of the records changed, as recorded in the audit trail
+const AUDIT_SUBJECT_GROUP = "group"
+const AUDIT_SUBJECT_RELATION = "relation"
+
+const SNAPSHOT_VERSION = 1
+
+const IMPORT_MATCH_BY_ID = "id"
+const IMPORT_MATCH_BY_HANDLE = "handle"
+
+const IMPORT_CONFLICT_FAIL = "fail"
+const IMPORT_CONFLICT_OVERWRITE = "overwrite"
+const IMPORT_CONFLICT_SKIP = "skip"
+
+const CSV_COLUMN_ENTITY_TYPE = "entity_type"
+const CSV_COLUMN_ENTITY_ID = "entity_id"
+const CSV_COLUMN_GROUP_ID = "group_id"
+const CSV_COLUMN_GROUP_HANDLE = "group_handle"
+
+const RECONCILE_ACTION_GROUP_CREATE = "group_create"
+const RECONCILE_ACTION_GROUP_UPDATE = "group_update"
+const RECONCILE_ACTION_GROUP_DELETE = "group_delete"
+const RECONCILE_ACTION_RELATION_CREATE = "relation_create"
+const RECONCILE_ACTION_RELATION_DELETE = "relation_delete"
+
+const RULE_MATCH_ALL = "all"
+const RULE_MATCH_ANY = "any"
+
+const RULE_OPERATOR_EQ = "eq"
+const RULE_OPERATOR_NE = "ne"
+const RULE_OPERATOR_IN = "in"
+const RULE_OPERATOR_NOT_IN = "not_in"
+const RULE_OPERATOR_CONTAINS = "contains"
+const RULE_OPERATOR_EXISTS = "exists"
+const RULE_OPERATOR_NOT_EXISTS = "not_exists"
@@ -5,18 +5,23 @@ go 1.23.3
 require (
 	github.com/doug-martin/goqu/v9 v9.19.0
 	github.com/dromara/carbon/v2 v2.6.1
+	github.com/go-sql-driver/mysql v1.8.1
 	github.com/gouniverse/base v0.9.0
 	github.com/gouniverse/dataobject v1.3.0
+	github.com/gouniverse/hb v1.83.4
 	github.com/gouniverse/maputils v0.7.0
 	github.com/gouniverse/sb v0.8.0
 	github.com/gouniverse/uid v1.5.0
 	github.com/gouniverse/utils v1.45.4
+	github.com/lib/pq v1.10.1
 	github.com/samber/lo v1.49.1
 	github.com/spf13/cast v1.7.1
+	gopkg.in/yaml.v3 v3.0.1
 	modernc.org/sqlite v1.37.0
 )
 
 require (
+	filippo.io/edwards25519 v1.1.0 // indirect
 	github.com/darkoatanasovski/htmltags v1.0.0 // indirect
 	github.com/dustin/go-humanize v1.0.1 // indirect
 	github.com/georgysavva/scany v1.2.3 // indirect
@@ -25,7 +30,6 @@ require (
 	github.com/gouniverse/cdn v1.6.0 // indirect
 	github.com/gouniverse/crypto v0.2.0 // indirect
 	github.com/gouniverse/envenc v0.10.0 // indirect
-	github.com/gouniverse/hb v1.83.4 // indirect
 	github.com/gouniverse/webserver v0.1.0 // indirect
 	github.com/joho/godotenv v1.5.1 // indirect
 	github.com/jordan-wright/email v4.0.1-0.20210109023952-943e75fe5223+incompatible // indirect
@@ -0,0 +1,205 @@
+// Package groupstoreadmin is a server-rendered admin UI for a groupstore
+//
+// The UI lists, searches, creates and edits groups, manages their
+// members, and soft deletes and restores both. The handler performs no
+// authentication, it must be wrapped in the application's own access
+// control. Cross-site form posts are rejected.
+//
+// The audit page lists the changes recorded in the audit trail of the
+// store, when its AuditTableName option is set. The Actor option names
+// the user making the changes through the UI.
+package groupstoreadmin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gouniverse/groupstore"
+)
+
+// maxBodyBytes is the maximum size of a submitted form
+const maxBodyBytes = 1 << 20
+
+// groupsPerPage is the number of groups on a page of the group list
+const groupsPerPage = 20
+
+// membersPerPage is the number of members on a page of the group page
+const membersPerPage = 50
+
+// auditRecordsPerPage is the number of records on a page of the audit page
+const auditRecordsPerPage = 50
+
+// NewHandlerOptions define the options for creating a new admin handler
+type NewHandlerOptions struct {
+	// Store is the group store to manage
+	Store groupstore.StoreInterface
+
+	// BasePath is the path the handler is mounted on (i.e. "/admin/groups"),
+	// it prefixes all the links and form actions
+	BasePath string
+
+	// Title is shown in the page header, defaults to "Groups"
+	Title string
+
+	// Actor returns who makes the request (i.e. the ID of the signed in
+	// user), recorded in the audit trail with the changes, optional
+	Actor func(r *http.Request) string
+}
+
+// == TYPE ====================================================================
+
+type handler struct {
+	store    groupstore.StoreInterface
+	basePath string
+	title    string
+	actor    func(r *http.Request) string
+	mux      *http.ServeMux
+}
+
+// == CONSTRUCTOR =============================================================
+
+// NewHandler returns an http.Handler serving the admin UI
+//
+// To mount the handler under the base path, use http.StripPrefix:
+//
+//	http.Handle("/admin/groups/", http.StripPrefix("/admin/groups", handler))
+func NewHandler(opts NewHandlerOptions) (http.Handler, error) {
+	if opts.Store == nil {
+		return nil, errors.New("groupstoreadmin: Store is required")
+	}
+
+	if opts.Title == "" {
+		opts.Title = "Groups"
+	}
+
+	h := &handler{
+		store:    opts.Store,
+		basePath: strings.TrimSuffix(opts.BasePath, "/"),
+		title:    opts.Title,
+		actor:    opts.Actor,
+		mux:      http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc("GET /{$}", h.groupListPage)
+	h.mux.HandleFunc("GET /audit", h.auditPage)
+	h.mux.HandleFunc("GET /groups/new", h.groupCreatePage)
+	h.mux.HandleFunc("POST /groups", h.groupCreate)
+	h.mux.HandleFunc("GET /groups/{id}", h.groupPage)
+	h.mux.HandleFunc("POST /groups/{id}", h.groupUpdate)
+	h.mux.HandleFunc("POST /groups/{id}/delete", h.groupSoftDelete)
+	h.mux.HandleFunc("POST /groups/{id}/restore", h.groupRestore)
+	h.mux.HandleFunc("POST /groups/{id}/members", h.memberAdd)
+	h.mux.HandleFunc("POST /groups/{id}/members/{relationID}/delete", h.memberSoftDelete)
+	h.mux.HandleFunc("POST /groups/{id}/members/{relationID}/restore", h.memberRestore)
+
+	return h, nil
+}
+
+// == PUBLIC METHODS ==========================================================
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost && !isSameOrigin(r) {
+		http.Error(w, "cross-origin form posts are not allowed", http.StatusForbidden)
+		return
+	}
+
+	if h.actor != nil {
+		r = r.WithContext(groupstore.AuditActorContext(r.Context(), h.actor(r)))
+	}
+
+	h.mux.ServeHTTP(w, r)
+}
+
+// == PRIVATE METHODS =========================================================
+
+// url returns the link to the path within the handler
+func (h *handler) url(path string, query url.Values) string {
+	link := h.basePath + path
+
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+
+	return link
+}
+
+// redirect redirects to the path within the handler, with an optional
+// message shown on the next page
+func (h *handler) redirect(w http.ResponseWriter, r *http.Request, path string, message string) {
+	query := url.Values{}
+
+	if message != "" {
+		query.Set("message", message)
+	}
+
+	http.Redirect(w, r, h.url(path, query), http.StatusSeeOther)
+}
+
+// groupFind returns the group with the ID from the path, including
+// the soft deleted groups so they can be restored
+func (h *handler) groupFind(ctx context.Context, id string) (groupstore.GroupInterface, error) {
+	groups, err := h.store.GroupList(ctx, groupstore.NewGroupQuery().
+		SetID(id).
+		SetSoftDeletedIncluded(true).
+		SetLimit(1))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(groups) == 0 {
+		return nil, nil
+	}
+
+	return groups[0], nil
+}
+
+// relationFind returns the relation with the ID from the path, when it
+// belongs to the group, including the soft deleted relations
+func (h *handler) relationFind(ctx context.Context, groupID string, relationID string) (groupstore.RelationInterface, error) {
+	relations, err := h.store.RelationList(ctx, groupstore.NewRelationQuery().
+		SetID(relationID).
+		SetGroupID(groupID).
+		SetSoftDeletedIncluded(true).
+		SetLimit(1))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(relations) == 0 {
+		return nil, nil
+	}
+
+	return relations[0], nil
+}
+
+// == HELPERS =================================================================
+
+// isSameOrigin returns false for browser requests sent from another site,
+// requests without the browser headers (i.e. from scripts) are allowed
+func isSameOrigin(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" {
+		return site == "same-origin" || site == "none"
+	}
+
+	origin := r.Header.Get("Origin")
+
+	if origin == "" {
+		return true
+	}
+
+	parsed, err := url.Parse(origin)
+
+	return err == nil && parsed.Host == r.Host
+}
+
+// parseForm parses the submitted form, limiting its size
+func parseForm(w http.ResponseWriter, r *http.Request) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+
+	return r.ParseForm()
+}
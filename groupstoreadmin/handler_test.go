@@ -0,0 +1,340 @@
+package groupstoreadmin
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gouniverse/groupstore"
+	_ "modernc.org/sqlite"
+)
+
+func initHandler(t *testing.T) (http.Handler, groupstore.StoreInterface) {
+	db, err := sql.Open("sqlite", ":memory:?parseTime=true")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// each connection has its own in-memory database
+	db.SetMaxOpenConns(1)
+
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	store, err := groupstore.NewStore(groupstore.NewStoreOptions{
+		DB:                           db,
+		GroupTableName:               "groups_group_table",
+		GroupEntityRelationTableName: "groups_group_entity_relation_table",
+		AuditTableName:               "groups_audit_table",
+		AutomigrateEnabled:           true,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	handler, err := NewHandler(NewHandlerOptions{
+		Store:    store,
+		BasePath: "/admin/groups",
+		Actor: func(r *http.Request) string {
+			return r.Header.Get("X-Admin-User")
+		},
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	return handler, store
+}
+
+func doGet(handler http.Handler, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+
+	return recorder
+}
+
+func doPost(handler http.Handler, target string, form url.Values) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Sec-Fetch-Site", "same-origin")
+	request.Header.Set("X-Admin-User", "ADMIN_01")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func TestNewHandlerRequiresStore(t *testing.T) {
+	_, err := NewHandler(NewHandlerOptions{})
+
+	if err == nil {
+		t.Fatal("must return error as store is required")
+	}
+}
+
+func TestHandlerGroupCreateAndEdit(t *testing.T) {
+	handler, store := initHandler(t)
+
+	recorder := doGet(handler, "/groups/new")
+
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `action="/admin/groups/groups"`) {
+		t.Fatal("unexpected new group page:", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = doPost(handler, "/groups", url.Values{
+		"title":  {"Support <Team>"},
+		"handle": {"support"},
+		"status": {groupstore.GROUP_STATUS_ACTIVE},
+		"metas":  {"color = red\n\nlevel=2"},
+	})
+
+	if recorder.Code != http.StatusSeeOther {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	group, err := store.GroupFindByHandle(context.Background(), "support")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if group == nil || group.Title() != "Support <Team>" || group.Meta("color") != "red" || group.Meta("level") != "2" {
+		t.Fatal("unexpected group:", group)
+	}
+
+	if location := recorder.Header().Get("Location"); !strings.HasPrefix(location, "/admin/groups/groups/"+group.ID()) {
+		t.Fatal("unexpected redirect:", location)
+	}
+
+	recorder = doGet(handler, "/")
+
+	if !strings.Contains(recorder.Body.String(), "Support &lt;Team&gt;") {
+		t.Fatal("group list must show the escaped title:", recorder.Body.String())
+	}
+
+	recorder = doPost(handler, "/groups/"+group.ID(), url.Values{
+		"title":  {"Support"},
+		"status": {groupstore.GROUP_STATUS_INACTIVE},
+		"metas":  {"no separator"},
+	})
+
+	if recorder.Code != http.StatusBadRequest || !strings.Contains(recorder.Body.String(), "key=value") {
+		t.Fatal("invalid metas must be rejected:", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = doPost(handler, "/groups/"+group.ID(), url.Values{
+		"title":  {"Support"},
+		"handle": {"support"},
+		"status": {groupstore.GROUP_STATUS_INACTIVE},
+		"memo":   {"first line"},
+	})
+
+	if recorder.Code != http.StatusSeeOther {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	group, err = store.GroupFindByID(context.Background(), group.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if group.Title() != "Support" || group.Status() != groupstore.GROUP_STATUS_INACTIVE || group.Memo() != "first line" {
+		t.Fatal("unexpected group:", group.Data())
+	}
+
+	if group.Meta("color") != "" {
+		t.Fatal("metas must be replaced, color:", group.Meta("color"))
+	}
+
+	recorder = doGet(handler, "/?q=supp&status="+groupstore.GROUP_STATUS_ACTIVE)
+
+	if strings.Contains(recorder.Body.String(), ">Support</a>") {
+		t.Fatal("status filter must exclude the inactive group")
+	}
+}
+
+func TestHandlerGroupSoftDeleteAndRestore(t *testing.T) {
+	handler, store := initHandler(t)
+
+	group := groupstore.NewGroup().SetTitle("Sales").SetHandle("sales")
+
+	if err := store.GroupCreate(context.Background(), group); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	doPost(handler, "/groups/"+group.ID()+"/delete", url.Values{})
+
+	found, err := store.GroupFindByID(context.Background(), group.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found != nil {
+		t.Fatal("group must be soft deleted")
+	}
+
+	recorder := doGet(handler, "/groups/"+group.ID())
+
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "Restore") {
+		t.Fatal("soft deleted group must be shown with a restore button:", recorder.Code)
+	}
+
+	doPost(handler, "/groups/"+group.ID()+"/restore", url.Values{})
+
+	found, err = store.GroupFindByID(context.Background(), group.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found == nil {
+		t.Fatal("group must be restored")
+	}
+}
+
+func TestHandlerMembers(t *testing.T) {
+	handler, store := initHandler(t)
+
+	group := groupstore.NewGroup().SetTitle("Sales").SetHandle("sales")
+
+	if err := store.GroupCreate(context.Background(), group); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	recorder := doPost(handler, "/groups/"+group.ID()+"/members", url.Values{
+		"entity_type": {"user"},
+		"entity_id":   {"USER_01"},
+	})
+
+	if recorder.Code != http.StatusSeeOther {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = doPost(handler, "/groups/"+group.ID()+"/members", url.Values{"entity_type": {"user"}})
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatal("missing entity ID must be rejected, status:", recorder.Code)
+	}
+
+	relation, err := store.RelationFindByEntityAndGroup(context.Background(), "user", "USER_01", group.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if relation == nil {
+		t.Fatal("member must be added")
+	}
+
+	if !strings.Contains(doGet(handler, "/groups/"+group.ID()).Body.String(), "USER_01") {
+		t.Fatal("group page must list the member")
+	}
+
+	doPost(handler, "/groups/"+group.ID()+"/members/"+relation.ID()+"/delete", url.Values{})
+
+	isMember, err := store.IsMember(context.Background(), "user", "USER_01", group.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if isMember {
+		t.Fatal("member must be removed")
+	}
+
+	doPost(handler, "/groups/"+group.ID()+"/members/"+relation.ID()+"/restore", url.Values{})
+
+	isMember, err = store.IsMember(context.Background(), "user", "USER_01", group.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !isMember {
+		t.Fatal("member must be restored")
+	}
+}
+
+func TestHandlerAuditPage(t *testing.T) {
+	handler, store := initHandler(t)
+
+	doPost(handler, "/groups", url.Values{
+		"title":  {"Support"},
+		"handle": {"support"},
+		"status": {groupstore.GROUP_STATUS_ACTIVE},
+	})
+
+	group, err := store.GroupFindByHandle(context.Background(), "support")
+
+	if err != nil || group == nil {
+		t.Fatal("unexpected group:", group, err)
+	}
+
+	doPost(handler, "/groups/"+group.ID()+"/members", url.Values{
+		"entity_type": {"user"},
+		"entity_id":   {"USER_01"},
+	})
+
+	recorder := doGet(handler, "/audit?group_id="+group.ID())
+
+	if recorder.Code != http.StatusOK {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	body := recorder.Body.String()
+
+	if strings.Count(body, "ADMIN_01") != 2 || !strings.Contains(body, "entity_id=USER_01") || !strings.Contains(body, `href="/admin/groups/groups/`+group.ID()+`"`) {
+		t.Fatal("audit page must list the changes with their actor:", body)
+	}
+
+	recorder = doGet(handler, "/audit?action="+groupstore.AUDIT_ACTION_DELETED)
+
+	if !strings.Contains(recorder.Body.String(), "No changes recorded") {
+		t.Fatal("audit page must filter by action:", recorder.Body.String())
+	}
+
+	recorder = doGet(handler, "/groups/"+group.ID())
+
+	if !strings.Contains(recorder.Body.String(), `href="/admin/groups/audit?group_id=`+group.ID()+`"`) {
+		t.Fatal("group page must link to its audit trail:", recorder.Body.String())
+	}
+}
+
+func TestHandlerRejectsCrossSitePosts(t *testing.T) {
+	handler, _ := initHandler(t)
+
+	request := httptest.NewRequest(http.MethodPost, "/groups", strings.NewReader("title=Evil&status=active"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Sec-Fetch-Site", "cross-site")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusForbidden {
+		t.Fatal("cross-site post must be rejected, status:", recorder.Code)
+	}
+
+	request = httptest.NewRequest(http.MethodPost, "/groups", strings.NewReader("title=Evil&status=active"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Origin", "https://evil.example")
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusForbidden {
+		t.Fatal("foreign origin post must be rejected, status:", recorder.Code)
+	}
+}
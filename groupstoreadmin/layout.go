@@ -0,0 +1,129 @@
+package groupstoreadmin
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gouniverse/hb"
+)
+
+// styles is the stylesheet of the admin pages, kept inline so the UI
+// has no external dependencies
+const styles = `
+body { font-family: system-ui, sans-serif; margin: 0; color: #212529; background: #f8f9fa; }
+header { background: #343a40; padding: 12px 24px; }
+header a { color: #fff; font-weight: 600; text-decoration: none; }
+main { max-width: 1100px; margin: 24px auto; padding: 0 24px; }
+section { background: #fff; border: 1px solid #dee2e6; border-radius: 6px; padding: 16px 20px; margin-bottom: 20px; }
+table { width: 100%; border-collapse: collapse; }
+th, td { text-align: left; padding: 8px; border-bottom: 1px solid #dee2e6; vertical-align: top; }
+label { display: block; font-weight: 600; margin: 12px 0 4px; }
+input[type=text], select, textarea { width: 100%; box-sizing: border-box; padding: 6px 8px; border: 1px solid #ced4da; border-radius: 4px; }
+textarea { min-height: 90px; font-family: monospace; }
+button { padding: 6px 14px; border: 1px solid #0d6efd; background: #0d6efd; color: #fff; border-radius: 4px; cursor: pointer; }
+button.danger { border-color: #dc3545; background: #dc3545; }
+button.secondary { border-color: #6c757d; background: #6c757d; }
+form.inline { display: inline; }
+.filters { display: flex; gap: 12px; align-items: end; }
+.filters > * { flex: 1; }
+.filters > button, .filters > label.check { flex: 0 0 auto; }
+.message { background: #d1e7dd; border: 1px solid #badbcc; padding: 10px 14px; border-radius: 4px; margin-bottom: 20px; }
+.error { background: #f8d7da; border: 1px solid #f5c2c7; padding: 10px 14px; border-radius: 4px; margin-bottom: 20px; }
+.muted { color: #6c757d; }
+.deleted { color: #6c757d; text-decoration: line-through; }
+.pagination { margin-top: 12px; display: flex; gap: 12px; }
+`
+
+// render writes the page with the title and content
+func (h *handler) render(w http.ResponseWriter, r *http.Request, status int, title string, content ...hb.TagInterface) {
+	main := hb.Main().
+		ChildIf(r.URL.Query().Get("message") != "", hb.Div().Class("message").Text(r.URL.Query().Get("message"))).
+		Children(content)
+
+	page := hb.Webpage().
+		SetTitle(title+" | "+h.title).
+		AddStyle(styles).
+		Child(hb.Header().Child(hb.A().Href(h.url("/", nil)).Text(h.title))).
+		Child(main)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(page.ToHTML()))
+}
+
+// renderError writes an error page
+func (h *handler) renderError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	h.render(w, r, status, http.StatusText(status),
+		hb.Div().Class("error").Text(err.Error()),
+		hb.A().Href(h.url("/", nil)).Text("Back to the groups"))
+}
+
+// postButton returns a form posting to the action with a single button
+func postButton(action string, label string, class string) *hb.Tag {
+	return hb.Form().
+		Class("inline").
+		Method(http.MethodPost).
+		Action(action).
+		Child(hb.Button().Type("submit").ClassIf(class != "", class).Text(label))
+}
+
+// textField returns a labelled text input
+func textField(name string, label string, value string, required bool) *hb.Tag {
+	return hb.Wrap().
+		Child(hb.Label().Attr("for", name).Text(label)).
+		Child(hb.Input().Type("text").ID(name).Name(name).Value(value).Required(required))
+}
+
+// selectField returns a labelled select, the options are value and label pairs
+func selectField(name string, label string, value string, options [][2]string) *hb.Tag {
+	field := hb.Select().ID(name).Name(name)
+
+	for _, option := range options {
+		field.Child(hb.Option().Value(option[0]).Selected(option[0] == value).Text(option[1]))
+	}
+
+	return hb.Wrap().
+		Child(hb.Label().Attr("for", name).Text(label)).
+		Child(field)
+}
+
+// textAreaField returns a labelled textarea
+func textAreaField(name string, label string, value string, hint string) *hb.Tag {
+	return hb.Wrap().
+		Child(hb.Label().Attr("for", name).Text(label)).
+		ChildIf(hint != "", hb.Div().Class("muted").Text(hint)).
+		Child(hb.TextArea().ID(name).Name(name).Text(value))
+}
+
+// pagination returns the previous and next page links, keeping the query
+func (h *handler) pagination(path string, query url.Values, param string, page int, hasNext bool) *hb.Tag {
+	link := func(target int) string {
+		linkQuery := url.Values{}
+
+		for key, values := range query {
+			if key != param && key != "message" {
+				linkQuery[key] = values
+			}
+		}
+
+		linkQuery.Set(param, strconv.Itoa(target))
+
+		return h.url(path, linkQuery)
+	}
+
+	return hb.Div().Class("pagination").
+		ChildIf(page > 1, hb.A().Href(link(page-1)).Text("« Previous")).
+		ChildIf(hasNext, hb.A().Href(link(page+1)).Text("Next »"))
+}
+
+// pageFromQuery returns the 1-based page number from the query
+func pageFromQuery(query url.Values, param string) int {
+	page, err := strconv.Atoi(query.Get(param))
+
+	if err != nil || page < 1 {
+		return 1
+	}
+
+	return page
+}
@@ -0,0 +1,106 @@
+package groupstoreadmin
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gouniverse/groupstore"
+	"github.com/gouniverse/hb"
+	"github.com/samber/lo"
+)
+
+// auditActions are the options of the action filter of the audit page
+var auditActions = [][2]string{
+	{"", "Any action"},
+	{groupstore.AUDIT_ACTION_CREATED, "Created"},
+	{groupstore.AUDIT_ACTION_UPDATED, "Updated"},
+	{groupstore.AUDIT_ACTION_SOFT_DELETED, "Soft deleted"},
+	{groupstore.AUDIT_ACTION_RESTORED, "Restored"},
+	{groupstore.AUDIT_ACTION_DELETED, "Deleted"},
+	{groupstore.AUDIT_ACTION_MERGED, "Merged"},
+	{groupstore.AUDIT_ACTION_SPLIT, "Split"},
+}
+
+// auditPage lists the audit trail, the most recent changes first,
+// optionally of one group and of one action
+func (h *handler) auditPage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	groupID := strings.TrimSpace(query.Get("group_id"))
+	action := query.Get("action")
+	page := pageFromQuery(query, "page")
+
+	records, err := h.store.AuditList(r.Context(), groupstore.AuditListOptions{
+		GroupID: groupID,
+		Action:  action,
+		Offset:  (page - 1) * auditRecordsPerPage,
+		Limit:   auditRecordsPerPage + 1, // one more, to know if there is a next page
+	})
+
+	if errors.Is(err, groupstore.ErrAuditTrailDisabled) {
+		h.render(w, r, http.StatusOK, "Audit trail",
+			hb.Section().
+				Child(hb.H1().Text("Audit trail")).
+				Child(hb.P().Class("muted").Text("The audit trail is not enabled, set AuditTableName in the store options to record the changes.")))
+		return
+	}
+
+	if err != nil {
+		h.renderError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	hasNext := len(records) > auditRecordsPerPage
+	records = records[:min(len(records), auditRecordsPerPage)]
+
+	filters := hb.Form().Class("filters").Method(http.MethodGet).Action(h.url("/audit", nil)).
+		Child(hb.Div().Child(textField("group_id", "Group ID", groupID, false))).
+		Child(hb.Div().Child(selectField("action", "Action", action, auditActions))).
+		Child(hb.Button().Type("submit").Text("Filter"))
+
+	rows := hb.Tbody()
+
+	for _, record := range records {
+		rows.Child(hb.TR().
+			Child(hb.TD().Text(record.CreatedAt)).
+			Child(hb.TD().Text(record.Action)).
+			Child(hb.TD().Text(record.SubjectType + " " + record.SubjectID)).
+			Child(hb.TD().ChildIf(record.GroupID != "", hb.A().Href(h.url("/groups/"+record.GroupID, nil)).Text(record.GroupID))).
+			Child(hb.TD().Text(lo.Ternary(record.Actor == "", "-", record.Actor))).
+			Child(hb.TD().Class("muted").Text(formatAuditDetails(record.Details))))
+	}
+
+	if len(records) == 0 {
+		rows.Child(hb.TR().Child(hb.TD().Attr("colspan", "6").Class("muted").Text("No changes recorded")))
+	}
+
+	h.render(w, r, http.StatusOK, "Audit trail",
+		hb.Section().
+			Child(hb.H1().Text("Audit trail")).
+			Child(filters),
+		hb.Section().
+			Child(hb.Table().
+				Child(hb.Thead().Child(hb.TR().
+					Child(hb.TH().Text("Time (UTC)")).
+					Child(hb.TH().Text("Action")).
+					Child(hb.TH().Text("Record")).
+					Child(hb.TH().Text("Group")).
+					Child(hb.TH().Text("Actor")).
+					Child(hb.TH().Text("Details")))).
+				Child(rows)).
+			Child(h.pagination("/audit", query, "page", page, hasNext)))
+}
+
+// == HELPERS =================================================================
+
+// formatAuditDetails formats the details of the audit record as key=value
+// pairs, sorted by key
+func formatAuditDetails(details map[string]string) string {
+	keys := lo.Keys(details)
+	slices.Sort(keys)
+
+	return strings.Join(lo.Map(keys, func(key string, _ int) string {
+		return key + "=" + details[key]
+	}), " · ")
+}
@@ -0,0 +1,399 @@
+package groupstoreadmin
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/groupstore"
+	"github.com/gouniverse/hb"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+// groupStatuses are the selectable group statuses, as value and label pairs
+var groupStatuses = [][2]string{
+	{groupstore.GROUP_STATUS_ACTIVE, "Active"},
+	{groupstore.GROUP_STATUS_INACTIVE, "Inactive"},
+	{groupstore.GROUP_STATUS_ARCHIVED, "Archived"},
+}
+
+// groupForm holds the submitted values of the group form
+type groupForm struct {
+	Title  string
+	Handle string
+	Status string
+	Memo   string
+	Metas  string
+}
+
+// groupFormFromGroup returns the form values of the group
+func groupFormFromGroup(group groupstore.GroupInterface) groupForm {
+	metas, _ := group.Metas()
+
+	return groupForm{
+		Title:  group.Title(),
+		Handle: group.Handle(),
+		Status: group.Status(),
+		Memo:   group.Memo(),
+		Metas:  formatMetas(metas),
+	}
+}
+
+// groupFormFromRequest returns the submitted form values
+func groupFormFromRequest(r *http.Request) groupForm {
+	return groupForm{
+		Title:  strings.TrimSpace(r.PostForm.Get("title")),
+		Handle: strings.TrimSpace(r.PostForm.Get("handle")),
+		Status: r.PostForm.Get("status"),
+		Memo:   r.PostForm.Get("memo"),
+		Metas:  r.PostForm.Get("metas"),
+	}
+}
+
+// apply validates the form values and copies them to the group
+func (form groupForm) apply(group groupstore.GroupInterface) error {
+	if form.Title == "" {
+		return errors.New("title is required")
+	}
+
+	if !slices.ContainsFunc(groupStatuses, func(status [2]string) bool { return status[0] == form.Status }) {
+		return errors.New("status is invalid")
+	}
+
+	metas, err := parseMetas(form.Metas)
+
+	if err != nil {
+		return err
+	}
+
+	group.SetTitle(form.Title).
+		SetHandle(form.Handle).
+		SetStatus(form.Status).
+		SetMemo(form.Memo)
+
+	return group.SetMetas(metas)
+}
+
+func (h *handler) groupListPage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	search := strings.TrimSpace(query.Get("q"))
+	status := query.Get("status")
+	softDeletedIncluded := query.Get("deleted") == "1"
+	page := pageFromQuery(query, "page")
+
+	groupQuery := groupstore.NewGroupQuery().
+		SetOrderBy(groupstore.COLUMN_TITLE).
+		SetSortDirection(sb.ASC).
+		SetSoftDeletedIncluded(softDeletedIncluded).
+		SetOffset((page - 1) * groupsPerPage).
+		SetLimit(groupsPerPage + 1) // one more, to know if there is a next page
+
+	if search != "" {
+		groupQuery.SetTitleLike(search)
+	}
+
+	if status != "" {
+		groupQuery.SetStatus(status)
+	}
+
+	groups, err := h.store.GroupList(r.Context(), groupQuery)
+
+	if err != nil {
+		h.renderError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	hasNext := len(groups) > groupsPerPage
+	groups = groups[:min(len(groups), groupsPerPage)]
+
+	table := hb.Table().Child(hb.Thead().Child(hb.TR().
+		Child(hb.TH().Text("Title")).
+		Child(hb.TH().Text("Handle")).
+		Child(hb.TH().Text("Status")).
+		Child(hb.TH().Text("Members")).
+		Child(hb.TH().Text("Updated"))))
+
+	rows := hb.Tbody()
+
+	for _, group := range groups {
+		memberCount, err := h.store.RelationCount(r.Context(), groupstore.NewRelationQuery().SetGroupID(group.ID()))
+
+		if err != nil {
+			h.renderError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		rows.Child(hb.TR().
+			Child(hb.TD().Child(hb.A().
+				Href(h.url("/groups/"+group.ID(), nil)).
+				ClassIf(group.IsSoftDeleted(), "deleted").
+				Text(group.Title()))).
+			Child(hb.TD().Text(group.Handle())).
+			Child(hb.TD().Text(group.Status())).
+			Child(hb.TD().Text(strconv.FormatInt(memberCount, 10))).
+			Child(hb.TD().Text(formatDateTime(group.UpdatedAtCarbon()))))
+	}
+
+	if len(groups) == 0 {
+		rows.Child(hb.TR().Child(hb.TD().Attr("colspan", "5").Class("muted").Text("No groups found")))
+	}
+
+	filters := hb.Form().Class("filters").Method(http.MethodGet).Action(h.url("/", nil)).
+		Child(hb.Div().Child(textField("q", "Title", search, false))).
+		Child(hb.Div().Child(selectField("status", "Status", status, append([][2]string{{"", "Any"}}, groupStatuses...)))).
+		Child(hb.Label().Class("check").
+			Child(hb.Input().Type("checkbox").Name("deleted").Value("1").AttrIf(softDeletedIncluded, "checked", "checked")).
+			Text(" Include soft deleted")).
+		Child(hb.Button().Type("submit").Text("Search"))
+
+	h.render(w, r, http.StatusOK, "Groups",
+		hb.Section().
+			Child(hb.H1().Text("Groups")).
+			Child(hb.P().
+				Child(hb.A().Href(h.url("/groups/new", nil)).Text("+ New group")).
+				Text(" · ").
+				Child(hb.A().Href(h.url("/audit", nil)).Text("Audit trail"))).
+			Child(filters),
+		hb.Section().
+			Child(table.Child(rows)).
+			Child(h.pagination("/", query, "page", page, hasNext)))
+}
+
+func (h *handler) groupCreatePage(w http.ResponseWriter, r *http.Request) {
+	h.renderGroupCreatePage(w, r, http.StatusOK, groupForm{Status: groupstore.GROUP_STATUS_ACTIVE}, nil)
+}
+
+func (h *handler) groupCreate(w http.ResponseWriter, r *http.Request) {
+	if err := parseForm(w, r); err != nil {
+		h.renderError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	form := groupFormFromRequest(r)
+	group := groupstore.NewGroup()
+
+	if err := form.apply(group); err != nil {
+		h.renderGroupCreatePage(w, r, http.StatusBadRequest, form, err)
+		return
+	}
+
+	if err := h.store.GroupCreate(r.Context(), group); err != nil {
+		h.renderGroupCreatePage(w, r, http.StatusInternalServerError, form, err)
+		return
+	}
+
+	h.redirect(w, r, "/groups/"+group.ID(), "Group created")
+}
+
+func (h *handler) groupPage(w http.ResponseWriter, r *http.Request) {
+	group, err := h.groupFind(r.Context(), r.PathValue("id"))
+
+	if err != nil {
+		h.renderError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	if group == nil {
+		h.renderError(w, r, http.StatusNotFound, errors.New("group not found"))
+		return
+	}
+
+	h.renderGroupPage(w, r, http.StatusOK, group, groupFormFromGroup(group), nil, nil)
+}
+
+func (h *handler) groupUpdate(w http.ResponseWriter, r *http.Request) {
+	group, err := h.groupFind(r.Context(), r.PathValue("id"))
+
+	if err != nil {
+		h.renderError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	if group == nil {
+		h.renderError(w, r, http.StatusNotFound, errors.New("group not found"))
+		return
+	}
+
+	if err := parseForm(w, r); err != nil {
+		h.renderError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	form := groupFormFromRequest(r)
+
+	if err := form.apply(group); err != nil {
+		h.renderGroupPage(w, r, http.StatusBadRequest, group, form, err, nil)
+		return
+	}
+
+	if err := h.store.GroupUpdate(r.Context(), group); err != nil {
+		status := lo.Ternary(errors.Is(err, groupstore.ErrGroupStatusTransition), http.StatusBadRequest, http.StatusInternalServerError)
+		h.renderGroupPage(w, r, status, group, form, err, nil)
+		return
+	}
+
+	h.redirect(w, r, "/groups/"+group.ID(), "Group saved")
+}
+
+func (h *handler) groupSoftDelete(w http.ResponseWriter, r *http.Request) {
+	group, err := h.groupFind(r.Context(), r.PathValue("id"))
+
+	if err != nil {
+		h.renderError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	if group == nil {
+		h.renderError(w, r, http.StatusNotFound, errors.New("group not found"))
+		return
+	}
+
+	if !group.IsSoftDeleted() {
+		if err := h.store.GroupSoftDelete(r.Context(), group); err != nil {
+			h.renderError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.redirect(w, r, "/groups/"+group.ID(), "Group soft deleted")
+}
+
+func (h *handler) groupRestore(w http.ResponseWriter, r *http.Request) {
+	group, err := h.groupFind(r.Context(), r.PathValue("id"))
+
+	if err != nil {
+		h.renderError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	if group == nil {
+		h.renderError(w, r, http.StatusNotFound, errors.New("group not found"))
+		return
+	}
+
+	if group.IsSoftDeleted() {
+		group.SetSoftDeletedAt(sb.MAX_DATETIME)
+
+		if err := h.store.GroupUpdate(r.Context(), group); err != nil {
+			h.renderError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.redirect(w, r, "/groups/"+group.ID(), "Group restored")
+}
+
+// renderGroupCreatePage writes the new group page
+func (h *handler) renderGroupCreatePage(w http.ResponseWriter, r *http.Request, status int, form groupForm, formErr error) {
+	h.render(w, r, status, "New group",
+		hb.Section().
+			Child(hb.H1().Text("New group")).
+			Child(h.groupFormTag(h.url("/groups", nil), form, formErr, "Create")))
+}
+
+// renderGroupPage writes the group page, with the details form and the members
+func (h *handler) renderGroupPage(w http.ResponseWriter, r *http.Request, status int, group groupstore.GroupInterface, form groupForm, formErr error, memberErr error) {
+	members, err := h.membersSection(r, group, memberErr)
+
+	if err != nil {
+		h.renderError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	groupPath := "/groups/" + group.ID()
+
+	timestamps := hb.P().Class("muted").
+		Text("ID " + group.ID() + " · created " + formatDateTime(group.CreatedAtCarbon()) + " · updated " + formatDateTime(group.UpdatedAtCarbon())).
+		TextIf(group.IsSoftDeleted(), " · soft deleted "+formatDateTime(group.SoftDeletedAtCarbon())).
+		Text(" · ").
+		Child(hb.A().Href(h.url("/audit", url.Values{"group_id": {group.ID()}})).Text("audit trail"))
+
+	actions := hb.P().
+		ChildIf(!group.IsSoftDeleted(), postButton(h.url(groupPath+"/delete", nil), "Soft delete", "danger")).
+		ChildIf(group.IsSoftDeleted(), postButton(h.url(groupPath+"/restore", nil), "Restore", "secondary"))
+
+	h.render(w, r, status, group.Title(),
+		hb.Section().
+			Child(hb.H1().ClassIf(group.IsSoftDeleted(), "deleted").Text(group.Title())).
+			Child(timestamps).
+			Child(actions).
+			Child(h.groupFormTag(h.url(groupPath, nil), form, formErr, "Save")),
+		members)
+}
+
+// groupFormTag returns the group form posting to the action
+func (h *handler) groupFormTag(action string, form groupForm, formErr error, submitLabel string) *hb.Tag {
+	return hb.Form().Method(http.MethodPost).Action(action).
+		ChildIfF(formErr != nil, func() hb.TagInterface { return hb.Div().Class("error").Text(formErr.Error()) }).
+		Child(textField("title", "Title", form.Title, true)).
+		Child(textField("handle", "Handle", form.Handle, false)).
+		Child(selectField("status", "Status", form.Status, groupStatuses)).
+		Child(textAreaField("memo", "Memo", form.Memo, "")).
+		Child(textAreaField("metas", "Metas", form.Metas, "One key=value pair per line")).
+		Child(hb.P().Child(hb.Button().Type("submit").Text(submitLabel)))
+}
+
+// == HELPERS =================================================================
+
+// parseMetas parses the key=value lines of the metas field
+func parseMetas(text string) (map[string]string, error) {
+	metas := map[string]string{}
+
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+
+		if line == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+
+		if !found || key == "" {
+			return nil, errors.New("metas line " + strconv.Itoa(i+1) + " must be in the key=value format")
+		}
+
+		metas[key] = strings.TrimSpace(value)
+	}
+
+	return metas, nil
+}
+
+// formatMetas formats the metas as key=value lines, sorted by key
+func formatMetas(metas map[string]string) string {
+	keys := lo.Keys(metas)
+	slices.Sort(keys)
+
+	return strings.Join(lo.Map(keys, func(key string, _ int) string {
+		return key + "=" + metas[key]
+	}), "\n")
+}
+
+// formatDateTime formats the datetime for display
+func formatDateTime(datetime *carbon.Carbon) string {
+	return datetime.SetTimezone(carbon.UTC).ToDateTimeString()
+}
+
+// queryWith returns a copy of the query with the value set
+func queryWith(query url.Values, key string, value string) url.Values {
+	copied := url.Values{}
+
+	for k, v := range query {
+		if k != "message" {
+			copied[k] = v
+		}
+	}
+
+	if value == "" {
+		copied.Del(key)
+	} else {
+		copied.Set(key, value)
+	}
+
+	return copied
+}
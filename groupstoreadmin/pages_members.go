@@ -0,0 +1,193 @@
+package groupstoreadmin
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gouniverse/groupstore"
+	"github.com/gouniverse/hb"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+// defaultEntityType is the preselected entity type of new members
+const defaultEntityType = "user"
+
+func (h *handler) memberAdd(w http.ResponseWriter, r *http.Request) {
+	group, err := h.groupFind(r.Context(), r.PathValue("id"))
+
+	if err != nil {
+		h.renderError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	if group == nil {
+		h.renderError(w, r, http.StatusNotFound, errors.New("group not found"))
+		return
+	}
+
+	if err := parseForm(w, r); err != nil {
+		h.renderError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	entityType := strings.TrimSpace(r.PostForm.Get("entity_type"))
+	entityID := strings.TrimSpace(r.PostForm.Get("entity_id"))
+
+	if entityType == "" || entityID == "" {
+		h.renderGroupPage(w, r, http.StatusBadRequest, group, groupFormFromGroup(group), nil, errors.New("entity type and entity ID are required"))
+		return
+	}
+
+	existing, err := h.store.RelationFindByEntityAndGroup(r.Context(), entityType, entityID, group.ID())
+
+	if err != nil {
+		h.renderError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	if existing != nil {
+		h.redirect(w, r, "/groups/"+group.ID(), entityType+" "+entityID+" is already a member")
+		return
+	}
+
+	if _, err := h.store.RelationEnsure(r.Context(), entityType, entityID, group.ID()); err != nil {
+		h.renderError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.redirect(w, r, "/groups/"+group.ID(), "Member added")
+}
+
+func (h *handler) memberSoftDelete(w http.ResponseWriter, r *http.Request) {
+	relation, err := h.relationFind(r.Context(), r.PathValue("id"), r.PathValue("relationID"))
+
+	if err != nil {
+		h.renderError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	if relation == nil {
+		h.renderError(w, r, http.StatusNotFound, errors.New("member not found"))
+		return
+	}
+
+	if !relation.IsSoftDeleted() {
+		if err := h.store.RelationSoftDelete(r.Context(), relation); err != nil {
+			h.renderError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	h.redirect(w, r, "/groups/"+relation.GroupID(), "Member removed")
+}
+
+func (h *handler) memberRestore(w http.ResponseWriter, r *http.Request) {
+	relation, err := h.relationFind(r.Context(), r.PathValue("id"), r.PathValue("relationID"))
+
+	if err != nil {
+		h.renderError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	if relation == nil {
+		h.renderError(w, r, http.StatusNotFound, errors.New("member not found"))
+		return
+	}
+
+	if !relation.IsSoftDeleted() {
+		h.redirect(w, r, "/groups/"+relation.GroupID(), "")
+		return
+	}
+
+	// the entity may have been added again since it was removed
+	existing, err := h.store.RelationFindByEntityAndGroup(r.Context(), relation.EntityType(), relation.EntityID(), relation.GroupID())
+
+	if err != nil {
+		h.renderError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	if existing != nil {
+		h.redirect(w, r, "/groups/"+relation.GroupID(), relation.EntityType()+" "+relation.EntityID()+" is already a member")
+		return
+	}
+
+	relation.SetSoftDeletedAt(sb.MAX_DATETIME)
+
+	if err := h.store.RelationUpdate(r.Context(), relation); err != nil {
+		h.renderError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.redirect(w, r, "/groups/"+relation.GroupID(), "Member restored")
+}
+
+// membersSection returns the members section of the group page, with
+// the add member form and the paginated list of the members
+func (h *handler) membersSection(r *http.Request, group groupstore.GroupInterface, memberErr error) (*hb.Tag, error) {
+	query := r.URL.Query()
+	softDeletedIncluded := query.Get("members_deleted") == "1"
+	page := pageFromQuery(query, "members_page")
+	groupPath := "/groups/" + group.ID()
+
+	relations, err := h.store.RelationList(r.Context(), groupstore.NewRelationQuery().
+		SetGroupID(group.ID()).
+		SetSoftDeletedIncluded(softDeletedIncluded).
+		SetOrderBy(groupstore.COLUMN_CREATED_AT).
+		SetSortDirection(sb.DESC).
+		SetOffset((page - 1) * membersPerPage).
+		SetLimit(membersPerPage + 1)) // one more, to know if there is a next page
+
+	if err != nil {
+		return nil, err
+	}
+
+	hasNext := len(relations) > membersPerPage
+	relations = relations[:min(len(relations), membersPerPage)]
+
+	addForm := hb.Form().Class("filters").Method(http.MethodPost).Action(h.url(groupPath+"/members", nil)).
+		Child(hb.Div().Child(textField("entity_type", "Entity type", defaultEntityType, true))).
+		Child(hb.Div().Child(textField("entity_id", "Entity ID", "", true))).
+		Child(hb.Button().Type("submit").Text("Add member"))
+
+	rows := hb.Tbody()
+
+	for _, relation := range relations {
+		relationPath := groupPath + "/members/" + relation.ID()
+
+		rows.Child(hb.TR().
+			Child(hb.TD().Text(relation.EntityType())).
+			Child(hb.TD().ClassIf(relation.IsSoftDeleted(), "deleted").Text(relation.EntityID())).
+			Child(hb.TD().Text(formatDateTime(relation.CreatedAtCarbon()))).
+			Child(hb.TD().
+				ChildIf(!relation.IsSoftDeleted(), postButton(h.url(relationPath+"/delete", nil), "Remove", "danger")).
+				ChildIf(relation.IsSoftDeleted(), postButton(h.url(relationPath+"/restore", nil), "Restore", "secondary"))))
+	}
+
+	if len(relations) == 0 {
+		rows.Child(hb.TR().Child(hb.TD().Attr("colspan", "4").Class("muted").Text("No members")))
+	}
+
+	toggleQuery := queryWith(query, "members_page", "")
+	toggleQuery = queryWith(toggleQuery, "members_deleted", lo.Ternary(softDeletedIncluded, "", "1"))
+
+	toggle := hb.A().
+		Href(h.url(groupPath, toggleQuery)).
+		Text(lo.Ternary(softDeletedIncluded, "Hide removed members", "Show removed members"))
+
+	return hb.Section().
+		Child(hb.H2().Text("Members")).
+		ChildIfF(memberErr != nil, func() hb.TagInterface { return hb.Div().Class("error").Text(memberErr.Error()) }).
+		Child(addForm).
+		Child(hb.P().Child(toggle)).
+		Child(hb.Table().
+			Child(hb.Thead().Child(hb.TR().
+				Child(hb.TH().Text("Entity type")).
+				Child(hb.TH().Text("Entity ID")).
+				Child(hb.TH().Text("Added")).
+				Child(hb.TH()))).
+			Child(rows)).
+		Child(h.pagination(groupPath, query, "members_page", page, hasNext)), nil
+}
@@ -0,0 +1,188 @@
+package groupstoreformats
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gouniverse/groupstore"
+	"github.com/samber/lo"
+)
+
+// EtcGroupParseOptions define how the /etc/group lines map to groups and relations
+type EtcGroupParseOptions struct {
+	// EntityType is the entity type of the members, defaults to ENTITY_TYPE_USER
+	EntityType string
+
+	// EntityID maps a user name to the entity ID, defaults to the user name
+	EntityID func(userName string) (string, error)
+
+	// Handle maps a group name to the group handle, defaults to the group name
+	Handle func(groupName string) string
+}
+
+// EtcGroupWriteOptions define how the groups and relations map to /etc/group lines
+type EtcGroupWriteOptions struct {
+	// EntityType is the entity type of the members, defaults to ENTITY_TYPE_USER
+	EntityType string
+
+	// UserName maps an entity ID to the user name, defaults to the entity ID
+	UserName func(entityID string) (string, error)
+
+	// GIDStart is the first numeric group ID given to the groups without
+	// the META_GID meta, defaults to 10000
+	GIDStart int
+}
+
+// ParseEtcGroup reads a Unix group file, with "name:password:GID:user,user"
+// lines, the GID is kept in the META_GID meta, and the password is ignored
+func ParseEtcGroup(r io.Reader, options EtcGroupParseOptions) (Records, error) {
+	options.EntityType = lo.CoalesceOrEmpty(options.EntityType, ENTITY_TYPE_USER)
+
+	records := Records{}
+	groupNames := map[string]bool{}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+
+		// comments and NIS compat entries
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "+") || strings.HasPrefix(text, "-") {
+			continue
+		}
+
+		fields := strings.Split(text, ":")
+
+		if len(fields) != 4 {
+			return Records{}, etcGroupError(line, "expected 4 fields, found "+strconv.Itoa(len(fields)))
+		}
+
+		name, gid, members := fields[0], fields[2], fields[3]
+
+		if name == "" {
+			return Records{}, etcGroupError(line, "group name is empty")
+		}
+
+		if groupNames[name] {
+			return Records{}, etcGroupError(line, "group is listed more than once: "+name)
+		}
+
+		groupNames[name] = true
+
+		if _, err := strconv.Atoi(gid); err != nil {
+			return Records{}, etcGroupError(line, "GID is not a number: "+gid)
+		}
+
+		handle := name
+
+		if options.Handle != nil {
+			handle = options.Handle(name)
+		}
+
+		group, err := newGroup(handle, name, "", map[string]string{META_GID: gid})
+
+		if err != nil {
+			return Records{}, err
+		}
+
+		records.Groups = append(records.Groups, group)
+
+		userNames := lo.Uniq(lo.Compact(lo.Map(strings.Split(members, ","), func(userName string, _ int) string {
+			return strings.TrimSpace(userName)
+		})))
+
+		for _, userName := range userNames {
+			entityID := userName
+
+			if options.EntityID != nil {
+				if entityID, err = options.EntityID(userName); err != nil {
+					return Records{}, etcGroupError(line, err.Error())
+				}
+			}
+
+			records.Relations = append(records.Relations, newRelation(options.EntityType, entityID, group.ID()))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return Records{}, err
+	}
+
+	return records, nil
+}
+
+// WriteEtcGroup writes the groups, which are not soft deleted, as Unix
+// group file lines, named by their handles, with the members of the entity type
+func WriteEtcGroup(ctx context.Context, w io.Writer, store groupstore.StoreInterface, options EtcGroupWriteOptions) error {
+	options.EntityType = lo.CoalesceOrEmpty(options.EntityType, ENTITY_TYPE_USER)
+	options.GIDStart = lo.CoalesceOrEmpty(options.GIDStart, 10000)
+
+	groups, err := exportGroups(ctx, store, options.EntityType)
+
+	if err != nil {
+		return err
+	}
+
+	gids := &gidAssigner{used: map[int]bool{}, next: options.GIDStart}
+
+	for _, exported := range groups {
+		if gid, err := strconv.Atoi(exported.metas[META_GID]); err == nil {
+			gids.used[gid] = true
+		}
+	}
+
+	out := bufio.NewWriter(w)
+
+	for _, exported := range groups {
+		name := exported.group.Handle()
+
+		if !etcGroupNameValid(name) {
+			return errors.New("at etc group write > invalid group name, group " + exported.group.ID() + ": " + name)
+		}
+
+		gid, err := strconv.Atoi(exported.metas[META_GID])
+
+		if err != nil {
+			gid = gids.assign()
+		}
+
+		userNames := make([]string, 0, len(exported.entityIDs))
+
+		for _, entityID := range exported.entityIDs {
+			userName := entityID
+
+			if options.UserName != nil {
+				if userName, err = options.UserName(entityID); err != nil {
+					return err
+				}
+			}
+
+			if !etcGroupNameValid(userName) {
+				return errors.New("at etc group write > invalid user name, group " + name + ": " + userName)
+			}
+
+			userNames = append(userNames, userName)
+		}
+
+		out.WriteString(name + ":x:" + strconv.Itoa(gid) + ":" + strings.Join(userNames, ",") + "\n")
+	}
+
+	return out.Flush()
+}
+
+// == HELPERS =================================================================
+
+func etcGroupError(line int, message string) error {
+	return errors.New("at etc group parse > line " + strconv.Itoa(line) + ": " + message)
+}
+
+// etcGroupNameValid checks the name is not empty, and has no separators or spaces
+func etcGroupNameValid(name string) bool {
+	return name != "" && !strings.ContainsAny(name, ":, \t\r\n")
+}
@@ -0,0 +1,138 @@
+package groupstoreformats
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/gouniverse/groupstore"
+	_ "modernc.org/sqlite"
+)
+
+func initStore(t *testing.T) groupstore.StoreInterface {
+	db, err := sql.Open("sqlite", ":memory:?parseTime=true")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// each connection has its own in-memory database
+	db.SetMaxOpenConns(1)
+
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	store, err := groupstore.NewStore(groupstore.NewStoreOptions{
+		DB:                           db,
+		GroupTableName:               "groups_group_table",
+		GroupEntityRelationTableName: "groups_group_entity_relation_table",
+		AutomigrateEnabled:           true,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	return store
+}
+
+const testEtcGroup = `# local groups
+root:x:0:
+wheel:x:10:alice, bob
+developers:x:1001:bob,carol,bob
++nisgroup
+`
+
+func TestParseEtcGroup(t *testing.T) {
+	records, err := ParseEtcGroup(strings.NewReader(testEtcGroup), EtcGroupParseOptions{
+		EntityID: func(userName string) (string, error) { return "USER_" + userName, nil },
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(records.Groups) != 3 {
+		t.Fatal("groups must be 3, found:", len(records.Groups))
+	}
+
+	if records.Groups[1].Handle() != "wheel" || records.Groups[1].Meta(META_GID) != "10" {
+		t.Fatal("unexpected group:", records.Groups[1].Data())
+	}
+
+	if len(records.Relations) != 4 {
+		t.Fatal("relations must be 4, found:", len(records.Relations))
+	}
+
+	relation := records.Relations[0]
+
+	if relation.EntityType() != ENTITY_TYPE_USER || relation.EntityID() != "USER_alice" || relation.GroupID() != records.Groups[1].ID() {
+		t.Fatal("unexpected relation:", relation.Data())
+	}
+
+	invalid := []string{
+		"wheel:x:10\n",
+		"wheel:x:ten:alice\n",
+		"wheel:x:10:alice\nwheel:x:11:bob\n",
+		":x:10:alice\n",
+	}
+
+	for _, text := range invalid {
+		if _, err := ParseEtcGroup(strings.NewReader(text), EtcGroupParseOptions{}); err == nil {
+			t.Fatal("must return error for:", text)
+		}
+	}
+}
+
+func TestWriteEtcGroup(t *testing.T) {
+	store := initStore(t)
+
+	records, err := ParseEtcGroup(strings.NewReader(testEtcGroup), EtcGroupParseOptions{})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := records.Save(context.Background(), store); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// saving again matches the groups by handle
+	records, _ = ParseEtcGroup(strings.NewReader(testEtcGroup), EtcGroupParseOptions{})
+
+	if err := records.Save(context.Background(), store); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	staff := groupstore.NewGroup().SetHandle("staff").SetTitle("Staff").SetStatus(groupstore.GROUP_STATUS_ACTIVE)
+
+	if err := store.GroupCreate(context.Background(), staff); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := store.RelationEnsure(context.Background(), ENTITY_TYPE_USER, "dave", staff.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	buffer := &bytes.Buffer{}
+
+	err = WriteEtcGroup(context.Background(), buffer, store, EtcGroupWriteOptions{GIDStart: 1001})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := "developers:x:1001:bob,carol\n" +
+		"root:x:0:\n" +
+		"staff:x:1002:dave\n" +
+		"wheel:x:10:alice,bob\n"
+
+	if buffer.String() != expected {
+		t.Fatal("unexpected output:", buffer.String())
+	}
+}
@@ -0,0 +1,291 @@
+package groupstoreformats
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gouniverse/groupstore"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+// GraphOptions define which part of the membership graph is rendered
+type GraphOptions struct {
+	// RootGroup is the ID or handle of the group to start from, defaults
+	// to all the groups, which are not nested in another group
+	RootGroup string
+
+	// Depth is the number of levels rendered below the roots, 0 for all
+	Depth int
+
+	// EntityTypes are the entity types of the rendered leaf entities,
+	// defaults to all
+	EntityTypes []string
+
+	// LeavesIncluded renders the entities, which are not groups, otherwise
+	// only the groups nested with groupstore.ENTITY_TYPE_GROUP are rendered
+	LeavesIncluded bool
+}
+
+// graphNode is a group or an entity of the membership graph
+type graphNode struct {
+	key     string // unique, i.e. "group:ID" or "user:123456"
+	label   string
+	isGroup bool
+}
+
+// graphEdge is a membership of the "to" node in the "from" group
+type graphEdge struct {
+	from string
+	to   string
+}
+
+// graph is the membership graph, with the nodes and edges in the order found
+type graph struct {
+	nodes []graphNode
+	edges []graphEdge
+}
+
+// WriteDOT renders the membership graph as a Graphviz DOT digraph, the
+// groups as boxes and the entities as ellipses, with an edge from each
+// group to its members
+func WriteDOT(ctx context.Context, w io.Writer, store groupstore.StoreInterface, options GraphOptions) error {
+	g, err := buildGraph(ctx, store, options)
+
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(w)
+	out.WriteString("digraph groups {\n")
+	out.WriteString("  rankdir=LR;\n")
+
+	for _, node := range g.nodes {
+		shape := lo.Ternary(node.isGroup, "box", "ellipse")
+		out.WriteString("  " + dotQuote(node.key) + " [label=" + dotQuote(node.label) + ", shape=" + shape + "];\n")
+	}
+
+	for _, edge := range g.edges {
+		out.WriteString("  " + dotQuote(edge.from) + " -> " + dotQuote(edge.to) + ";\n")
+	}
+
+	out.WriteString("}\n")
+
+	return out.Flush()
+}
+
+// WriteMermaid renders the membership graph as a Mermaid flowchart, the
+// groups as rectangles and the entities as stadiums, with an arrow from
+// each group to its members
+func WriteMermaid(ctx context.Context, w io.Writer, store groupstore.StoreInterface, options GraphOptions) error {
+	g, err := buildGraph(ctx, store, options)
+
+	if err != nil {
+		return err
+	}
+
+	// the keys are not valid Mermaid IDs, the nodes are numbered instead
+	ids := map[string]string{}
+
+	out := bufio.NewWriter(w)
+	out.WriteString("flowchart LR\n")
+
+	for index, node := range g.nodes {
+		id := "n" + strconv.Itoa(index+1)
+		ids[node.key] = id
+
+		if node.isGroup {
+			out.WriteString("  " + id + "[\"" + mermaidEscape(node.label) + "\"]\n")
+		} else {
+			out.WriteString("  " + id + "([\"" + mermaidEscape(node.label) + "\"])\n")
+		}
+	}
+
+	for _, edge := range g.edges {
+		out.WriteString("  " + ids[edge.from] + " --> " + ids[edge.to] + "\n")
+	}
+
+	return out.Flush()
+}
+
+// == PRIVATE FUNCTIONS =======================================================
+
+// buildGraph walks the groups, which are not soft deleted, breadth first
+// from the roots, following the nested groups, cycles are rendered once
+func buildGraph(ctx context.Context, store groupstore.StoreInterface, options GraphOptions) (graph, error) {
+	if store == nil {
+		return graph{}, errors.New("groupstoreformats: store is nil")
+	}
+
+	if options.Depth < 0 {
+		return graph{}, errors.New("at graph > depth cannot be negative")
+	}
+
+	groups, err := store.GroupList(ctx, groupstore.NewGroupQuery().
+		SetOrderBy(groupstore.COLUMN_HANDLE).
+		SetSortDirection(sb.ASC))
+
+	if err != nil {
+		return graph{}, err
+	}
+
+	groupsByID := lo.KeyBy(groups, func(group groupstore.GroupInterface) string { return group.ID() })
+
+	// the relations by group, collected before walking the graph
+	relationsByGroup := map[string][]groupstore.RelationInterface{}
+
+	relations := store.RelationIter(ctx, groupstore.NewRelationQuery().
+		SetOrderBy(groupstore.COLUMN_CREATED_AT).
+		SetSortDirection(sb.ASC))
+
+	for relation, err := range relations {
+		if err != nil {
+			return graph{}, err
+		}
+
+		relationsByGroup[relation.GroupID()] = append(relationsByGroup[relation.GroupID()], relation)
+	}
+
+	roots, err := graphRoots(groups, relationsByGroup, options.RootGroup)
+
+	if err != nil {
+		return graph{}, err
+	}
+
+	g := graph{}
+	added := map[string]bool{}
+
+	addNode := func(node graphNode) {
+		if !added[node.key] {
+			added[node.key] = true
+			g.nodes = append(g.nodes, node)
+		}
+	}
+
+	type queued struct {
+		group groupstore.GroupInterface
+		depth int
+	}
+
+	queue := lo.Map(roots, func(group groupstore.GroupInterface, _ int) queued { return queued{group, 0} })
+	visited := map[string]bool{}
+
+	for _, root := range roots {
+		addNode(graphGroupNode(root))
+		visited[root.ID()] = true
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if options.Depth > 0 && current.depth >= options.Depth {
+			continue
+		}
+
+		from := graphGroupKey(current.group.ID())
+
+		for _, relation := range relationsByGroup[current.group.ID()] {
+			if relation.EntityType() == groupstore.ENTITY_TYPE_GROUP {
+				nested := groupsByID[relation.EntityID()]
+
+				if nested == nil {
+					continue // soft deleted, or missing
+				}
+
+				addNode(graphGroupNode(nested))
+				g.edges = append(g.edges, graphEdge{from: from, to: graphGroupKey(nested.ID())})
+
+				if !visited[nested.ID()] {
+					visited[nested.ID()] = true
+					queue = append(queue, queued{nested, current.depth + 1})
+				}
+
+				continue
+			}
+
+			if !options.LeavesIncluded {
+				continue
+			}
+
+			if len(options.EntityTypes) > 0 && !slices.Contains(options.EntityTypes, relation.EntityType()) {
+				continue
+			}
+
+			node := graphNode{
+				key:   relation.EntityType() + ":" + relation.EntityID(),
+				label: relation.EntityType() + " " + relation.EntityID(),
+			}
+
+			addNode(node)
+			g.edges = append(g.edges, graphEdge{from: from, to: node.key})
+		}
+	}
+
+	return g, nil
+}
+
+// graphRoots returns the root group, found by ID or handle, or else the
+// groups, which are not nested in another group
+func graphRoots(groups []groupstore.GroupInterface, relationsByGroup map[string][]groupstore.RelationInterface, rootGroup string) ([]groupstore.GroupInterface, error) {
+	if rootGroup != "" {
+		root, found := lo.Find(groups, func(group groupstore.GroupInterface) bool {
+			return group.ID() == rootGroup || group.Handle() == rootGroup
+		})
+
+		if !found {
+			return nil, errors.New("at graph > root group not found: " + rootGroup)
+		}
+
+		return []groupstore.GroupInterface{root}, nil
+	}
+
+	nested := map[string]bool{}
+
+	for _, relations := range relationsByGroup {
+		for _, relation := range relations {
+			if relation.EntityType() == groupstore.ENTITY_TYPE_GROUP {
+				nested[relation.EntityID()] = true
+			}
+		}
+	}
+
+	return lo.Filter(groups, func(group groupstore.GroupInterface, _ int) bool {
+		return !nested[group.ID()]
+	}), nil
+}
+
+// == HELPERS =================================================================
+
+func graphGroupKey(groupID string) string {
+	return groupstore.ENTITY_TYPE_GROUP + ":" + groupID
+}
+
+func graphGroupNode(group groupstore.GroupInterface) graphNode {
+	label := lo.CoalesceOrEmpty(group.Title(), group.Handle(), group.ID())
+
+	if group.Handle() != "" && group.Handle() != label {
+		label += " (" + group.Handle() + ")"
+	}
+
+	return graphNode{key: graphGroupKey(group.ID()), label: label, isGroup: true}
+}
+
+// dotQuote returns the DOT quoted string
+func dotQuote(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// mermaidEscape escapes the characters of a quoted Mermaid label
+func mermaidEscape(value string) string {
+	replacer := strings.NewReplacer(`"`, "#quot;", "\n", " ", "\r", "")
+
+	return replacer.Replace(value)
+}
@@ -0,0 +1,110 @@
+package groupstoreformats
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/gouniverse/groupstore"
+)
+
+func seedGraph(t *testing.T, store groupstore.StoreInterface) map[string]groupstore.GroupInterface {
+	groups := map[string]groupstore.GroupInterface{}
+
+	for _, handle := range []string{"company", "sales", "sales-eu"} {
+		group := groupstore.NewGroup().SetHandle(handle).SetTitle(handle).SetStatus(groupstore.GROUP_STATUS_ACTIVE)
+
+		if err := store.GroupCreate(context.Background(), group); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		groups[handle] = group
+	}
+
+	relations := [][3]string{
+		{groupstore.ENTITY_TYPE_GROUP, groups["sales"].ID(), groups["company"].ID()},
+		{groupstore.ENTITY_TYPE_GROUP, groups["sales-eu"].ID(), groups["sales"].ID()},
+		{"user", "alice", groups["sales"].ID()},
+		{"user", "bob", groups["sales-eu"].ID()},
+		{"product", "P1", groups["sales-eu"].ID()},
+	}
+
+	for _, relation := range relations {
+		if _, err := store.RelationEnsure(context.Background(), relation[0], relation[1], relation[2]); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	return groups
+}
+
+func TestWriteDOT(t *testing.T) {
+	store := initStore(t)
+	groups := seedGraph(t, store)
+
+	buffer := &bytes.Buffer{}
+
+	err := WriteDOT(context.Background(), buffer, store, GraphOptions{
+		LeavesIncluded: true,
+		EntityTypes:    []string{"user"},
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	company := `"group:` + groups["company"].ID() + `"`
+	sales := `"group:` + groups["sales"].ID() + `"`
+	salesEU := `"group:` + groups["sales-eu"].ID() + `"`
+
+	expected := "digraph groups {\n" +
+		"  rankdir=LR;\n" +
+		"  " + company + ` [label="company", shape=box];` + "\n" +
+		"  " + sales + ` [label="sales", shape=box];` + "\n" +
+		"  " + salesEU + ` [label="sales-eu", shape=box];` + "\n" +
+		`  "user:alice" [label="user alice", shape=ellipse];` + "\n" +
+		`  "user:bob" [label="user bob", shape=ellipse];` + "\n" +
+		"  " + company + " -> " + sales + ";\n" +
+		"  " + sales + " -> " + salesEU + ";\n" +
+		"  " + sales + ` -> "user:alice";` + "\n" +
+		"  " + salesEU + ` -> "user:bob";` + "\n" +
+		"}\n"
+
+	if buffer.String() != expected {
+		t.Fatal("unexpected output:", buffer.String())
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	store := initStore(t)
+	seedGraph(t, store)
+
+	buffer := &bytes.Buffer{}
+
+	err := WriteMermaid(context.Background(), buffer, store, GraphOptions{
+		RootGroup:      "sales",
+		Depth:          1,
+		LeavesIncluded: true,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := "flowchart LR\n" +
+		`  n1["sales"]` + "\n" +
+		`  n2["sales-eu"]` + "\n" +
+		`  n3(["user alice"])` + "\n" +
+		"  n1 --> n2\n" +
+		"  n1 --> n3\n"
+
+	if buffer.String() != expected {
+		t.Fatal("unexpected output:", buffer.String())
+	}
+
+	err = WriteMermaid(context.Background(), buffer, store, GraphOptions{RootGroup: "unknown"})
+
+	if err == nil {
+		t.Fatal("must return error as the root group does not exist")
+	}
+}
@@ -0,0 +1,501 @@
+package groupstoreformats
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gouniverse/groupstore"
+	"github.com/samber/lo"
+)
+
+// LDIF_OBJECT_CLASS_GROUP_OF_NAMES is the object class of groups listing
+// their members by DN, in "member" attributes
+const LDIF_OBJECT_CLASS_GROUP_OF_NAMES = "groupOfNames"
+
+// LDIF_OBJECT_CLASS_GROUP_OF_UNIQUE_NAMES is the object class of groups
+// listing their members by DN, in "uniqueMember" attributes
+const LDIF_OBJECT_CLASS_GROUP_OF_UNIQUE_NAMES = "groupOfUniqueNames"
+
+// LDIF_OBJECT_CLASS_POSIX_GROUP is the object class of groups listing
+// their members by user name, in "memberUid" attributes
+const LDIF_OBJECT_CLASS_POSIX_GROUP = "posixGroup"
+
+// LDIFParseOptions define how the LDIF entries map to groups and relations
+type LDIFParseOptions struct {
+	// EntityType is the entity type of the members, defaults to ENTITY_TYPE_USER
+	EntityType string
+
+	// EntityID maps a member to the entity ID. The member is a DN for
+	// groupOfNames and groupOfUniqueNames, and a user name for posixGroup.
+	// Defaults to the value of the first RDN of DNs (i.e. "jdoe" for
+	// "uid=jdoe,ou=people,dc=example,dc=com"), and to the user name
+	EntityID func(member string) (string, error)
+
+	// Handle maps the DN and the common name of a group to the group
+	// handle, defaults to the common name
+	Handle func(dn string, cn string) string
+}
+
+// LDIFWriteOptions define how the groups and relations map to LDIF entries
+type LDIFWriteOptions struct {
+	// BaseDN is the DN under which the groups are written, i.e.
+	// "ou=groups,dc=example,dc=com", required. Groups with the META_LDAP_DN
+	// meta keep their DN
+	BaseDN string
+
+	// ObjectClass is LDIF_OBJECT_CLASS_GROUP_OF_NAMES (default),
+	// LDIF_OBJECT_CLASS_GROUP_OF_UNIQUE_NAMES or LDIF_OBJECT_CLASS_POSIX_GROUP
+	ObjectClass string
+
+	// MemberBaseDN is the DN under which the members are, i.e.
+	// "ou=people,dc=example,dc=com", required except for posixGroup
+	MemberBaseDN string
+
+	// MemberRDNAttribute is the RDN attribute of the member DNs, defaults to "uid"
+	MemberRDNAttribute string
+
+	// EntityType is the entity type of the members, defaults to ENTITY_TYPE_USER
+	EntityType string
+
+	// UserName maps an entity ID to the RDN value or the user name of the
+	// member, defaults to the entity ID
+	UserName func(entityID string) (string, error)
+
+	// GIDStart is the first gidNumber given to the posix groups without
+	// the META_GID meta, defaults to 10000
+	GIDStart int
+}
+
+// ldifEntry is an LDIF entry, with its attributes in the order read
+type ldifEntry struct {
+	line       int
+	attributes [][2]string
+}
+
+// values returns the values of the attribute, the name is case-insensitive
+func (e ldifEntry) values(name string) []string {
+	values := []string{}
+
+	for _, attribute := range e.attributes {
+		if strings.EqualFold(attribute[0], name) {
+			values = append(values, attribute[1])
+		}
+	}
+
+	return values
+}
+
+// value returns the first value of the attribute, or an empty string
+func (e ldifEntry) value(name string) string {
+	values := e.values(name)
+
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// ParseLDIF reads the groupOfNames, groupOfUniqueNames and posixGroup
+// entries of an LDIF file, other entries are skipped
+//
+// Business logic:
+//   - the common name is the title, and the description the memo
+//   - the DN is kept in the META_LDAP_DN meta, and the gidNumber in META_GID
+//   - the members, which are groups in the same file, are nested with
+//     groupstore.ENTITY_TYPE_GROUP relations
+func ParseLDIF(r io.Reader, options LDIFParseOptions) (Records, error) {
+	options.EntityType = lo.CoalesceOrEmpty(options.EntityType, ENTITY_TYPE_USER)
+
+	entries, err := ldifRead(r)
+
+	if err != nil {
+		return Records{}, err
+	}
+
+	groupClasses := []string{
+		strings.ToLower(LDIF_OBJECT_CLASS_GROUP_OF_NAMES),
+		strings.ToLower(LDIF_OBJECT_CLASS_GROUP_OF_UNIQUE_NAMES),
+		strings.ToLower(LDIF_OBJECT_CLASS_POSIX_GROUP),
+	}
+
+	entries = lo.Filter(entries, func(entry ldifEntry, _ int) bool {
+		return lo.SomeBy(entry.values("objectClass"), func(objectClass string) bool {
+			return lo.Contains(groupClasses, strings.ToLower(objectClass))
+		})
+	})
+
+	records := Records{}
+
+	// the groups by DN, for nesting the member groups
+	groupsByDN := map[string]groupstore.GroupInterface{}
+
+	for _, entry := range entries {
+		dn := entry.value("dn")
+		cn := entry.value("cn")
+
+		if cn == "" {
+			cn = ldifFirstRDNValue(dn)
+		}
+
+		if cn == "" {
+			return Records{}, ldifError(entry.line, "group without a common name: "+dn)
+		}
+
+		handle := cn
+
+		if options.Handle != nil {
+			handle = options.Handle(dn, cn)
+		}
+
+		metas := map[string]string{META_LDAP_DN: dn}
+
+		if gid := entry.value("gidNumber"); gid != "" {
+			metas[META_GID] = gid
+		}
+
+		group, err := newGroup(handle, cn, entry.value("description"), metas)
+
+		if err != nil {
+			return Records{}, err
+		}
+
+		records.Groups = append(records.Groups, group)
+		groupsByDN[ldifNormalizeDN(dn)] = group
+	}
+
+	for index, entry := range entries {
+		group := records.Groups[index]
+
+		memberDNs := lo.Compact(append(entry.values("member"), entry.values("uniqueMember")...))
+
+		members := lo.Filter(memberDNs, func(member string, _ int) bool {
+			return groupsByDN[ldifNormalizeDN(member)] == nil
+		})
+
+		members = append(members, lo.Compact(entry.values("memberUid"))...)
+
+		for _, memberDN := range memberDNs {
+			if nested := groupsByDN[ldifNormalizeDN(memberDN)]; nested != nil {
+				records.Relations = append(records.Relations, newRelation(groupstore.ENTITY_TYPE_GROUP, nested.ID(), group.ID()))
+			}
+		}
+
+		entityIDs := []string{}
+
+		for _, member := range members {
+			var err error
+			entityID := member
+
+			if options.EntityID != nil {
+				entityID, err = options.EntityID(member)
+			} else if strings.Contains(member, "=") {
+				entityID = ldifFirstRDNValue(member)
+			}
+
+			if err != nil {
+				return Records{}, ldifError(entry.line, err.Error())
+			}
+
+			if entityID == "" {
+				return Records{}, ldifError(entry.line, "member without an entity ID: "+member)
+			}
+
+			entityIDs = append(entityIDs, entityID)
+		}
+
+		for _, entityID := range lo.Uniq(entityIDs) {
+			records.Relations = append(records.Relations, newRelation(options.EntityType, entityID, group.ID()))
+		}
+	}
+
+	return records, nil
+}
+
+// WriteLDIF writes the groups, which are not soft deleted, as LDIF entries
+// of the object class, named by their handles, with the members of the
+// entity type. The description is the memo, or else the title
+func WriteLDIF(ctx context.Context, w io.Writer, store groupstore.StoreInterface, options LDIFWriteOptions) error {
+	options.ObjectClass = lo.CoalesceOrEmpty(options.ObjectClass, LDIF_OBJECT_CLASS_GROUP_OF_NAMES)
+	options.MemberRDNAttribute = lo.CoalesceOrEmpty(options.MemberRDNAttribute, "uid")
+	options.EntityType = lo.CoalesceOrEmpty(options.EntityType, ENTITY_TYPE_USER)
+	options.GIDStart = lo.CoalesceOrEmpty(options.GIDStart, 10000)
+
+	objectClasses := []string{LDIF_OBJECT_CLASS_GROUP_OF_NAMES, LDIF_OBJECT_CLASS_GROUP_OF_UNIQUE_NAMES, LDIF_OBJECT_CLASS_POSIX_GROUP}
+
+	if !slices.Contains(objectClasses, options.ObjectClass) {
+		return errors.New("at ldif write > unsupported object class: " + options.ObjectClass)
+	}
+
+	isPosix := options.ObjectClass == LDIF_OBJECT_CLASS_POSIX_GROUP
+
+	if options.BaseDN == "" {
+		return errors.New("at ldif write > base DN is required")
+	}
+
+	if options.MemberBaseDN == "" && !isPosix {
+		return errors.New("at ldif write > member base DN is required")
+	}
+
+	groups, err := exportGroups(ctx, store, options.EntityType)
+
+	if err != nil {
+		return err
+	}
+
+	gids := &gidAssigner{used: map[int]bool{}, next: options.GIDStart}
+
+	for _, exported := range groups {
+		if gid, err := strconv.Atoi(exported.metas[META_GID]); err == nil {
+			gids.used[gid] = true
+		}
+	}
+
+	memberAttribute := lo.Switch[string, string](options.ObjectClass).
+		Case(LDIF_OBJECT_CLASS_GROUP_OF_UNIQUE_NAMES, "uniqueMember").
+		Case(LDIF_OBJECT_CLASS_POSIX_GROUP, "memberUid").
+		Default("member")
+
+	out := bufio.NewWriter(w)
+	out.WriteString("version: 1\n")
+
+	for _, exported := range groups {
+		cn := lo.CoalesceOrEmpty(exported.group.Handle(), exported.group.ID())
+		dn := lo.CoalesceOrEmpty(exported.metas[META_LDAP_DN], "cn="+ldifEscapeDNValue(cn)+","+options.BaseDN)
+
+		out.WriteString("\n")
+		out.WriteString(ldifLine("dn", dn))
+		out.WriteString(ldifLine("objectClass", "top"))
+		out.WriteString(ldifLine("objectClass", options.ObjectClass))
+		out.WriteString(ldifLine("cn", cn))
+
+		if description := lo.CoalesceOrEmpty(exported.group.Memo(), exported.group.Title()); description != "" {
+			out.WriteString(ldifLine("description", description))
+		}
+
+		if isPosix {
+			gid, err := strconv.Atoi(exported.metas[META_GID])
+
+			if err != nil {
+				gid = gids.assign()
+			}
+
+			out.WriteString(ldifLine("gidNumber", strconv.Itoa(gid)))
+		}
+
+		for _, entityID := range exported.entityIDs {
+			userName := entityID
+
+			if options.UserName != nil {
+				if userName, err = options.UserName(entityID); err != nil {
+					return err
+				}
+			}
+
+			member := userName
+
+			if !isPosix {
+				member = options.MemberRDNAttribute + "=" + ldifEscapeDNValue(userName) + "," + options.MemberBaseDN
+			}
+
+			out.WriteString(ldifLine(memberAttribute, member))
+		}
+	}
+
+	return out.Flush()
+}
+
+// == PRIVATE FUNCTIONS =======================================================
+
+// ldifRead reads the entries of an LDIF file, unfolding the continued
+// lines, and decoding the base64 values
+func ldifRead(r io.Reader) ([]ldifEntry, error) {
+	entries := []ldifEntry{}
+	current := ldifEntry{}
+	lines := []string{} // the unfolded lines of the current entry
+	lineNumbers := []int{}
+
+	flush := func() error {
+		for i, line := range lines {
+			if strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			name, value, err := ldifParseLine(line)
+
+			if err != nil {
+				return ldifError(lineNumbers[i], err.Error())
+			}
+
+			current.attributes = append(current.attributes, [2]string{name, value})
+		}
+
+		changeType := current.value("changetype")
+
+		if changeType != "" && !strings.EqualFold(changeType, "add") {
+			return ldifError(current.line, "unsupported changetype: "+changeType)
+		}
+
+		// the version line is not an entry
+		if current.value("dn") != "" {
+			entries = append(entries, current)
+		}
+
+		current = ldifEntry{}
+		lines = lines[:0]
+		lineNumbers = lineNumbers[:0]
+
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+
+		switch {
+		case text == "":
+			if len(lines) > 0 {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+		case strings.HasPrefix(text, " "):
+			if len(lines) == 0 {
+				return nil, ldifError(lineNumber, "continuation without a line to continue")
+			}
+
+			lines[len(lines)-1] += text[1:]
+		default:
+			if len(lines) == 0 {
+				current.line = lineNumber
+			}
+
+			lines = append(lines, text)
+			lineNumbers = append(lineNumbers, lineNumber)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(lines) > 0 {
+		if err := flush(); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// ldifParseLine parses an "attribute: value" or "attribute:: base64" line,
+// the attribute options (i.e. ";lang-en") are dropped
+func ldifParseLine(line string) (name string, value string, err error) {
+	name, value, found := strings.Cut(line, ":")
+
+	if !found || name == "" {
+		return "", "", errors.New("invalid line: " + line)
+	}
+
+	name, _, _ = strings.Cut(name, ";")
+
+	switch {
+	case strings.HasPrefix(value, ":"):
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value[1:]))
+
+		if err != nil {
+			return "", "", errors.New("invalid base64 value of " + name + ": " + err.Error())
+		}
+
+		return name, string(decoded), nil
+	case strings.HasPrefix(value, "<"):
+		return "", "", errors.New("URL values are not supported: " + name)
+	default:
+		return name, strings.TrimLeft(value, " "), nil
+	}
+}
+
+// == HELPERS =================================================================
+
+func ldifError(line int, message string) error {
+	return errors.New("at ldif parse > line " + strconv.Itoa(line) + ": " + message)
+}
+
+// ldifLine returns the attribute line, base64 encoding the values, which
+// are not safe strings (RFC 2849)
+func ldifLine(name string, value string) string {
+	safe := !strings.HasPrefix(value, " ") &&
+		!strings.HasPrefix(value, ":") &&
+		!strings.HasPrefix(value, "<") &&
+		!strings.HasSuffix(value, " ") &&
+		lo.EveryBy([]rune(value), func(r rune) bool { return r >= 0x20 && r < 0x7f })
+
+	if !safe {
+		return name + ":: " + base64.StdEncoding.EncodeToString([]byte(value)) + "\n"
+	}
+
+	return name + ": " + value + "\n"
+}
+
+// ldifFirstRDNValue returns the unescaped value of the first RDN of the DN,
+// i.e. "jdoe" for "uid=jdoe,ou=people,dc=example,dc=com"
+func ldifFirstRDNValue(dn string) string {
+	escaped := false
+	value := strings.Builder{}
+	inValue := false
+
+	for _, r := range dn {
+		switch {
+		case escaped:
+			escaped = false
+			value.WriteRune(r)
+		case r == '\\':
+			escaped = true
+		case !inValue && r == '=':
+			inValue = true
+		case inValue && (r == ',' || r == '+'):
+			return strings.TrimSpace(value.String())
+		case inValue:
+			value.WriteRune(r)
+		}
+	}
+
+	return strings.TrimSpace(value.String())
+}
+
+// ldifEscapeDNValue escapes the special characters of an RDN value (RFC 4514)
+func ldifEscapeDNValue(value string) string {
+	escaped := strings.Builder{}
+
+	for i, r := range value {
+		if strings.ContainsRune(`,+"\<>;=`, r) ||
+			(i == 0 && (r == ' ' || r == '#')) ||
+			(i == len(value)-1 && r == ' ') {
+			escaped.WriteRune('\\')
+		}
+
+		escaped.WriteRune(r)
+	}
+
+	return escaped.String()
+}
+
+// ldifNormalizeDN lower cases the DN and removes the spaces around the
+// separators, for comparing DNs
+func ldifNormalizeDN(dn string) string {
+	parts := strings.Split(strings.ToLower(dn), ",")
+
+	return strings.Join(lo.Map(parts, func(part string, _ int) string {
+		return strings.TrimSpace(part)
+	}), ",")
+}
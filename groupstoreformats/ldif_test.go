@@ -0,0 +1,194 @@
+package groupstoreformats
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gouniverse/groupstore"
+)
+
+const testLDIF = `version: 1
+
+# the organisation
+dn: dc=example,dc=com
+objectClass: dcObject
+dc: example
+
+dn: cn=admins,ou=groups,dc=example,dc=com
+objectClass: top
+objectClass: groupOfNames
+cn: admins
+description:: QWRtaW5pc3RyYXRvcnMgw6Agc2l0ZQ==
+member: uid=alice,ou=people,dc=example,dc=com
+member: uid=bob,ou=people,
+ dc=example,dc=com
+member: cn=support, ou=groups, dc=example, dc=com
+
+dn: cn=support,ou=groups,dc=example,dc=com
+objectClass: groupOfUniqueNames
+cn: support
+uniqueMember: uid=carol,ou=people,dc=example,dc=com
+
+dn: cn=developers,ou=groups,dc=example,dc=com
+objectClass: posixGroup
+cn: developers
+gidNumber: 1001
+memberUid: bob
+memberUid: dave
+`
+
+func TestParseLDIF(t *testing.T) {
+	records, err := ParseLDIF(strings.NewReader(testLDIF), LDIFParseOptions{})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(records.Groups) != 3 {
+		t.Fatal("groups must be 3, found:", len(records.Groups))
+	}
+
+	admins := records.Groups[0]
+
+	if admins.Handle() != "admins" || admins.Memo() != "Administrators à site" || admins.Meta(META_LDAP_DN) != "cn=admins,ou=groups,dc=example,dc=com" {
+		t.Fatal("unexpected group:", admins.Data())
+	}
+
+	if records.Groups[2].Meta(META_GID) != "1001" {
+		t.Fatal("gidNumber must be kept:", records.Groups[2].Data())
+	}
+
+	members := []string{}
+	nested := []groupstore.RelationInterface{}
+
+	for _, relation := range records.Relations {
+		if relation.EntityType() == groupstore.ENTITY_TYPE_GROUP {
+			nested = append(nested, relation)
+			continue
+		}
+
+		members = append(members, relation.EntityID())
+	}
+
+	if strings.Join(members, ",") != "alice,bob,carol,bob,dave" {
+		t.Fatal("unexpected members:", members)
+	}
+
+	// the support group is nested in the admins group
+	if len(nested) != 1 || nested[0].EntityID() != records.Groups[1].ID() || nested[0].GroupID() != admins.ID() {
+		t.Fatal("unexpected nested groups:", nested)
+	}
+
+	invalid := []string{
+		"dn: cn=admins,dc=example,dc=com\nchangetype: modify\n",
+		"dn: cn=admins,dc=example,dc=com\nobjectClass: groupOfNames\ncn:: not base64!\n",
+		"dn: cn=admins,dc=example,dc=com\nobjectClass: groupOfNames\nmember:< file:///etc/passwd\n",
+		" continued\n",
+	}
+
+	for _, text := range invalid {
+		if _, err := ParseLDIF(strings.NewReader(text), LDIFParseOptions{}); err == nil {
+			t.Fatal("must return error for:", text)
+		}
+	}
+}
+
+func TestWriteLDIF(t *testing.T) {
+	store := initStore(t)
+
+	group := groupstore.NewGroup().SetHandle("sales, eu").SetTitle("Sales EU").SetStatus(groupstore.GROUP_STATUS_ACTIVE)
+
+	if err := store.GroupCreate(context.Background(), group); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for _, entityID := range []string{"alice", "bob"} {
+		if _, err := store.RelationEnsure(context.Background(), ENTITY_TYPE_USER, entityID, group.ID()); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	buffer := &bytes.Buffer{}
+
+	err := WriteLDIF(context.Background(), buffer, store, LDIFWriteOptions{
+		BaseDN:       "ou=groups,dc=example,dc=com",
+		MemberBaseDN: "ou=people,dc=example,dc=com",
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := "version: 1\n\n" +
+		"dn: cn=sales\\, eu,ou=groups,dc=example,dc=com\n" +
+		"objectClass: top\n" +
+		"objectClass: groupOfNames\n" +
+		"cn: sales, eu\n" +
+		"description: Sales EU\n" +
+		"member: uid=alice,ou=people,dc=example,dc=com\n" +
+		"member: uid=bob,ou=people,dc=example,dc=com\n"
+
+	if buffer.String() != expected {
+		t.Fatal("unexpected output:", buffer.String())
+	}
+
+	// round trip
+	records, err := ParseLDIF(bytes.NewReader(buffer.Bytes()), LDIFParseOptions{})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(records.Groups) != 1 || records.Groups[0].Handle() != "sales, eu" || len(records.Relations) != 2 {
+		t.Fatal("unexpected records:", records)
+	}
+
+	buffer.Reset()
+
+	err = WriteLDIF(context.Background(), buffer, store, LDIFWriteOptions{
+		BaseDN:      "ou=groups,dc=example,dc=com",
+		ObjectClass: LDIF_OBJECT_CLASS_POSIX_GROUP,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !strings.Contains(buffer.String(), "gidNumber: 10000\nmemberUid: alice\nmemberUid: bob\n") {
+		t.Fatal("unexpected output:", buffer.String())
+	}
+}
+
+func TestRecordsSave_NestedGroups(t *testing.T) {
+	store := initStore(t)
+
+	for range 2 {
+		records, err := ParseLDIF(strings.NewReader(testLDIF), LDIFParseOptions{})
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if err := records.Save(context.Background(), store); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	support, err := store.GroupFindByHandle(context.Background(), "support")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	nested, err := store.RelationList(context.Background(), groupstore.NewRelationQuery().SetEntityType(groupstore.ENTITY_TYPE_GROUP))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(nested) != 1 || nested[0].EntityID() != support.ID() {
+		t.Fatal("the stored support group must be nested once:", nested)
+	}
+}
@@ -0,0 +1,174 @@
+// Package groupstoreformats reads and writes the groups of a groupstore
+// in the file formats of legacy systems: Unix /etc/group files, and LDAP
+// LDIF exports of groupOfNames, groupOfUniqueNames and posixGroup entries,
+// and renders the membership graph as Graphviz DOT and Mermaid
+//
+// The parsers return the groups and their members as groupstore records,
+// which can be reviewed and then saved. The writers export the groups and
+// the members of one entity type back to these formats.
+package groupstoreformats
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gouniverse/groupstore"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+// ENTITY_TYPE_USER is the default entity type of the group members
+const ENTITY_TYPE_USER = groupstore.ENTITY_TYPE_USER
+
+// META_GID is the group meta, which stores the numeric group ID
+const META_GID = "gid"
+
+// META_LDAP_DN is the group meta, which stores the distinguished name of
+// the LDAP entry
+const META_LDAP_DN = "ldap_dn"
+
+// Records are the groups, and their members as relations, read from a file
+type Records struct {
+	Groups    []groupstore.GroupInterface
+	Relations []groupstore.RelationInterface
+}
+
+// Save upserts the groups by handle, and adds the missing relations, in a
+// single transaction
+//
+// The groups matched to existing ones take their IDs, the relations (and
+// the nested groups) are moved to these IDs.
+func (records Records) Save(ctx context.Context, store groupstore.StoreInterface) error {
+	if store == nil {
+		return errors.New("groupstoreformats: store is nil")
+	}
+
+	return store.WithTransaction(ctx, func(txCtx context.Context) error {
+		groupIDs := map[string]string{}
+
+		for _, group := range records.Groups {
+			parsedID := group.ID()
+
+			if err := store.GroupUpsertByHandle(txCtx, group); err != nil {
+				return err
+			}
+
+			groupIDs[parsedID] = group.ID()
+		}
+
+		for _, relation := range records.Relations {
+			if groupID, ok := groupIDs[relation.GroupID()]; ok {
+				relation.SetGroupID(groupID)
+			}
+
+			if relation.EntityType() != groupstore.ENTITY_TYPE_GROUP {
+				continue
+			}
+
+			if groupID, ok := groupIDs[relation.EntityID()]; ok {
+				relation.SetEntityID(groupID)
+			}
+		}
+
+		return store.RelationBulkCreate(txCtx, records.Relations)
+	})
+}
+
+// == PRIVATE METHODS =========================================================
+
+// exportGroup is a group with the IDs of its members
+type exportGroup struct {
+	group     groupstore.GroupInterface
+	metas     map[string]string
+	entityIDs []string
+}
+
+// exportGroups loads the groups, which are not soft deleted, ordered by
+// handle, with the IDs of their members of the entity type
+func exportGroups(ctx context.Context, store groupstore.StoreInterface, entityType string) ([]exportGroup, error) {
+	if store == nil {
+		return nil, errors.New("groupstoreformats: store is nil")
+	}
+
+	groups, err := store.GroupList(ctx, groupstore.NewGroupQuery().
+		SetOrderBy(groupstore.COLUMN_HANDLE).
+		SetSortDirection(sb.ASC))
+
+	if err != nil {
+		return nil, err
+	}
+
+	entityIDs := map[string][]string{}
+
+	relations := store.RelationIter(ctx, groupstore.NewRelationQuery().
+		SetEntityType(entityType).
+		SetOrderBy(groupstore.COLUMN_CREATED_AT).
+		SetSortDirection(sb.ASC))
+
+	for relation, err := range relations {
+		if err != nil {
+			return nil, err
+		}
+
+		entityIDs[relation.GroupID()] = append(entityIDs[relation.GroupID()], relation.EntityID())
+	}
+
+	exported := make([]exportGroup, 0, len(groups))
+
+	for _, group := range groups {
+		metas, err := group.Metas()
+
+		if err != nil {
+			return nil, err
+		}
+
+		exported = append(exported, exportGroup{
+			group:     group,
+			metas:     metas,
+			entityIDs: lo.Uniq(entityIDs[group.ID()]),
+		})
+	}
+
+	return exported, nil
+}
+
+// == HELPERS =================================================================
+
+// newGroup returns an active group with the handle, title, memo and metas
+func newGroup(handle string, title string, memo string, metas map[string]string) (groupstore.GroupInterface, error) {
+	group := groupstore.NewGroup().
+		SetHandle(handle).
+		SetTitle(title).
+		SetMemo(memo).
+		SetStatus(groupstore.GROUP_STATUS_ACTIVE)
+
+	if err := group.SetMetas(metas); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// newRelation returns the relation of the entity to the group
+func newRelation(entityType string, entityID string, groupID string) groupstore.RelationInterface {
+	return groupstore.NewRelation().
+		SetEntityType(entityType).
+		SetEntityID(entityID).
+		SetGroupID(groupID)
+}
+
+// gidAssigner assigns free numeric group IDs to the groups without one
+type gidAssigner struct {
+	used map[int]bool
+	next int
+}
+
+func (a *gidAssigner) assign() int {
+	for a.used[a.next] {
+		a.next++
+	}
+
+	a.used[a.next] = true
+
+	return a.next
+}
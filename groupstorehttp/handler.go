@@ -0,0 +1,123 @@
+// Package groupstorehttp exposes a groupstore over a REST/JSON HTTP API
+package groupstorehttp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gouniverse/groupstore"
+)
+
+// maxBodyBytes is the maximum size of a request body
+const maxBodyBytes = 1 << 20
+
+// == TYPE ====================================================================
+
+type handler struct {
+	store groupstore.StoreInterface
+	mux   *http.ServeMux
+}
+
+// == CONSTRUCTOR =============================================================
+
+// NewHandler returns an http.Handler exposing the store
+//
+// Routes:
+//   - GET    /groups                 lists the groups (see the OpenAPI document for the filters)
+//   - POST   /groups                 creates a group
+//   - GET    /groups/{id}            returns a group
+//   - PATCH  /groups/{id}            updates a group
+//   - DELETE /groups/{id}            soft deletes a group (?hard=true deletes it)
+//   - GET    /relations              lists the relations
+//   - POST   /relations              creates a relation
+//   - GET    /relations/{id}         returns a relation
+//   - PATCH  /relations/{id}         updates a relation
+//   - DELETE /relations/{id}         soft deletes a relation (?hard=true deletes it)
+//   - GET    /membership             checks a membership
+//   - POST   /membership/batch       checks many memberships
+//   - GET    /openapi.json           returns the OpenAPI document
+//
+// To mount the handler under a path prefix, use http.StripPrefix.
+func NewHandler(store groupstore.StoreInterface) http.Handler {
+	h := &handler{
+		store: store,
+		mux:   http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc("GET /groups", h.groupList)
+	h.mux.HandleFunc("POST /groups", h.groupCreate)
+	h.mux.HandleFunc("GET /groups/{id}", h.groupGet)
+	h.mux.HandleFunc("PATCH /groups/{id}", h.groupUpdate)
+	h.mux.HandleFunc("DELETE /groups/{id}", h.groupDelete)
+
+	h.mux.HandleFunc("GET /relations", h.relationList)
+	h.mux.HandleFunc("POST /relations", h.relationCreate)
+	h.mux.HandleFunc("GET /relations/{id}", h.relationGet)
+	h.mux.HandleFunc("PATCH /relations/{id}", h.relationUpdate)
+	h.mux.HandleFunc("DELETE /relations/{id}", h.relationDelete)
+	h.mux.HandleFunc("POST /relations/{id}/approve", h.relationApprove)
+	h.mux.HandleFunc("POST /relations/{id}/reject", h.relationReject)
+
+	h.mux.HandleFunc("GET /membership", h.membershipCheck)
+	h.mux.HandleFunc("POST /membership/batch", h.membershipBatchCheck)
+
+	h.mux.HandleFunc("GET /openapi.json", h.openAPI)
+
+	return h
+}
+
+// == PUBLIC METHODS ==========================================================
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// == PRIVATE METHODS =========================================================
+
+func (h *handler) openAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(openAPIDocument)
+}
+
+// == HELPERS =================================================================
+
+// errorResponse is the body of all the error responses
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// dataResponse is the body of the single object responses
+type dataResponse struct {
+	Data map[string]string `json:"data"`
+}
+
+// listResponse is the body of the list responses
+type listResponse struct {
+	Data  []map[string]string `json:"data"`
+	Total int64               `json:"total"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+// decodeBody decodes the JSON request body into the target,
+// unknown fields are rejected
+func decodeBody(w http.ResponseWriter, r *http.Request, target any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(target); err != nil {
+		return errors.New("invalid request body: " + err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,282 @@
+package groupstorehttp
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/groupstore"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+// groupRequest is the body of the group create and update requests,
+// the omitted fields are left unchanged on update
+type groupRequest struct {
+	Status       *string            `json:"status"`
+	Handle       *string            `json:"handle"`
+	Title        *string            `json:"title"`
+	Memo         *string            `json:"memo"`
+	Metas        *map[string]string `json:"metas"`
+	Capacity     *int               `json:"capacity"`
+	ExclusiveSet *string            `json:"exclusive_set"`
+	ActivateAt   *string            `json:"activate_at"`
+	DeactivateAt *string            `json:"deactivate_at"`
+}
+
+// apply copies the fields present in the request to the group
+func (req groupRequest) apply(group groupstore.GroupInterface) error {
+	if req.Status != nil {
+		group.SetStatus(*req.Status)
+	}
+
+	if req.Handle != nil {
+		group.SetHandle(*req.Handle)
+	}
+
+	if req.Title != nil {
+		group.SetTitle(*req.Title)
+	}
+
+	if req.Memo != nil {
+		group.SetMemo(*req.Memo)
+	}
+
+	if req.Capacity != nil {
+		if *req.Capacity < 0 {
+			return errors.New("capacity cannot be negative")
+		}
+
+		group.SetCapacity(*req.Capacity)
+	}
+
+	if req.ExclusiveSet != nil {
+		group.SetExclusiveSet(*req.ExclusiveSet)
+	}
+
+	if req.ActivateAt != nil {
+		activateAt, err := scheduleFromRequest(*req.ActivateAt)
+
+		if err != nil {
+			return errors.New("activate_at " + err.Error())
+		}
+
+		group.SetActivateAt(activateAt)
+	}
+
+	if req.DeactivateAt != nil {
+		deactivateAt, err := scheduleFromRequest(*req.DeactivateAt)
+
+		if err != nil {
+			return errors.New("deactivate_at " + err.Error())
+		}
+
+		group.SetDeactivateAt(deactivateAt)
+	}
+
+	if req.Metas != nil {
+		return group.SetMetas(*req.Metas)
+	}
+
+	return nil
+}
+
+// scheduleFromRequest parses the scheduled datetime of the request to
+// UTC, an empty one clearing the schedule
+func scheduleFromRequest(value string) (string, error) {
+	if value == "" {
+		return sb.MAX_DATETIME, nil
+	}
+
+	datetime := carbon.Parse(value, carbon.UTC)
+
+	if datetime.IsInvalid() {
+		return "", errors.New("is not a valid datetime")
+	}
+
+	return datetime.SetTimezone(carbon.UTC).ToDateTimeString(carbon.UTC), nil
+}
+
+func (h *handler) groupList(w http.ResponseWriter, r *http.Request) {
+	query, err := groupQueryFromRequest(r, true)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	countQuery, err := groupQueryFromRequest(r, false)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	groups, err := h.store.GroupList(r.Context(), query)
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	total, err := h.store.GroupCount(r.Context(), countQuery)
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, listResponse{
+		Data: lo.Map(groups, func(group groupstore.GroupInterface, _ int) map[string]string {
+			return group.Data()
+		}),
+		Total: total,
+	})
+}
+
+func (h *handler) groupCreate(w http.ResponseWriter, r *http.Request) {
+	req := groupRequest{}
+
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Title == nil || *req.Title == "" {
+		writeError(w, http.StatusBadRequest, errors.New("title is required"))
+		return
+	}
+
+	// the handle column is not nullable, an omitted handle is stored empty
+	group := groupstore.NewGroup().SetHandle("")
+
+	if err := req.apply(group); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.store.GroupCreate(r.Context(), group); err != nil {
+		writeError(w, groupErrorStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, dataResponse{Data: group.Data()})
+}
+
+func (h *handler) groupGet(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.findGroup(w, r)
+
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dataResponse{Data: group.Data()})
+}
+
+func (h *handler) groupUpdate(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.findGroup(w, r)
+
+	if !ok {
+		return
+	}
+
+	req := groupRequest{}
+
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Title != nil && *req.Title == "" {
+		writeError(w, http.StatusBadRequest, errors.New("title cannot be empty"))
+		return
+	}
+
+	if err := req.apply(group); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.store.GroupUpdate(r.Context(), group); err != nil {
+		writeError(w, groupErrorStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dataResponse{Data: group.Data()})
+}
+
+func (h *handler) groupDelete(w http.ResponseWriter, r *http.Request) {
+	hard, err := hardDeleteRequested(r)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	group, ok := h.findGroup(w, r)
+
+	if !ok {
+		return
+	}
+
+	if hard {
+		err = h.store.GroupDelete(r.Context(), group)
+	} else {
+		err = h.store.GroupSoftDelete(r.Context(), group)
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// groupErrorStatus returns the response status of the error of a group
+// create or update
+func groupErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, groupstore.ErrGroupStatusInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, groupstore.ErrGroupStatusTransition),
+		errors.Is(err, groupstore.ErrGroupHandleUnavailable):
+		return http.StatusConflict
+	}
+
+	return http.StatusInternalServerError
+}
+
+// findGroup returns the group with the ID from the path, writing
+// the error response when it cannot be found
+func (h *handler) findGroup(w http.ResponseWriter, r *http.Request) (groupstore.GroupInterface, bool) {
+	group, err := h.store.GroupFindByID(r.Context(), r.PathValue("id"))
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	if group == nil {
+		writeError(w, http.StatusNotFound, errors.New("group not found"))
+		return nil, false
+	}
+
+	return group, true
+}
+
+// hardDeleteRequested returns true, when the request asks for a hard delete
+func hardDeleteRequested(r *http.Request) (bool, error) {
+	if !r.URL.Query().Has("hard") {
+		return false, nil
+	}
+
+	hard, err := strconv.ParseBool(r.URL.Query().Get("hard"))
+
+	if err != nil {
+		return false, errors.New("hard must be true or false")
+	}
+
+	return hard, nil
+}
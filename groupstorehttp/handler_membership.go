@@ -0,0 +1,95 @@
+package groupstorehttp
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gouniverse/groupstore"
+	"github.com/samber/lo"
+)
+
+// maxBatchChecks is the maximum number of checks in a batch membership request
+const maxBatchChecks = 1000
+
+// membershipCheckRequest is a single check of the batch membership request
+type membershipCheckRequest struct {
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+	Group      string `json:"group"`
+}
+
+// membershipBatchRequest is the body of the batch membership request
+type membershipBatchRequest struct {
+	Checks []membershipCheckRequest `json:"checks"`
+}
+
+// membershipResponse is the body of the membership check response
+type membershipResponse struct {
+	IsMember bool `json:"is_member"`
+}
+
+// membershipBatchResponse is the body of the batch membership response,
+// the results follow the order of the checks
+type membershipBatchResponse struct {
+	Results []bool `json:"results"`
+}
+
+func (h *handler) membershipCheck(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+
+	entityType := values.Get("entity_type")
+	entityID := values.Get("entity_id")
+	group := values.Get("group")
+
+	if entityType == "" || entityID == "" || group == "" {
+		writeError(w, http.StatusBadRequest, errors.New("entity_type, entity_id and group (ID or handle) are required"))
+		return
+	}
+
+	isMember, err := h.store.IsMember(r.Context(), entityType, entityID, group)
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, membershipResponse{IsMember: isMember})
+}
+
+func (h *handler) membershipBatchCheck(w http.ResponseWriter, r *http.Request) {
+	req := membershipBatchRequest{}
+
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(req.Checks) > maxBatchChecks {
+		writeError(w, http.StatusBadRequest, errors.New("too many checks, the maximum is 1000"))
+		return
+	}
+
+	for _, check := range req.Checks {
+		if check.EntityType == "" || check.EntityID == "" || check.Group == "" {
+			writeError(w, http.StatusBadRequest, errors.New("entity_type, entity_id and group (ID or handle) are required for each check"))
+			return
+		}
+	}
+
+	checks := lo.Map(req.Checks, func(check membershipCheckRequest, _ int) groupstore.MembershipCheck {
+		return groupstore.MembershipCheck{
+			EntityType:      check.EntityType,
+			EntityID:        check.EntityID,
+			GroupIDOrHandle: check.Group,
+		}
+	})
+
+	results, err := h.store.BatchIsMember(r.Context(), checks)
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, membershipBatchResponse{Results: results})
+}
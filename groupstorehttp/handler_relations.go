@@ -0,0 +1,256 @@
+package groupstorehttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gouniverse/groupstore"
+	"github.com/samber/lo"
+)
+
+// relationCreateRequest is the body of the relation create request
+type relationCreateRequest struct {
+	EntityType string            `json:"entity_type"`
+	EntityID   string            `json:"entity_id"`
+	GroupID    string            `json:"group_id"`
+	Status     string            `json:"status"`
+	Memo       string            `json:"memo"`
+	Metas      map[string]string `json:"metas"`
+}
+
+// relationUpdateRequest is the body of the relation update request,
+// the omitted fields are left unchanged
+type relationUpdateRequest struct {
+	Memo  *string            `json:"memo"`
+	Metas *map[string]string `json:"metas"`
+}
+
+func (h *handler) relationList(w http.ResponseWriter, r *http.Request) {
+	query, err := relationQueryFromRequest(r, true)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	countQuery, err := relationQueryFromRequest(r, false)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	relations, err := h.store.RelationList(r.Context(), query)
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	total, err := h.store.RelationCount(r.Context(), countQuery)
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, listResponse{
+		Data: lo.Map(relations, func(relation groupstore.RelationInterface, _ int) map[string]string {
+			return relation.Data()
+		}),
+		Total: total,
+	})
+}
+
+func (h *handler) relationCreate(w http.ResponseWriter, r *http.Request) {
+	req := relationCreateRequest{}
+
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.EntityType == "" || req.EntityID == "" || req.GroupID == "" {
+		writeError(w, http.StatusBadRequest, errors.New("entity_type, entity_id and group_id are required"))
+		return
+	}
+
+	if req.Status != "" && !lo.Contains(groupstore.RELATION_STATUSES, req.Status) {
+		writeError(w, http.StatusBadRequest, errors.New("status is invalid: "+req.Status))
+		return
+	}
+
+	existing, err := h.store.RelationFindByEntityAndGroup(r.Context(), req.EntityType, req.EntityID, req.GroupID)
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if existing != nil {
+		writeError(w, http.StatusConflict, errors.New("relation already exists"))
+		return
+	}
+
+	relation := groupstore.NewRelation().
+		SetEntityType(req.EntityType).
+		SetEntityID(req.EntityID).
+		SetGroupID(req.GroupID).
+		SetStatus(lo.CoalesceOrEmpty(req.Status, groupstore.RELATION_STATUS_ACTIVE)).
+		SetMemo(req.Memo)
+
+	if req.Metas != nil {
+		if err := relation.SetMetas(req.Metas); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if err := h.store.RelationCreate(r.Context(), relation); err != nil {
+		var policyErr *groupstore.EntityPolicyError
+
+		switch {
+		case errors.Is(err, groupstore.ErrRelationExists):
+			writeError(w, http.StatusConflict, errors.New("relation already exists"))
+		case errors.As(err, &policyErr):
+			writeError(w, http.StatusUnprocessableEntity, err)
+		default:
+			writeError(w, http.StatusInternalServerError, err)
+		}
+
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, dataResponse{Data: relation.Data()})
+}
+
+func (h *handler) relationGet(w http.ResponseWriter, r *http.Request) {
+	relation, ok := h.findRelation(w, r)
+
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dataResponse{Data: relation.Data()})
+}
+
+func (h *handler) relationUpdate(w http.ResponseWriter, r *http.Request) {
+	relation, ok := h.findRelation(w, r)
+
+	if !ok {
+		return
+	}
+
+	req := relationUpdateRequest{}
+
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Memo != nil {
+		relation.SetMemo(*req.Memo)
+	}
+
+	if req.Metas != nil {
+		if err := relation.SetMetas(*req.Metas); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if err := h.store.RelationUpdate(r.Context(), relation); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dataResponse{Data: relation.Data()})
+}
+
+func (h *handler) relationDelete(w http.ResponseWriter, r *http.Request) {
+	hard, err := hardDeleteRequested(r)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	relation, ok := h.findRelation(w, r)
+
+	if !ok {
+		return
+	}
+
+	if hard {
+		err = h.store.RelationDelete(r.Context(), relation)
+	} else {
+		err = h.store.RelationSoftDelete(r.Context(), relation)
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) relationApprove(w http.ResponseWriter, r *http.Request) {
+	h.relationTransition(w, r, h.store.RelationApprove)
+}
+
+func (h *handler) relationReject(w http.ResponseWriter, r *http.Request) {
+	h.relationTransition(w, r, h.store.RelationReject)
+}
+
+// relationTransition moves the relation with the ID from the path to
+// another status, and writes the updated relation
+func (h *handler) relationTransition(w http.ResponseWriter, r *http.Request, transition func(ctx context.Context, relationID string) error) {
+	relation, ok := h.findRelation(w, r)
+
+	if !ok {
+		return
+	}
+
+	if err := transition(r.Context(), relation.ID()); err != nil {
+		var policyErr *groupstore.EntityPolicyError
+
+		switch {
+		case errors.Is(err, groupstore.ErrRelationStatusTransition):
+			writeError(w, http.StatusConflict, err)
+		case errors.As(err, &policyErr):
+			writeError(w, http.StatusUnprocessableEntity, err)
+		default:
+			writeError(w, http.StatusInternalServerError, err)
+		}
+
+		return
+	}
+
+	relation, ok = h.findRelation(w, r)
+
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dataResponse{Data: relation.Data()})
+}
+
+// findRelation returns the relation with the ID from the path, writing
+// the error response when it cannot be found
+func (h *handler) findRelation(w http.ResponseWriter, r *http.Request) (groupstore.RelationInterface, bool) {
+	relation, err := h.store.RelationFindByID(r.Context(), r.PathValue("id"))
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	if relation == nil {
+		writeError(w, http.StatusNotFound, errors.New("relation not found"))
+		return nil, false
+	}
+
+	return relation, true
+}
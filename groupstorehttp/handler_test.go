@@ -0,0 +1,405 @@
+package groupstorehttp
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gouniverse/groupstore"
+	_ "modernc.org/sqlite"
+)
+
+func initHandler(t *testing.T) (http.Handler, groupstore.StoreInterface) {
+	db, err := sql.Open("sqlite", ":memory:?parseTime=true")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// each connection has its own in-memory database
+	db.SetMaxOpenConns(1)
+
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	store, err := groupstore.NewStore(groupstore.NewStoreOptions{
+		DB:                           db,
+		GroupTableName:               "groups_group_table",
+		GroupEntityRelationTableName: "groups_group_entity_relation_table",
+		AutomigrateEnabled:           true,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	return NewHandler(store), store
+}
+
+func doRequest(t *testing.T, handler http.Handler, method string, target string, body any) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+
+	if body == nil {
+		reader = bytes.NewReader(nil)
+	} else {
+		encoded, err := json.Marshal(body)
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		reader = bytes.NewReader(encoded)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, target, reader))
+
+	return recorder
+}
+
+func decodeResponse[T any](t *testing.T, recorder *httptest.ResponseRecorder) T {
+	var target T
+
+	if err := json.Unmarshal(recorder.Body.Bytes(), &target); err != nil {
+		t.Fatal("unexpected error:", err, recorder.Body.String())
+	}
+
+	return target
+}
+
+func TestHandlerGroups(t *testing.T) {
+	handler, _ := initHandler(t)
+
+	recorder := doRequest(t, handler, http.MethodPost, "/groups", map[string]any{
+		"title":  "Admins",
+		"handle": "admins",
+		"status": groupstore.GROUP_STATUS_ACTIVE,
+		"metas":  map[string]string{"color": "red"},
+	})
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	created := decodeResponse[dataResponse](t, recorder)
+	id := created.Data[groupstore.COLUMN_ID]
+
+	if id == "" {
+		t.Fatal("group id must not be empty")
+	}
+
+	recorder = doRequest(t, handler, http.MethodPost, "/groups", map[string]any{"title": "Editors"})
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = doRequest(t, handler, http.MethodPatch, "/groups/"+id, map[string]any{"title": "Administrators"})
+
+	if recorder.Code != http.StatusOK {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	updated := decodeResponse[dataResponse](t, recorder)
+
+	if updated.Data[groupstore.COLUMN_TITLE] != "Administrators" {
+		t.Fatal("unexpected title:", updated.Data[groupstore.COLUMN_TITLE])
+	}
+
+	if updated.Data[groupstore.COLUMN_HANDLE] != "admins" {
+		t.Fatal("omitted fields must be left unchanged, handle:", updated.Data[groupstore.COLUMN_HANDLE])
+	}
+
+	recorder = doRequest(t, handler, http.MethodGet, "/groups?limit=1&order_by=title&sort_direction=asc", nil)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	list := decodeResponse[listResponse](t, recorder)
+
+	if list.Total != 2 || len(list.Data) != 1 {
+		t.Fatal("unexpected list:", list.Total, len(list.Data))
+	}
+
+	if list.Data[0][groupstore.COLUMN_TITLE] != "Administrators" {
+		t.Fatal("unexpected first group:", list.Data[0][groupstore.COLUMN_TITLE])
+	}
+
+	recorder = doRequest(t, handler, http.MethodDelete, "/groups/"+id, nil)
+
+	if recorder.Code != http.StatusNoContent {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = doRequest(t, handler, http.MethodGet, "/groups/"+id, nil)
+
+	if recorder.Code != http.StatusNotFound {
+		t.Fatal("soft deleted group must not be found, status:", recorder.Code)
+	}
+
+	recorder = doRequest(t, handler, http.MethodGet, "/groups?include_soft_deleted=true", nil)
+
+	if total := decodeResponse[listResponse](t, recorder).Total; total != 2 {
+		t.Fatal("unexpected total:", total)
+	}
+}
+
+func TestHandlerGroupsValidation(t *testing.T) {
+	handler, _ := initHandler(t)
+
+	tests := []struct {
+		method string
+		target string
+		body   any
+		status int
+	}{
+		{http.MethodPost, "/groups", map[string]any{"handle": "no-title"}, http.StatusBadRequest},
+		{http.MethodPost, "/groups", map[string]any{"title": "T", "unknown": "x"}, http.StatusBadRequest},
+		{http.MethodGet, "/groups?limit=0", nil, http.StatusBadRequest},
+		{http.MethodGet, "/groups?limit=5000", nil, http.StatusBadRequest},
+		{http.MethodGet, "/groups?order_by=memo", nil, http.StatusBadRequest},
+		{http.MethodGet, "/groups?sort_direction=up", nil, http.StatusBadRequest},
+		{http.MethodGet, "/groups/missing", nil, http.StatusNotFound},
+		{http.MethodDelete, "/groups/missing?hard=maybe", nil, http.StatusBadRequest},
+		{http.MethodPut, "/groups/missing", nil, http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+		recorder := doRequest(t, handler, test.method, test.target, test.body)
+
+		if recorder.Code != test.status {
+			t.Fatal(test.method, test.target, "unexpected status:", recorder.Code, recorder.Body.String())
+		}
+	}
+}
+
+func TestHandlerRelations(t *testing.T) {
+	handler, store := initHandler(t)
+
+	relation := map[string]any{
+		"entity_type": "USER",
+		"entity_id":   "USER_01",
+		"group_id":    "GROUP_01",
+	}
+
+	recorder := doRequest(t, handler, http.MethodPost, "/relations", relation)
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	id := decodeResponse[dataResponse](t, recorder).Data[groupstore.COLUMN_ID]
+
+	recorder = doRequest(t, handler, http.MethodPost, "/relations", relation)
+
+	if recorder.Code != http.StatusConflict {
+		t.Fatal("duplicate relation must conflict, status:", recorder.Code)
+	}
+
+	recorder = doRequest(t, handler, http.MethodPatch, "/relations/"+id, map[string]any{"memo": "note"})
+
+	if recorder.Code != http.StatusOK {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = doRequest(t, handler, http.MethodGet, "/relations?entity_id=USER_01", nil)
+
+	list := decodeResponse[listResponse](t, recorder)
+
+	if list.Total != 1 || list.Data[0][groupstore.COLUMN_MEMO] != "note" {
+		t.Fatal("unexpected list:", list)
+	}
+
+	recorder = doRequest(t, handler, http.MethodDelete, "/relations/"+id+"?hard=true", nil)
+
+	if recorder.Code != http.StatusNoContent {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	count, err := store.RelationCount(context.Background(), groupstore.NewRelationQuery().SetSoftDeletedIncluded(true))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 0 {
+		t.Fatal("hard deleted relation must be removed, count:", count)
+	}
+}
+
+// raceStore finds no relations, as if the duplicate was created after
+// the check of the handler
+type raceStore struct {
+	groupstore.StoreInterface
+}
+
+func (raceStore) RelationFindByEntityAndGroup(context.Context, string, string, string) (groupstore.RelationInterface, error) {
+	return nil, nil
+}
+
+func TestHandlerRelationsDuplicateRace(t *testing.T) {
+	_, store := initHandler(t)
+
+	handler := NewHandler(raceStore{StoreInterface: store})
+
+	relation := map[string]any{
+		"entity_type": "USER",
+		"entity_id":   "USER_01",
+		"group_id":    "GROUP_01",
+	}
+
+	if recorder := doRequest(t, handler, http.MethodPost, "/relations", relation); recorder.Code != http.StatusCreated {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	if recorder := doRequest(t, handler, http.MethodPost, "/relations", relation); recorder.Code != http.StatusConflict {
+		t.Fatal("duplicate relation must conflict, status:", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestHandlerMembership(t *testing.T) {
+	handler, store := initHandler(t)
+
+	group := groupstore.NewGroup().SetTitle("Admins").SetHandle("admins")
+
+	if err := store.GroupCreate(context.Background(), group); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := store.RelationEnsure(context.Background(), "USER", "USER_01", group.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	recorder := doRequest(t, handler, http.MethodGet, "/membership?entity_type=USER&entity_id=USER_01&group=admins", nil)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	if !decodeResponse[membershipResponse](t, recorder).IsMember {
+		t.Fatal("USER_01 must be a member of admins")
+	}
+
+	recorder = doRequest(t, handler, http.MethodGet, "/membership?entity_type=USER&entity_id=USER_01", nil)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatal("missing group must be rejected, status:", recorder.Code)
+	}
+
+	recorder = doRequest(t, handler, http.MethodPost, "/membership/batch", map[string]any{
+		"checks": []map[string]string{
+			{"entity_type": "USER", "entity_id": "USER_01", "group": group.ID()},
+			{"entity_type": "USER", "entity_id": "USER_02", "group": "admins"},
+		},
+	})
+
+	if recorder.Code != http.StatusOK {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	results := decodeResponse[membershipBatchResponse](t, recorder).Results
+
+	if len(results) != 2 || !results[0] || results[1] {
+		t.Fatal("unexpected results:", results)
+	}
+}
+
+func TestHandlerOpenAPI(t *testing.T) {
+	handler, _ := initHandler(t)
+
+	recorder := doRequest(t, handler, http.MethodGet, "/openapi.json", nil)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatal("unexpected status:", recorder.Code)
+	}
+
+	document := decodeResponse[map[string]any](t, recorder)
+
+	if document["openapi"] != "3.0.3" {
+		t.Fatal("unexpected openapi version:", document["openapi"])
+	}
+}
+
+func TestHandlerRelationsCapacity(t *testing.T) {
+	handler, _ := initHandler(t)
+
+	recorder := doRequest(t, handler, http.MethodPost, "/groups", map[string]any{"title": "Cohort", "capacity": 1})
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	groupID := decodeResponse[dataResponse](t, recorder).Data[groupstore.COLUMN_ID]
+
+	for i, status := range []int{http.StatusCreated, http.StatusUnprocessableEntity} {
+		recorder = doRequest(t, handler, http.MethodPost, "/relations", map[string]any{
+			"entity_type": "USER",
+			"entity_id":   fmt.Sprintf("USER_%02d", i+1),
+			"group_id":    groupID,
+		})
+
+		if recorder.Code != status {
+			t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+		}
+	}
+
+	recorder = doRequest(t, handler, http.MethodPatch, "/groups/"+groupID, map[string]any{"capacity": -1})
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatal("negative capacity must be rejected, status:", recorder.Code)
+	}
+}
+
+func TestHandlerRelationsApproval(t *testing.T) {
+	handler, store := initHandler(t)
+
+	group := groupstore.NewGroup().SetHandle("club").SetTitle("Club")
+
+	if err := store.GroupCreate(context.Background(), group); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	recorder := doRequest(t, handler, http.MethodPost, "/relations", map[string]any{
+		"entity_type": "USER",
+		"entity_id":   "USER_01",
+		"group_id":    group.ID(),
+		"status":      groupstore.RELATION_STATUS_PENDING,
+	})
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	relationID := decodeResponse[dataResponse](t, recorder).Data[groupstore.COLUMN_ID]
+
+	recorder = doRequest(t, handler, http.MethodGet, "/relations?group_id="+group.ID()+"&status=pending", nil)
+
+	if list := decodeResponse[listResponse](t, recorder); list.Total != 1 {
+		t.Fatal("expected 1 pending relation, found:", list.Total)
+	}
+
+	recorder = doRequest(t, handler, http.MethodPost, "/relations/"+relationID+"/approve", nil)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	if status := decodeResponse[dataResponse](t, recorder).Data[groupstore.COLUMN_STATUS]; status != groupstore.RELATION_STATUS_ACTIVE {
+		t.Fatal("expected the relation to be active, found:", status)
+	}
+
+	recorder = doRequest(t, handler, http.MethodPost, "/relations/"+relationID+"/reject", nil)
+
+	if recorder.Code != http.StatusConflict {
+		t.Fatal("rejecting an active relation must conflict, status:", recorder.Code)
+	}
+}
@@ -0,0 +1,8 @@
+package groupstorehttp
+
+import _ "embed"
+
+// openAPIDocument is the OpenAPI 3 description of the HTTP API
+//
+//go:embed openapi.json
+var openAPIDocument []byte
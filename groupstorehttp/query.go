@@ -0,0 +1,243 @@
+package groupstorehttp
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gouniverse/groupstore"
+	"github.com/gouniverse/sb"
+)
+
+// defaultLimit is the page size, when no limit is requested
+const defaultLimit = 100
+
+// maxLimit is the largest page size, which can be requested
+const maxLimit = 1000
+
+// groupOrderColumns are the columns the groups can be ordered by
+var groupOrderColumns = []string{
+	groupstore.COLUMN_CREATED_AT,
+	groupstore.COLUMN_HANDLE,
+	groupstore.COLUMN_ID,
+	groupstore.COLUMN_POSITION,
+	groupstore.COLUMN_STATUS,
+	groupstore.COLUMN_TITLE,
+	groupstore.COLUMN_UPDATED_AT,
+}
+
+// relationOrderColumns are the columns the relations can be ordered by
+var relationOrderColumns = []string{
+	groupstore.COLUMN_CREATED_AT,
+	groupstore.COLUMN_ENTITY_ID,
+	groupstore.COLUMN_ENTITY_TYPE,
+	groupstore.COLUMN_GROUP_ID,
+	groupstore.COLUMN_ID,
+	groupstore.COLUMN_POSITION,
+	groupstore.COLUMN_UPDATED_AT,
+}
+
+// page holds the pagination and ordering parameters shared by the lists
+type page struct {
+	limit               int
+	offset              int
+	orderBy             string
+	sortDirection       string
+	softDeletedIncluded bool
+}
+
+// groupQueryFromRequest maps the query string of the request to a group query,
+// with pagination applied only when paginated is true (i.e. not for counting)
+func groupQueryFromRequest(r *http.Request, paginated bool) (groupstore.GroupQueryInterface, error) {
+	values := r.URL.Query()
+
+	p, err := pageFromValues(values, groupOrderColumns)
+
+	if err != nil {
+		return nil, err
+	}
+
+	query := groupstore.NewGroupQuery()
+
+	if values.Has("id") {
+		query.SetID(values.Get("id"))
+	}
+
+	if values.Has("id_in") {
+		query.SetIDIn(splitList(values.Get("id_in")))
+	}
+
+	if values.Has("status") {
+		query.SetStatus(values.Get("status"))
+	}
+
+	if values.Has("status_in") {
+		query.SetStatusIn(splitList(values.Get("status_in")))
+	}
+
+	if values.Has("handle") {
+		query.SetHandle(values.Get("handle"))
+	}
+
+	if values.Has("title_like") {
+		query.SetTitleLike(values.Get("title_like"))
+	}
+
+	if values.Has("created_at_gte") {
+		query.SetCreatedAtGte(values.Get("created_at_gte"))
+	}
+
+	if values.Has("created_at_lte") {
+		query.SetCreatedAtLte(values.Get("created_at_lte"))
+	}
+
+	query.SetSoftDeletedIncluded(p.softDeletedIncluded)
+
+	if paginated {
+		query.SetLimit(p.limit).SetOffset(p.offset)
+
+		if p.orderBy != "" {
+			query.SetOrderBy(p.orderBy).SetSortDirection(p.sortDirection)
+		}
+	}
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+// relationQueryFromRequest maps the query string of the request to a relation
+// query, with pagination applied only when paginated is true (i.e. not for counting)
+func relationQueryFromRequest(r *http.Request, paginated bool) (groupstore.RelationQueryInterface, error) {
+	values := r.URL.Query()
+
+	p, err := pageFromValues(values, relationOrderColumns)
+
+	if err != nil {
+		return nil, err
+	}
+
+	query := groupstore.NewRelationQuery()
+
+	if values.Has("id") {
+		query.SetID(values.Get("id"))
+	}
+
+	if values.Has("id_in") {
+		query.SetIDIn(splitList(values.Get("id_in")))
+	}
+
+	if values.Has("entity_type") {
+		query.SetEntityType(values.Get("entity_type"))
+	}
+
+	if values.Has("entity_id") {
+		query.SetEntityID(values.Get("entity_id"))
+	}
+
+	if values.Has("group_id") {
+		query.SetGroupID(values.Get("group_id"))
+	}
+
+	if values.Has("status") {
+		query.SetStatus(values.Get("status"))
+	}
+
+	if values.Has("status_in") {
+		query.SetStatusIn(splitList(values.Get("status_in")))
+	}
+
+	if values.Has("created_at_gte") {
+		query.SetCreatedAtGte(values.Get("created_at_gte"))
+	}
+
+	if values.Has("created_at_lte") {
+		query.SetCreatedAtLte(values.Get("created_at_lte"))
+	}
+
+	query.SetSoftDeletedIncluded(p.softDeletedIncluded)
+
+	if paginated {
+		query.SetLimit(p.limit).SetOffset(p.offset)
+
+		if p.orderBy != "" {
+			query.SetOrderBy(p.orderBy).SetSortDirection(p.sortDirection)
+		}
+	}
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+// pageFromValues parses the pagination and ordering parameters
+func pageFromValues(values url.Values, orderColumns []string) (page, error) {
+	p := page{
+		limit:         defaultLimit,
+		sortDirection: sb.DESC,
+	}
+
+	var err error
+
+	if values.Has("limit") {
+		p.limit, err = strconv.Atoi(values.Get("limit"))
+
+		if err != nil || p.limit < 1 || p.limit > maxLimit {
+			return p, errors.New("limit must be a number between 1 and " + strconv.Itoa(maxLimit))
+		}
+	}
+
+	if values.Has("offset") {
+		p.offset, err = strconv.Atoi(values.Get("offset"))
+
+		if err != nil || p.offset < 0 {
+			return p, errors.New("offset must be a number greater than or equal to 0")
+		}
+	}
+
+	if values.Has("order_by") {
+		p.orderBy = values.Get("order_by")
+
+		if !slices.Contains(orderColumns, p.orderBy) {
+			return p, errors.New("order_by must be one of: " + strings.Join(orderColumns, ", "))
+		}
+	}
+
+	if values.Has("sort_direction") {
+		p.sortDirection = strings.ToLower(values.Get("sort_direction"))
+
+		if p.sortDirection != sb.ASC && p.sortDirection != sb.DESC {
+			return p, errors.New("sort_direction must be asc or desc")
+		}
+	}
+
+	if values.Has("include_soft_deleted") {
+		p.softDeletedIncluded, err = strconv.ParseBool(values.Get("include_soft_deleted"))
+
+		if err != nil {
+			return p, errors.New("include_soft_deleted must be true or false")
+		}
+	}
+
+	return p, nil
+}
+
+// splitList splits a comma separated list, skipping the empty items
+func splitList(list string) []string {
+	items := []string{}
+
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -0,0 +1,402 @@
+package groupstorescim
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/gouniverse/groupstore"
+)
+
+// filterExpr is a parsed SCIM filter (RFC 7644, section 3.4.2.2),
+// matched against the attributes of a resource keyed by their lower
+// case names
+type filterExpr interface {
+	match(attributes map[string]string) bool
+}
+
+// filterAnd matches, when both sides match
+type filterAnd struct {
+	left  filterExpr
+	right filterExpr
+}
+
+func (f filterAnd) match(attributes map[string]string) bool {
+	return f.left.match(attributes) && f.right.match(attributes)
+}
+
+// filterOr matches, when any side matches
+type filterOr struct {
+	left  filterExpr
+	right filterExpr
+}
+
+func (f filterOr) match(attributes map[string]string) bool {
+	return f.left.match(attributes) || f.right.match(attributes)
+}
+
+// filterNot matches, when the wrapped filter does not
+type filterNot struct {
+	expr filterExpr
+}
+
+func (f filterNot) match(attributes map[string]string) bool {
+	return !f.expr.match(attributes)
+}
+
+// filterCompare compares an attribute with a value
+type filterCompare struct {
+	attribute string
+	operator  string
+	value     string
+	caseExact bool
+}
+
+func (f filterCompare) match(attributes map[string]string) bool {
+	actual, present := attributes[f.attribute]
+
+	if f.operator == "pr" {
+		return present && actual != ""
+	}
+
+	expected := f.value
+
+	if !f.caseExact {
+		actual = strings.ToLower(actual)
+		expected = strings.ToLower(expected)
+	}
+
+	switch f.operator {
+	case "eq":
+		return actual == expected
+	case "ne":
+		return actual != expected
+	case "co":
+		return strings.Contains(actual, expected)
+	case "sw":
+		return strings.HasPrefix(actual, expected)
+	case "ew":
+		return strings.HasSuffix(actual, expected)
+	case "gt":
+		return actual > expected
+	case "ge":
+		return actual >= expected
+	case "lt":
+		return actual < expected
+	case "le":
+		return actual <= expected
+	}
+
+	return false
+}
+
+// filterGroupQuery narrows the group query with the comparisons, which
+// all the matching groups must satisfy, and the query can express (the
+// equality of the id and of the externalId, which are indexed), the filter
+// still being matched on the groups returned
+func filterGroupQuery(filter filterExpr, query groupstore.GroupQueryInterface) groupstore.GroupQueryInterface {
+	switch f := filter.(type) {
+	case filterAnd:
+		return filterGroupQuery(f.right, filterGroupQuery(f.left, query))
+	case filterCompare:
+		if f.value == "" {
+			return query
+		}
+
+		switch {
+		case f.operator == "eq" && f.attribute == attributeID:
+			return query.SetID(f.value)
+		case f.operator == "eq" && f.attribute == attributeExternalID:
+			return query.SetExternalID(f.value)
+		}
+	}
+
+	return query
+}
+
+// filterOperators are the supported attribute operators
+var filterOperators = []string{"eq", "ne", "co", "sw", "ew", "pr", "gt", "ge", "lt", "le"}
+
+// parseFilter parses a SCIM filter
+//
+// Business logic:
+//   - attribute names are case insensitive, and may carry the schema URN
+//   - only the given attributes can be filtered on
+//   - the attributes not listed as case exact are compared case insensitively
+func parseFilter(filter string, attributes []string, caseExactAttributes []string) (filterExpr, error) {
+	tokens, err := tokenizeFilter(filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, errors.New("filter is empty")
+	}
+
+	p := &filterParser{
+		tokens:              tokens,
+		attributes:          attributes,
+		caseExactAttributes: caseExactAttributes,
+	}
+
+	expr, err := p.parseOr()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if p.position < len(p.tokens) {
+		return nil, errors.New("unexpected token in filter: " + p.tokens[p.position].text)
+	}
+
+	return expr, nil
+}
+
+// filterToken is a lexical token of a filter, string values are
+// already unquoted and flagged as such
+type filterToken struct {
+	text   string
+	quoted bool
+}
+
+// tokenizeFilter splits the filter into words, parentheses, brackets
+// and quoted strings
+func tokenizeFilter(filter string) ([]filterToken, error) {
+	tokens := []filterToken{}
+	runes := []rune(filter)
+
+	for i := 0; i < len(runes); {
+		switch r := runes[i]; {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == '[' || r == ']':
+			tokens = append(tokens, filterToken{text: string(r)})
+			i++
+		case r == '"':
+			end := i + 1
+
+			for ; end < len(runes) && runes[end] != '"'; end++ {
+				if runes[end] == '\\' {
+					end++
+				}
+			}
+
+			if end >= len(runes) {
+				return nil, errors.New("unterminated string in filter")
+			}
+
+			value := ""
+
+			if err := json.Unmarshal([]byte(string(runes[i:end+1])), &value); err != nil {
+				return nil, errors.New("invalid string in filter: " + string(runes[i:end+1]))
+			}
+
+			tokens = append(tokens, filterToken{text: value, quoted: true})
+			i = end + 1
+		default:
+			end := i
+
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune("()[]\"", runes[end]) {
+				end++
+			}
+
+			tokens = append(tokens, filterToken{text: string(runes[i:end])})
+			i = end
+		}
+	}
+
+	return tokens, nil
+}
+
+// filterParser is a recursive descent parser over the filter tokens
+type filterParser struct {
+	tokens              []filterToken
+	position            int
+	attributes          []string
+	caseExactAttributes []string
+}
+
+func (p *filterParser) peekKeyword(keyword string) bool {
+	if p.position >= len(p.tokens) {
+		return false
+	}
+
+	token := p.tokens[p.position]
+
+	return !token.quoted && strings.EqualFold(token.text, keyword)
+}
+
+func (p *filterParser) next() (filterToken, error) {
+	if p.position >= len(p.tokens) {
+		return filterToken{}, errors.New("unexpected end of filter")
+	}
+
+	token := p.tokens[p.position]
+	p.position++
+
+	return token, nil
+}
+
+func (p *filterParser) expect(text string) error {
+	token, err := p.next()
+
+	if err != nil {
+		return err
+	}
+
+	if token.quoted || token.text != text {
+		return errors.New("expected " + text + " in filter, got: " + token.text)
+	}
+
+	return nil
+}
+
+func (p *filterParser) parseOr() (filterExpr, error) {
+	left, err := p.parseAnd()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peekKeyword("or") {
+		p.position++
+
+		right, err := p.parseAnd()
+
+		if err != nil {
+			return nil, err
+		}
+
+		left = filterOr{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterExpr, error) {
+	left, err := p.parseUnary()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peekKeyword("and") {
+		p.position++
+
+		right, err := p.parseUnary()
+
+		if err != nil {
+			return nil, err
+		}
+
+		left = filterAnd{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterExpr, error) {
+	if p.peekKeyword("not") {
+		p.position++
+
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+
+		expr, err := p.parseOr()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+
+		return filterNot{expr: expr}, nil
+	}
+
+	if p.peekKeyword("(") {
+		p.position++
+
+		expr, err := p.parseOr()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+
+		return expr, nil
+	}
+
+	return p.parseCompare()
+}
+
+func (p *filterParser) parseCompare() (filterExpr, error) {
+	token, err := p.next()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if token.quoted {
+		return nil, errors.New("expected attribute in filter, got: \"" + token.text + "\"")
+	}
+
+	attribute := attributeName(token.text)
+
+	if !slices.Contains(p.attributes, attribute) {
+		return nil, errors.New("filtering is not supported on attribute: " + token.text)
+	}
+
+	operatorToken, err := p.next()
+
+	if err != nil {
+		return nil, err
+	}
+
+	operator := strings.ToLower(operatorToken.text)
+
+	if operatorToken.quoted || !slices.Contains(filterOperators, operator) {
+		return nil, errors.New("unsupported operator in filter: " + operatorToken.text)
+	}
+
+	compare := filterCompare{
+		attribute: attribute,
+		operator:  operator,
+		caseExact: slices.Contains(p.caseExactAttributes, attribute),
+	}
+
+	if operator == "pr" {
+		return compare, nil
+	}
+
+	valueToken, err := p.next()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !valueToken.quoted && strings.ContainsAny(valueToken.text, "()[]") {
+		return nil, errors.New("expected value in filter, got: " + valueToken.text)
+	}
+
+	compare.value = valueToken.text
+
+	return compare, nil
+}
+
+// attributeName returns the lower case attribute name, without the schema URN
+func attributeName(name string) string {
+	if index := strings.LastIndex(name, ":"); index >= 0 {
+		name = name[index+1:]
+	}
+
+	return strings.ToLower(name)
+}
@@ -0,0 +1,66 @@
+package groupstorescim
+
+import (
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	attributes := map[string]string{
+		attributeID:          "GROUP_01",
+		attributeDisplayName: "Engineering Team",
+		attributeExternalID:  "ext-01",
+	}
+
+	tests := []struct {
+		filter  string
+		matches bool
+	}{
+		{`displayName eq "Engineering Team"`, true},
+		{`DISPLAYNAME eq "engineering team"`, true},
+		{`urn:ietf:params:scim:schemas:core:2.0:Group:displayName eq "Engineering Team"`, true},
+		{`externalId eq "EXT-01"`, false},
+		{`externalId eq "ext-01"`, true},
+		{`displayName sw "Eng" and externalId ew "01"`, true},
+		{`displayName co "sales" or externalId pr`, true},
+		{`not (displayName co "team")`, false},
+		{`(displayName eq "x" or displayName eq "y") and id eq "GROUP_01"`, false},
+		{`displayName ne "x"`, true},
+		{`displayName eq "Say \"hi\""`, false},
+	}
+
+	for _, test := range tests {
+		filter, err := parseFilter(test.filter,
+			[]string{attributeID, attributeDisplayName, attributeExternalID},
+			[]string{attributeID, attributeExternalID})
+
+		if err != nil {
+			t.Fatal(test.filter, "unexpected error:", err)
+		}
+
+		if filter.match(attributes) != test.matches {
+			t.Fatal(test.filter, "must match:", test.matches)
+		}
+	}
+}
+
+func TestParseFilterInvalid(t *testing.T) {
+	invalid := []string{
+		``,
+		`displayName`,
+		`displayName eq`,
+		`displayName xx "a"`,
+		`members eq "a"`,
+		`displayName eq "a" and`,
+		`(displayName eq "a"`,
+		`displayName eq "a`,
+		`displayName eq "a" extra`,
+	}
+
+	for _, filter := range invalid {
+		_, err := parseFilter(filter, []string{attributeDisplayName}, []string{})
+
+		if err == nil {
+			t.Fatal(filter, "must return error")
+		}
+	}
+}
@@ -0,0 +1,376 @@
+// Package groupstorescim exposes the groups of a groupstore as a
+// SCIM 2.0 /Groups resource (RFC 7643, RFC 7644)
+//
+// Groups map to groupstore groups, with the displayName stored as the
+// title and the externalId as the external ID. Members map to relations
+// with the groupstore.ENTITY_TYPE_USER entity type, the member value being
+// the entity ID.
+package groupstorescim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gouniverse/groupstore"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+// maxBodyBytes is the maximum size of a request body
+const maxBodyBytes = 1 << 20
+
+// defaultCount is the page size, when no count is requested
+const defaultCount = 100
+
+// maxCount is the largest page size, which can be requested
+const maxCount = 1000
+
+// == TYPE ====================================================================
+
+type handler struct {
+	store groupstore.StoreInterface
+	mux   *http.ServeMux
+}
+
+// == CONSTRUCTOR =============================================================
+
+// NewHandler returns an http.Handler serving the SCIM /Groups resource
+//
+// Routes:
+//   - GET    /Groups           lists the groups (filter, startIndex, count, excludedAttributes)
+//   - POST   /Groups           creates a group
+//   - GET    /Groups/{id}      returns a group
+//   - PATCH  /Groups/{id}      updates a group and its members
+//   - DELETE /Groups/{id}      soft deletes a group and its memberships
+//
+// To mount the handler under the SCIM base path, use http.StripPrefix.
+func NewHandler(store groupstore.StoreInterface) http.Handler {
+	h := &handler{
+		store: store,
+		mux:   http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc("GET /Groups", h.groupList)
+	h.mux.HandleFunc("POST /Groups", h.groupCreate)
+	h.mux.HandleFunc("GET /Groups/{id}", h.groupGet)
+	h.mux.HandleFunc("PATCH /Groups/{id}", h.groupPatch)
+	h.mux.HandleFunc("DELETE /Groups/{id}", h.groupDelete)
+
+	return h
+}
+
+// == PUBLIC METHODS ==========================================================
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// == PRIVATE METHODS =========================================================
+
+func (h *handler) groupList(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+
+	startIndex, count, err := pageFromRequest(r)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalidValue", err)
+		return
+	}
+
+	var filter filterExpr
+
+	if values.Get("filter") != "" {
+		filter, err = parseFilter(values.Get("filter"),
+			[]string{attributeID, attributeDisplayName, attributeExternalID},
+			[]string{attributeID, attributeExternalID})
+
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalidFilter", err)
+			return
+		}
+	}
+
+	withMembers := !membersExcluded(r)
+	response := listResponse{
+		Schemas:    []string{SCHEMA_LIST_RESPONSE},
+		StartIndex: startIndex,
+		Resources:  []groupResource{},
+	}
+
+	pageGroups := []groupstore.GroupInterface{}
+
+	query := groupstore.NewGroupQuery().
+		SetOrderBy(groupstore.COLUMN_CREATED_AT).
+		SetSortDirection(sb.ASC)
+
+	// the filter narrows the query, where it can be translated, and is
+	// matched in full on the groups returned
+	for group, err := range h.store.GroupIter(r.Context(), filterGroupQuery(filter, query)) {
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "", err)
+			return
+		}
+
+		if filter != nil && !filter.match(groupAttributes(group)) {
+			continue
+		}
+
+		response.TotalResults++
+
+		if response.TotalResults >= startIndex && len(pageGroups) < count {
+			pageGroups = append(pageGroups, group)
+		}
+	}
+
+	// the members are loaded after the iteration completes, so the
+	// iterator is not holding a connection while querying
+	for _, group := range pageGroups {
+		resource, err := h.groupToResource(r.Context(), group, withMembers)
+
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "", err)
+			return
+		}
+
+		response.Resources = append(response.Resources, resource)
+	}
+
+	response.ItemsPerPage = len(response.Resources)
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *handler) groupCreate(w http.ResponseWriter, r *http.Request) {
+	resource := groupResource{}
+
+	if err := decodeBody(w, r, &resource); err != nil {
+		writeError(w, http.StatusBadRequest, "invalidSyntax", err)
+		return
+	}
+
+	if resource.DisplayName == "" {
+		writeError(w, http.StatusBadRequest, "invalidValue", errors.New("displayName is required"))
+		return
+	}
+
+	members := []member{}
+
+	if resource.Members != nil {
+		members = *resource.Members
+	}
+
+	if err := validateMembers(members); err != nil {
+		writeError(w, http.StatusBadRequest, "invalidValue", err)
+		return
+	}
+
+	group := groupstore.NewGroup().
+		SetStatus(groupstore.GROUP_STATUS_ACTIVE).
+		SetHandle("").
+		SetTitle(resource.DisplayName)
+
+	group.SetExternalID(resource.ExternalID)
+
+	err := h.store.WithTransaction(r.Context(), func(txCtx context.Context) error {
+		if err := h.externalIDCheck(txCtx, group); err != nil {
+			return err
+		}
+
+		if err := h.store.GroupCreate(txCtx, group); err != nil {
+			return err
+		}
+
+		return h.membersAdd(txCtx, group.ID(), members)
+	})
+
+	if reqErr := (requestError{}); errors.As(err, &reqErr) {
+		writeError(w, reqErr.statusCode(), reqErr.scimType, reqErr)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err)
+		return
+	}
+
+	created, err := h.groupToResource(r.Context(), group, true)
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (h *handler) groupGet(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.findGroup(w, r)
+
+	if !ok {
+		return
+	}
+
+	resource, err := h.groupToResource(r.Context(), group, !membersExcluded(r))
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resource)
+}
+
+func (h *handler) groupDelete(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.findGroup(w, r)
+
+	if !ok {
+		return
+	}
+
+	err := h.store.WithTransaction(r.Context(), func(txCtx context.Context) error {
+		// the memberships are removed as well, so checks by group ID
+		// do not keep reporting the users of the deleted group
+		if err := h.membersReplace(txCtx, group.ID(), []member{}); err != nil {
+			return err
+		}
+
+		return h.store.GroupSoftDelete(txCtx, group)
+	})
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findGroup returns the group with the ID from the path, writing
+// the error response when it cannot be found
+func (h *handler) findGroup(w http.ResponseWriter, r *http.Request) (groupstore.GroupInterface, bool) {
+	group, err := h.store.GroupFindByID(r.Context(), r.PathValue("id"))
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err)
+		return nil, false
+	}
+
+	if group == nil {
+		writeError(w, http.StatusNotFound, "", errors.New("group not found"))
+		return nil, false
+	}
+
+	return group, true
+}
+
+// groupFindByExternalID returns the group with the external ID, other
+// than the excluded group, or nil
+func (h *handler) groupFindByExternalID(ctx context.Context, externalID string, excludedID string) (groupstore.GroupInterface, error) {
+	groups, err := h.store.GroupList(ctx, groupstore.NewGroupQuery().
+		SetExternalID(externalID).
+		SetLimit(2))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.FindOrElse(groups, nil, func(group groupstore.GroupInterface) bool {
+		return group.ID() != excludedID
+	}), nil
+}
+
+// externalIDCheck returns a conflict error, when the external ID of the
+// group, if set, belongs to another group
+func (h *handler) externalIDCheck(ctx context.Context, group groupstore.GroupInterface) error {
+	if group.ExternalID() == "" {
+		return nil
+	}
+
+	existing, err := h.groupFindByExternalID(ctx, group.ExternalID(), group.ID())
+
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		return requestError{status: http.StatusConflict, scimType: "uniqueness", detail: "a group with this externalId exists already"}
+	}
+
+	return nil
+}
+
+// == HELPERS =================================================================
+
+// pageFromRequest parses the 1-based startIndex and the count
+func pageFromRequest(r *http.Request) (startIndex int, count int, err error) {
+	values := r.URL.Query()
+
+	startIndex = 1
+	count = defaultCount
+
+	if values.Has("startIndex") {
+		startIndex, err = strconv.Atoi(values.Get("startIndex"))
+
+		if err != nil {
+			return 0, 0, errors.New("startIndex must be a number")
+		}
+
+		// RFC 7644: a value less than 1 is interpreted as 1
+		startIndex = max(startIndex, 1)
+	}
+
+	if values.Has("count") {
+		count, err = strconv.Atoi(values.Get("count"))
+
+		if err != nil {
+			return 0, 0, errors.New("count must be a number")
+		}
+
+		// RFC 7644: a negative value is interpreted as 0
+		count = min(max(count, 0), maxCount)
+	}
+
+	return startIndex, count, nil
+}
+
+// membersExcluded returns true, when the request excludes the members attribute
+func membersExcluded(r *http.Request) bool {
+	for _, attribute := range strings.Split(r.URL.Query().Get("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(attribute), "members") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, scimType string, err error) {
+	writeJSON(w, status, errorResponse{
+		Schemas:  []string{SCHEMA_ERROR},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   err.Error(),
+	})
+}
+
+// decodeBody decodes the JSON request body into the target, unknown
+// attributes are ignored as identity providers commonly send extensions
+func decodeBody(w http.ResponseWriter, r *http.Request, target any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+
+	if err := decoder.Decode(target); err != nil {
+		return errors.New("invalid request body: " + err.Error())
+	}
+
+	return nil
+}
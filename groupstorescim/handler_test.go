@@ -0,0 +1,411 @@
+package groupstorescim
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/gouniverse/groupstore"
+	_ "modernc.org/sqlite"
+)
+
+func initHandler(t *testing.T) (http.Handler, groupstore.StoreInterface) {
+	db, err := sql.Open("sqlite", ":memory:?parseTime=true")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// each connection has its own in-memory database
+	db.SetMaxOpenConns(1)
+
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	store, err := groupstore.NewStore(groupstore.NewStoreOptions{
+		DB:                           db,
+		GroupTableName:               "groups_group_table",
+		GroupEntityRelationTableName: "groups_group_entity_relation_table",
+		AutomigrateEnabled:           true,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	return NewHandler(store), store
+}
+
+func doRequest(t *testing.T, handler http.Handler, method string, target string, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	request.Header.Set("Content-Type", "application/scim+json")
+	handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func decodeResponse[T any](t *testing.T, recorder *httptest.ResponseRecorder) T {
+	var target T
+
+	if err := json.Unmarshal(recorder.Body.Bytes(), &target); err != nil {
+		t.Fatal("unexpected error:", err, recorder.Body.String())
+	}
+
+	return target
+}
+
+func memberValues(resource groupResource) []string {
+	values := []string{}
+
+	if resource.Members != nil {
+		for _, m := range *resource.Members {
+			values = append(values, m.Value)
+		}
+	}
+
+	return values
+}
+
+func createGroup(t *testing.T, handler http.Handler, body string) groupResource {
+	recorder := doRequest(t, handler, http.MethodPost, "/Groups", body)
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	return decodeResponse[groupResource](t, recorder)
+}
+
+func TestHandlerGroupCreateAndGet(t *testing.T) {
+	handler, store := initHandler(t)
+
+	created := createGroup(t, handler, `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+		"displayName": "Engineering",
+		"externalId": "ext-eng",
+		"members": [{"value": "USER_01", "display": "Jane"}, {"value": "USER_02"}]
+	}`)
+
+	if created.ID == "" || created.DisplayName != "Engineering" || created.ExternalID != "ext-eng" {
+		t.Fatal("unexpected group:", created)
+	}
+
+	if !slices.Equal(memberValues(created), []string{"USER_01", "USER_02"}) {
+		t.Fatal("unexpected members:", memberValues(created))
+	}
+
+	isMember, err := store.IsMember(context.Background(), groupstore.ENTITY_TYPE_USER, "USER_02", created.ID)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !isMember {
+		t.Fatal("members must be stored as user relations")
+	}
+
+	recorder := doRequest(t, handler, http.MethodPost, "/Groups", `{"displayName": "Other", "externalId": "ext-eng"}`)
+
+	if recorder.Code != http.StatusConflict {
+		t.Fatal("duplicate externalId must conflict, status:", recorder.Code)
+	}
+
+	recorder = doRequest(t, handler, http.MethodPost, "/Groups", `{"externalId": "ext-none"}`)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatal("missing displayName must be rejected, status:", recorder.Code)
+	}
+
+	if decodeResponse[errorResponse](t, recorder).Status != "400" {
+		t.Fatal("unexpected error response:", recorder.Body.String())
+	}
+
+	recorder = doRequest(t, handler, http.MethodGet, "/Groups/"+created.ID+"?excludedAttributes=members", "")
+
+	if recorder.Code != http.StatusOK {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	if found := decodeResponse[groupResource](t, recorder); found.Members != nil || found.Meta == nil {
+		t.Fatal("unexpected group:", recorder.Body.String())
+	}
+
+	if recorder.Header().Get("Content-Type") != "application/scim+json" {
+		t.Fatal("unexpected content type:", recorder.Header().Get("Content-Type"))
+	}
+}
+
+func TestHandlerGroupList(t *testing.T) {
+	handler, _ := initHandler(t)
+
+	createGroup(t, handler, `{"displayName": "Engineering", "externalId": "ext-eng"}`)
+	createGroup(t, handler, `{"displayName": "Sales", "externalId": "ext-sales"}`)
+	createGroup(t, handler, `{"displayName": "Support"}`)
+
+	recorder := doRequest(t, handler, http.MethodGet, "/Groups?filter="+url.QueryEscape(`displayName eq "sales"`), "")
+
+	if recorder.Code != http.StatusOK {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	list := decodeResponse[listResponse](t, recorder)
+
+	if list.TotalResults != 1 || list.Resources[0].ExternalID != "ext-sales" {
+		t.Fatal("unexpected list:", recorder.Body.String())
+	}
+
+	recorder = doRequest(t, handler, http.MethodGet, "/Groups?filter="+url.QueryEscape(`externalId pr`)+"&startIndex=2&count=5", "")
+
+	list = decodeResponse[listResponse](t, recorder)
+
+	if list.TotalResults != 2 || list.ItemsPerPage != 1 || list.StartIndex != 2 {
+		t.Fatal("unexpected list:", recorder.Body.String())
+	}
+
+	recorder = doRequest(t, handler, http.MethodGet, "/Groups?filter="+url.QueryEscape(`externalId eq "ext-eng" and displayName co "engine"`), "")
+
+	list = decodeResponse[listResponse](t, recorder)
+
+	if list.TotalResults != 1 || list.Resources[0].DisplayName != "Engineering" {
+		t.Fatal("unexpected list:", recorder.Body.String())
+	}
+
+	recorder = doRequest(t, handler, http.MethodGet, "/Groups?filter="+url.QueryEscape(`externalId eq "ext-eng" and displayName eq "Sales"`), "")
+
+	if list = decodeResponse[listResponse](t, recorder); list.TotalResults != 0 {
+		t.Fatal("unexpected list:", recorder.Body.String())
+	}
+
+	recorder = doRequest(t, handler, http.MethodGet, "/Groups?count=0", "")
+
+	list = decodeResponse[listResponse](t, recorder)
+
+	if list.TotalResults != 3 || len(list.Resources) != 0 {
+		t.Fatal("unexpected list:", recorder.Body.String())
+	}
+
+	recorder = doRequest(t, handler, http.MethodGet, "/Groups?filter="+url.QueryEscape(`title eq "Sales"`), "")
+
+	if recorder.Code != http.StatusBadRequest || decodeResponse[errorResponse](t, recorder).ScimType != "invalidFilter" {
+		t.Fatal("unsupported filter must be rejected:", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestHandlerGroupPatch(t *testing.T) {
+	handler, _ := initHandler(t)
+
+	created := createGroup(t, handler, `{"displayName": "Engineering", "members": [{"value": "USER_01"}]}`)
+
+	patch := func(operations string) *httptest.ResponseRecorder {
+		return doRequest(t, handler, http.MethodPatch, "/Groups/"+created.ID, `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": `+operations+`
+		}`)
+	}
+
+	recorder := patch(`[
+		{"op": "Add", "path": "members", "value": [{"value": "USER_02"}, {"value": "USER_03"}]},
+		{"op": "replace", "value": {"displayName": "Platform", "externalId": "ext-platform"}}
+	]`)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	patched := decodeResponse[groupResource](t, recorder)
+
+	if patched.DisplayName != "Platform" || patched.ExternalID != "ext-platform" {
+		t.Fatal("unexpected group:", recorder.Body.String())
+	}
+
+	if !slices.Equal(memberValues(patched), []string{"USER_01", "USER_02", "USER_03"}) {
+		t.Fatal("unexpected members:", memberValues(patched))
+	}
+
+	recorder = patch(`[{"op": "remove", "path": "members[value eq \"USER_02\"]"}]`)
+
+	if members := memberValues(decodeResponse[groupResource](t, recorder)); !slices.Equal(members, []string{"USER_01", "USER_03"}) {
+		t.Fatal("unexpected members:", members)
+	}
+
+	recorder = patch(`[{"op": "remove", "path": "members", "value": [{"value": "USER_01"}]}]`)
+
+	if members := memberValues(decodeResponse[groupResource](t, recorder)); !slices.Equal(members, []string{"USER_03"}) {
+		t.Fatal("unexpected members:", members)
+	}
+
+	recorder = patch(`[{"op": "replace", "path": "members", "value": [{"value": "USER_04"}, {"value": "USER_03"}]}]`)
+
+	if members := memberValues(decodeResponse[groupResource](t, recorder)); !slices.Equal(members, []string{"USER_03", "USER_04"}) {
+		t.Fatal("unexpected members:", members)
+	}
+
+	recorder = patch(`[{"op": "remove", "path": "externalId"}, {"op": "remove", "path": "members"}]`)
+
+	patched = decodeResponse[groupResource](t, recorder)
+
+	if patched.ExternalID != "" || len(memberValues(patched)) != 0 {
+		t.Fatal("unexpected group:", recorder.Body.String())
+	}
+
+	// a failing operation rolls back the whole request
+	recorder = patch(`[
+		{"op": "add", "path": "members", "value": [{"value": "USER_05"}]},
+		{"op": "remove", "path": "displayName"}
+	]`)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatal("removing displayName must be rejected, status:", recorder.Code)
+	}
+
+	recorder = doRequest(t, handler, http.MethodGet, "/Groups/"+created.ID, "")
+
+	if members := memberValues(decodeResponse[groupResource](t, recorder)); len(members) != 0 {
+		t.Fatal("failed patch must be rolled back, members:", members)
+	}
+
+	recorder = doRequest(t, handler, http.MethodPatch, "/Groups/"+created.ID, `{"Operations": [{"op": "add", "path": "members", "value": []}]}`)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatal("patch without the PatchOp schema must be rejected, status:", recorder.Code)
+	}
+}
+
+func TestHandlerGroupPatchExternalIDConflict(t *testing.T) {
+	handler, _ := initHandler(t)
+
+	createGroup(t, handler, `{"displayName": "Engineering", "externalId": "ext-eng"}`)
+	sales := createGroup(t, handler, `{"displayName": "Sales", "externalId": "ext-sales"}`)
+
+	patch := func(operations string) *httptest.ResponseRecorder {
+		return doRequest(t, handler, http.MethodPatch, "/Groups/"+sales.ID, `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": `+operations+`
+		}`)
+	}
+
+	recorder := patch(`[{"op": "replace", "path": "externalId", "value": "ext-eng"}]`)
+
+	if recorder.Code != http.StatusConflict || decodeResponse[errorResponse](t, recorder).ScimType != "uniqueness" {
+		t.Fatal("taken externalId must conflict:", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = doRequest(t, handler, http.MethodGet, "/Groups/"+sales.ID, "")
+
+	if decodeResponse[groupResource](t, recorder).ExternalID != "ext-sales" {
+		t.Fatal("conflicting patch must be rolled back:", recorder.Body.String())
+	}
+
+	// the own externalId is not a conflict
+	recorder = patch(`[{"op": "replace", "value": {"displayName": "Sales EMEA", "externalId": "ext-sales"}}]`)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestHandlerGroupPatchMemberStatuses(t *testing.T) {
+	handler, store := initHandler(t)
+
+	created := createGroup(t, handler, `{"displayName": "Engineering"}`)
+
+	ctx := context.Background()
+
+	if _, err := store.RelationRequest(ctx, groupstore.ENTITY_TYPE_USER, "USER_01", created.ID); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := store.RelationInvite(ctx, groupstore.ENTITY_TYPE_USER, "USER_02", created.ID); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	banned, err := store.RelationInvite(ctx, groupstore.ENTITY_TYPE_USER, "USER_03", created.ID)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.RelationBan(ctx, banned.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	patch := func(operations string) *httptest.ResponseRecorder {
+		return doRequest(t, handler, http.MethodPatch, "/Groups/"+created.ID, `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": `+operations+`
+		}`)
+	}
+
+	// the pending and invited users are activated
+	recorder := patch(`[{"op": "add", "path": "members", "value": [{"value": "USER_01"}]}]`)
+
+	if members := memberValues(decodeResponse[groupResource](t, recorder)); !slices.Equal(members, []string{"USER_01"}) {
+		t.Fatal("unexpected members:", members)
+	}
+
+	recorder = patch(`[{"op": "replace", "path": "members", "value": [{"value": "USER_01"}, {"value": "USER_02"}]}]`)
+
+	if members := memberValues(decodeResponse[groupResource](t, recorder)); !slices.Equal(members, []string{"USER_01", "USER_02"}) {
+		t.Fatal("unexpected members:", members)
+	}
+
+	// the banned users are rejected
+	for _, operations := range []string{
+		`[{"op": "add", "path": "members", "value": [{"value": "USER_03"}]}]`,
+		`[{"op": "replace", "path": "members", "value": [{"value": "USER_03"}]}]`,
+	} {
+		recorder = patch(operations)
+
+		if recorder.Code != http.StatusBadRequest || decodeResponse[errorResponse](t, recorder).ScimType != "invalidValue" {
+			t.Fatal("banned member must be rejected:", recorder.Code, recorder.Body.String())
+		}
+	}
+
+	relation, err := store.RelationFindByID(ctx, banned.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if relation.Status() != groupstore.RELATION_STATUS_BANNED {
+		t.Fatal("expected the user to stay banned, found:", relation.Status())
+	}
+}
+
+func TestHandlerGroupDelete(t *testing.T) {
+	handler, store := initHandler(t)
+
+	created := createGroup(t, handler, `{"displayName": "Engineering", "members": [{"value": "USER_01"}]}`)
+
+	recorder := doRequest(t, handler, http.MethodDelete, "/Groups/"+created.ID, "")
+
+	if recorder.Code != http.StatusNoContent {
+		t.Fatal("unexpected status:", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = doRequest(t, handler, http.MethodGet, "/Groups/"+created.ID, "")
+
+	if recorder.Code != http.StatusNotFound {
+		t.Fatal("deleted group must not be found, status:", recorder.Code)
+	}
+
+	isMember, err := store.IsMember(context.Background(), groupstore.ENTITY_TYPE_USER, "USER_01", created.ID)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if isMember {
+		t.Fatal("memberships of the deleted group must be removed")
+	}
+}
@@ -0,0 +1,378 @@
+package groupstorescim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gouniverse/groupstore"
+	"github.com/samber/lo"
+)
+
+// patchRequest is the SCIM patch request (RFC 7644, section 3.5.2)
+type patchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []patchOperation `json:"Operations"`
+}
+
+// patchOperation is a single operation of the patch request
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// requestError is an error caused by the request, reported with the
+// status, 400 when not set, and the SCIM error type
+type requestError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (e requestError) Error() string {
+	return e.detail
+}
+
+// statusCode returns the status of the error response
+func (e requestError) statusCode() int {
+	return lo.CoalesceOrEmpty(e.status, http.StatusBadRequest)
+}
+
+func (h *handler) groupPatch(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.findGroup(w, r)
+
+	if !ok {
+		return
+	}
+
+	req := patchRequest{}
+
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalidSyntax", err)
+		return
+	}
+
+	if !slices.Contains(req.Schemas, SCHEMA_PATCH_OP) {
+		writeError(w, http.StatusBadRequest, "invalidSyntax", errors.New("schemas must contain "+SCHEMA_PATCH_OP))
+		return
+	}
+
+	if len(req.Operations) == 0 {
+		writeError(w, http.StatusBadRequest, "invalidSyntax", errors.New("Operations are required"))
+		return
+	}
+
+	err := h.store.WithTransaction(r.Context(), func(txCtx context.Context) error {
+		for _, operation := range req.Operations {
+			if err := h.applyOperation(txCtx, group, operation); err != nil {
+				return err
+			}
+		}
+
+		if len(group.DataChanged()) == 0 {
+			return nil
+		}
+
+		if lo.HasKey(group.DataChanged(), groupstore.COLUMN_EXTERNAL_ID) {
+			if err := h.externalIDCheck(txCtx, group); err != nil {
+				return err
+			}
+		}
+
+		return h.store.GroupUpdate(txCtx, group)
+	})
+
+	if reqErr := (requestError{}); errors.As(err, &reqErr) {
+		writeError(w, reqErr.statusCode(), reqErr.scimType, reqErr)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err)
+		return
+	}
+
+	resource, err := h.groupToResource(r.Context(), group, !membersExcluded(r))
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resource)
+}
+
+// applyOperation applies a patch operation to the group
+//
+// Business logic:
+//   - the operation names are case insensitive (some providers send "Add")
+//   - without a path, the value is an object of the attributes to add or replace
+//   - the members can be removed by a value filter, i.e. members[value eq "id"],
+//     by a list of members in the value, or all at once
+func (h *handler) applyOperation(ctx context.Context, group groupstore.GroupInterface, operation patchOperation) error {
+	op := strings.ToLower(operation.Op)
+
+	if !slices.Contains([]string{"add", "remove", "replace"}, op) {
+		return requestError{scimType: "invalidSyntax", detail: "unsupported operation: " + operation.Op}
+	}
+
+	path := strings.TrimSpace(operation.Path)
+
+	if path == "" {
+		if op == "remove" {
+			return requestError{scimType: "noTarget", detail: "path is required for remove"}
+		}
+
+		attributes := map[string]json.RawMessage{}
+
+		if err := json.Unmarshal(operation.Value, &attributes); err != nil {
+			return requestError{scimType: "invalidValue", detail: "value must be an object, when the path is omitted"}
+		}
+
+		for name, value := range attributes {
+			if err := h.applyAttribute(ctx, group, op, attributeName(name), nil, value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	name, filter, err := parsePath(path)
+
+	if err != nil {
+		return err
+	}
+
+	return h.applyAttribute(ctx, group, op, name, filter, operation.Value)
+}
+
+// applyAttribute applies the operation to a single attribute of the group
+func (h *handler) applyAttribute(ctx context.Context, group groupstore.GroupInterface, op string, name string, filter filterExpr, value json.RawMessage) error {
+	if filter != nil && name != "members" {
+		return requestError{scimType: "invalidPath", detail: "value filters are only supported on members"}
+	}
+
+	switch name {
+	case attributeDisplayName:
+		if op == "remove" {
+			return requestError{scimType: "mutability", detail: "displayName is required and cannot be removed"}
+		}
+
+		displayName, err := decodeString(value)
+
+		if err != nil || displayName == "" {
+			return requestError{scimType: "invalidValue", detail: "displayName must be a non empty string"}
+		}
+
+		group.SetTitle(displayName)
+
+		return nil
+	case attributeExternalID:
+		if op == "remove" {
+			group.SetExternalID("")
+			return nil
+		}
+
+		externalID, err := decodeString(value)
+
+		if err != nil {
+			return requestError{scimType: "invalidValue", detail: "externalId must be a string"}
+		}
+
+		group.SetExternalID(externalID)
+
+		return nil
+	case "members":
+		return h.applyMembers(ctx, group.ID(), op, filter, value)
+	}
+
+	return requestError{scimType: "invalidPath", detail: "unsupported attribute: " + name}
+}
+
+// applyMembers applies the operation to the members of the group
+func (h *handler) applyMembers(ctx context.Context, groupID string, op string, filter filterExpr, value json.RawMessage) error {
+	if filter != nil && op != "remove" {
+		return requestError{scimType: "invalidPath", detail: "member filters are only supported for remove"}
+	}
+
+	if op == "remove" && filter != nil {
+		memberIDs, err := h.memberIDs(ctx, groupID)
+
+		if err != nil {
+			return err
+		}
+
+		return h.membersRemove(ctx, groupID, lo.Filter(memberIDs, func(memberID string, _ int) bool {
+			return filter.match(map[string]string{attributeValue: memberID})
+		}))
+	}
+
+	members, err := decodeMembers(value)
+
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case "add":
+		return h.membersAdd(ctx, groupID, members)
+	case "replace":
+		return h.membersReplace(ctx, groupID, members)
+	}
+
+	// remove without a value removes all the members
+	if len(members) == 0 {
+		return h.membersReplace(ctx, groupID, []member{})
+	}
+
+	return h.membersRemove(ctx, groupID, lo.Map(members, func(m member, _ int) string {
+		return m.Value
+	}))
+}
+
+// membersAdd adds the members to the group, the existing members are skipped
+//
+// Business logic:
+//   - the pending, invited and rejected users are activated
+//   - a banned user cannot be added, the request is rejected
+func (h *handler) membersAdd(ctx context.Context, groupID string, members []member) error {
+	for _, m := range members {
+		_, err := h.store.RelationActivate(ctx, groupstore.ENTITY_TYPE_USER, m.Value, groupID)
+
+		if errors.Is(err, groupstore.ErrRelationBanned) {
+			return requestError{scimType: "invalidValue", detail: "member " + m.Value + " is banned from the group"}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// membersRemove removes the members with the IDs from the group
+func (h *handler) membersRemove(ctx context.Context, groupID string, memberIDs []string) error {
+	for _, memberID := range memberIDs {
+		relation, err := h.store.RelationFindByEntityAndGroup(ctx, groupstore.ENTITY_TYPE_USER, memberID, groupID)
+
+		if err != nil {
+			return err
+		}
+
+		if relation == nil {
+			continue
+		}
+
+		if err := h.store.RelationSoftDelete(ctx, relation); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// membersReplace sets the members of the group to exactly the given members
+func (h *handler) membersReplace(ctx context.Context, groupID string, members []member) error {
+	currentIDs, err := h.memberIDs(ctx, groupID)
+
+	if err != nil {
+		return err
+	}
+
+	desiredIDs := lo.Uniq(lo.Map(members, func(m member, _ int) string {
+		return m.Value
+	}))
+
+	removedIDs, addedIDs := lo.Difference(currentIDs, desiredIDs)
+
+	if err := h.membersRemove(ctx, groupID, removedIDs); err != nil {
+		return err
+	}
+
+	return h.membersAdd(ctx, groupID, lo.Map(addedIDs, func(memberID string, _ int) member {
+		return member{Value: memberID}
+	}))
+}
+
+// == HELPERS =================================================================
+
+// parsePath parses a patch path into the attribute name and the optional
+// value filter, i.e. members[value eq "2819c223"]
+func parsePath(path string) (string, filterExpr, error) {
+	open := strings.Index(path, "[")
+
+	if open < 0 {
+		return attributeName(path), nil, nil
+	}
+
+	if !strings.HasSuffix(path, "]") {
+		return "", nil, requestError{scimType: "invalidPath", detail: "unsupported path: " + path}
+	}
+
+	filter, err := parseFilter(path[open+1:len(path)-1], []string{attributeValue}, []string{attributeValue})
+
+	if err != nil {
+		return "", nil, requestError{scimType: "invalidFilter", detail: err.Error()}
+	}
+
+	return attributeName(path[:open]), filter, nil
+}
+
+// decodeMembers decodes a list of members, or a single member, an empty
+// or null value decodes to no members
+func decodeMembers(value json.RawMessage) ([]member, error) {
+	value = bytes.TrimSpace(value)
+
+	if len(value) == 0 || bytes.Equal(value, []byte("null")) {
+		return []member{}, nil
+	}
+
+	members := []member{}
+
+	if value[0] == '{' {
+		single := member{}
+
+		if err := json.Unmarshal(value, &single); err != nil {
+			return nil, requestError{scimType: "invalidValue", detail: "invalid member: " + err.Error()}
+		}
+
+		members = append(members, single)
+	} else if err := json.Unmarshal(value, &members); err != nil {
+		return nil, requestError{scimType: "invalidValue", detail: "invalid members: " + err.Error()}
+	}
+
+	if err := validateMembers(members); err != nil {
+		return nil, requestError{scimType: "invalidValue", detail: err.Error()}
+	}
+
+	return members, nil
+}
+
+// validateMembers checks that all the members have a value
+func validateMembers(members []member) error {
+	for _, m := range members {
+		if m.Value == "" {
+			return errors.New("member value is required")
+		}
+	}
+
+	return nil
+}
+
+// decodeString decodes a string value
+func decodeString(value json.RawMessage) (string, error) {
+	decoded := ""
+
+	if err := json.Unmarshal(value, &decoded); err != nil {
+		return "", err
+	}
+
+	return decoded, nil
+}
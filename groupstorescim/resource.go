@@ -0,0 +1,138 @@
+package groupstorescim
+
+import (
+	"context"
+
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/groupstore"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+// SCHEMA_GROUP is the URN of the SCIM core group schema
+const SCHEMA_GROUP = "urn:ietf:params:scim:schemas:core:2.0:Group"
+
+// SCHEMA_LIST_RESPONSE is the URN of the SCIM list response message
+const SCHEMA_LIST_RESPONSE = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+
+// SCHEMA_PATCH_OP is the URN of the SCIM patch request message
+const SCHEMA_PATCH_OP = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+
+// SCHEMA_ERROR is the URN of the SCIM error message
+const SCHEMA_ERROR = "urn:ietf:params:scim:api:messages:2.0:Error"
+
+// filter attributes (lower case) of the groups and of the members
+const (
+	attributeDisplayName = "displayname"
+	attributeExternalID  = "externalid"
+	attributeID          = "id"
+	attributeValue       = "value"
+)
+
+// groupResource is the SCIM representation of a group
+type groupResource struct {
+	Schemas     []string      `json:"schemas"`
+	ID          string        `json:"id,omitempty"`
+	ExternalID  string        `json:"externalId,omitempty"`
+	DisplayName string        `json:"displayName"`
+	Members     *[]member     `json:"members,omitempty"`
+	Meta        *resourceMeta `json:"meta,omitempty"`
+}
+
+// member is a member of a group, the value is the user ID
+type member struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+	Type    string `json:"type,omitempty"`
+}
+
+// resourceMeta is the SCIM resource metadata
+type resourceMeta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created"`
+	LastModified string `json:"lastModified"`
+}
+
+// listResponse is the SCIM list response
+type listResponse struct {
+	Schemas      []string        `json:"schemas"`
+	TotalResults int             `json:"totalResults"`
+	StartIndex   int             `json:"startIndex"`
+	ItemsPerPage int             `json:"itemsPerPage"`
+	Resources    []groupResource `json:"Resources"`
+}
+
+// errorResponse is the SCIM error response, the status is a string as
+// required by RFC 7644
+type errorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// groupAttributes returns the filterable attributes of the group
+func groupAttributes(group groupstore.GroupInterface) map[string]string {
+	return map[string]string{
+		attributeID:          group.ID(),
+		attributeDisplayName: group.Title(),
+		attributeExternalID:  group.ExternalID(),
+	}
+}
+
+// groupToResource converts the group to its SCIM representation,
+// loading its members unless excluded
+func (h *handler) groupToResource(ctx context.Context, group groupstore.GroupInterface, withMembers bool) (groupResource, error) {
+	resource := groupResource{
+		Schemas:     []string{SCHEMA_GROUP},
+		ID:          group.ID(),
+		ExternalID:  group.ExternalID(),
+		DisplayName: group.Title(),
+		Meta: &resourceMeta{
+			ResourceType: "Group",
+			Created:      scimDateTime(group.CreatedAtCarbon()),
+			LastModified: scimDateTime(group.UpdatedAtCarbon()),
+		},
+	}
+
+	if !withMembers {
+		return resource, nil
+	}
+
+	memberIDs, err := h.memberIDs(ctx, group.ID())
+
+	if err != nil {
+		return groupResource{}, err
+	}
+
+	members := lo.Map(memberIDs, func(memberID string, _ int) member {
+		return member{Value: memberID}
+	})
+
+	resource.Members = &members
+
+	return resource, nil
+}
+
+// memberIDs returns the IDs of the users, which are members of the group
+func (h *handler) memberIDs(ctx context.Context, groupID string) ([]string, error) {
+	relations, err := h.store.RelationList(ctx, groupstore.NewRelationQuery().
+		SetGroupID(groupID).
+		SetEntityType(groupstore.ENTITY_TYPE_USER).
+		SetOrderBy(groupstore.COLUMN_ENTITY_ID).
+		SetSortDirection(sb.ASC))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.Map(relations, func(relation groupstore.RelationInterface, _ int) string {
+		return relation.EntityID()
+	}), nil
+}
+
+// scimDateTime formats the datetime as required by SCIM (RFC 3339)
+func scimDateTime(datetime *carbon.Carbon) string {
+	return datetime.SetTimezone(carbon.UTC).ToRfc3339String()
+}
@@ -3,6 +3,8 @@ package groupstore
 import (
 	"context"
 	"database/sql"
+	"io"
+	"iter"
 
 	"github.com/dromara/carbon/v2"
 )
@@ -17,12 +19,35 @@ type StoreInterface interface {
 	// DB returns the underlying database connection
 	DB() *sql.DB
 
+	// WithTransaction runs fn in a transaction, committed when fn succeeds
+	// and rolled back otherwise, the store methods called with the context
+	// of fn joining it. A context carrying a transaction already is joined
+	WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error
+
+	// AuditList returns the records of the audit trail, the most recent
+	// first, ErrAuditTrailDisabled when the store has no audit table
+	AuditList(ctx context.Context, options AuditListOptions) ([]AuditRecord, error)
+
+	// == Entity Methods ======================================================//
+
+	// EntitiesInAllGroups returns the IDs of the entities, which are members of all the given groups
+	EntitiesInAllGroups(ctx context.Context, groupIDs []string, query EntityQueryInterface) ([]string, error)
+
+	// EntitiesInAnyGroup returns the IDs of the entities, which are members of at least one of the given groups
+	EntitiesInAnyGroup(ctx context.Context, groupIDs []string, query EntityQueryInterface) ([]string, error)
+
+	// EntitiesNotInGroups returns the IDs of the entities, which are not members of any of the given groups
+	EntitiesNotInGroups(ctx context.Context, groupIDs []string, query EntityQueryInterface) ([]string, error)
+
 	// == Group Methods =======================================================//
 
+	// GroupClone clones a group, with its nested groups and its relations when included, in one transaction
+	GroupClone(ctx context.Context, groupID string, options GroupCloneOptions) (GroupInterface, error)
+
 	// GroupCount returns the number of groups based on the given query options
 	GroupCount(ctx context.Context, options GroupQueryInterface) (int64, error)
 
-	// GroupCreate creates a new group
+	// GroupCreate creates a new group, with a valid status, generating a unique handle when enabled
 	GroupCreate(ctx context.Context, group GroupInterface) error
 
 	// GroupDelete deletes a group
@@ -31,27 +56,71 @@ type StoreInterface interface {
 	// GroupDeleteByID deletes a group by its ID
 	GroupDeleteByID(ctx context.Context, id string) error
 
-	// GroupFindByHandle returns a group by its handle
+	// GroupFindByHandle returns a group by its handle, or by a previous handle when the handle history is enabled
 	GroupFindByHandle(ctx context.Context, handle string) (GroupInterface, error)
 
 	// GroupFindByID returns a group by its ID
 	GroupFindByID(ctx context.Context, id string) (GroupInterface, error)
 
+	// GroupIter streams the groups based on the given query options, without loading them all in memory
+	GroupIter(ctx context.Context, query GroupQueryInterface) iter.Seq2[GroupInterface, error]
+
 	// GroupList returns a list of groups based on the given query options
 	GroupList(ctx context.Context, query GroupQueryInterface) ([]GroupInterface, error)
 
+	// GroupMerge moves the relations and the metas of the source groups to the target group, and soft deletes the sources, in one transaction
+	GroupMerge(ctx context.Context, sourceIDs []string, targetID string, options GroupMergeOptions) (GroupMergeResult, error)
+
+	// GroupRemainingCapacity returns the number of members, which can still be added to the group, or GROUP_CAPACITY_UNLIMITED
+	GroupRemainingCapacity(ctx context.Context, groupID string) (int, error)
+
+	// GroupReorder positions the groups of the scope (nil for all the groups) in the given order, followed by the others, in the positions held by the scope
+	GroupReorder(ctx context.Context, scope GroupQueryInterface, groupIDs []string) error
+
+	// GroupScheduleSweep activates and deactivates the groups, which are due according to their schedules
+	GroupScheduleSweep(ctx context.Context) (GroupScheduleSweepResult, error)
+
 	// GroupSoftDelete soft deletes a group
 	GroupSoftDelete(ctx context.Context, group GroupInterface) error
 
 	// GroupSoftDeleteByID soft deletes a group by its ID
 	GroupSoftDeleteByID(ctx context.Context, id string) error
 
-	// GroupUpdate updates a group
+	// GroupSplit creates the new group, and moves to it the relations of the group matching the predicate, in one transaction
+	GroupSplit(ctx context.Context, groupID string, newGroup GroupInterface, predicate func(relation RelationInterface) bool) (GroupSplitResult, error)
+
+	// GroupTemplateInstantiate creates the groups of the template, with the variables substituted, in one transaction
+	GroupTemplateInstantiate(ctx context.Context, template GroupTemplate, variables map[string]string) (GroupInterface, error)
+
+	// GroupUpdate updates a group, a changed status must be allowed by the status transitions, a changed handle is kept in the handle history
 	GroupUpdate(ctx context.Context, group GroupInterface) error
 
+	// GroupUpsertByHandle creates a group, or updates the existing group with the same handle
+	GroupUpsertByHandle(ctx context.Context, group GroupInterface) error
+
+	// == Membership Methods ==================================================//
+
+	// IsMember checks if an entity is a member of a group, given by its ID or handle
+	IsMember(ctx context.Context, entityType string, entityID string, groupIDOrHandle string) (bool, error)
+
+	// BatchIsMember checks many entity to group memberships at once, results follow the order of the checks
+	BatchIsMember(ctx context.Context, checks []MembershipCheck) ([]bool, error)
+
 	// == Relation Methods ====================================================//
 
-	// RelationCount returns the number of group entities mappings based on the given query options
+	// RelationActivate makes sure the entity is an active member of the group, approving the pending, invited and rejected relations, and returns the relation
+	RelationActivate(ctx context.Context, entityType string, entityID string, groupID string) (RelationInterface, error)
+
+	// RelationApprove approves the pending request, or accepts the invitation, the relation becomes active
+	RelationApprove(ctx context.Context, relationID string) error
+
+	// RelationBan bans the entity from the group, so that it cannot request to join again
+	RelationBan(ctx context.Context, relationID string) error
+
+	// RelationBulkCreate creates many group entity mappings with multi-row inserts, skipping the existing ones
+	RelationBulkCreate(ctx context.Context, relations []RelationInterface) error
+
+	// RelationCount returns the number of group entities mappings based on the given query options, only the active ones unless a status is given
 	RelationCount(ctx context.Context, options RelationQueryInterface) (int64, error)
 
 	// RelationCreate creates a new group entity mapping
@@ -63,15 +132,39 @@ type StoreInterface interface {
 	// RelationDeleteByID deletes a group entity mapping by its ID
 	RelationDeleteByID(ctx context.Context, id string) error
 
-	// RelationFindByEntityAndGroup returns a group entity mapping by its entity type, entity ID and group ID
+	// RelationEnsure creates a group entity mapping, unless it exists already (with any status), and returns it
+	RelationEnsure(ctx context.Context, entityType string, entityID string, groupID string) (RelationInterface, error)
+
+	// RelationFindByEntityAndGroup returns a group entity mapping by its entity type, entity ID and group ID, with any status
 	RelationFindByEntityAndGroup(ctx context.Context, entityType string, entityID string, groupID string) (RelationInterface, error)
 
-	// RelationFindByID returns a group entity mapping by its ID
+	// RelationFindByID returns a group entity mapping by its ID, with any status
 	RelationFindByID(ctx context.Context, id string) (RelationInterface, error)
 
-	// RelationList returns a list of group entity mappings based on the given query options
+	// RelationInvite invites the entity to join the group, the relation is invited until approved or rejected
+	RelationInvite(ctx context.Context, entityType string, entityID string, groupID string) (RelationInterface, error)
+
+	// RelationIter streams the group entity mappings based on the given query options, without loading them all in memory
+	RelationIter(ctx context.Context, query RelationQueryInterface) iter.Seq2[RelationInterface, error]
+
+	// RelationList returns a list of group entity mappings based on the given query options, only the active ones unless a status is given
 	RelationList(ctx context.Context, query RelationQueryInterface) ([]RelationInterface, error)
 
+	// RelationMoveAfter moves the relation after the other relation of its group, renumbering the positions of the group
+	RelationMoveAfter(ctx context.Context, relationID string, afterRelationID string) error
+
+	// RelationMoveBefore moves the relation before the other relation of its group, renumbering the positions of the group
+	RelationMoveBefore(ctx context.Context, relationID string, beforeRelationID string) error
+
+	// RelationReject rejects the pending request, or declines the invitation
+	RelationReject(ctx context.Context, relationID string) error
+
+	// RelationReorder positions the relations of the group in the given order, followed by the others, from 1
+	RelationReorder(ctx context.Context, groupID string, relationIDs []string) error
+
+	// RelationRequest records the request of the entity to join the group, the relation is pending until approved or rejected
+	RelationRequest(ctx context.Context, entityType string, entityID string, groupID string) (RelationInterface, error)
+
 	// RelationSoftDelete soft deletes a group entity mapping
 	RelationSoftDelete(ctx context.Context, relation RelationInterface) error
 
@@ -80,6 +173,59 @@ type StoreInterface interface {
 
 	// RelationUpdate updates a group entity mapping
 	RelationUpdate(ctx context.Context, relation RelationInterface) error
+
+	// == Exclusive Set Methods ===============================================//
+
+	// SwitchGroup moves the entity to the group atomically, leaving the other groups of the exclusive set
+	SwitchGroup(ctx context.Context, entityType string, entityID string, exclusiveSet string, toGroupID string) (RelationInterface, error)
+
+	// == Dynamic Group Methods ===============================================//
+
+	// DynamicGroupMaterialize syncs the relations of the dynamic group with the entities matching its rule
+	DynamicGroupMaterialize(ctx context.Context, groupID string) (MaterializeResult, error)
+
+	// DynamicGroupsMaterialize syncs the relations of all the dynamic groups with the entities matching their rules
+	DynamicGroupsMaterialize(ctx context.Context) (MaterializeResult, error)
+
+	// == Snapshot Methods ====================================================//
+
+	// Export writes a snapshot of the groups and relations as a versioned JSON document
+	Export(ctx context.Context, w io.Writer, options ExportOptions) error
+
+	// Import reads a snapshot written by Export, and imports it in a single transaction
+	Import(ctx context.Context, r io.Reader, options ImportOptions) (ImportResult, error)
+
+	// == CSV Methods =========================================================//
+
+	// RelationExportCSV writes the group entity mappings as CSV rows
+	RelationExportCSV(ctx context.Context, w io.Writer, options CSVExportOptions) error
+
+	// RelationImportCSV reads group entity mappings from CSV rows, validates them, and adds (and optionally removes) them
+	RelationImportCSV(ctx context.Context, r io.Reader, options CSVImportOptions) (CSVImportResult, error)
+
+	// == Reconcile Methods ===================================================//
+
+	// Reconcile brings the groups and memberships in line with the manifest, and returns the plan of the changes
+	Reconcile(ctx context.Context, manifest Manifest, options ReconcileOptions) (ReconcilePlan, error)
+}
+
+// EntityAttributeResolver provides the attributes of the entities, which
+// the rules of the dynamic groups are evaluated against
+type EntityAttributeResolver interface {
+	// EntityAttributes returns the attributes of the entities of the type,
+	// the entities not found are left out
+	EntityAttributes(ctx context.Context, entityType string, entityIDs []string) ([]EntityAttributes, error)
+
+	// EntityIter streams all the entities of the type with their attributes,
+	// used for materialising the members of the dynamic groups
+	EntityIter(ctx context.Context, entityType string) iter.Seq2[EntityAttributes, error]
+}
+
+// EntityAttributes are the attributes of an entity, an attribute may
+// have several values (i.e. tags)
+type EntityAttributes struct {
+	EntityID   string
+	Attributes map[string][]string
 }
 
 type GroupInterface interface {
@@ -92,15 +238,35 @@ type GroupInterface interface {
 	// methods
 
 	IsActive() bool
+	IsArchived() bool
+	IsDynamic() bool
 	IsInactive() bool
 	IsSoftDeleted() bool
 
 	// setters and getters
 
+	ActivateAt() string
+	ActivateAtCarbon() *carbon.Carbon
+	SetActivateAt(activateAt string) GroupInterface
+
+	Capacity() int
+	SetCapacity(capacity int) GroupInterface
+
 	CreatedAt() string
 	CreatedAtCarbon() *carbon.Carbon
 	SetCreatedAt(createdAt string) GroupInterface
 
+	DeactivateAt() string
+	DeactivateAtCarbon() *carbon.Carbon
+	SetDeactivateAt(deactivateAt string) GroupInterface
+
+	ExclusiveSet() string
+	SetExclusiveSet(exclusiveSet string) GroupInterface
+
+	// ExternalID is the ID of the group in an external system
+	ExternalID() string
+	SetExternalID(externalID string) GroupInterface
+
 	Handle() string
 	SetHandle(handle string) GroupInterface
 
@@ -115,6 +281,15 @@ type GroupInterface interface {
 	Metas() (map[string]string, error)
 	SetMetas(metas map[string]string) error
 
+	// Position is the position of the group in its list, 0 when not positioned
+	Position() int
+	SetPosition(position int) GroupInterface
+
+	Rule() (*GroupRule, error)
+	SetRule(rule *GroupRule) error
+	RuleJSON() string
+	SetRuleJSON(ruleJSON string) GroupInterface
+
 	Status() string
 	SetStatus(status string) GroupInterface
 
@@ -139,6 +314,11 @@ type RelationInterface interface {
 
 	// methods
 
+	IsActive() bool
+	IsBanned() bool
+	IsInvited() bool
+	IsPending() bool
+	IsRejected() bool
 	IsSoftDeleted() bool
 
 	// setters and getters
@@ -167,10 +347,17 @@ type RelationInterface interface {
 	GroupID() string
 	SetGroupID(groupID string) RelationInterface
 
+	// Position is the position of the relation in its group, 0 when not positioned
+	Position() int
+	SetPosition(position int) RelationInterface
+
 	SoftDeletedAt() string
 	SoftDeletedAtCarbon() *carbon.Carbon
 	SetSoftDeletedAt(softDeletedAt string) RelationInterface
 
+	Status() string
+	SetStatus(status string) RelationInterface
+
 	UpdatedAt() string
 	UpdatedAtCarbon() *carbon.Carbon
 	SetUpdatedAt(updatedAt string) RelationInterface
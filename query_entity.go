@@ -0,0 +1,160 @@
+package groupstore
+
+import "errors"
+
+// EntityQueryInterface defines the options for the entity set operations
+// (EntitiesInAllGroups, EntitiesInAnyGroup, EntitiesNotInGroups)
+type EntityQueryInterface interface {
+	Validate() error
+
+	HasEntityType() bool
+	EntityType() string
+	SetEntityType(entityType string) EntityQueryInterface
+
+	HasGroupIDNotIn() bool
+	GroupIDNotIn() []string
+	SetGroupIDNotIn(groupIDNotIn []string) EntityQueryInterface
+
+	HasLimit() bool
+	Limit() int
+	SetLimit(limit int) EntityQueryInterface
+
+	HasOffset() bool
+	Offset() int
+	SetOffset(offset int) EntityQueryInterface
+
+	HasSortDirection() bool
+	SortDirection() string
+	SetSortDirection(sortDirection string) EntityQueryInterface
+
+	hasProperty(name string) bool
+}
+
+func NewEntityQuery() EntityQueryInterface {
+	return &entityQueryImplementation{
+		properties: make(map[string]any),
+	}
+}
+
+type entityQueryImplementation struct {
+	properties map[string]any
+}
+
+func (c *entityQueryImplementation) Validate() error {
+	if !c.HasEntityType() || c.EntityType() == "" {
+		return errors.New("entity query. entity_type cannot be empty")
+	}
+
+	if c.HasGroupIDNotIn() && len(c.GroupIDNotIn()) == 0 {
+		return errors.New("entity query. group_id_not_in cannot be empty")
+	}
+
+	if c.HasSortDirection() && c.SortDirection() == "" {
+		return errors.New("entity query. sort_direction cannot be empty")
+	}
+
+	if c.HasLimit() && c.Limit() <= 0 {
+		return errors.New("entity query. limit must be greater than 0")
+	}
+
+	if c.HasOffset() && c.Offset() < 0 {
+		return errors.New("entity query. offset must be greater than or equal to 0")
+	}
+
+	return nil
+}
+
+func (c *entityQueryImplementation) HasEntityType() bool {
+	return c.hasProperty("entity_type")
+}
+
+func (c *entityQueryImplementation) EntityType() string {
+	if !c.HasEntityType() {
+		return ""
+	}
+
+	return c.properties["entity_type"].(string)
+}
+
+func (c *entityQueryImplementation) SetEntityType(entityType string) EntityQueryInterface {
+	c.properties["entity_type"] = entityType
+
+	return c
+}
+
+func (c *entityQueryImplementation) HasGroupIDNotIn() bool {
+	return c.hasProperty("group_id_not_in")
+}
+
+func (c *entityQueryImplementation) GroupIDNotIn() []string {
+	if !c.HasGroupIDNotIn() {
+		return []string{}
+	}
+
+	return c.properties["group_id_not_in"].([]string)
+}
+
+func (c *entityQueryImplementation) SetGroupIDNotIn(groupIDNotIn []string) EntityQueryInterface {
+	c.properties["group_id_not_in"] = groupIDNotIn
+
+	return c
+}
+
+func (c *entityQueryImplementation) HasLimit() bool {
+	return c.hasProperty("limit")
+}
+
+func (c *entityQueryImplementation) Limit() int {
+	if !c.HasLimit() {
+		return 0
+	}
+
+	return c.properties["limit"].(int)
+}
+
+func (c *entityQueryImplementation) SetLimit(limit int) EntityQueryInterface {
+	c.properties["limit"] = limit
+
+	return c
+}
+
+func (c *entityQueryImplementation) HasOffset() bool {
+	return c.hasProperty("offset")
+}
+
+func (c *entityQueryImplementation) Offset() int {
+	if !c.HasOffset() {
+		return 0
+	}
+
+	return c.properties["offset"].(int)
+}
+
+func (c *entityQueryImplementation) SetOffset(offset int) EntityQueryInterface {
+	c.properties["offset"] = offset
+
+	return c
+}
+
+func (c *entityQueryImplementation) HasSortDirection() bool {
+	return c.hasProperty("sort_direction")
+}
+
+func (c *entityQueryImplementation) SortDirection() string {
+	if !c.HasSortDirection() {
+		return ""
+	}
+
+	return c.properties["sort_direction"].(string)
+}
+
+func (c *entityQueryImplementation) SetSortDirection(sortDirection string) EntityQueryInterface {
+	c.properties["sort_direction"] = sortDirection
+
+	return c
+}
+
+func (c *entityQueryImplementation) hasProperty(name string) bool {
+	_, ok := c.properties[name]
+	return ok
+}
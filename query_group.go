@@ -5,6 +5,10 @@ import "errors"
 type GroupQueryInterface interface {
 	Validate() error
 
+	HasActivateAtLte() bool
+	ActivateAtLte() string
+	SetActivateAtLte(activateAtLte string) GroupQueryInterface
+
 	Columns() []string
 	SetColumns(columns []string) GroupQueryInterface
 
@@ -20,6 +24,18 @@ type GroupQueryInterface interface {
 	CreatedAtLte() string
 	SetCreatedAtLte(createdAtLte string) GroupQueryInterface
 
+	HasDeactivateAtLte() bool
+	DeactivateAtLte() string
+	SetDeactivateAtLte(deactivateAtLte string) GroupQueryInterface
+
+	HasExclusiveSet() bool
+	ExclusiveSet() string
+	SetExclusiveSet(exclusiveSet string) GroupQueryInterface
+
+	HasExternalID() bool
+	ExternalID() string
+	SetExternalID(externalID string) GroupQueryInterface
+
 	HasHandle() bool
 	Handle() string
 	SetHandle(handle string) GroupQueryInterface
@@ -90,6 +106,22 @@ func (c *groupQueryImplementation) Validate() error {
 		return errors.New("group query. status cannot be empty")
 	}
 
+	if c.HasActivateAtLte() && c.ActivateAtLte() == "" {
+		return errors.New("group query. activate_at_lte cannot be empty")
+	}
+
+	if c.HasDeactivateAtLte() && c.DeactivateAtLte() == "" {
+		return errors.New("group query. deactivate_at_lte cannot be empty")
+	}
+
+	if c.HasExclusiveSet() && c.ExclusiveSet() == "" {
+		return errors.New("group query. exclusive_set cannot be empty")
+	}
+
+	if c.HasExternalID() && c.ExternalID() == "" {
+		return errors.New("group query. external_id cannot be empty")
+	}
+
 	if c.HasTitleLike() && c.TitleLike() == "" {
 		return errors.New("group query. title_like cannot be empty")
 	}
@@ -181,6 +213,78 @@ func (c *groupQueryImplementation) SetCreatedAtLte(createdAtLte string) GroupQue
 	return c
 }
 
+func (c *groupQueryImplementation) HasActivateAtLte() bool {
+	return c.hasProperty("activate_at_lte")
+}
+
+func (c *groupQueryImplementation) ActivateAtLte() string {
+	if !c.HasActivateAtLte() {
+		return ""
+	}
+
+	return c.properties["activate_at_lte"].(string)
+}
+
+func (c *groupQueryImplementation) SetActivateAtLte(activateAtLte string) GroupQueryInterface {
+	c.properties["activate_at_lte"] = activateAtLte
+
+	return c
+}
+
+func (c *groupQueryImplementation) HasDeactivateAtLte() bool {
+	return c.hasProperty("deactivate_at_lte")
+}
+
+func (c *groupQueryImplementation) DeactivateAtLte() string {
+	if !c.HasDeactivateAtLte() {
+		return ""
+	}
+
+	return c.properties["deactivate_at_lte"].(string)
+}
+
+func (c *groupQueryImplementation) SetDeactivateAtLte(deactivateAtLte string) GroupQueryInterface {
+	c.properties["deactivate_at_lte"] = deactivateAtLte
+
+	return c
+}
+
+func (c *groupQueryImplementation) HasExclusiveSet() bool {
+	return c.hasProperty("exclusive_set")
+}
+
+func (c *groupQueryImplementation) ExclusiveSet() string {
+	if !c.HasExclusiveSet() {
+		return ""
+	}
+
+	return c.properties["exclusive_set"].(string)
+}
+
+func (c *groupQueryImplementation) SetExclusiveSet(exclusiveSet string) GroupQueryInterface {
+	c.properties["exclusive_set"] = exclusiveSet
+
+	return c
+}
+
+func (c *groupQueryImplementation) HasExternalID() bool {
+	return c.hasProperty("external_id")
+}
+
+func (c *groupQueryImplementation) ExternalID() string {
+	if !c.HasExternalID() {
+		return ""
+	}
+
+	return c.properties["external_id"].(string)
+}
+
+func (c *groupQueryImplementation) SetExternalID(externalID string) GroupQueryInterface {
+	c.properties["external_id"] = externalID
+
+	return c
+}
+
 func (c *groupQueryImplementation) HasID() bool {
 	return c.hasProperty("id")
 }
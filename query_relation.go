@@ -60,6 +60,15 @@ type RelationQueryInterface interface {
 	SoftDeletedIncluded() bool
 	SetSoftDeletedIncluded(softDeletedIncluded bool) RelationQueryInterface
 
+	// without a status, or a status in, only the active relations are selected
+	HasStatus() bool
+	Status() string
+	SetStatus(status string) RelationQueryInterface
+
+	HasStatusIn() bool
+	StatusIn() []string
+	SetStatusIn(statusIn []string) RelationQueryInterface
+
 	hasProperty(name string) bool
 }
 
@@ -98,6 +107,14 @@ func (c *groupEntityQueryImplementation) Validate() error {
 		return errors.New("group query. id_in cannot be empty")
 	}
 
+	if c.HasStatus() && c.Status() == "" {
+		return errors.New("group query. status cannot be empty")
+	}
+
+	if c.HasStatusIn() && len(c.StatusIn()) == 0 {
+		return errors.New("group query. status_in cannot be empty")
+	}
+
 	if c.HasOrderBy() && c.OrderBy() == "" {
 		return errors.New("group query. order_by cannot be empty")
 	}
@@ -365,6 +382,42 @@ func (c *groupEntityQueryImplementation) SetSoftDeletedIncluded(softDeletedInclu
 	return c
 }
 
+func (c *groupEntityQueryImplementation) HasStatus() bool {
+	return c.hasProperty("status")
+}
+
+func (c *groupEntityQueryImplementation) Status() string {
+	if !c.HasStatus() {
+		return ""
+	}
+
+	return c.properties["status"].(string)
+}
+
+func (c *groupEntityQueryImplementation) SetStatus(status string) RelationQueryInterface {
+	c.properties["status"] = status
+
+	return c
+}
+
+func (c *groupEntityQueryImplementation) HasStatusIn() bool {
+	return c.hasProperty("status_in")
+}
+
+func (c *groupEntityQueryImplementation) StatusIn() []string {
+	if !c.HasStatusIn() {
+		return []string{}
+	}
+
+	return c.properties["status_in"].([]string)
+}
+
+func (c *groupEntityQueryImplementation) SetStatusIn(statusIn []string) RelationQueryInterface {
+	c.properties["status_in"] = statusIn
+
+	return c
+}
+
 func (c *groupEntityQueryImplementation) HasTitleLike() bool {
 	return c.hasProperty("title_like")
 }
@@ -1,7 +1,10 @@
 package groupstore
 
 import (
+	"strings"
+
 	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
 )
 
 // sqlGroupTableCreate returns a SQL string for creating the group table
@@ -51,10 +54,76 @@ func (st *store) sqlGroupTableCreate() string {
 			Name:   COLUMN_SOFT_DELETED_AT,
 			Type:   sb.COLUMN_TYPE_DATETIME,
 			Length: 0,
-		}).
-		CreateIfNotExists()
+		})
+
+	for _, column := range st.groupTableColumnsAdded() {
+		sql = sql.Column(column)
+	}
 
-	return sql
+	return sql.CreateIfNotExists()
+}
+
+// groupTableColumnsAdded returns the columns added to the group table
+// after its first release, which are migrated on existing tables
+//
+// The columns are nullable, as the existing rows have no values for them
+func (st *store) groupTableColumnsAdded() []sb.Column {
+	return []sb.Column{
+		{
+			Name:     COLUMN_RULE,
+			Type:     sb.COLUMN_TYPE_TEXT,
+			Nullable: true,
+		},
+		{
+			Name:     COLUMN_CAPACITY,
+			Type:     sb.COLUMN_TYPE_INTEGER,
+			Nullable: true,
+		},
+		{
+			Name:     COLUMN_EXCLUSIVE_SET,
+			Type:     sb.COLUMN_TYPE_STRING,
+			Length:   100,
+			Nullable: true,
+		},
+		{
+			Name:     COLUMN_ACTIVATE_AT,
+			Type:     sb.COLUMN_TYPE_DATETIME,
+			Nullable: true,
+		},
+		{
+			Name:     COLUMN_DEACTIVATE_AT,
+			Type:     sb.COLUMN_TYPE_DATETIME,
+			Nullable: true,
+		},
+		{
+			Name:     COLUMN_POSITION,
+			Type:     sb.COLUMN_TYPE_INTEGER,
+			Nullable: true,
+		},
+		{
+			Name:     COLUMN_EXTERNAL_ID,
+			Type:     sb.COLUMN_TYPE_STRING,
+			Length:   255,
+			Nullable: true,
+		},
+	}
+}
+
+// groupExternalIDIndexName returns the name of the index of the external
+// IDs of the groups
+func (st *store) groupExternalIDIndexName() string {
+	return st.groupTableName + "_external_id_index"
+}
+
+// sqlIndexCreate returns a SQL string for creating the index of the
+// column of the table
+func (st *store) sqlIndexCreate(tableName string, indexName string, column string) string {
+	// MySQL does not support CREATE INDEX IF NOT EXISTS
+	ifNotExists := lo.Ternary(st.dbDriverName == sb.DIALECT_MYSQL, "", "IF NOT EXISTS ")
+
+	return "CREATE INDEX " + ifNotExists + st.sqlQuote(indexName) +
+		" ON " + st.sqlQuote(tableName) +
+		" (" + st.sqlQuote(column) + ");"
 }
 
 // sqlGroupEntityRelationTableCreate returns a SQL string for creating the  entity to group relation table
@@ -104,8 +173,228 @@ func (st *store) sqlGroupEntityRelationTableCreate() string {
 			Name:   COLUMN_SOFT_DELETED_AT,
 			Type:   sb.COLUMN_TYPE_DATETIME,
 			Length: 0,
+		})
+
+	for _, column := range st.relationTableColumnsAdded() {
+		sql = sql.Column(column)
+	}
+
+	return sql.CreateIfNotExists()
+}
+
+// relationTableColumnsAdded returns the columns added to the relation
+// table after its first release, which are migrated on existing tables
+//
+// The columns are nullable, as the existing rows have no values for them
+func (st *store) relationTableColumnsAdded() []sb.Column {
+	return []sb.Column{
+		{
+			Name:     COLUMN_STATUS,
+			Type:     sb.COLUMN_TYPE_STRING,
+			Length:   20,
+			Nullable: true,
+		},
+		{
+			Name:     COLUMN_POSITION,
+			Type:     sb.COLUMN_TYPE_INTEGER,
+			Nullable: true,
+		},
+	}
+}
+
+// liveUniqueIndex is a unique index, which covers the live rows of a
+// table only, so that any number of soft deleted rows may be kept
+type liveUniqueIndex struct {
+	// tableName is the name of the indexed table
+	tableName string
+
+	// name is the name of the index
+	name string
+
+	// columns are the indexed columns
+	columns []string
+
+	// condition further limits the indexed rows (i.e. the non empty
+	// handles), optional
+	condition string
+}
+
+// relationUniqueIndexNameLegacy returns the name of the unique index, which
+// covered the soft deleted at column too, replaced by relationUniqueIndex
+func (st *store) relationUniqueIndexNameLegacy() string {
+	return st.groupEntityRelationTableName + "_entity_group_unique"
+}
+
+// relationUniqueIndex returns the unique index of the live relations, one
+// per entity and group
+func (st *store) relationUniqueIndex() liveUniqueIndex {
+	return liveUniqueIndex{
+		tableName: st.groupEntityRelationTableName,
+		name:      st.groupEntityRelationTableName + "_entity_group_live_unique",
+		columns:   []string{COLUMN_ENTITY_TYPE, COLUMN_ENTITY_ID, COLUMN_GROUP_ID},
+	}
+}
+
+// groupHandleUniqueIndex returns the unique index of the handles of the
+// live groups, the empty handles excluded
+func (st *store) groupHandleUniqueIndex() liveUniqueIndex {
+	return liveUniqueIndex{
+		tableName: st.groupTableName,
+		name:      st.groupTableName + "_handle_live_unique",
+		columns:   []string{COLUMN_HANDLE},
+		condition: st.sqlQuote(COLUMN_HANDLE) + " <> ''",
+	}
+}
+
+// sqlLiveUniqueIndexCreate returns a SQL string for creating the unique
+// index of the live rows
+//
+// On SQLite and PostgreSQL the index is partial, with the live rows
+// condition. MySQL has no partial indexes, the index has a functional key
+// part (MySQL 8.0.13+) instead, which is NULL for the rows not covered,
+// the NULL values never being equal
+func (st *store) sqlLiveUniqueIndexCreate(index liveUniqueIndex) string {
+	columns := lo.Map(index.columns, func(column string, _ int) string {
+		return st.sqlQuote(column)
+	})
+
+	condition := st.sqlLiveCondition(index)
+
+	if st.dbDriverName == sb.DIALECT_MYSQL {
+		// MySQL does not support CREATE INDEX IF NOT EXISTS
+		columns = append(columns, "(IF("+condition+", 1, NULL))")
+
+		return "CREATE UNIQUE INDEX " + st.sqlQuote(index.name) +
+			" ON " + st.sqlQuote(index.tableName) +
+			" (" + strings.Join(columns, ", ") + ");"
+	}
+
+	return "CREATE UNIQUE INDEX IF NOT EXISTS " + st.sqlQuote(index.name) +
+		" ON " + st.sqlQuote(index.tableName) +
+		" (" + strings.Join(columns, ", ") + ")" +
+		" WHERE " + condition + ";"
+}
+
+// sqlLiveCondition returns the condition of the rows covered by the index
+func (st *store) sqlLiveCondition(index liveUniqueIndex) string {
+	condition := st.sqlQuote(COLUMN_SOFT_DELETED_AT) + " = '" + sb.MAX_DATETIME + "'"
+
+	if index.condition == "" {
+		return condition
+	}
+
+	return condition + " AND " + index.condition
+}
+
+// sqlLiveConflictTarget returns the conflict target of the upserts on the
+// unique index of the live rows (PostgreSQL and SQLite), the predicate of
+// a partial index being part of the target
+//
+// The target is wrapped in parentheses by goqu, hence the parentheses
+// left open
+func (st *store) sqlLiveConflictTarget(index liveUniqueIndex) string {
+	columns := lo.Map(index.columns, func(column string, _ int) string {
+		return st.sqlQuote(column)
+	})
+
+	return strings.Join(columns, ", ") + ") WHERE (" + st.sqlLiveCondition(index)
+}
+
+// sqlIndexDrop returns a SQL string for dropping the index of the table,
+// if it exists (MySQL does not support DROP INDEX IF EXISTS, its index
+// must exist)
+func (st *store) sqlIndexDrop(tableName string, indexName string) string {
+	if st.dbDriverName == sb.DIALECT_MYSQL {
+		return "DROP INDEX " + st.sqlQuote(indexName) + " ON " + st.sqlQuote(tableName) + ";"
+	}
+
+	return "DROP INDEX IF EXISTS " + st.sqlQuote(indexName) + ";"
+}
+
+// sqlQuote returns the quoted identifier
+func (st *store) sqlQuote(identifier string) string {
+	quote := lo.Ternary(st.dbDriverName == sb.DIALECT_MYSQL, "`", `"`)
+	return quote + identifier + quote
+}
+
+// sqlGroupHandleHistoryTableCreate returns a SQL string for creating the
+// group handle history table
+func (st *store) sqlGroupHandleHistoryTableCreate() string {
+	return sb.NewBuilder(sb.DatabaseDriverName(st.db)).
+		Table(st.groupHandleHistoryTableName).
+		Column(sb.Column{
+			Name:       COLUMN_ID,
+			Type:       sb.COLUMN_TYPE_STRING,
+			PrimaryKey: true,
+			Length:     40,
+		}).
+		Column(sb.Column{
+			Name:   COLUMN_GROUP_ID,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 40,
+		}).
+		Column(sb.Column{
+			Name:   COLUMN_HANDLE,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 50,
+		}).
+		Column(sb.Column{
+			Name:   COLUMN_CREATED_AT,
+			Type:   sb.COLUMN_TYPE_DATETIME,
+			Length: 0,
 		}).
 		CreateIfNotExists()
+}
+
+// auditGroupIndexName returns the name of the index of the groups of the
+// audit records
+func (st *store) auditGroupIndexName() string {
+	return st.auditTableName + "_group_id_index"
+}
 
-	return sql
+// sqlAuditTableCreate returns a SQL string for creating the audit table
+func (st *store) sqlAuditTableCreate() string {
+	return sb.NewBuilder(sb.DatabaseDriverName(st.db)).
+		Table(st.auditTableName).
+		Column(sb.Column{
+			Name:       COLUMN_ID,
+			Type:       sb.COLUMN_TYPE_STRING,
+			PrimaryKey: true,
+			Length:     40,
+		}).
+		Column(sb.Column{
+			Name:   COLUMN_ACTION,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 40,
+		}).
+		Column(sb.Column{
+			Name:   COLUMN_SUBJECT_TYPE,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 40,
+		}).
+		Column(sb.Column{
+			Name:   COLUMN_SUBJECT_ID,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 40,
+		}).
+		Column(sb.Column{
+			Name:   COLUMN_GROUP_ID,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 40,
+		}).
+		Column(sb.Column{
+			Name:   COLUMN_ACTOR,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 255,
+		}).
+		Column(sb.Column{
+			Name: COLUMN_DETAILS,
+			Type: sb.COLUMN_TYPE_TEXT,
+		}).
+		Column(sb.Column{
+			Name:   COLUMN_CREATED_AT,
+			Type:   sb.COLUMN_TYPE_DATETIME,
+			Length: 0,
+		}).
+		CreateIfNotExists()
 }
@@ -4,9 +4,21 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"iter"
 	"log/slog"
+	"strings"
 
+	"github.com/doug-martin/goqu/v9"
+	"github.com/dromara/carbon/v2"
 	"github.com/gouniverse/base/database"
+	"github.com/gouniverse/maputils"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+
+	// registers the goqu dialects, so that the generated SQL
+	// (quoting, placeholders, upserts) matches the database
+	_ "github.com/doug-martin/goqu/v9/dialect/mysql"
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
 )
 
 // == TYPE ====================================================================
@@ -18,6 +30,18 @@ type store struct {
 	// groupEntityRelationTableName is the name of the group entity relation table
 	groupEntityRelationTableName string
 
+	// groupHandleHistoryTableName is the name of the group handle history
+	// table, empty when the handle history is disabled
+	groupHandleHistoryTableName string
+
+	// auditTableName is the name of the audit table, empty when the audit
+	// trail is disabled
+	auditTableName string
+
+	// groupHandleGenerationEnabled enables or disables the generation of
+	// unique handles, when creating the groups
+	groupHandleGenerationEnabled bool
+
 	// db is the underlying database connection
 	db *sql.DB
 
@@ -32,6 +56,21 @@ type store struct {
 
 	// sqlLogger is the sql logger used when debug mode is enabled
 	sqlLogger *slog.Logger
+
+	// entityAttributeResolver provides the entity attributes for the rules
+	// of the dynamic groups, optional
+	entityAttributeResolver EntityAttributeResolver
+
+	// entityTypes are the policies of the registered entity types, by
+	// entity type, nil when any entity type is allowed
+	entityTypes map[string]EntityTypePolicy
+
+	// membershipQuotas limit the number of groups of the entities
+	membershipQuotas []MembershipQuota
+
+	// groupStatusTransitions are the statuses of the groups, with the
+	// statuses each one can move to
+	groupStatusTransitions map[string][]string
 }
 
 // == INTERFACE ===============================================================
@@ -58,6 +97,12 @@ func (store *store) AutoMigrate() error {
 		return err
 	}
 
+	err = store.tableColumnsAdd(store.groupTableName, store.groupTableColumnsAdded())
+
+	if err != nil {
+		return err
+	}
+
 	sqlStr = store.sqlGroupEntityRelationTableCreate()
 
 	if sqlStr == "" {
@@ -70,6 +115,305 @@ func (store *store) AutoMigrate() error {
 		return err
 	}
 
+	err = store.tableColumnsAdd(store.groupEntityRelationTableName, store.relationTableColumnsAdded())
+
+	if err != nil {
+		return err
+	}
+
+	if err := store.uniqueIndexesMigrate(); err != nil {
+		return err
+	}
+
+	if err := store.groupExternalIDIndexCreate(); err != nil {
+		return err
+	}
+
+	if store.groupHandleHistoryTableName != "" {
+		if _, err := store.db.Exec(store.sqlGroupHandleHistoryTableCreate()); err != nil {
+			return err
+		}
+	}
+
+	if store.auditTableName != "" {
+		if _, err := store.db.Exec(store.sqlAuditTableCreate()); err != nil {
+			return err
+		}
+
+		return store.indexCreate(store.auditTableName, store.auditGroupIndexName(), COLUMN_GROUP_ID)
+	}
+
+	return nil
+}
+
+// uniqueIndexesMigrate creates the unique indexes of the live relations,
+// and of the handles of the live groups, if they do not exist yet
+//
+// Business logic:
+//   - the legacy relation index, which covered the soft deleted at column,
+//     is dropped, as soft deleting a relation twice in one second broke it
+//   - the duplicate live relations are soft deleted, the oldest one kept
+//   - the duplicate handles of the live groups fail the migration, as they
+//     must be renamed, which only the owner of the data can decide
+func (store *store) uniqueIndexesMigrate() error {
+	if store.dbDriverName == sb.DIALECT_MSSQL {
+		return nil // not supported, duplicates are prevented by the store only
+	}
+
+	if err := store.indexDrop(store.groupEntityRelationTableName, store.relationUniqueIndexNameLegacy()); err != nil {
+		return err
+	}
+
+	if err := store.relationsLiveDuplicatesSoftDelete(); err != nil {
+		return err
+	}
+
+	if err := store.groupsLiveDuplicateHandlesCheck(); err != nil {
+		return err
+	}
+
+	for _, index := range []liveUniqueIndex{store.relationUniqueIndex(), store.groupHandleUniqueIndex()} {
+		exists, err := store.indexExists(index.tableName, index.name)
+
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			continue
+		}
+
+		sqlStr := store.sqlLiveUniqueIndexCreate(index)
+
+		store.logSql("create", sqlStr)
+
+		if _, err := store.db.Exec(sqlStr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// groupExternalIDIndexCreate creates the index of the external IDs of the
+// groups, if it does not exist yet
+func (store *store) groupExternalIDIndexCreate() error {
+	return store.indexCreate(store.groupTableName, store.groupExternalIDIndexName(), COLUMN_EXTERNAL_ID)
+}
+
+// indexCreate creates the index of the column of the table, if it does
+// not exist yet
+func (store *store) indexCreate(tableName string, indexName string, column string) error {
+	if store.dbDriverName == sb.DIALECT_MSSQL {
+		return nil // not supported, the column is looked up unindexed
+	}
+
+	exists, err := store.indexExists(tableName, indexName)
+
+	if err != nil || exists {
+		return err
+	}
+
+	sqlStr := store.sqlIndexCreate(tableName, indexName, column)
+
+	store.logSql("create", sqlStr)
+
+	_, err = store.db.Exec(sqlStr)
+
+	return err
+}
+
+// indexDrop drops the index of the table, if it exists
+func (store *store) indexDrop(tableName string, indexName string) error {
+	exists, err := store.indexExists(tableName, indexName)
+
+	if err != nil {
+		return err
+	}
+
+	if !exists && store.dbDriverName == sb.DIALECT_MYSQL {
+		return nil
+	}
+
+	sqlStr := store.sqlIndexDrop(tableName, indexName)
+
+	store.logSql("drop", sqlStr)
+
+	_, err = store.db.Exec(sqlStr)
+
+	return err
+}
+
+// indexExists returns true, if the index of the table exists
+//
+// It is checked on MySQL only, which does not support IF [NOT] EXISTS for
+// the indexes, on the other databases it returns false, the statements
+// having IF [NOT] EXISTS
+func (store *store) indexExists(tableName string, indexName string) (bool, error) {
+	if store.dbDriverName != sb.DIALECT_MYSQL {
+		return false, nil
+	}
+
+	rows, err := database.SelectToMapString(
+		database.Context(context.Background(), store.db),
+		"SELECT index_name FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?",
+		tableName,
+		indexName,
+	)
+
+	if err != nil {
+		return false, err
+	}
+
+	return len(rows) > 0, nil
+}
+
+// relationsLiveDuplicatesSoftDelete soft deletes the live relations, which
+// duplicate an older live relation of the same entity and group
+func (store *store) relationsLiveDuplicatesSoftDelete() error {
+	keyColumns := store.relationUniqueIndex().columns
+	ctx := database.Context(context.Background(), store.db)
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		From(store.groupEntityRelationTableName).
+		Prepared(true).
+		Select(lo.ToAnySlice(keyColumns)...).
+		Where(goqu.C(COLUMN_SOFT_DELETED_AT).Eq(sb.MAX_DATETIME)).
+		GroupBy(lo.ToAnySlice(keyColumns)...).
+		Having(goqu.COUNT(goqu.Star()).Gt(1)).
+		ToSQL()
+
+	if errSql != nil {
+		return errSql
+	}
+
+	store.logSql("select", sqlStr, params...)
+
+	duplicates, err := database.SelectToMapString(ctx, sqlStr, params...)
+
+	if err != nil {
+		return err
+	}
+
+	now := carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)
+
+	for _, duplicate := range duplicates {
+		relations, err := store.RelationList(ctx, NewRelationQuery().
+			SetEntityType(duplicate[COLUMN_ENTITY_TYPE]).
+			SetEntityID(duplicate[COLUMN_ENTITY_ID]).
+			SetGroupID(duplicate[COLUMN_GROUP_ID]).
+			SetStatusIn(RELATION_STATUSES).
+			SetOrderBy(COLUMN_CREATED_AT).
+			SetSortDirection(sb.ASC))
+
+		if err != nil {
+			return err
+		}
+
+		ids := lo.FilterMap(relations, func(relation RelationInterface, index int) (string, bool) {
+			return relation.ID(), index > 0 && strings.Contains(relation.SoftDeletedAt(), sb.MAX_DATETIME)
+		})
+
+		if len(ids) == 0 {
+			continue
+		}
+
+		sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+			Update(store.groupEntityRelationTableName).
+			Prepared(true).
+			Set(goqu.Record{COLUMN_SOFT_DELETED_AT: now}).
+			Where(goqu.C(COLUMN_ID).In(ids)).
+			ToSQL()
+
+		if errSql != nil {
+			return errSql
+		}
+
+		store.logSql("update", sqlStr, params...)
+
+		if _, err := database.Execute(ctx, sqlStr, params...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// groupsLiveDuplicateHandlesCheck returns an error listing the handles,
+// which more than one live group has
+func (store *store) groupsLiveDuplicateHandlesCheck() error {
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		From(store.groupTableName).
+		Prepared(true).
+		Select(COLUMN_HANDLE).
+		Where(
+			goqu.C(COLUMN_SOFT_DELETED_AT).Eq(sb.MAX_DATETIME),
+			goqu.C(COLUMN_HANDLE).Neq(""),
+		).
+		GroupBy(COLUMN_HANDLE).
+		Having(goqu.COUNT(goqu.Star()).Gt(1)).
+		ToSQL()
+
+	if errSql != nil {
+		return errSql
+	}
+
+	store.logSql("select", sqlStr, params...)
+
+	duplicates, err := database.SelectToMapString(database.Context(context.Background(), store.db), sqlStr, params...)
+
+	if err != nil {
+		return err
+	}
+
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	handles := lo.Map(duplicates, func(duplicate map[string]string, _ int) string { return duplicate[COLUMN_HANDLE] })
+
+	return errors.New("groupstore: the handles of the groups must be unique, rename the duplicates before migrating: " + strings.Join(handles, ", "))
+}
+
+// tableColumnsAdd adds the columns, which are missing from the table
+// (i.e. created by an older version), to the table
+func (store *store) tableColumnsAdd(tableName string, columns []sb.Column) error {
+	quote := lo.Ternary(store.dbDriverName == sb.DIALECT_MYSQL, "`", `"`)
+
+	rows, err := store.db.Query("SELECT * FROM " + quote + tableName + quote + " WHERE 1 = 0")
+
+	if err != nil {
+		return err
+	}
+
+	existing, err := rows.Columns()
+
+	if errClose := rows.Close(); err == nil {
+		err = errClose
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		if lo.ContainsBy(existing, func(name string) bool { return strings.EqualFold(name, column.Name) }) {
+			continue
+		}
+
+		sqlStr, err := sb.NewBuilder(store.dbDriverName).TableColumnAdd(tableName, column)
+
+		if err != nil {
+			return err
+		}
+
+		store.logSql("alter", sqlStr)
+
+		if _, err := store.db.Exec(sqlStr); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -94,6 +438,46 @@ func (store *store) logSql(sqlOperationType string, sql string, params ...interf
 	}
 }
 
+// WithTransaction runs fn within a transaction, see withTransaction
+func (store *store) WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	return store.withTransaction(ctx, fn)
+}
+
+// withTransaction runs fn within a transaction, which is committed when
+// fn succeeds and rolled back otherwise. When the context already carries
+// a transaction, fn joins it and the caller remains in charge of it.
+func (store *store) withTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	return store.withTransactionOptions(ctx, nil, fn)
+}
+
+// withTransactionOptions is withTransaction with the options (i.e. the
+// isolation level) of the new transaction, nil for the defaults
+func (store *store) withTransactionOptions(ctx context.Context, options *sql.TxOptions, fn func(txCtx context.Context) error) error {
+	if database.IsQueryableContext(ctx) && ctx.(database.QueryableContext).IsTx() {
+		return fn(ctx)
+	}
+
+	if store.db == nil {
+		return errors.New("groupstore: database is nil")
+	}
+
+	tx, err := store.db.BeginTx(ctx, options)
+
+	if err != nil {
+		return err
+	}
+
+	if err := fn(database.Context(ctx, tx)); err != nil {
+		if errRollback := tx.Rollback(); errRollback != nil {
+			return errors.Join(err, errRollback)
+		}
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
 // toQuerableContext converts the context to a QueryableContext
 func (store *store) toQuerableContext(ctx context.Context) database.QueryableContext {
 	if database.IsQueryableContext(ctx) {
@@ -102,3 +486,64 @@ func (store *store) toQuerableContext(ctx context.Context) database.QueryableCon
 
 	return database.Context(ctx, store.db)
 }
+
+// selectIter streams the rows returned by the sql query as string maps,
+// without loading them all in memory. Iteration stops at the first error,
+// which is yielded, or when the context is cancelled.
+func (store *store) selectIter(ctx context.Context, sqlStr string, params ...any) iter.Seq2[map[string]string, error] {
+	return func(yield func(map[string]string, error) bool) {
+		if store.db == nil {
+			yield(nil, errors.New("groupstore: database is nil"))
+			return
+		}
+
+		rows, err := database.Query(store.toQuerableContext(ctx), sqlStr, params...)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		columns, err := rows.Columns()
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		for rows.Next() {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if err := rows.Scan(pointers...); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			row := make(map[string]any, len(columns))
+
+			for i, column := range columns {
+				row[column] = values[i]
+			}
+
+			if !yield(maputils.MapStringAnyToMapStringString(row), nil) {
+				return
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
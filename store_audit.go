@@ -0,0 +1,285 @@
+package groupstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/base/database"
+	"github.com/gouniverse/sb"
+	"github.com/gouniverse/uid"
+	"github.com/samber/lo"
+)
+
+// ErrAuditTrailDisabled is returned by AuditList, when the store has no
+// audit table
+var ErrAuditTrailDisabled = errors.New("groupstore > audit trail is not enabled")
+
+// AuditRecord is an entry of the audit trail, a change of a group or of a
+// relation
+type AuditRecord struct {
+	ID string
+
+	// Action is the change, one of the AUDIT_ACTION_* constants
+	Action string
+
+	// SubjectType is the type of the changed record, AUDIT_SUBJECT_GROUP or
+	// AUDIT_SUBJECT_RELATION
+	SubjectType string
+
+	// SubjectID is the ID of the changed record
+	SubjectID string
+
+	// GroupID is the ID of the group concerned, the group itself or the
+	// group of the relation
+	GroupID string
+
+	// Actor is who made the change, as set with AuditActorContext, empty
+	// when not set
+	Actor string
+
+	// Details describe the change (i.e. the changed columns, the entity of
+	// a relation)
+	Details map[string]string
+
+	CreatedAt string
+}
+
+// AuditListOptions filter the audit records, the empty filters are not
+// applied
+type AuditListOptions struct {
+	GroupID   string
+	SubjectID string
+	Action    string
+
+	// Limit is the maximum number of records returned, defaults to 100
+	Limit int
+
+	Offset int
+}
+
+// auditActorKey is the context key of the audit actor
+type auditActorKey struct{}
+
+// AuditActorContext returns a copy of the context, the changes made with
+// which are recorded in the audit trail as made by the actor (i.e. the ID
+// of the signed in user)
+func AuditActorContext(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// AuditList returns the audit records, the most recent first
+func (store *store) AuditList(ctx context.Context, options AuditListOptions) ([]AuditRecord, error) {
+	if store.auditTableName == "" {
+		return nil, ErrAuditTrailDisabled
+	}
+
+	q := goqu.Dialect(store.dbDriverName).
+		From(store.auditTableName).
+		Prepared(true).
+		Order(goqu.C(COLUMN_CREATED_AT).Desc(), goqu.C(COLUMN_ID).Desc()).
+		Limit(uint(lo.Ternary(options.Limit > 0, options.Limit, 100))).
+		Offset(uint(max(options.Offset, 0)))
+
+	if options.GroupID != "" {
+		q = q.Where(goqu.C(COLUMN_GROUP_ID).Eq(options.GroupID))
+	}
+
+	if options.SubjectID != "" {
+		q = q.Where(goqu.C(COLUMN_SUBJECT_ID).Eq(options.SubjectID))
+	}
+
+	if options.Action != "" {
+		q = q.Where(goqu.C(COLUMN_ACTION).Eq(options.Action))
+	}
+
+	sqlStr, params, errSql := q.ToSQL()
+
+	if errSql != nil {
+		return nil, errSql
+	}
+
+	store.logSql("select", sqlStr, params...)
+
+	rows, err := database.SelectToMapString(store.toQuerableContext(ctx), sqlStr, params...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]AuditRecord, 0, len(rows))
+
+	for _, row := range rows {
+		details := map[string]string{}
+
+		if row[COLUMN_DETAILS] != "" {
+			if err := json.Unmarshal([]byte(row[COLUMN_DETAILS]), &details); err != nil {
+				return nil, err
+			}
+		}
+
+		records = append(records, AuditRecord{
+			ID:          row[COLUMN_ID],
+			Action:      row[COLUMN_ACTION],
+			SubjectType: row[COLUMN_SUBJECT_TYPE],
+			SubjectID:   row[COLUMN_SUBJECT_ID],
+			GroupID:     row[COLUMN_GROUP_ID],
+			Actor:       row[COLUMN_ACTOR],
+			Details:     details,
+			CreatedAt:   carbon.Parse(row[COLUMN_CREATED_AT], carbon.UTC).ToDateTimeString(carbon.UTC),
+		})
+	}
+
+	return records, nil
+}
+
+// == PRIVATE METHODS =========================================================
+
+// withAuditTransaction runs fn in a transaction, when the audit trail is
+// enabled, so that the changes and their audit records are written
+// together, and runs fn as is otherwise
+func (store *store) withAuditTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	if store.auditTableName == "" {
+		return fn(ctx)
+	}
+
+	return store.withTransaction(ctx, fn)
+}
+
+// executeAudited executes the write statement, and records the audit
+// record in the same transaction, when the audit trail is enabled
+func (store *store) executeAudited(ctx context.Context, record AuditRecord, sqlStr string, params ...any) error {
+	return store.withAuditTransaction(ctx, func(txCtx context.Context) error {
+		if _, err := database.Execute(store.toQuerableContext(txCtx), sqlStr, params...); err != nil {
+			return err
+		}
+
+		return store.auditRecord(txCtx, record)
+	})
+}
+
+// auditRecord inserts the audit record, when the audit trail is enabled,
+// with the actor of the context
+func (store *store) auditRecord(ctx context.Context, record AuditRecord) error {
+	if store.auditTableName == "" {
+		return nil
+	}
+
+	actor, _ := ctx.Value(auditActorKey{}).(string)
+
+	details, err := json.Marshal(lo.CoalesceMapOrEmpty(record.Details))
+
+	if err != nil {
+		return err
+	}
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		Insert(store.auditTableName).
+		Prepared(true).
+		Rows(map[string]any{
+			COLUMN_ID:           uid.HumanUid(),
+			COLUMN_ACTION:       record.Action,
+			COLUMN_SUBJECT_TYPE: record.SubjectType,
+			COLUMN_SUBJECT_ID:   record.SubjectID,
+			COLUMN_GROUP_ID:     record.GroupID,
+			COLUMN_ACTOR:        actor,
+			COLUMN_DETAILS:      string(details),
+			COLUMN_CREATED_AT:   carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC),
+		}).
+		ToSQL()
+
+	if errSql != nil {
+		return errSql
+	}
+
+	store.logSql("insert", sqlStr, params...)
+
+	_, err = database.Execute(store.toQuerableContext(ctx), sqlStr, params...)
+
+	return err
+}
+
+// relationsAuditCreated records the relations, which were inserted, as
+// created, when the audit trail is enabled
+//
+// The relations are inserted with their own new IDs, so the ones found by
+// their IDs were inserted, and the others skipped as existing
+func (store *store) relationsAuditCreated(ctx context.Context, relations []RelationInterface) error {
+	if store.auditTableName == "" || len(relations) == 0 {
+		return nil
+	}
+
+	inserted, err := store.RelationList(ctx, NewRelationQuery().
+		SetIDIn(lo.Map(relations, func(relation RelationInterface, _ int) string { return relation.ID() })).
+		SetStatusIn(RELATION_STATUSES))
+
+	if err != nil {
+		return err
+	}
+
+	for _, relation := range inserted {
+		if err := store.auditRecord(ctx, relationAuditRecord(AUDIT_ACTION_CREATED, relation, nil)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// == HELPERS =================================================================
+
+// groupAuditRecord returns the audit record of the change of the group
+func groupAuditRecord(action string, groupID string, details map[string]string) AuditRecord {
+	return AuditRecord{
+		Action:      action,
+		SubjectType: AUDIT_SUBJECT_GROUP,
+		SubjectID:   groupID,
+		GroupID:     groupID,
+		Details:     details,
+	}
+}
+
+// relationAuditRecord returns the audit record of the change of the
+// relation, with its entity and status
+func relationAuditRecord(action string, relation RelationInterface, details map[string]string) AuditRecord {
+	return AuditRecord{
+		Action:      action,
+		SubjectType: AUDIT_SUBJECT_RELATION,
+		SubjectID:   relation.ID(),
+		GroupID:     relation.GroupID(),
+		Details: lo.Assign(map[string]string{
+			COLUMN_ENTITY_TYPE: relation.EntityType(),
+			COLUMN_ENTITY_ID:   relation.EntityID(),
+			COLUMN_STATUS:      relation.Status(),
+		}, details),
+	}
+}
+
+// auditUpdateAction returns the action of an update with the changed
+// columns, the changes of the soft deleted at column being soft deletes
+// or restores
+func auditUpdateAction(dataChanged map[string]string) string {
+	softDeletedAt, changed := dataChanged[COLUMN_SOFT_DELETED_AT]
+
+	switch {
+	case !changed:
+		return AUDIT_ACTION_UPDATED
+	case strings.Contains(softDeletedAt, sb.MAX_DATETIME):
+		return AUDIT_ACTION_RESTORED
+	default:
+		return AUDIT_ACTION_SOFT_DELETED
+	}
+}
+
+// auditUpdateDetails returns the details of an update, the names of the
+// changed columns
+func auditUpdateDetails(dataChanged map[string]string) map[string]string {
+	columns := lo.Without(lo.Keys(dataChanged), COLUMN_ID, COLUMN_UPDATED_AT)
+	slices.Sort(columns)
+
+	return map[string]string{"columns": strings.Join(columns, ",")}
+}
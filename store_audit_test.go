@@ -0,0 +1,137 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStoreAuditList(t *testing.T) {
+	store, err := initStoreWithOptions(":memory:", func(options *NewStoreOptions) {
+		options.AuditTableName = "groups_audit_table"
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := AuditActorContext(context.Background(), "ADMIN_01")
+
+	group := NewGroup().SetStatus(GROUP_STATUS_ACTIVE).SetHandle("audited").SetTitle("Audited")
+
+	if err := store.GroupCreate(ctx, group); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	relation, err := store.RelationEnsure(ctx, ENTITY_TYPE_USER, "USER_01", group.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// the existing relation is not created again
+	if _, err := store.RelationEnsure(ctx, ENTITY_TYPE_USER, "USER_01", group.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.RelationSoftDelete(ctx, relation); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	group.SetTitle("Audited Group")
+
+	if err := store.GroupUpdate(ctx, group); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.GroupSoftDelete(context.Background(), group); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	records, err := store.AuditList(context.Background(), AuditListOptions{GroupID: group.ID()})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(records) != 5 {
+		t.Fatal("expected 5 audit records, found:", len(records), records)
+	}
+
+	actions := map[string]int{}
+
+	for _, record := range records {
+		actions[record.SubjectType+" "+record.Action]++
+	}
+
+	expected := map[string]int{
+		"group " + AUDIT_ACTION_CREATED:         1,
+		"relation " + AUDIT_ACTION_CREATED:      1,
+		"relation " + AUDIT_ACTION_SOFT_DELETED: 1,
+		"group " + AUDIT_ACTION_UPDATED:         1,
+		"group " + AUDIT_ACTION_SOFT_DELETED:    1,
+	}
+
+	for key, count := range expected {
+		if actions[key] != count {
+			t.Fatal("unexpected audit actions:", actions)
+		}
+	}
+
+	created, err := store.AuditList(context.Background(), AuditListOptions{
+		SubjectID: relation.ID(),
+		Action:    AUDIT_ACTION_CREATED,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(created) != 1 || created[0].Actor != "ADMIN_01" || created[0].Details[COLUMN_ENTITY_ID] != "USER_01" {
+		t.Fatal("unexpected audit record:", created)
+	}
+
+	updated, err := store.AuditList(context.Background(), AuditListOptions{GroupID: group.ID(), Action: AUDIT_ACTION_UPDATED})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(updated) != 1 || updated[0].Details["columns"] != COLUMN_TITLE {
+		t.Fatal("unexpected audit record:", updated)
+	}
+
+	page, err := store.AuditList(context.Background(), AuditListOptions{GroupID: group.ID(), Limit: 2, Offset: 4})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(page) != 1 {
+		t.Fatal("expected 1 audit record, found:", len(page))
+	}
+}
+
+func TestStoreAuditListDisabled(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if _, err := store.AuditList(context.Background(), AuditListOptions{}); !errors.Is(err, ErrAuditTrailDisabled) {
+		t.Fatal("expected ErrAuditTrailDisabled, found:", err)
+	}
+}
@@ -0,0 +1,973 @@
+package groupstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"iter"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gouniverse/base/database"
+	"github.com/gouniverse/uid"
+	"github.com/samber/lo"
+)
+
+// cachedNull is the cached representation of a not found value
+const cachedNull = "null"
+
+// cacheTagAll is the tag shared by all cached values
+const cacheTagAll = "*"
+
+// NewCachedStoreOptions define the options for creating a new cached store
+type NewCachedStoreOptions struct {
+	// Store is the store to cache, required
+	Store StoreInterface
+
+	// Cache is the cache backend, defaults to an in-memory LRU cache with 10000 entries
+	Cache CacheInterface
+
+	// TTL is how long the values are cached, defaults to 5 minutes
+	TTL time.Duration
+
+	// KeyPrefix is prepended to all the cache keys, defaults to "groupstore:"
+	KeyPrefix string
+}
+
+// CacheStats are the hit and miss statistics of the cached store
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// CachedStoreInterface is a StoreInterface, which caches the group
+// lookups and the membership checks
+type CachedStoreInterface interface {
+	StoreInterface
+
+	// CacheFlush invalidates all the cached values
+	CacheFlush()
+
+	// CacheStats returns the cache hit and miss statistics
+	CacheStats() CacheStats
+}
+
+// == TYPE ====================================================================
+
+// cachedStore is a read-through caching decorator for a store
+//
+// Cached are GroupFindByID, GroupFindByHandle, IsMember, BatchIsMember and
+// RelationFindByEntityAndGroup, all the other methods are passed through.
+//
+// Invalidation is done with tags. Each cached value is stored under a key,
+// which includes a token for each of its tags (the group ID or handle, the
+// entity). Invalidating a tag replaces its token, which orphans all the
+// values stored with the old one, until they expire or are evicted. As the
+// tokens live in the cache backend, invalidation works across processes
+// sharing a backend.
+//
+// Reads within a transaction bypass the cache, so that uncommitted data
+// is never cached.
+type cachedStore struct {
+	store     StoreInterface
+	cache     CacheInterface
+	ttl       time.Duration
+	keyPrefix string
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+}
+
+var _ CachedStoreInterface = (*cachedStore)(nil) // verify it extends the interface
+
+// == CONSTRUCTOR =============================================================
+
+// NewCachedStore wraps a store with a read-through cache
+func NewCachedStore(opts NewCachedStoreOptions) (CachedStoreInterface, error) {
+	if opts.Store == nil {
+		return nil, errors.New("cached store: Store is required")
+	}
+
+	if opts.Cache == nil {
+		opts.Cache = NewMemoryCache(10000)
+	}
+
+	if opts.TTL <= 0 {
+		opts.TTL = 5 * time.Minute
+	}
+
+	if opts.KeyPrefix == "" {
+		opts.KeyPrefix = "groupstore:"
+	}
+
+	return &cachedStore{
+		store:     opts.Store,
+		cache:     opts.Cache,
+		ttl:       opts.TTL,
+		keyPrefix: opts.KeyPrefix,
+	}, nil
+}
+
+// == CACHE METHODS ===========================================================
+
+func (c *cachedStore) CacheFlush() {
+	c.invalidate(cacheTagAll)
+}
+
+func (c *cachedStore) CacheStats() CacheStats {
+	return CacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+}
+
+// == STORE METHODS ===========================================================
+
+func (c *cachedStore) AutoMigrate() error {
+	return c.store.AutoMigrate()
+}
+
+func (c *cachedStore) EnableDebug(debug bool) {
+	c.store.EnableDebug(debug)
+}
+
+func (c *cachedStore) DB() *sql.DB {
+	return c.store.DB()
+}
+
+// WithTransaction runs fn in a transaction of the store, the reads of the
+// cached store within it bypassing the cache
+func (c *cachedStore) WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	return c.store.WithTransaction(ctx, fn)
+}
+
+// AuditList is not cached, the audit trail growing with every write
+func (c *cachedStore) AuditList(ctx context.Context, options AuditListOptions) ([]AuditRecord, error) {
+	return c.store.AuditList(ctx, options)
+}
+
+// == Entity Methods ======================================================== //
+
+func (c *cachedStore) EntitiesInAllGroups(ctx context.Context, groupIDs []string, query EntityQueryInterface) ([]string, error) {
+	return c.store.EntitiesInAllGroups(ctx, groupIDs, query)
+}
+
+func (c *cachedStore) EntitiesInAnyGroup(ctx context.Context, groupIDs []string, query EntityQueryInterface) ([]string, error) {
+	return c.store.EntitiesInAnyGroup(ctx, groupIDs, query)
+}
+
+func (c *cachedStore) EntitiesNotInGroups(ctx context.Context, groupIDs []string, query EntityQueryInterface) ([]string, error) {
+	return c.store.EntitiesNotInGroups(ctx, groupIDs, query)
+}
+
+// == Group Methods ========================================================= //
+
+func (c *cachedStore) GroupCount(ctx context.Context, options GroupQueryInterface) (int64, error) {
+	return c.store.GroupCount(ctx, options)
+}
+
+func (c *cachedStore) GroupClone(ctx context.Context, groupID string, options GroupCloneOptions) (GroupInterface, error) {
+	clone, err := c.store.GroupClone(ctx, groupID, options)
+
+	if err == nil {
+		// the handles of the clones may have been cached as not found
+		c.invalidate(cacheTagAll)
+	}
+
+	return clone, err
+}
+
+func (c *cachedStore) GroupCreate(ctx context.Context, group GroupInterface) error {
+	err := c.store.GroupCreate(ctx, group)
+
+	c.invalidateGroup(group)
+
+	return err
+}
+
+func (c *cachedStore) GroupDelete(ctx context.Context, group GroupInterface) error {
+	if group == nil {
+		return c.store.GroupDelete(ctx, group)
+	}
+
+	return c.GroupDeleteByID(ctx, group.ID())
+}
+
+func (c *cachedStore) GroupDeleteByID(ctx context.Context, id string) error {
+	stored := c.storedGroup(ctx, id)
+
+	err := c.store.GroupDeleteByID(ctx, id)
+
+	c.invalidate(cacheGroupTag(id))
+	c.invalidateGroup(stored)
+
+	return err
+}
+
+func (c *cachedStore) GroupFindByHandle(ctx context.Context, handle string) (GroupInterface, error) {
+	if handle == "" {
+		return c.store.GroupFindByHandle(ctx, handle)
+	}
+
+	key := c.key("group_by_handle", []string{handle}, cacheGroupTag(handle))
+
+	value, err := c.readThrough(ctx, key, func() (string, error) {
+		group, err := c.store.GroupFindByHandle(ctx, handle)
+		return cacheEncodeGroup(group), err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := cacheDecodeGroup(value)
+
+	if group != nil && group.Handle() != handle {
+		// a previous handle, not kept, as the updates of the group do not
+		// invalidate its previous handles
+		c.cache.Delete(key)
+	}
+
+	return group, err
+}
+
+func (c *cachedStore) GroupFindByID(ctx context.Context, id string) (GroupInterface, error) {
+	if id == "" {
+		return c.store.GroupFindByID(ctx, id)
+	}
+
+	key := c.key("group_by_id", []string{id}, cacheGroupTag(id))
+
+	value, err := c.readThrough(ctx, key, func() (string, error) {
+		group, err := c.store.GroupFindByID(ctx, id)
+		return cacheEncodeGroup(group), err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cacheDecodeGroup(value)
+}
+
+func (c *cachedStore) GroupIter(ctx context.Context, query GroupQueryInterface) iter.Seq2[GroupInterface, error] {
+	return c.store.GroupIter(ctx, query)
+}
+
+func (c *cachedStore) GroupList(ctx context.Context, query GroupQueryInterface) ([]GroupInterface, error) {
+	return c.store.GroupList(ctx, query)
+}
+
+func (c *cachedStore) GroupMerge(ctx context.Context, sourceIDs []string, targetID string, options GroupMergeOptions) (GroupMergeResult, error) {
+	result, err := c.store.GroupMerge(ctx, sourceIDs, targetID, options)
+
+	if err == nil {
+		// a merge changes the memberships of the groups nested in the sources too
+		c.invalidate(cacheTagAll)
+	}
+
+	return result, err
+}
+
+func (c *cachedStore) GroupRemainingCapacity(ctx context.Context, groupID string) (int, error) {
+	return c.store.GroupRemainingCapacity(ctx, groupID)
+}
+
+func (c *cachedStore) GroupReorder(ctx context.Context, scope GroupQueryInterface, groupIDs []string) error {
+	err := c.store.GroupReorder(ctx, scope, groupIDs)
+
+	// the other groups of the scope may be repositioned too
+	c.invalidate(cacheTagAll)
+
+	return err
+}
+
+func (c *cachedStore) GroupScheduleSweep(ctx context.Context) (GroupScheduleSweepResult, error) {
+	result, err := c.store.GroupScheduleSweep(ctx)
+
+	c.invalidateGroup(result.Activated...)
+	c.invalidateGroup(result.Deactivated...)
+	c.invalidateGroup(result.Skipped...)
+
+	return result, err
+}
+
+func (c *cachedStore) GroupSoftDelete(ctx context.Context, group GroupInterface) error {
+	if group == nil {
+		return c.store.GroupSoftDelete(ctx, group)
+	}
+
+	err := c.store.GroupSoftDelete(ctx, group)
+
+	// the groups after the soft deleted group are repositioned
+	c.invalidate(cacheTagAll)
+
+	return err
+}
+
+func (c *cachedStore) GroupSoftDeleteByID(ctx context.Context, id string) error {
+	err := c.store.GroupSoftDeleteByID(ctx, id)
+
+	// the groups after the soft deleted group are repositioned
+	c.invalidate(cacheTagAll)
+
+	return err
+}
+
+func (c *cachedStore) GroupSplit(ctx context.Context, groupID string, newGroup GroupInterface, predicate func(relation RelationInterface) bool) (GroupSplitResult, error) {
+	result, err := c.store.GroupSplit(ctx, groupID, newGroup, predicate)
+
+	if err == nil {
+		// a split changes the memberships of the groups nested in the group too
+		c.invalidate(cacheTagAll)
+	}
+
+	return result, err
+}
+
+func (c *cachedStore) GroupTemplateInstantiate(ctx context.Context, template GroupTemplate, variables map[string]string) (GroupInterface, error) {
+	group, err := c.store.GroupTemplateInstantiate(ctx, template, variables)
+
+	if err == nil {
+		// the handles of the new groups may have been cached as not found
+		c.invalidate(cacheTagAll)
+	}
+
+	return group, err
+}
+
+func (c *cachedStore) GroupUpdate(ctx context.Context, group GroupInterface) error {
+	if group == nil {
+		return c.store.GroupUpdate(ctx, group)
+	}
+
+	if lo.HasKey(group.DataChanged(), COLUMN_SOFT_DELETED_AT) {
+		err := c.store.GroupUpdate(ctx, group)
+
+		// the groups after a soft deleted group are repositioned
+		c.invalidate(cacheTagAll)
+
+		return err
+	}
+
+	stored := c.storedGroup(ctx, group.ID())
+
+	err := c.store.GroupUpdate(ctx, group)
+
+	c.invalidateGroup(stored, group)
+
+	return err
+}
+
+func (c *cachedStore) GroupUpsertByHandle(ctx context.Context, group GroupInterface) error {
+	err := c.store.GroupUpsertByHandle(ctx, group)
+
+	c.invalidateGroup(group)
+
+	return err
+}
+
+// == Membership Methods ==================================================== //
+
+func (c *cachedStore) IsMember(ctx context.Context, entityType string, entityID string, groupIDOrHandle string) (bool, error) {
+	if entityType == "" || entityID == "" || groupIDOrHandle == "" {
+		return c.store.IsMember(ctx, entityType, entityID, groupIDOrHandle)
+	}
+
+	key := c.membershipKey(entityType, entityID, groupIDOrHandle)
+
+	value, err := c.readThrough(ctx, key, func() (string, error) {
+		isMember, err := c.store.IsMember(ctx, entityType, entityID, groupIDOrHandle)
+		return cacheEncodeBool(isMember), err
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return value == cacheEncodeBool(true), nil
+}
+
+func (c *cachedStore) BatchIsMember(ctx context.Context, checks []MembershipCheck) ([]bool, error) {
+	if c.isBypassed(ctx) {
+		return c.store.BatchIsMember(ctx, checks)
+	}
+
+	results := make([]bool, len(checks))
+	keys := make([]string, len(checks))
+	missed := []int{}
+
+	for i, check := range checks {
+		if check.EntityType == "" || check.EntityID == "" || check.GroupIDOrHandle == "" {
+			return c.store.BatchIsMember(ctx, checks) // let the store report the error
+		}
+
+		keys[i] = c.membershipKey(check.EntityType, check.EntityID, check.GroupIDOrHandle)
+
+		if value, found := c.cache.Get(keys[i]); found {
+			c.hits.Add(1)
+			results[i] = value == cacheEncodeBool(true)
+			continue
+		}
+
+		c.misses.Add(1)
+		missed = append(missed, i)
+	}
+
+	if len(missed) < 1 {
+		return results, nil
+	}
+
+	missedChecks := make([]MembershipCheck, len(missed))
+
+	for i, index := range missed {
+		missedChecks[i] = checks[index]
+	}
+
+	missedResults, err := c.store.BatchIsMember(ctx, missedChecks)
+
+	if err != nil {
+		return []bool{}, err
+	}
+
+	for i, index := range missed {
+		results[index] = missedResults[i]
+		c.cache.Set(keys[index], cacheEncodeBool(missedResults[i]), c.ttl)
+	}
+
+	return results, nil
+}
+
+// == Relation Methods ====================================================== //
+
+func (c *cachedStore) RelationActivate(ctx context.Context, entityType string, entityID string, groupID string) (RelationInterface, error) {
+	relation, err := c.store.RelationActivate(ctx, entityType, entityID, groupID)
+
+	c.invalidate(cacheEntityTag(entityType, entityID))
+
+	return relation, err
+}
+
+func (c *cachedStore) RelationApprove(ctx context.Context, relationID string) error {
+	err := c.store.RelationApprove(ctx, relationID)
+
+	c.invalidateRelation(c.storedRelation(ctx, relationID))
+
+	return err
+}
+
+func (c *cachedStore) RelationBan(ctx context.Context, relationID string) error {
+	err := c.store.RelationBan(ctx, relationID)
+
+	c.invalidateRelation(c.storedRelation(ctx, relationID))
+
+	return err
+}
+
+func (c *cachedStore) RelationBulkCreate(ctx context.Context, relations []RelationInterface) error {
+	err := c.store.RelationBulkCreate(ctx, relations)
+
+	c.invalidateRelation(relations...)
+
+	return err
+}
+
+func (c *cachedStore) RelationCount(ctx context.Context, options RelationQueryInterface) (int64, error) {
+	return c.store.RelationCount(ctx, options)
+}
+
+func (c *cachedStore) RelationCreate(ctx context.Context, relation RelationInterface) error {
+	err := c.store.RelationCreate(ctx, relation)
+
+	c.invalidateRelation(relation)
+
+	return err
+}
+
+func (c *cachedStore) RelationDelete(ctx context.Context, relation RelationInterface) error {
+	if relation == nil {
+		return c.store.RelationDelete(ctx, relation)
+	}
+
+	stored := c.storedRelation(ctx, relation.ID())
+
+	err := c.store.RelationDelete(ctx, relation)
+
+	c.invalidateRelation(stored, relation)
+
+	return err
+}
+
+func (c *cachedStore) RelationDeleteByID(ctx context.Context, id string) error {
+	stored := c.storedRelation(ctx, id)
+
+	err := c.store.RelationDeleteByID(ctx, id)
+
+	c.invalidateRelation(stored)
+
+	return err
+}
+
+func (c *cachedStore) RelationEnsure(ctx context.Context, entityType string, entityID string, groupID string) (RelationInterface, error) {
+	relation, err := c.store.RelationEnsure(ctx, entityType, entityID, groupID)
+
+	c.invalidate(cacheEntityTag(entityType, entityID))
+
+	return relation, err
+}
+
+func (c *cachedStore) RelationFindByEntityAndGroup(ctx context.Context, entityType string, entityID string, groupID string) (RelationInterface, error) {
+	if entityType == "" || entityID == "" || groupID == "" {
+		return c.store.RelationFindByEntityAndGroup(ctx, entityType, entityID, groupID)
+	}
+
+	key := c.key("relation_by_entity_and_group", []string{entityType, entityID, groupID}, cacheEntityTag(entityType, entityID))
+
+	value, err := c.readThrough(ctx, key, func() (string, error) {
+		relation, err := c.store.RelationFindByEntityAndGroup(ctx, entityType, entityID, groupID)
+		return cacheEncodeRelation(relation), err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cacheDecodeRelation(value)
+}
+
+func (c *cachedStore) RelationFindByID(ctx context.Context, id string) (RelationInterface, error) {
+	return c.store.RelationFindByID(ctx, id)
+}
+
+func (c *cachedStore) RelationInvite(ctx context.Context, entityType string, entityID string, groupID string) (RelationInterface, error) {
+	relation, err := c.store.RelationInvite(ctx, entityType, entityID, groupID)
+
+	c.invalidate(cacheEntityTag(entityType, entityID))
+
+	return relation, err
+}
+
+func (c *cachedStore) RelationIter(ctx context.Context, query RelationQueryInterface) iter.Seq2[RelationInterface, error] {
+	return c.store.RelationIter(ctx, query)
+}
+
+func (c *cachedStore) RelationList(ctx context.Context, query RelationQueryInterface) ([]RelationInterface, error) {
+	return c.store.RelationList(ctx, query)
+}
+
+func (c *cachedStore) RelationMoveAfter(ctx context.Context, relationID string, afterRelationID string) error {
+	err := c.store.RelationMoveAfter(ctx, relationID, afterRelationID)
+
+	if relation := c.storedRelation(ctx, relationID); relation != nil {
+		c.invalidateGroupRelations(ctx, relation.GroupID())
+	}
+
+	return err
+}
+
+func (c *cachedStore) RelationMoveBefore(ctx context.Context, relationID string, beforeRelationID string) error {
+	err := c.store.RelationMoveBefore(ctx, relationID, beforeRelationID)
+
+	if relation := c.storedRelation(ctx, relationID); relation != nil {
+		c.invalidateGroupRelations(ctx, relation.GroupID())
+	}
+
+	return err
+}
+
+func (c *cachedStore) RelationReject(ctx context.Context, relationID string) error {
+	err := c.store.RelationReject(ctx, relationID)
+
+	c.invalidateRelation(c.storedRelation(ctx, relationID))
+
+	return err
+}
+
+func (c *cachedStore) RelationReorder(ctx context.Context, groupID string, relationIDs []string) error {
+	err := c.store.RelationReorder(ctx, groupID, relationIDs)
+
+	c.invalidateGroupRelations(ctx, groupID)
+
+	return err
+}
+
+func (c *cachedStore) RelationRequest(ctx context.Context, entityType string, entityID string, groupID string) (RelationInterface, error) {
+	relation, err := c.store.RelationRequest(ctx, entityType, entityID, groupID)
+
+	c.invalidate(cacheEntityTag(entityType, entityID))
+
+	return relation, err
+}
+
+func (c *cachedStore) RelationSoftDelete(ctx context.Context, relation RelationInterface) error {
+	if relation == nil {
+		return c.store.RelationSoftDelete(ctx, relation)
+	}
+
+	stored := c.storedRelation(ctx, relation.ID())
+
+	err := c.store.RelationSoftDelete(ctx, relation)
+
+	c.invalidateRelation(stored, relation)
+
+	if stored != nil {
+		// the relations after the soft deleted relation are repositioned
+		c.invalidateGroupRelations(ctx, stored.GroupID())
+	}
+
+	return err
+}
+
+func (c *cachedStore) RelationSoftDeleteByID(ctx context.Context, id string) error {
+	stored := c.storedRelation(ctx, id)
+
+	err := c.store.RelationSoftDeleteByID(ctx, id)
+
+	c.invalidateRelation(stored)
+
+	if stored != nil {
+		// the relations after the soft deleted relation are repositioned
+		c.invalidateGroupRelations(ctx, stored.GroupID())
+	}
+
+	return err
+}
+
+func (c *cachedStore) RelationUpdate(ctx context.Context, relation RelationInterface) error {
+	if relation == nil {
+		return c.store.RelationUpdate(ctx, relation)
+	}
+
+	stored := c.storedRelation(ctx, relation.ID())
+
+	moved := lo.HasKey(relation.DataChanged(), COLUMN_GROUP_ID) || lo.HasKey(relation.DataChanged(), COLUMN_SOFT_DELETED_AT)
+
+	err := c.store.RelationUpdate(ctx, relation)
+
+	c.invalidateRelation(stored, relation)
+
+	if moved && stored != nil {
+		// the relations after the moved relation are repositioned
+		c.invalidateGroupRelations(ctx, stored.GroupID())
+	}
+
+	return err
+}
+
+func (c *cachedStore) SwitchGroup(ctx context.Context, entityType string, entityID string, exclusiveSet string, toGroupID string) (RelationInterface, error) {
+	relation, err := c.store.SwitchGroup(ctx, entityType, entityID, exclusiveSet, toGroupID)
+
+	c.invalidate(cacheEntityTag(entityType, entityID))
+
+	return relation, err
+}
+
+func (c *cachedStore) DynamicGroupMaterialize(ctx context.Context, groupID string) (MaterializeResult, error) {
+	result, err := c.store.DynamicGroupMaterialize(ctx, groupID)
+
+	c.invalidateRelation(result.Added...)
+	c.invalidateRelation(result.Removed...)
+	c.invalidate(cacheGroupTag(groupID))
+
+	return result, err
+}
+
+func (c *cachedStore) DynamicGroupsMaterialize(ctx context.Context) (MaterializeResult, error) {
+	result, err := c.store.DynamicGroupsMaterialize(ctx)
+
+	c.invalidateRelation(result.Added...)
+	c.invalidateRelation(result.Removed...)
+
+	for _, relation := range append(result.Added, result.Removed...) {
+		c.invalidate(cacheGroupTag(relation.GroupID()))
+	}
+
+	return result, err
+}
+
+func (c *cachedStore) Export(ctx context.Context, w io.Writer, options ExportOptions) error {
+	return c.store.Export(ctx, w, options)
+}
+
+func (c *cachedStore) Import(ctx context.Context, r io.Reader, options ImportOptions) (ImportResult, error) {
+	result, err := c.store.Import(ctx, r, options)
+
+	// an import can touch any group and entity
+	c.invalidate(cacheTagAll)
+
+	return result, err
+}
+
+func (c *cachedStore) RelationExportCSV(ctx context.Context, w io.Writer, options CSVExportOptions) error {
+	return c.store.RelationExportCSV(ctx, w, options)
+}
+
+func (c *cachedStore) RelationImportCSV(ctx context.Context, r io.Reader, options CSVImportOptions) (CSVImportResult, error) {
+	result, err := c.store.RelationImportCSV(ctx, r, options)
+
+	if options.DryRun {
+		return result, err
+	}
+
+	c.invalidateRelation(result.Added...)
+	c.invalidateRelation(result.Removed...)
+
+	return result, err
+}
+
+func (c *cachedStore) Reconcile(ctx context.Context, manifest Manifest, options ReconcileOptions) (ReconcilePlan, error) {
+	plan, err := c.store.Reconcile(ctx, manifest, options)
+
+	if !options.DryRun {
+		// a reconcile can touch any group and entity
+		c.invalidate(cacheTagAll)
+	}
+
+	return plan, err
+}
+
+// == PRIVATE METHODS =========================================================
+
+// readThrough returns the value cached under the key, or loads it
+// and caches it, when not found
+func (c *cachedStore) readThrough(ctx context.Context, key string, load func() (string, error)) (string, error) {
+	if c.isBypassed(ctx) {
+		return load()
+	}
+
+	if value, found := c.cache.Get(key); found {
+		c.hits.Add(1)
+		return value, nil
+	}
+
+	c.misses.Add(1)
+
+	value, err := load()
+
+	if err != nil {
+		return "", err
+	}
+
+	c.cache.Set(key, value, c.ttl)
+
+	return value, nil
+}
+
+// isBypassed returns true, when the cache must not be used (i.e. within a transaction)
+func (c *cachedStore) isBypassed(ctx context.Context) bool {
+	if !database.IsQueryableContext(ctx) {
+		return false
+	}
+
+	return ctx.(database.QueryableContext).IsTx()
+}
+
+// key returns the cache key for the given kind and parts, which embeds
+// the current tokens of the given tags (and of the shared tag)
+func (c *cachedStore) key(kind string, parts []string, tags ...string) string {
+	tokens := []string{c.tagToken(cacheTagAll)}
+
+	for _, tag := range tags {
+		tokens = append(tokens, c.tagToken(tag))
+	}
+
+	return c.keyPrefix + kind + ":" + strings.Join(parts, "\x00") + "@" + strings.Join(tokens, ".")
+}
+
+// membershipKey returns the cache key of a membership check
+func (c *cachedStore) membershipKey(entityType string, entityID string, groupIDOrHandle string) string {
+	return c.key(
+		"is_member",
+		[]string{entityType, entityID, groupIDOrHandle},
+		cacheEntityTag(entityType, entityID),
+		cacheGroupTag(groupIDOrHandle),
+	)
+}
+
+// tagToken returns the current token of the tag, creating a new one if missing
+func (c *cachedStore) tagToken(tag string) string {
+	tagKey := c.keyPrefix + "tag:" + tag
+
+	if token, found := c.cache.Get(tagKey); found {
+		return token
+	}
+
+	token := uid.UuidV4()
+
+	c.cache.Set(tagKey, token, 0)
+
+	return token
+}
+
+// invalidate invalidates all the values cached with any of the tags
+func (c *cachedStore) invalidate(tags ...string) {
+	for _, tag := range tags {
+		c.cache.Delete(c.keyPrefix + "tag:" + tag)
+	}
+}
+
+// invalidateGroup invalidates the values cached for the IDs and handles
+// of the groups, nil groups are skipped
+func (c *cachedStore) invalidateGroup(groups ...GroupInterface) {
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+
+		c.invalidate(cacheGroupTag(group.ID()))
+
+		if group.Handle() != "" {
+			c.invalidate(cacheGroupTag(group.Handle()))
+		}
+	}
+}
+
+// invalidateRelation invalidates the values cached for the entities
+// of the relations, nil relations are skipped
+func (c *cachedStore) invalidateRelation(relations ...RelationInterface) {
+	for _, relation := range relations {
+		if relation == nil {
+			continue
+		}
+
+		c.invalidate(cacheEntityTag(relation.EntityType(), relation.EntityID()))
+	}
+}
+
+// invalidateGroupRelations invalidates the values cached for the entities
+// related to the group, with any status (i.e. after their positions changed)
+func (c *cachedStore) invalidateGroupRelations(ctx context.Context, groupID string) {
+	if groupID == "" {
+		return
+	}
+
+	relations, err := c.store.RelationList(ctx, NewRelationQuery().
+		SetGroupID(groupID).
+		SetStatusIn(RELATION_STATUSES))
+
+	if err != nil {
+		c.invalidate(cacheTagAll) // the entities are unknown
+		return
+	}
+
+	c.invalidateRelation(relations...)
+}
+
+// storedGroup returns the group as currently stored (i.e. with its handle
+// before an update), including soft deleted ones, or nil if not found
+func (c *cachedStore) storedGroup(ctx context.Context, id string) GroupInterface {
+	if id == "" {
+		return nil
+	}
+
+	list, err := c.store.GroupList(ctx, NewGroupQuery().
+		SetID(id).
+		SetSoftDeletedIncluded(true).
+		SetLimit(1))
+
+	if err != nil || len(list) < 1 {
+		return nil
+	}
+
+	return list[0]
+}
+
+// storedRelation returns the relation as currently stored (i.e. with its
+// entity before an update), including soft deleted ones, or nil if not found
+func (c *cachedStore) storedRelation(ctx context.Context, id string) RelationInterface {
+	if id == "" {
+		return nil
+	}
+
+	list, err := c.store.RelationList(ctx, NewRelationQuery().
+		SetID(id).
+		SetSoftDeletedIncluded(true).
+		SetStatusIn(RELATION_STATUSES).
+		SetLimit(1))
+
+	if err != nil || len(list) < 1 {
+		return nil
+	}
+
+	return list[0]
+}
+
+// == HELPERS =================================================================
+
+func cacheGroupTag(groupIDOrHandle string) string {
+	return "group:" + groupIDOrHandle
+}
+
+func cacheEntityTag(entityType string, entityID string) string {
+	return "entity:" + entityType + "\x00" + entityID
+}
+
+func cacheEncodeBool(value bool) string {
+	if value {
+		return "1"
+	}
+
+	return "0"
+}
+
+func cacheEncodeData(data map[string]string) string {
+	encoded, err := json.Marshal(data)
+
+	if err != nil {
+		return cachedNull
+	}
+
+	return string(encoded)
+}
+
+func cacheDecodeData(value string) (map[string]string, error) {
+	if value == cachedNull {
+		return nil, nil
+	}
+
+	data := map[string]string{}
+
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func cacheEncodeGroup(group GroupInterface) string {
+	if group == nil {
+		return cachedNull
+	}
+
+	return cacheEncodeData(group.Data())
+}
+
+func cacheDecodeGroup(value string) (GroupInterface, error) {
+	data, err := cacheDecodeData(value)
+
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	return NewGroupFromExistingData(data), nil
+}
+
+func cacheEncodeRelation(relation RelationInterface) string {
+	if relation == nil {
+		return cachedNull
+	}
+
+	return cacheEncodeData(relation.Data())
+}
+
+func cacheDecodeRelation(value string) (RelationInterface, error) {
+	data, err := cacheDecodeData(value)
+
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	return NewGroupEntityRelationFromExistingData(data), nil
+}
@@ -0,0 +1,302 @@
+package groupstore
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/gouniverse/base/database"
+)
+
+func initCachedStore(t *testing.T) CachedStoreInterface {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	cachedStore, err := NewCachedStore(NewCachedStoreOptions{
+		Store: store,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	return cachedStore
+}
+
+func TestNewCachedStoreRequiresStore(t *testing.T) {
+	_, err := NewCachedStore(NewCachedStoreOptions{})
+
+	if err == nil {
+		t.Fatal("must return error as store is required")
+	}
+}
+
+func TestCachedStoreGroupFindByHandle(t *testing.T) {
+	store := initCachedStore(t)
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	group := NewGroup().
+		SetStatus(GROUP_STATUS_ACTIVE).
+		SetHandle("ADMINS").
+		SetTitle("Administrators")
+
+	if err := store.GroupCreate(context.Background(), group); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		found, err := store.GroupFindByHandle(context.Background(), "ADMINS")
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if found == nil || found.ID() != group.ID() {
+			t.Fatal("unexpected group:", found)
+		}
+	}
+
+	stats := store.CacheStats()
+
+	if stats.Hits != 2 || stats.Misses != 1 {
+		t.Fatal("unexpected stats:", stats)
+	}
+
+	group.SetHandle("SUPERUSERS")
+
+	if err := store.GroupUpdate(context.Background(), group); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	found, err := store.GroupFindByHandle(context.Background(), "ADMINS")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found != nil {
+		t.Fatal("old handle MUST NOT be found after update")
+	}
+
+	found, err = store.GroupFindByHandle(context.Background(), "SUPERUSERS")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found == nil || found.ID() != group.ID() {
+		t.Fatal("new handle MUST be found after update")
+	}
+}
+
+func TestCachedStoreIsMemberInvalidation(t *testing.T) {
+	store := initCachedStore(t)
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	group := NewGroup().
+		SetStatus(GROUP_STATUS_ACTIVE).
+		SetHandle("EDITORS").
+		SetTitle("Editors")
+
+	if err := store.GroupCreate(context.Background(), group); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	isMember, err := store.IsMember(context.Background(), "USER", "USER_01", "EDITORS")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if isMember {
+		t.Fatal("USER_01 MUST NOT be member yet")
+	}
+
+	otherIsMember, err := store.IsMember(context.Background(), "USER", "USER_02", "EDITORS")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if otherIsMember {
+		t.Fatal("USER_02 MUST NOT be member")
+	}
+
+	relation := NewRelation().
+		SetEntityType("USER").
+		SetEntityID("USER_01").
+		SetGroupID(group.ID())
+
+	if err := store.RelationCreate(context.Background(), relation); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	isMember, err = store.IsMember(context.Background(), "USER", "USER_01", "EDITORS")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !isMember {
+		t.Fatal("USER_01 MUST be member after relation create")
+	}
+
+	statsBefore := store.CacheStats()
+
+	_, err = store.IsMember(context.Background(), "USER", "USER_02", "EDITORS")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if store.CacheStats().Hits != statsBefore.Hits+1 {
+		t.Fatal("USER_02 membership MUST still be cached, as only USER_01 changed")
+	}
+
+	if err := store.RelationDeleteByID(context.Background(), relation.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	isMember, err = store.IsMember(context.Background(), "USER", "USER_01", group.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if isMember {
+		t.Fatal("USER_01 MUST NOT be member after relation delete")
+	}
+}
+
+func TestCachedStoreBatchIsMember(t *testing.T) {
+	store := initCachedStore(t)
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	relation := NewRelation().
+		SetEntityType("USER").
+		SetEntityID("USER_01").
+		SetGroupID("GROUP_01")
+
+	if err := store.RelationCreate(context.Background(), relation); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := store.IsMember(context.Background(), "USER", "USER_01", "GROUP_01"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	results, err := store.BatchIsMember(context.Background(), []MembershipCheck{
+		{EntityType: "USER", EntityID: "USER_01", GroupIDOrHandle: "GROUP_01"},
+		{EntityType: "USER", EntityID: "USER_02", GroupIDOrHandle: "GROUP_01"},
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !slices.Equal(results, []bool{true, false}) {
+		t.Fatal("unexpected results:", results)
+	}
+
+	stats := store.CacheStats()
+
+	if stats.Hits != 1 || stats.Misses != 2 {
+		t.Fatal("unexpected stats:", stats)
+	}
+}
+
+func TestCachedStoreCacheFlush(t *testing.T) {
+	store := initCachedStore(t)
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if _, err := store.IsMember(context.Background(), "USER", "USER_01", "GROUP_01"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// bypasses the cached store, so the cache is not invalidated
+	relation := NewRelation().
+		SetEntityType("USER").
+		SetEntityID("USER_01").
+		SetGroupID("GROUP_01")
+
+	if err := store.(*cachedStore).store.RelationCreate(context.Background(), relation); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	isMember, err := store.IsMember(context.Background(), "USER", "USER_01", "GROUP_01")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if isMember {
+		t.Fatal("stale cached membership expected")
+	}
+
+	store.CacheFlush()
+
+	isMember, err = store.IsMember(context.Background(), "USER", "USER_01", "GROUP_01")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !isMember {
+		t.Fatal("USER_01 MUST be member after flush")
+	}
+}
+
+func TestCachedStoreBypassesCacheInTransaction(t *testing.T) {
+	store := initCachedStore(t)
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	tx, err := store.DB().Begin()
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	txCtx := database.Context(context.Background(), tx)
+
+	if _, err := store.GroupFindByHandle(txCtx, "ADMINS"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	stats := store.CacheStats()
+
+	if stats.Hits != 0 || stats.Misses != 0 {
+		t.Fatal("unexpected stats:", stats)
+	}
+}
@@ -0,0 +1,369 @@
+package groupstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/gouniverse/base/database"
+	"github.com/samber/lo"
+)
+
+// MembershipQuota limits the number of groups an entity of the entity type
+// can belong to, among the groups accepted by the filter
+//
+// Example, each user can join at most 2 cohorts:
+//
+//	groupstore.MembershipQuota{
+//		Name:        "cohorts",
+//		EntityType:  "user",
+//		MaxGroups:   2,
+//		GroupFilter: func(group groupstore.GroupInterface) bool { return group.Meta("kind") == "cohort" },
+//	}
+type MembershipQuota struct {
+	// Name identifies the quota in the errors, optional
+	Name string
+
+	// EntityType is the entity type of the limited entities, required
+	EntityType string
+
+	// MaxGroups is the maximum number of groups, required
+	MaxGroups int
+
+	// GroupFilter selects the groups counted, nil for all the groups
+	GroupFilter func(group GroupInterface) bool
+}
+
+// The capacity and quota violations, wrapped in an EntityPolicyError
+var (
+	ErrGroupCapacityExceeded = errors.New("group is at capacity")
+	ErrEntityQuotaExceeded   = errors.New("entity exceeds the membership quota")
+)
+
+// GroupRemainingCapacity returns the number of members, which can still be
+// added to the group, or GROUP_CAPACITY_UNLIMITED
+func (store *store) GroupRemainingCapacity(ctx context.Context, groupID string) (int, error) {
+	if groupID == "" {
+		return 0, errors.New("at group remaining capacity > group ID is empty")
+	}
+
+	group, err := store.GroupFindByID(ctx, groupID)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if group == nil {
+		return 0, errors.New("at group remaining capacity > group not found: " + groupID)
+	}
+
+	if group.Capacity() <= 0 {
+		return GROUP_CAPACITY_UNLIMITED, nil
+	}
+
+	count, err := store.RelationCount(ctx, NewRelationQuery().SetGroupID(groupID))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return max(0, group.Capacity()-int(count)), nil
+}
+
+// == PRIVATE METHODS =========================================================
+
+// membershipQuotasValidate validates the membership quotas
+func membershipQuotasValidate(quotas []MembershipQuota) error {
+	for _, quota := range quotas {
+		if quota.EntityType == "" {
+			return errors.New("group store: MembershipQuotas entity type is required")
+		}
+
+		if quota.MaxGroups <= 0 {
+			return errors.New("group store: MembershipQuotas max groups must be greater than 0")
+		}
+	}
+
+	return nil
+}
+
+// withRelationsChecked checks the new or changed relations against the
+// entity type policies, the group capacities, the membership quotas and
+// the exclusive sets, and runs fn (i.e. the insert) atomically with the
+// checks
+//
+// Business logic:
+//   - the groups with a capacity are locked first, so that the concurrent
+//     inserts into a group are serialised
+//   - with entity limits (max groups, quotas, exclusive sets), the transaction is
+//     serializable, so that the concurrent inserts of an entity into
+//     different groups cannot both pass the checks, one of them fails
+//     with a serialization error instead, and can be retried
+//   - only the active relations are limited, the pending, invited,
+//     rejected and banned ones are checked against the policies only
+//   - without limits, fn runs without a transaction
+//   - within a transaction of the caller, its isolation level is kept
+func (store *store) withRelationsChecked(ctx context.Context, relations []RelationInterface, fn func(txCtx context.Context) error) error {
+	active := lo.Filter(relations, relationIsActive)
+	groupIDs := lo.Uniq(lo.Map(active, func(relation RelationInterface, _ int) string { return relation.GroupID() }))
+	slices.Sort(groupIDs)
+
+	capacities, err := store.groupCapacities(ctx, groupIDs)
+
+	if err != nil {
+		return err
+	}
+
+	exclusiveSets, err := store.groupExclusiveSets(ctx, groupIDs)
+
+	if err != nil {
+		return err
+	}
+
+	entityLimited := len(exclusiveSets) > 0 || store.relationsEntityLimited(active)
+
+	if len(capacities) == 0 && !entityLimited {
+		if err := store.relationsPolicyCheck(ctx, relations); err != nil {
+			return err
+		}
+
+		return fn(ctx)
+	}
+
+	txOptions := lo.Ternary(entityLimited, &sql.TxOptions{Isolation: sql.LevelSerializable}, nil)
+
+	return store.withTransactionOptions(ctx, txOptions, func(txCtx context.Context) error {
+		if len(capacities) > 0 {
+			if err := store.groupsLock(txCtx, lo.Keys(capacities)); err != nil {
+				return err
+			}
+
+			// read again, now the groups are locked
+			if capacities, err = store.groupCapacities(txCtx, groupIDs); err != nil {
+				return err
+			}
+		}
+
+		if err := store.relationsPolicyCheck(txCtx, relations); err != nil {
+			return err
+		}
+
+		if err := store.relationsCapacityCheck(txCtx, active, capacities); err != nil {
+			return err
+		}
+
+		for _, quota := range store.membershipQuotas {
+			if err := store.relationsQuotaCheck(txCtx, active, quota, ErrEntityQuotaExceeded); err != nil {
+				return err
+			}
+		}
+
+		if len(exclusiveSets) > 0 {
+			if err := store.relationsExclusiveSetCheck(txCtx, active); err != nil {
+				return err
+			}
+		}
+
+		return fn(txCtx)
+	})
+}
+
+// relationsEntityLimited returns true if the entities of the relations
+// are limited by a max groups policy, or a membership quota
+func (store *store) relationsEntityLimited(relations []RelationInterface) bool {
+	return lo.SomeBy(relations, func(relation RelationInterface) bool {
+		if store.entityTypes[relation.EntityType()].MaxGroups > 0 {
+			return true
+		}
+
+		return lo.SomeBy(store.membershipQuotas, func(quota MembershipQuota) bool {
+			return quota.EntityType == relation.EntityType()
+		})
+	})
+}
+
+// groupCapacities returns the capacities of the groups, which have one,
+// by group ID
+func (store *store) groupCapacities(ctx context.Context, groupIDs []string) (map[string]int, error) {
+	capacities := map[string]int{}
+
+	if len(groupIDs) == 0 {
+		return capacities, nil
+	}
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		From(store.groupTableName).
+		Prepared(true).
+		Select(goqu.C(COLUMN_ID), goqu.C(COLUMN_CAPACITY)).
+		Where(goqu.C(COLUMN_ID).In(groupIDs)).
+		Where(goqu.C(COLUMN_CAPACITY).Gt(0)).
+		ToSQL()
+
+	if errSql != nil {
+		return nil, errSql
+	}
+
+	store.logSql("select", sqlStr, params...)
+
+	if store.db == nil {
+		return nil, errors.New("groupstore: database is nil")
+	}
+
+	mapped, err := database.SelectToMapString(store.toQuerableContext(ctx), sqlStr, params...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range mapped {
+		capacities[row[COLUMN_ID]] = NewGroupFromExistingData(row).Capacity()
+	}
+
+	return capacities, nil
+}
+
+// groupsLock locks the rows of the groups, in the order of their IDs,
+// until the end of the transaction, with an update leaving them unchanged
+func (store *store) groupsLock(ctx context.Context, groupIDs []string) error {
+	groupIDs = slices.Sorted(slices.Values(groupIDs))
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		Update(store.groupTableName).
+		Prepared(true).
+		Set(goqu.Record{COLUMN_UPDATED_AT: goqu.C(COLUMN_UPDATED_AT)}).
+		Where(goqu.C(COLUMN_ID).In(groupIDs)).
+		ToSQL()
+
+	if errSql != nil {
+		return errSql
+	}
+
+	store.logSql("update", sqlStr, params...)
+
+	_, err := database.Execute(store.toQuerableContext(ctx), sqlStr, params...)
+
+	return err
+}
+
+// relationsCapacityCheck checks the new members of the groups do not
+// exceed their capacities
+func (store *store) relationsCapacityCheck(ctx context.Context, relations []RelationInterface, capacities map[string]int) error {
+	relationIDs := lo.SliceToMap(relations, func(relation RelationInterface) (string, bool) {
+		return relation.ID(), true
+	})
+
+	for groupID, capacity := range capacities {
+		existing, err := store.RelationList(ctx, NewRelationQuery().
+			SetGroupID(groupID).
+			SetColumns([]string{COLUMN_ID, COLUMN_ENTITY_TYPE, COLUMN_ENTITY_ID}))
+
+		if err != nil {
+			return err
+		}
+
+		members := map[string]bool{}
+
+		for _, relation := range existing {
+			if !relationIDs[relation.ID()] {
+				members[relation.EntityType()+"\x00"+relation.EntityID()] = true
+			}
+		}
+
+		for _, relation := range relations {
+			key := relation.EntityType() + "\x00" + relation.EntityID()
+
+			if relation.GroupID() != groupID || members[key] {
+				continue
+			}
+
+			members[key] = true
+
+			if len(members) > capacity {
+				return relationPolicyError(ErrGroupCapacityExceeded, relation, nil)
+			}
+		}
+	}
+
+	return nil
+}
+
+// relationsQuotaCheck checks the entities of the relations do not exceed
+// the maximum number of groups of the quota
+func (store *store) relationsQuotaCheck(ctx context.Context, relations []RelationInterface, quota MembershipQuota, violation error) error {
+	relationIDs := lo.SliceToMap(relations, func(relation RelationInterface) (string, bool) {
+		return relation.ID(), true
+	})
+
+	quotaRelations := lo.Filter(relations, func(relation RelationInterface, _ int) bool {
+		return relation.EntityType() == quota.EntityType
+	})
+
+	for _, entityRelations := range lo.GroupBy(quotaRelations, func(relation RelationInterface) string { return relation.EntityID() }) {
+		existing, err := store.RelationList(ctx, NewRelationQuery().
+			SetEntityType(quota.EntityType).
+			SetEntityID(entityRelations[0].EntityID()))
+
+		if err != nil {
+			return err
+		}
+
+		existing = lo.Filter(existing, func(relation RelationInterface, _ int) bool {
+			return !relationIDs[relation.ID()]
+		})
+
+		counted, err := store.quotaGroupFilter(ctx, quota, append(existing, entityRelations...))
+
+		if err != nil {
+			return err
+		}
+
+		groupIDs := map[string]bool{}
+
+		for _, relation := range existing {
+			if counted[relation.GroupID()] {
+				groupIDs[relation.GroupID()] = true
+			}
+		}
+
+		for _, relation := range entityRelations {
+			if !counted[relation.GroupID()] || groupIDs[relation.GroupID()] {
+				continue
+			}
+
+			groupIDs[relation.GroupID()] = true
+
+			if len(groupIDs) > quota.MaxGroups {
+				policyErr := relationPolicyError(violation, relation, nil)
+				policyErr.Quota = quota.Name
+				return policyErr
+			}
+		}
+	}
+
+	return nil
+}
+
+// quotaGroupFilter returns the groups of the relations counted by the quota
+func (store *store) quotaGroupFilter(ctx context.Context, quota MembershipQuota, relations []RelationInterface) (map[string]bool, error) {
+	groupIDs := lo.Uniq(lo.Map(relations, func(relation RelationInterface, _ int) string { return relation.GroupID() }))
+
+	if quota.GroupFilter == nil {
+		return lo.SliceToMap(groupIDs, func(groupID string) (string, bool) { return groupID, true }), nil
+	}
+
+	counted := map[string]bool{}
+
+	groups, err := store.GroupList(ctx, NewGroupQuery().SetIDIn(groupIDs))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		counted[group.ID()] = quota.GroupFilter(group)
+	}
+
+	return counted, nil
+}
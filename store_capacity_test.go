@@ -0,0 +1,258 @@
+package groupstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestStoreGroupCapacity(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+
+	cohort := NewGroup().SetHandle("cohort-1").SetTitle("Cohort 1").SetCapacity(2)
+	open := NewGroup().SetHandle("open").SetTitle("Open")
+
+	for _, group := range []GroupInterface{cohort, open} {
+		if err := store.GroupCreate(ctx, group); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	remaining, err := store.GroupRemainingCapacity(ctx, open.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if remaining != GROUP_CAPACITY_UNLIMITED {
+		t.Fatal("expected unlimited capacity, found:", remaining)
+	}
+
+	if _, err := store.RelationEnsure(ctx, "user", "USER_01", cohort.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	remaining, err = store.GroupRemainingCapacity(ctx, cohort.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if remaining != 1 {
+		t.Fatal("expected remaining capacity 1, found:", remaining)
+	}
+
+	err = store.RelationBulkCreate(ctx, []RelationInterface{
+		NewRelation().SetEntityType("user").SetEntityID("USER_01").SetGroupID(cohort.ID()), // existing
+		NewRelation().SetEntityType("user").SetEntityID("USER_02").SetGroupID(cohort.ID()),
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err = store.RelationCreate(ctx, NewRelation().
+		SetEntityType("user").
+		SetEntityID("USER_03").
+		SetGroupID(cohort.ID()))
+
+	if !errors.Is(err, ErrGroupCapacityExceeded) {
+		t.Fatal("expected ErrGroupCapacityExceeded, found:", err)
+	}
+
+	var policyErr *EntityPolicyError
+
+	if !errors.As(err, &policyErr) || policyErr.GroupID != cohort.ID() || policyErr.EntityID != "USER_03" {
+		t.Fatal("expected EntityPolicyError for USER_03, found:", err)
+	}
+
+	// moving a member in is limited too
+	relation, err := store.RelationEnsure(ctx, "user", "USER_03", open.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	relation.SetGroupID(cohort.ID())
+
+	if err := store.RelationUpdate(ctx, relation); !errors.Is(err, ErrGroupCapacityExceeded) {
+		t.Fatal("expected ErrGroupCapacityExceeded on update, found:", err)
+	}
+
+	remaining, err = store.GroupRemainingCapacity(ctx, cohort.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if remaining != 0 {
+		t.Fatal("expected remaining capacity 0, found:", remaining)
+	}
+
+	// a soft deleted member frees a seat
+	existing, err := store.RelationFindByEntityAndGroup(ctx, "user", "USER_01", cohort.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.RelationSoftDelete(ctx, existing); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := store.RelationEnsure(ctx, "user", "USER_03", cohort.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+}
+
+func TestStoreGroupCapacity_Concurrent(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "capacity.db") + "?_pragma=busy_timeout(10000)&_txlock=immediate"
+
+	db, err := sql.Open("sqlite", dsn)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                           db,
+		GroupTableName:               "groups_group_table",
+		GroupEntityRelationTableName: "groups_group_entity_relation_table",
+		AutomigrateEnabled:           true,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	ctx := context.Background()
+	cohort := NewGroup().SetHandle("cohort").SetTitle("Cohort").SetCapacity(3)
+
+	if err := store.GroupCreate(ctx, cohort); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+
+	for i := range errs {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			_, errs[i] = store.RelationEnsure(ctx, "user", "USER_"+strconv.Itoa(i), cohort.ID())
+		}()
+	}
+
+	wg.Wait()
+
+	added := 0
+
+	for _, err := range errs {
+		if err == nil {
+			added++
+		} else if !errors.Is(err, ErrGroupCapacityExceeded) {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	count, err := store.RelationCount(ctx, NewRelationQuery().SetGroupID(cohort.ID()))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if added != 3 || count != 3 {
+		t.Fatal("expected 3 members, found:", added, count)
+	}
+}
+
+func TestStoreMembershipQuota(t *testing.T) {
+	if _, err := initStoreWithOptions(":memory:", func(options *NewStoreOptions) {
+		options.MembershipQuotas = []MembershipQuota{{EntityType: "user"}}
+	}); err == nil {
+		t.Fatal("expected error for quota without max groups")
+	}
+
+	store, err := initStoreWithOptions(":memory:", func(options *NewStoreOptions) {
+		options.MembershipQuotas = []MembershipQuota{{
+			Name:        "cohorts",
+			EntityType:  "user",
+			MaxGroups:   1,
+			GroupFilter: func(group GroupInterface) bool { return group.Meta("kind") == "cohort" },
+		}}
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	groups := []GroupInterface{}
+
+	for _, handle := range []string{"cohort-1", "cohort-2", "alumni"} {
+		group := NewGroup().SetHandle(handle).SetTitle(handle)
+
+		if handle != "alumni" {
+			if err := group.SetMeta("kind", "cohort"); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+		}
+
+		if err := store.GroupCreate(ctx, group); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		groups = append(groups, group)
+	}
+
+	if _, err := store.RelationEnsure(ctx, "user", "USER_01", groups[0].ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// the other groups are not counted
+	if _, err := store.RelationEnsure(ctx, "user", "USER_01", groups[2].ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// nor are the other entity types
+	if _, err := store.RelationEnsure(ctx, "team", "USER_01", groups[1].ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	_, err = store.RelationEnsure(ctx, "user", "USER_01", groups[1].ID())
+
+	var policyErr *EntityPolicyError
+
+	if !errors.As(err, &policyErr) || !errors.Is(err, ErrEntityQuotaExceeded) || policyErr.Quota != "cohorts" {
+		t.Fatal("expected ErrEntityQuotaExceeded for the cohorts quota, found:", err)
+	}
+}
@@ -0,0 +1,473 @@
+package groupstore
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+// CSVExportOptions define the options for exporting relations as CSV
+type CSVExportOptions struct {
+	// EntityType exports only the relations of this entity type, optional
+	EntityType string
+
+	// GroupID exports only the relations of this group, optional
+	GroupID string
+
+	// Comma is the field delimiter, defaults to ','
+	Comma rune
+}
+
+// CSVImportOptions define the options for importing relations from CSV
+type CSVImportOptions struct {
+	// EntityType is the entity type of the rows without an entity_type
+	// column (or with an empty value), i.e. "user"
+	EntityType string
+
+	// Columns maps the headers of the file to the recognised columns
+	// (CSV_COLUMN_ENTITY_TYPE, CSV_COLUMN_ENTITY_ID, CSV_COLUMN_GROUP_ID,
+	// CSV_COLUMN_GROUP_HANDLE), i.e. {"email": CSV_COLUMN_ENTITY_ID}.
+	// Headers named as the recognised columns are mapped automatically
+	Columns map[string]string
+
+	// Sync removes the relations of the groups in the file, which are
+	// of the entity types in the file, but are missing from the file
+	Sync bool
+
+	// DryRun only returns what would be added and removed, without any change
+	DryRun bool
+
+	// Comma is the field delimiter, defaults to ','
+	Comma rune
+}
+
+// CSVImportResult holds what was (or in dry-run mode, would be) added
+// and removed by a CSV import, and the invalid rows
+type CSVImportResult struct {
+	Added   []RelationInterface
+	Removed []RelationInterface
+
+	// Unchanged is the number of the rows, which are active relations
+	// already
+	Unchanged int
+
+	// NotActive are the existing relations of the rows, which are not
+	// active (i.e. pending, invited or banned), left unchanged
+	NotActive []RelationInterface
+
+	Errors []CSVRowError
+}
+
+// CSVRowError is a validation error of a CSV row
+type CSVRowError struct {
+	// Line is the line number in the file, the header being line 1
+	Line    int
+	Message string
+}
+
+func (e CSVRowError) Error() string {
+	return "line " + strconv.Itoa(e.Line) + ": " + e.Message
+}
+
+// csvRow is a validated CSV row
+type csvRow struct {
+	entityType string
+	entityID   string
+	groupID    string
+}
+
+// RelationExportCSV writes the relations as CSV, with a header row and
+// the entity_type, entity_id, group_id and group_handle columns
+func (store *store) RelationExportCSV(ctx context.Context, w io.Writer, options CSVExportOptions) error {
+	writer := csv.NewWriter(w)
+
+	if options.Comma != 0 {
+		writer.Comma = options.Comma
+	}
+
+	// collected before iterating the relations, which holds the connection
+	groupHandles := map[string]string{}
+
+	for group, err := range store.GroupIter(ctx, NewGroupQuery().SetSoftDeletedIncluded(true)) {
+		if err != nil {
+			return err
+		}
+
+		groupHandles[group.ID()] = group.Handle()
+	}
+
+	header := []string{CSV_COLUMN_ENTITY_TYPE, CSV_COLUMN_ENTITY_ID, CSV_COLUMN_GROUP_ID, CSV_COLUMN_GROUP_HANDLE}
+
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	query := NewRelationQuery().
+		SetOrderBy(COLUMN_CREATED_AT).
+		SetSortDirection(sb.ASC)
+
+	if options.EntityType != "" {
+		query.SetEntityType(options.EntityType)
+	}
+
+	if options.GroupID != "" {
+		query.SetGroupID(options.GroupID)
+	}
+
+	for relation, err := range store.RelationIter(ctx, query) {
+		if err != nil {
+			return err
+		}
+
+		err = writer.Write([]string{
+			relation.EntityType(),
+			relation.EntityID(),
+			relation.GroupID(),
+			groupHandles[relation.GroupID()],
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// RelationImportCSV reads relations from CSV, and adds the missing ones
+// with bulk inserts
+//
+// Business logic:
+//   - the first row is the header, the columns are mapped by name
+//   - every row needs an entity ID, an entity type (from the file or the
+//     options), and a group, given by ID or handle, of an existing group
+//   - all the rows are validated first, if any row is invalid nothing is
+//     changed, and the row errors are returned in the result
+//   - the rows of the existing relations, which are not active (i.e.
+//     pending or banned), are reported as not active, and left unchanged
+//   - in sync mode the active relations missing from the file are soft
+//     deleted, the others (i.e. the bans) are kept
+//   - in dry-run mode the result is returned without any change
+func (store *store) RelationImportCSV(ctx context.Context, r io.Reader, options CSVImportOptions) (CSVImportResult, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	if options.Comma != 0 {
+		reader.Comma = options.Comma
+	}
+
+	header, err := reader.Read()
+
+	if err == io.EOF {
+		return CSVImportResult{}, errors.New("at relation csv import > header row is missing")
+	}
+
+	if err != nil {
+		return CSVImportResult{}, errors.New("at relation csv import > " + err.Error())
+	}
+
+	columns, err := csvColumnIndexes(header, options.Columns)
+
+	if err != nil {
+		return CSVImportResult{}, err
+	}
+
+	if _, ok := columns[CSV_COLUMN_ENTITY_ID]; !ok {
+		return CSVImportResult{}, errors.New("at relation csv import > entity_id column is missing")
+	}
+
+	_, hasGroupID := columns[CSV_COLUMN_GROUP_ID]
+	_, hasGroupHandle := columns[CSV_COLUMN_GROUP_HANDLE]
+
+	if !hasGroupID && !hasGroupHandle {
+		return CSVImportResult{}, errors.New("at relation csv import > group_id or group_handle column is missing")
+	}
+
+	if _, ok := columns[CSV_COLUMN_ENTITY_TYPE]; !ok && options.EntityType == "" {
+		return CSVImportResult{}, errors.New("at relation csv import > entity_type column is missing, and no default entity type is set")
+	}
+
+	result := CSVImportResult{}
+	rows := []csvRow{}
+	groups := map[string]GroupInterface{} // by ID and by handle, nil if not found
+
+	for {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+
+			if errors.As(err, &parseErr) {
+				result.Errors = append(result.Errors, CSVRowError{Line: parseErr.StartLine, Message: parseErr.Err.Error()})
+				continue
+			}
+
+			return CSVImportResult{}, err
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		if lo.EveryBy(record, func(field string) bool { return strings.TrimSpace(field) == "" }) {
+			continue // blank line
+		}
+
+		row, message, err := store.csvRowValidate(ctx, record, columns, options, groups)
+
+		if err != nil {
+			return CSVImportResult{}, err
+		}
+
+		if message != "" {
+			result.Errors = append(result.Errors, CSVRowError{Line: line, Message: message})
+			continue
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(result.Errors) > 0 {
+		return result, errors.New("at relation csv import > " + strconv.Itoa(len(result.Errors)) + " invalid rows")
+	}
+
+	if err := store.csvImportPlan(ctx, rows, options, &result); err != nil {
+		return CSVImportResult{}, err
+	}
+
+	if options.DryRun {
+		return result, nil
+	}
+
+	err = store.withTransaction(ctx, func(txCtx context.Context) error {
+		if err := store.RelationBulkCreate(txCtx, result.Added); err != nil {
+			return err
+		}
+
+		for _, relation := range result.Removed {
+			if err := store.RelationSoftDelete(txCtx, relation); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return CSVImportResult{}, err
+	}
+
+	return result, nil
+}
+
+// csvImportPlan compares the rows to the relations of their groups, and
+// fills the relations to add and remove, and the number of unchanged ones
+func (store *store) csvImportPlan(ctx context.Context, rows []csvRow, options CSVImportOptions, result *CSVImportResult) error {
+	csvKey := func(entityType, entityID, groupID string) string {
+		return entityType + "\x00" + entityID + "\x00" + groupID
+	}
+
+	groupIDs := lo.Uniq(lo.Map(rows, func(row csvRow, _ int) string { return row.groupID }))
+	entityTypes := lo.Uniq(lo.Map(rows, func(row csvRow, _ int) string { return row.entityType }))
+
+	existing := map[string]RelationInterface{}
+	existingList := []RelationInterface{}
+
+	for _, groupID := range groupIDs {
+		relations, err := store.RelationList(ctx, NewRelationQuery().
+			SetGroupID(groupID).
+			SetStatusIn(RELATION_STATUSES).
+			SetOrderBy(COLUMN_CREATED_AT).
+			SetSortDirection(sb.ASC))
+
+		if err != nil {
+			return err
+		}
+
+		for _, relation := range relations {
+			existing[csvKey(relation.EntityType(), relation.EntityID(), relation.GroupID())] = relation
+		}
+
+		existingList = append(existingList, relations...)
+	}
+
+	seen := map[string]bool{}
+
+	for _, row := range rows {
+		key := csvKey(row.entityType, row.entityID, row.groupID)
+
+		if seen[key] {
+			continue // duplicate row
+		}
+
+		seen[key] = true
+
+		if relation := existing[key]; relation != nil && relation.IsActive() {
+			result.Unchanged++
+			continue
+		} else if relation != nil {
+			result.NotActive = append(result.NotActive, relation)
+			continue
+		}
+
+		result.Added = append(result.Added, NewRelation().
+			SetEntityType(row.entityType).
+			SetEntityID(row.entityID).
+			SetGroupID(row.groupID))
+	}
+
+	if !options.Sync {
+		return nil
+	}
+
+	for _, relation := range existingList {
+		if !relation.IsActive() || !lo.Contains(entityTypes, relation.EntityType()) {
+			continue
+		}
+
+		if !seen[csvKey(relation.EntityType(), relation.EntityID(), relation.GroupID())] {
+			result.Removed = append(result.Removed, relation)
+		}
+	}
+
+	return nil
+}
+
+// csvRowValidate validates the record, and returns the row, or a message
+// describing why the record is invalid
+func (store *store) csvRowValidate(ctx context.Context, record []string, columns map[string]int, options CSVImportOptions, groups map[string]GroupInterface) (csvRow, string, error) {
+	field := func(column string) string {
+		index, ok := columns[column]
+
+		if !ok || index >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[index])
+	}
+
+	row := csvRow{
+		entityType: lo.CoalesceOrEmpty(field(CSV_COLUMN_ENTITY_TYPE), options.EntityType),
+		entityID:   field(CSV_COLUMN_ENTITY_ID),
+	}
+
+	if row.entityType == "" {
+		return row, "entity type is empty", nil
+	}
+
+	if row.entityID == "" {
+		return row, "entity ID is empty", nil
+	}
+
+	groupID := field(CSV_COLUMN_GROUP_ID)
+	groupHandle := field(CSV_COLUMN_GROUP_HANDLE)
+
+	if groupID == "" && groupHandle == "" {
+		return row, "group ID and group handle are empty", nil
+	}
+
+	if groupID != "" {
+		group, err := store.csvGroup("id:"+groupID, groups, func() (GroupInterface, error) {
+			return store.GroupFindByID(ctx, groupID)
+		})
+
+		if err != nil {
+			return row, "", err
+		}
+
+		if group == nil {
+			return row, "group not found: " + groupID, nil
+		}
+
+		if groupHandle != "" && group.Handle() != groupHandle {
+			return row, "group " + groupID + " does not have the handle: " + groupHandle, nil
+		}
+
+		row.groupID = group.ID()
+
+		return row, "", nil
+	}
+
+	group, err := store.csvGroup("handle:"+groupHandle, groups, func() (GroupInterface, error) {
+		return store.GroupFindByHandle(ctx, groupHandle)
+	})
+
+	if err != nil {
+		return row, "", err
+	}
+
+	if group == nil {
+		return row, "group not found: " + groupHandle, nil
+	}
+
+	row.groupID = group.ID()
+
+	return row, "", nil
+}
+
+// csvGroup returns the group from the groups already looked up, or finds it
+func (store *store) csvGroup(key string, groups map[string]GroupInterface, find func() (GroupInterface, error)) (GroupInterface, error) {
+	if group, ok := groups[key]; ok {
+		return group, nil
+	}
+
+	group, err := find()
+
+	if err != nil {
+		return nil, err
+	}
+
+	groups[key] = group
+
+	return group, nil
+}
+
+// csvColumnIndexes maps the recognised columns to their indexes in the header
+func csvColumnIndexes(header []string, aliases map[string]string) (map[string]int, error) {
+	recognised := []string{CSV_COLUMN_ENTITY_TYPE, CSV_COLUMN_ENTITY_ID, CSV_COLUMN_GROUP_ID, CSV_COLUMN_GROUP_HANDLE}
+
+	normalizedAliases := map[string]string{}
+
+	for alias, column := range aliases {
+		if !lo.Contains(recognised, column) {
+			return nil, errors.New("at relation csv import > unknown column: " + column)
+		}
+
+		normalizedAliases[strings.ToLower(strings.TrimSpace(alias))] = column
+	}
+
+	indexes := map[string]int{}
+
+	for index, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+
+		column, ok := normalizedAliases[name]
+
+		if !ok && lo.Contains(recognised, name) {
+			column = name
+		}
+
+		if column == "" {
+			continue // not imported
+		}
+
+		if _, exists := indexes[column]; exists {
+			return nil, errors.New("at relation csv import > column mapped more than once: " + column)
+		}
+
+		indexes[column] = index
+	}
+
+	return indexes, nil
+}
@@ -0,0 +1,256 @@
+package groupstore
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func seedCSV(t *testing.T, store StoreInterface) (GroupInterface, GroupInterface) {
+	admins := NewGroup().SetTitle("Admins").SetHandle("admins").SetStatus(GROUP_STATUS_ACTIVE)
+	billing := NewGroup().SetTitle("Billing").SetHandle("billing").SetStatus(GROUP_STATUS_ACTIVE)
+
+	for _, group := range []GroupInterface{admins, billing} {
+		if err := store.GroupCreate(context.Background(), group); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	for _, entityID := range []string{"ann@example.com", "bob@example.com"} {
+		if _, err := store.RelationEnsure(context.Background(), "user", entityID, admins.ID()); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	return admins, billing
+}
+
+func TestStoreRelationExportCSV(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	admins, _ := seedCSV(t, store)
+
+	buffer := &bytes.Buffer{}
+
+	err = store.RelationExportCSV(context.Background(), buffer, CSVExportOptions{EntityType: "user"})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := "entity_type,entity_id,group_id,group_handle\n" +
+		"user,ann@example.com," + admins.ID() + ",admins\n" +
+		"user,bob@example.com," + admins.ID() + ",admins\n"
+
+	if buffer.String() != expected {
+		t.Fatal("unexpected csv:", buffer.String())
+	}
+}
+
+func TestStoreRelationImportCSV(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	admins, billing := seedCSV(t, store)
+
+	csvText := "Email,Group\n" +
+		"ann@example.com,admins\n" +
+		"carl@example.com,admins\n" +
+		"carl@example.com,billing\n" +
+		"carl@example.com,billing\n"
+
+	options := CSVImportOptions{
+		EntityType: "user",
+		Columns:    map[string]string{"email": CSV_COLUMN_ENTITY_ID, "group": CSV_COLUMN_GROUP_HANDLE},
+		Sync:       true,
+		DryRun:     true,
+	}
+
+	// dry run
+	result, err := store.RelationImportCSV(context.Background(), strings.NewReader(csvText), options)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(result.Added) != 2 || len(result.Removed) != 1 || result.Unchanged != 1 {
+		t.Fatal("unexpected result:", len(result.Added), len(result.Removed), result.Unchanged)
+	}
+
+	if result.Removed[0].EntityID() != "bob@example.com" {
+		t.Fatal("bob@example.com must be removed, found:", result.Removed[0].EntityID())
+	}
+
+	count, err := store.RelationCount(context.Background(), NewRelationQuery())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 2 {
+		t.Fatal("dry run must not change the relations, found:", count)
+	}
+
+	// apply
+	options.DryRun = false
+
+	_, err = store.RelationImportCSV(context.Background(), strings.NewReader(csvText), options)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for _, check := range []struct {
+		entityID string
+		groupID  string
+		isMember bool
+	}{
+		{"ann@example.com", admins.ID(), true},
+		{"bob@example.com", admins.ID(), false},
+		{"carl@example.com", admins.ID(), true},
+		{"carl@example.com", billing.ID(), true},
+	} {
+		isMember, err := store.IsMember(context.Background(), "user", check.entityID, check.groupID)
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if isMember != check.isMember {
+			t.Fatal("unexpected membership of", check.entityID, "in", check.groupID, ":", isMember)
+		}
+	}
+}
+
+func TestStoreRelationImportCSV_NotActive(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	admins, _ := seedCSV(t, store)
+
+	for entityID, status := range map[string]string{"dan@example.com": RELATION_STATUS_PENDING, "eve@example.com": RELATION_STATUS_BANNED} {
+		relation := NewRelation().SetEntityType("user").SetEntityID(entityID).SetGroupID(admins.ID()).SetStatus(status)
+
+		if err := store.RelationCreate(context.Background(), relation); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	csvText := "entity_id,group_handle\n" +
+		"ann@example.com,admins\n" +
+		"dan@example.com,admins\n" +
+		"fay@example.com,admins\n"
+
+	result, err := store.RelationImportCSV(context.Background(), strings.NewReader(csvText), CSVImportOptions{
+		EntityType: "user",
+		Sync:       true,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(result.Added) != 1 || result.Added[0].EntityID() != "fay@example.com" || result.Unchanged != 1 {
+		t.Fatal("only fay@example.com must be added:", result.Added, result.Unchanged)
+	}
+
+	if len(result.NotActive) != 1 || result.NotActive[0].Status() != RELATION_STATUS_PENDING {
+		t.Fatal("the pending relation must be reported as not active:", result.NotActive)
+	}
+
+	// bob@example.com is removed, the ban of eve@example.com is kept
+	if len(result.Removed) != 1 || result.Removed[0].EntityID() != "bob@example.com" {
+		t.Fatal("unexpected removed relations:", result.Removed)
+	}
+
+	banned, err := store.RelationFindByEntityAndGroup(context.Background(), "user", "eve@example.com", admins.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if banned == nil || !banned.IsBanned() {
+		t.Fatal("the ban must be kept:", banned)
+	}
+}
+
+func TestStoreRelationImportCSV_InvalidRows(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	admins, _ := seedCSV(t, store)
+
+	csvText := "entity_type,entity_id,group_handle,group_id\n" +
+		"user,dan@example.com,admins,\n" +
+		"user,,admins,\n" +
+		"user,eve@example.com,unknown,\n" +
+		"user,eve@example.com,billing," + admins.ID() + "\n"
+
+	result, err := store.RelationImportCSV(context.Background(), strings.NewReader(csvText), CSVImportOptions{})
+
+	if err == nil {
+		t.Fatal("must return error as there are invalid rows")
+	}
+
+	if len(result.Errors) != 3 {
+		t.Fatal("invalid rows must be 3, found:", result.Errors)
+	}
+
+	if result.Errors[0].Line != 3 || result.Errors[1].Line != 4 || result.Errors[2].Line != 5 {
+		t.Fatal("unexpected lines:", result.Errors)
+	}
+
+	isMember, err := store.IsMember(context.Background(), "user", "dan@example.com", admins.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if isMember {
+		t.Fatal("no relation must be added, when there are invalid rows")
+	}
+
+	_, err = store.RelationImportCSV(context.Background(), strings.NewReader("email,group\n"), CSVImportOptions{EntityType: "user"})
+
+	if err == nil {
+		t.Fatal("must return error as the entity_id column is missing")
+	}
+}
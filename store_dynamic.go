@@ -0,0 +1,305 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/base/database"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+// MaterializeResult holds the relations added and removed by materialising
+// the dynamic groups
+type MaterializeResult struct {
+	Added   []RelationInterface
+	Removed []RelationInterface
+}
+
+// DynamicGroupMaterialize syncs the relations of the dynamic group with the
+// entities matching its rule
+//
+// Business logic:
+//   - the entities of the rule entity type are read from the resolver
+//   - the matching entities without a relation are added, in bulk
+//   - the relations of the rule entity type, whose entities no longer
+//     match, are soft deleted, the relations of other types are kept
+func (store *store) DynamicGroupMaterialize(ctx context.Context, groupID string) (MaterializeResult, error) {
+	if groupID == "" {
+		return MaterializeResult{}, errors.New("at dynamic group materialize > group ID is empty")
+	}
+
+	if store.entityAttributeResolver == nil {
+		return MaterializeResult{}, errors.New("at dynamic group materialize > entity attribute resolver is not set")
+	}
+
+	group, err := store.GroupFindByID(ctx, groupID)
+
+	if err != nil {
+		return MaterializeResult{}, err
+	}
+
+	if group == nil {
+		return MaterializeResult{}, errors.New("at dynamic group materialize > group not found: " + groupID)
+	}
+
+	rule, err := group.Rule()
+
+	if err != nil {
+		return MaterializeResult{}, err
+	}
+
+	if rule == nil {
+		return MaterializeResult{}, errors.New("at dynamic group materialize > group is not dynamic: " + groupID)
+	}
+
+	return store.dynamicGroupMaterialize(ctx, group, *rule)
+}
+
+// DynamicGroupsMaterialize syncs the relations of all the dynamic groups,
+// which are not soft deleted, with the entities matching their rules,
+// meant to be run periodically
+func (store *store) DynamicGroupsMaterialize(ctx context.Context) (MaterializeResult, error) {
+	if store.entityAttributeResolver == nil {
+		return MaterializeResult{}, errors.New("at dynamic groups materialize > entity attribute resolver is not set")
+	}
+
+	groups, err := store.GroupList(ctx, NewGroupQuery().
+		SetOrderBy(COLUMN_CREATED_AT).
+		SetSortDirection(sb.ASC))
+
+	if err != nil {
+		return MaterializeResult{}, err
+	}
+
+	result := MaterializeResult{}
+
+	for _, group := range groups {
+		rule, err := group.Rule()
+
+		if err != nil {
+			return MaterializeResult{}, err
+		}
+
+		if rule == nil {
+			continue
+		}
+
+		groupResult, err := store.dynamicGroupMaterialize(ctx, group, *rule)
+
+		if err != nil {
+			return MaterializeResult{}, err
+		}
+
+		result.Added = append(result.Added, groupResult.Added...)
+		result.Removed = append(result.Removed, groupResult.Removed...)
+	}
+
+	return result, nil
+}
+
+// == PRIVATE METHODS =========================================================
+
+func (store *store) dynamicGroupMaterialize(ctx context.Context, group GroupInterface, rule GroupRule) (MaterializeResult, error) {
+	matching := map[string]bool{}
+	matchingIDs := []string{}
+
+	for entity, err := range store.entityAttributeResolver.EntityIter(ctx, rule.EntityType) {
+		if err != nil {
+			return MaterializeResult{}, err
+		}
+
+		if rule.Matches(entity.Attributes) && !matching[entity.EntityID] {
+			matching[entity.EntityID] = true
+			matchingIDs = append(matchingIDs, entity.EntityID)
+		}
+	}
+
+	relations, err := store.RelationList(ctx, NewRelationQuery().
+		SetGroupID(group.ID()).
+		SetEntityType(rule.EntityType).
+		SetOrderBy(COLUMN_CREATED_AT).
+		SetSortDirection(sb.ASC))
+
+	if err != nil {
+		return MaterializeResult{}, err
+	}
+
+	related := lo.SliceToMap(relations, func(relation RelationInterface) (string, bool) {
+		return relation.EntityID(), true
+	})
+
+	result := MaterializeResult{}
+
+	for _, entityID := range matchingIDs {
+		if !related[entityID] {
+			result.Added = append(result.Added, NewRelation().
+				SetEntityType(rule.EntityType).
+				SetEntityID(entityID).
+				SetGroupID(group.ID()))
+		}
+	}
+
+	result.Removed = lo.Filter(relations, func(relation RelationInterface, _ int) bool {
+		return !matching[relation.EntityID()]
+	})
+
+	err = store.withTransaction(ctx, func(txCtx context.Context) error {
+		if err := store.RelationBulkCreate(txCtx, result.Added); err != nil {
+			return err
+		}
+
+		for _, relation := range result.Removed {
+			if err := store.RelationSoftDelete(txCtx, relation); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return MaterializeResult{}, err
+	}
+
+	return result, nil
+}
+
+// dynamicGroupRules returns the rules of the dynamic groups, which are not
+// soft deleted, among the given groups, by group ID and by handle
+func (store *store) dynamicGroupRules(ctx context.Context, groupIDsOrHandles []string) (map[string]GroupRule, error) {
+	groupIDsOrHandles = lo.Uniq(groupIDsOrHandles)
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		From(store.groupTableName).
+		Prepared(true).
+		Select(goqu.C(COLUMN_ID), goqu.C(COLUMN_HANDLE), goqu.C(COLUMN_RULE)).
+		Where(goqu.Or(
+			goqu.C(COLUMN_ID).In(groupIDsOrHandles),
+			goqu.C(COLUMN_HANDLE).In(groupIDsOrHandles),
+		)).
+		Where(goqu.C(COLUMN_SOFT_DELETED_AT).Gt(carbon.Now(carbon.UTC).ToDateTimeString())).
+		ToSQL()
+
+	if errSql != nil {
+		return nil, errSql
+	}
+
+	store.logSql("select", sqlStr, params...)
+
+	if store.db == nil {
+		return nil, errors.New("groupstore: database is nil")
+	}
+
+	mapped, err := database.SelectToMapString(store.toQuerableContext(ctx), sqlStr, params...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rules := map[string]GroupRule{}
+
+	for _, row := range mapped {
+		rule, err := NewGroupFromExistingData(row).Rule()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if rule == nil {
+			continue // static, the rule is not compared in SQL as TEXT columns are not comparable in MSSQL
+		}
+
+		rules[row[COLUMN_ID]] = *rule
+
+		if row[COLUMN_HANDLE] != "" {
+			rules[row[COLUMN_HANDLE]] = *rule
+		}
+	}
+
+	return rules, nil
+}
+
+// dynamicMembers returns which of the entities match the rule, by entity ID
+func (store *store) dynamicMembers(ctx context.Context, rule GroupRule, entityIDs []string) (map[string]bool, error) {
+	entities, err := store.entityAttributeResolver.EntityAttributes(ctx, rule.EntityType, lo.Uniq(entityIDs))
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the entities unknown to the resolver are not members
+	members := map[string]bool{}
+
+	for _, entity := range entities {
+		members[entity.EntityID] = rule.Matches(entity.Attributes)
+	}
+
+	return members, nil
+}
+
+// dynamicMembershipChecks evaluates the checks of the dynamic groups
+// against their rules, and returns the remaining checks, which are checked
+// against the relations, and the membership keys of the evaluated checks
+func (store *store) dynamicMembershipChecks(ctx context.Context, checks []MembershipCheck) ([]MembershipCheck, map[string]bool, error) {
+	if store.entityAttributeResolver == nil || len(checks) == 0 {
+		return checks, map[string]bool{}, nil
+	}
+
+	groupRefs := lo.Map(checks, func(check MembershipCheck, _ int) string { return check.GroupIDOrHandle })
+
+	rules := map[string]GroupRule{}
+
+	for _, chunk := range lo.Chunk(lo.Uniq(groupRefs), membershipBatchSize) {
+		chunkRules, err := store.dynamicGroupRules(ctx, chunk)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for ref, rule := range chunkRules {
+			rules[ref] = rule
+		}
+	}
+
+	staticChecks := []MembershipCheck{}
+	dynamicChecks := []MembershipCheck{}
+
+	for _, check := range checks {
+		rule, isDynamic := rules[check.GroupIDOrHandle]
+
+		if isDynamic && rule.EntityType == check.EntityType {
+			dynamicChecks = append(dynamicChecks, check)
+		} else {
+			staticChecks = append(staticChecks, check)
+		}
+	}
+
+	found := map[string]bool{}
+
+	checksByEntityType := lo.GroupBy(dynamicChecks, func(check MembershipCheck) string { return check.EntityType })
+
+	for entityType, typeChecks := range checksByEntityType {
+		entityIDs := lo.Uniq(lo.Map(typeChecks, func(check MembershipCheck, _ int) string { return check.EntityID }))
+
+		entities, err := store.entityAttributeResolver.EntityAttributes(ctx, entityType, entityIDs)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		attributes := lo.SliceToMap(entities, func(entity EntityAttributes) (string, map[string][]string) {
+			return entity.EntityID, entity.Attributes
+		})
+
+		for _, check := range typeChecks {
+			entityAttributes, exists := attributes[check.EntityID]
+			isMember := exists && rules[check.GroupIDOrHandle].Matches(entityAttributes)
+			found[membershipKey(check.EntityType, check.EntityID, check.GroupIDOrHandle)] = isMember
+		}
+	}
+
+	return staticChecks, found, nil
+}
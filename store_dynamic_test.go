@@ -0,0 +1,518 @@
+package groupstore
+
+import (
+	"bytes"
+	"context"
+	"iter"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gouniverse/sb"
+)
+
+// fakeResolver resolves the attributes of the entities from a map
+type fakeResolver struct {
+	entityType string
+	attributes map[string]map[string][]string
+}
+
+func (r *fakeResolver) EntityAttributes(ctx context.Context, entityType string, entityIDs []string) ([]EntityAttributes, error) {
+	entities := []EntityAttributes{}
+
+	if entityType != r.entityType {
+		return entities, nil
+	}
+
+	for _, entityID := range entityIDs {
+		if attributes, ok := r.attributes[entityID]; ok {
+			entities = append(entities, EntityAttributes{EntityID: entityID, Attributes: attributes})
+		}
+	}
+
+	return entities, nil
+}
+
+func (r *fakeResolver) EntityIter(ctx context.Context, entityType string) iter.Seq2[EntityAttributes, error] {
+	return func(yield func(EntityAttributes, error) bool) {
+		if entityType != r.entityType {
+			return
+		}
+
+		entityIDs := make([]string, 0, len(r.attributes))
+
+		for entityID := range r.attributes {
+			entityIDs = append(entityIDs, entityID)
+		}
+
+		slices.Sort(entityIDs)
+
+		for _, entityID := range entityIDs {
+			if !yield(EntityAttributes{EntityID: entityID, Attributes: r.attributes[entityID]}, nil) {
+				return
+			}
+		}
+	}
+}
+
+func newUKUsersGroup(t *testing.T, store StoreInterface) GroupInterface {
+	group := NewGroup().
+		SetStatus(GROUP_STATUS_ACTIVE).
+		SetHandle("uk-users").
+		SetTitle("UK Users")
+
+	err := group.SetRule(&GroupRule{
+		EntityType: "user",
+		Conditions: []GroupRuleCondition{
+			{Attribute: "country", Operator: RULE_OPERATOR_EQ, Values: []string{"GB"}},
+		},
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.GroupCreate(context.Background(), group); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	return group
+}
+
+func TestGroupRuleValidate(t *testing.T) {
+	valid := GroupRule{
+		EntityType: "user",
+		Match:      RULE_MATCH_ANY,
+		Conditions: []GroupRuleCondition{
+			{Attribute: "country", Operator: RULE_OPERATOR_IN, Values: []string{"GB", "IE"}},
+			{Attribute: "deleted", Operator: RULE_OPERATOR_NOT_EXISTS},
+		},
+	}
+
+	if err := valid.Validate(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	invalid := map[string]GroupRule{
+		"no entity type": {Conditions: valid.Conditions},
+		"no conditions":  {EntityType: "user"},
+		"invalid match":  {EntityType: "user", Match: "some", Conditions: valid.Conditions},
+		"invalid operator": {EntityType: "user", Conditions: []GroupRuleCondition{
+			{Attribute: "country", Operator: "like", Values: []string{"GB"}},
+		}},
+		"eq without value": {EntityType: "user", Conditions: []GroupRuleCondition{
+			{Attribute: "country", Operator: RULE_OPERATOR_EQ},
+		}},
+		"exists with value": {EntityType: "user", Conditions: []GroupRuleCondition{
+			{Attribute: "country", Operator: RULE_OPERATOR_EXISTS, Values: []string{"GB"}},
+		}},
+	}
+
+	for name, rule := range invalid {
+		if err := rule.Validate(); err == nil {
+			t.Fatal("expected error for rule with", name)
+		}
+	}
+}
+
+func TestGroupRuleMatches(t *testing.T) {
+	attributes := map[string][]string{
+		"country": {"GB"},
+		"tags":    {"beta", "staff-engineering"},
+	}
+
+	cases := []struct {
+		condition GroupRuleCondition
+		expected  bool
+	}{
+		{GroupRuleCondition{Attribute: "country", Operator: RULE_OPERATOR_EQ, Values: []string{"GB"}}, true},
+		{GroupRuleCondition{Attribute: "country", Operator: RULE_OPERATOR_NE, Values: []string{"GB"}}, false},
+		{GroupRuleCondition{Attribute: "tags", Operator: RULE_OPERATOR_IN, Values: []string{"alpha", "beta"}}, true},
+		{GroupRuleCondition{Attribute: "tags", Operator: RULE_OPERATOR_NOT_IN, Values: []string{"alpha"}}, true},
+		{GroupRuleCondition{Attribute: "tags", Operator: RULE_OPERATOR_CONTAINS, Values: []string{"staff"}}, true},
+		{GroupRuleCondition{Attribute: "plan", Operator: RULE_OPERATOR_EXISTS}, false},
+		{GroupRuleCondition{Attribute: "plan", Operator: RULE_OPERATOR_NOT_EXISTS}, true},
+	}
+
+	for _, c := range cases {
+		rule := GroupRule{EntityType: "user", Conditions: []GroupRuleCondition{c.condition}}
+
+		if rule.Matches(attributes) != c.expected {
+			t.Fatal("expected", c.expected, "for", c.condition.Attribute, c.condition.Operator)
+		}
+	}
+
+	all := GroupRule{EntityType: "user", Conditions: []GroupRuleCondition{cases[0].condition, cases[1].condition}}
+
+	if all.Matches(attributes) {
+		t.Fatal("expected match all to fail when one condition fails")
+	}
+
+	all.Match = RULE_MATCH_ANY
+
+	if !all.Matches(attributes) {
+		t.Fatal("expected match any to pass when one condition passes")
+	}
+}
+
+func TestGroupRule(t *testing.T) {
+	group := NewGroup()
+
+	if group.IsDynamic() {
+		t.Fatal("expected a new group to be static")
+	}
+
+	rule, err := group.Rule()
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if rule != nil {
+		t.Fatal("expected nil rule, found:", rule)
+	}
+
+	if err := group.SetRule(&GroupRule{EntityType: "user"}); err == nil {
+		t.Fatal("expected error for rule without conditions")
+	}
+
+	err = group.SetRule(&GroupRule{
+		EntityType: "user",
+		Conditions: []GroupRuleCondition{{Attribute: "country", Operator: RULE_OPERATOR_EXISTS}},
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !group.IsDynamic() {
+		t.Fatal("expected the group to be dynamic")
+	}
+
+	rule, err = group.Rule()
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if rule == nil || rule.EntityType != "user" || len(rule.Conditions) != 1 {
+		t.Fatal("unexpected rule:", rule)
+	}
+
+	if err := group.SetRule(nil); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if group.IsDynamic() {
+		t.Fatal("expected the group to be static after removing the rule")
+	}
+}
+
+func TestStoreIsMember_DynamicGroup(t *testing.T) {
+	resolver := &fakeResolver{entityType: "user", attributes: map[string]map[string][]string{
+		"USER_01": {"country": {"GB"}},
+		"USER_02": {"country": {"FR"}},
+	}}
+
+	store, err := initStoreWithOptions(":memory:", func(options *NewStoreOptions) {
+		options.EntityAttributeResolver = resolver
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	group := newUKUsersGroup(t, store)
+
+	// the relations of other entity types are kept static
+	err = store.RelationCreate(ctx, NewRelation().
+		SetEntityType("service").
+		SetEntityID("SERVICE_01").
+		SetGroupID(group.ID()))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// the relations of the rule entity type are ignored, the rule decides
+	err = store.RelationCreate(ctx, NewRelation().
+		SetEntityType("user").
+		SetEntityID("USER_02").
+		SetGroupID(group.ID()))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	cases := []struct {
+		entityType string
+		entityID   string
+		groupRef   string
+		expected   bool
+	}{
+		{"user", "USER_01", group.ID(), true},
+		{"user", "USER_01", "uk-users", true},
+		{"user", "USER_02", group.ID(), false},
+		{"user", "USER_03", group.ID(), false},
+		{"service", "SERVICE_01", "uk-users", true},
+		{"service", "SERVICE_02", "uk-users", false},
+	}
+
+	for _, c := range cases {
+		isMember, err := store.IsMember(ctx, c.entityType, c.entityID, c.groupRef)
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if isMember != c.expected {
+			t.Fatal("expected", c.expected, "for", c.entityType, c.entityID, c.groupRef)
+		}
+	}
+
+	checks := make([]MembershipCheck, 0, len(cases))
+
+	for _, c := range cases {
+		checks = append(checks, MembershipCheck{EntityType: c.entityType, EntityID: c.entityID, GroupIDOrHandle: c.groupRef})
+	}
+
+	results, err := store.BatchIsMember(ctx, checks)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for i, c := range cases {
+		if results[i] != c.expected {
+			t.Fatal("expected batch", c.expected, "for", c.entityType, c.entityID, c.groupRef)
+		}
+	}
+}
+
+func TestStoreIsMember_DynamicGroupSingleQuery(t *testing.T) {
+	resolver := &fakeResolver{entityType: "user", attributes: map[string]map[string][]string{}}
+	logs := &bytes.Buffer{}
+
+	store, err := initStoreWithOptions(":memory:", func(options *NewStoreOptions) {
+		options.EntityAttributeResolver = resolver
+		options.SqlLogger = slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	group := NewGroup().SetStatus(GROUP_STATUS_ACTIVE).SetHandle("static").SetTitle("Static")
+
+	if err := store.GroupCreate(ctx, group); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := store.RelationEnsure(ctx, "user", "USER_01", group.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	logs.Reset()
+
+	isMember, err := store.IsMember(ctx, "user", "USER_01", "static")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !isMember {
+		t.Fatal("USER_01 must be a member of the static group")
+	}
+
+	if count := strings.Count(logs.String(), "sql: select"); count != 1 {
+		t.Fatal("membership must be checked with one query, found:", count)
+	}
+}
+
+func TestStoreDynamicGroupMaterialize(t *testing.T) {
+	resolver := &fakeResolver{entityType: "user", attributes: map[string]map[string][]string{
+		"USER_01": {"country": {"GB"}},
+		"USER_02": {"country": {"FR"}},
+		"USER_03": {"country": {"GB"}},
+	}}
+
+	store, err := initStoreWithOptions(":memory:", func(options *NewStoreOptions) {
+		options.EntityAttributeResolver = resolver
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	group := newUKUsersGroup(t, store)
+
+	if err := store.GroupCreate(ctx, NewGroup().SetHandle("static").SetTitle("Static")); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err = store.RelationCreate(ctx, NewRelation().
+		SetEntityType("user").
+		SetEntityID("USER_02").
+		SetGroupID(group.ID()))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	result, err := store.DynamicGroupMaterialize(ctx, group.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(result.Added) != 2 || len(result.Removed) != 1 {
+		t.Fatal("expected 2 added and 1 removed, found:", len(result.Added), len(result.Removed))
+	}
+
+	if result.Removed[0].EntityID() != "USER_02" {
+		t.Fatal("expected USER_02 removed, found:", result.Removed[0].EntityID())
+	}
+
+	relations, err := store.RelationList(ctx, NewRelationQuery().
+		SetGroupID(group.ID()).
+		SetOrderBy(COLUMN_ENTITY_ID).
+		SetSortDirection(sb.ASC))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(relations) != 2 || relations[0].EntityID() != "USER_01" || relations[1].EntityID() != "USER_03" {
+		t.Fatal("unexpected relations:", relations)
+	}
+
+	// the entities no longer matching are removed on the next run
+	resolver.attributes["USER_03"] = map[string][]string{"country": {"IE"}}
+
+	result, err = store.DynamicGroupsMaterialize(ctx)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(result.Added) != 0 || len(result.Removed) != 1 {
+		t.Fatal("expected 0 added and 1 removed, found:", len(result.Added), len(result.Removed))
+	}
+
+	if _, err := store.DynamicGroupMaterialize(ctx, "missing"); err == nil {
+		t.Fatal("expected error for missing group")
+	}
+}
+
+func TestStoreDynamicGroupMaterialize_RequiresResolver(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	group := newUKUsersGroup(t, store)
+
+	if _, err := store.DynamicGroupMaterialize(context.Background(), group.ID()); err == nil {
+		t.Fatal("expected error without resolver")
+	}
+
+	// without a resolver the materialised relations are used
+	isMember, err := store.IsMember(context.Background(), "user", "USER_01", group.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if isMember {
+		t.Fatal("expected no membership without relations")
+	}
+}
+
+func TestStoreAutoMigrate_AddsRuleColumn(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// the group table as created before the rule column
+	_, err = db.Exec(`CREATE TABLE groups_group_table (
+		id TEXT PRIMARY KEY, status TEXT, handle TEXT, title TEXT, memo TEXT, metas TEXT,
+		created_at DATETIME, updated_at DATETIME, soft_deleted_at DATETIME)`)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO groups_group_table VALUES ('GROUP_01', 'active', 'legacy', 'Legacy', '', '{}',
+		'2020-01-01 00:00:00', '2020-01-01 00:00:00', '9999-12-31 23:59:59')`)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                           db,
+		GroupTableName:               "groups_group_table",
+		GroupEntityRelationTableName: "groups_group_entity_relation_table",
+		AutomigrateEnabled:           true,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	group, err := store.GroupFindByID(context.Background(), "GROUP_01")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if group == nil {
+		t.Fatal("expected the legacy group")
+	}
+
+	if group.IsDynamic() || group.RuleJSON() != "" {
+		t.Fatal("expected a static legacy group, found rule:", group.RuleJSON())
+	}
+
+	// migrating again is a no-op
+	if err := store.AutoMigrate(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+}
@@ -0,0 +1,149 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/base/database"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+	"github.com/spf13/cast"
+)
+
+// EntitiesInAllGroups returns the IDs of the entities, which are members
+// of every one of the given groups
+func (store *store) EntitiesInAllGroups(ctx context.Context, groupIDs []string, query EntityQueryInterface) ([]string, error) {
+	if len(groupIDs) < 1 {
+		return []string{}, errors.New("entities in all groups > group ids are empty")
+	}
+
+	groupIDs = lo.Uniq(groupIDs)
+
+	having := goqu.COUNT(goqu.L("DISTINCT ?", store.entityGroupCase(groupIDs))).Eq(len(groupIDs))
+
+	return store.entitySetList(ctx, groupIDs, nil, having, query)
+}
+
+// EntitiesInAnyGroup returns the IDs of the entities, which are members
+// of at least one of the given groups
+func (store *store) EntitiesInAnyGroup(ctx context.Context, groupIDs []string, query EntityQueryInterface) ([]string, error) {
+	if len(groupIDs) < 1 {
+		return []string{}, errors.New("entities in any group > group ids are empty")
+	}
+
+	groupIDs = lo.Uniq(groupIDs)
+
+	having := goqu.COUNT(goqu.L("DISTINCT ?", store.entityGroupCase(groupIDs))).Gte(1)
+
+	return store.entitySetList(ctx, groupIDs, nil, having, query)
+}
+
+// EntitiesNotInGroups returns the IDs of the entities, which have at least
+// one relation, but are not members of any of the given groups
+func (store *store) EntitiesNotInGroups(ctx context.Context, groupIDs []string, query EntityQueryInterface) ([]string, error) {
+	if len(groupIDs) < 1 {
+		return []string{}, errors.New("entities not in groups > group ids are empty")
+	}
+
+	return store.entitySetList(ctx, nil, lo.Uniq(groupIDs), nil, query)
+}
+
+// entityGroupCase returns a CASE expression, which evaluates to the group ID
+// when it is one of the given group IDs, and to NULL otherwise
+func (store *store) entityGroupCase(groupIDs []string) exp.CaseExpression {
+	return goqu.Case().
+		When(goqu.C(COLUMN_GROUP_ID).In(groupIDs), goqu.C(COLUMN_GROUP_ID))
+}
+
+// entitySetList runs the grouped entity query shared by the set operations
+//
+// Business logic:
+//   - only relations of the queried entity type, which are not soft deleted, are considered
+//   - when groupIDs is not empty, only relations to these (and the excluded) groups are considered
+//   - the relations are grouped by entity ID and filtered with the having expression
+//   - entities with a relation to any of the excluded groups (the given ones and
+//     the ones from the query) are removed
+func (store *store) entitySetList(
+	ctx context.Context,
+	groupIDs []string,
+	excludedGroupIDs []string,
+	having exp.Expression,
+	query EntityQueryInterface,
+) ([]string, error) {
+	if query == nil {
+		return []string{}, errors.New("at entity set list > entity query is nil")
+	}
+
+	if err := query.Validate(); err != nil {
+		return []string{}, err
+	}
+
+	excluded := lo.Union(excludedGroupIDs, query.GroupIDNotIn())
+
+	q := goqu.Dialect(store.dbDriverName).
+		From(store.groupEntityRelationTableName).
+		Where(goqu.C(COLUMN_ENTITY_TYPE).Eq(query.EntityType())).
+		Where(goqu.C(COLUMN_SOFT_DELETED_AT).Gt(carbon.Now(carbon.UTC).ToDateTimeString())).
+		Where(relationStatusExpression(COLUMN_STATUS, []string{RELATION_STATUS_ACTIVE}))
+
+	if len(groupIDs) > 0 {
+		q = q.Where(goqu.C(COLUMN_GROUP_ID).In(lo.Union(groupIDs, excluded)))
+	}
+
+	q = q.GroupBy(goqu.C(COLUMN_ENTITY_ID))
+
+	if having != nil {
+		q = q.Having(having)
+	}
+
+	if len(excluded) > 0 {
+		excludedCount := goqu.SUM(goqu.Case().
+			When(goqu.C(COLUMN_GROUP_ID).In(excluded), goqu.L("1")).
+			Else(goqu.L("0")))
+
+		q = q.Having(excludedCount.Eq(0))
+	}
+
+	sort := lo.Ternary(query.HasSortDirection(), query.SortDirection(), sb.ASC)
+	if strings.EqualFold(sort, sb.DESC) {
+		q = q.Order(goqu.C(COLUMN_ENTITY_ID).Desc())
+	} else {
+		q = q.Order(goqu.C(COLUMN_ENTITY_ID).Asc())
+	}
+
+	if query.HasLimit() {
+		q = q.Limit(cast.ToUint(query.Limit()))
+	}
+
+	if query.HasOffset() {
+		q = q.Offset(cast.ToUint(query.Offset()))
+	}
+
+	sqlStr, sqlParams, errSql := q.Prepared(true).Select(goqu.C(COLUMN_ENTITY_ID)).ToSQL()
+
+	if errSql != nil {
+		return []string{}, errSql
+	}
+
+	store.logSql("select", sqlStr, sqlParams...)
+
+	if store.db == nil {
+		return []string{}, errors.New("groupstore: database is nil")
+	}
+
+	modelMaps, err := database.SelectToMapString(store.toQuerableContext(ctx), sqlStr, sqlParams...)
+
+	if err != nil {
+		return []string{}, err
+	}
+
+	entityIDs := lo.Map(modelMaps, func(modelMap map[string]string, _ int) string {
+		return modelMap[COLUMN_ENTITY_ID]
+	})
+
+	return entityIDs, nil
+}
@@ -0,0 +1,159 @@
+package groupstore
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func seedEntitySets(t *testing.T, store StoreInterface) {
+	relations := []RelationInterface{
+		NewRelation().SetEntityType("USER").SetEntityID("USER_01").SetGroupID("GROUP_A"),
+		NewRelation().SetEntityType("USER").SetEntityID("USER_01").SetGroupID("GROUP_B"),
+		NewRelation().SetEntityType("USER").SetEntityID("USER_02").SetGroupID("GROUP_A"),
+		NewRelation().SetEntityType("USER").SetEntityID("USER_02").SetGroupID("GROUP_B"),
+		NewRelation().SetEntityType("USER").SetEntityID("USER_02").SetGroupID("GROUP_C"),
+		NewRelation().SetEntityType("USER").SetEntityID("USER_03").SetGroupID("GROUP_B"),
+		NewRelation().SetEntityType("USER").SetEntityID("USER_04").SetGroupID("GROUP_D"),
+		NewRelation().SetEntityType("PRODUCT").SetEntityID("PRODUCT_01").SetGroupID("GROUP_A"),
+		NewRelation().SetEntityType("PRODUCT").SetEntityID("PRODUCT_01").SetGroupID("GROUP_B"),
+	}
+
+	for _, relation := range relations {
+		if err := store.RelationCreate(context.Background(), relation); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+}
+
+func TestStoreEntitiesInAllGroups(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	seedEntitySets(t, store)
+
+	ids, err := store.EntitiesInAllGroups(context.Background(), []string{"GROUP_A", "GROUP_B"}, NewEntityQuery().SetEntityType("USER"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !slices.Equal(ids, []string{"USER_01", "USER_02"}) {
+		t.Fatal("unexpected entity ids:", ids)
+	}
+
+	ids, err = store.EntitiesInAllGroups(context.Background(), []string{"GROUP_A", "GROUP_B"}, NewEntityQuery().
+		SetEntityType("USER").
+		SetGroupIDNotIn([]string{"GROUP_C"}))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !slices.Equal(ids, []string{"USER_01"}) {
+		t.Fatal("unexpected entity ids:", ids)
+	}
+
+	ids, err = store.EntitiesInAllGroups(context.Background(), []string{"GROUP_A", "GROUP_B"}, NewEntityQuery().
+		SetEntityType("USER").
+		SetSortDirection("desc").
+		SetLimit(1))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !slices.Equal(ids, []string{"USER_02"}) {
+		t.Fatal("unexpected entity ids:", ids)
+	}
+
+	_, err = store.EntitiesInAllGroups(context.Background(), []string{"GROUP_A"}, NewEntityQuery())
+
+	if err == nil {
+		t.Fatal("must return error as entity type is required")
+	}
+}
+
+func TestStoreEntitiesInAnyGroup(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	seedEntitySets(t, store)
+
+	ids, err := store.EntitiesInAnyGroup(context.Background(), []string{"GROUP_C", "GROUP_D"}, NewEntityQuery().SetEntityType("USER"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !slices.Equal(ids, []string{"USER_02", "USER_04"}) {
+		t.Fatal("unexpected entity ids:", ids)
+	}
+
+	ids, err = store.EntitiesInAnyGroup(context.Background(), []string{"GROUP_A", "GROUP_B"}, NewEntityQuery().
+		SetEntityType("USER").
+		SetLimit(2).
+		SetOffset(1))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !slices.Equal(ids, []string{"USER_02", "USER_03"}) {
+		t.Fatal("unexpected entity ids:", ids)
+	}
+}
+
+func TestStoreEntitiesNotInGroups(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	seedEntitySets(t, store)
+
+	ids, err := store.EntitiesNotInGroups(context.Background(), []string{"GROUP_A"}, NewEntityQuery().SetEntityType("USER"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !slices.Equal(ids, []string{"USER_03", "USER_04"}) {
+		t.Fatal("unexpected entity ids:", ids)
+	}
+
+	ids, err = store.EntitiesNotInGroups(context.Background(), []string{"GROUP_A"}, NewEntityQuery().SetEntityType("PRODUCT"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(ids) != 0 {
+		t.Fatal("unexpected entity ids:", ids)
+	}
+}
@@ -0,0 +1,252 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/samber/lo"
+)
+
+// EntityTypePolicy declares an entity type allowed in the relations, and
+// the policies for its entities
+type EntityTypePolicy struct {
+	// EntityType is the name of the entity type, required
+	EntityType string
+
+	// MaxGroups is the maximum number of groups an entity can belong to,
+	// 0 for unlimited
+	MaxGroups int
+
+	// NestedGroupsAllowed allows the entities to belong to the groups,
+	// which are nested in another group
+	NestedGroupsAllowed bool
+
+	// IDValidator validates the format of the entity IDs, optional
+	IDValidator func(entityID string) error
+}
+
+// The entity policy violations, wrapped in an EntityPolicyError
+var (
+	ErrEntityTypeUnknown           = errors.New("entity type is not registered")
+	ErrEntityIDInvalid             = errors.New("entity ID is invalid")
+	ErrEntityMaxGroupsExceeded     = errors.New("entity belongs to the maximum number of groups")
+	ErrEntityNestedGroupNotAllowed = errors.New("entity type cannot belong to nested groups")
+)
+
+// EntityPolicyError is returned when a relation violates the policies of
+// the registered entity types, a group capacity, a membership quota or an
+// exclusive set, it wraps one of the ErrEntity* or ErrGroup* errors
+//
+// Example:
+//
+//	var policyErr *groupstore.EntityPolicyError
+//
+//	if errors.As(err, &policyErr) { ... }
+//	if errors.Is(err, groupstore.ErrEntityTypeUnknown) { ... }
+type EntityPolicyError struct {
+	// Err is the violation, one of the ErrEntity* or ErrGroup* errors
+	Err error
+
+	EntityType string
+	EntityID   string
+	GroupID    string
+
+	// Quota is the name of the membership quota exceeded, if any
+	Quota string
+
+	// ExclusiveSet is the name of the exclusive set violated, if any
+	ExclusiveSet string
+
+	// Cause is the error of the ID validator, if any
+	Cause error
+}
+
+func (e *EntityPolicyError) Error() string {
+	message := "groupstore > " + e.Err.Error() + ": " + e.EntityType
+
+	if e.EntityID != "" {
+		message += " " + e.EntityID
+	}
+
+	message += " in group " + e.GroupID
+
+	if e.Quota != "" {
+		message += " (quota " + e.Quota + ")"
+	}
+
+	if e.ExclusiveSet != "" {
+		message += " (exclusive set " + e.ExclusiveSet + ")"
+	}
+
+	if e.Cause != nil {
+		message += ": " + e.Cause.Error()
+	}
+
+	return message
+}
+
+func (e *EntityPolicyError) Unwrap() []error {
+	return lo.Compact([]error{e.Err, e.Cause})
+}
+
+// == PRIVATE METHODS =========================================================
+
+// entityTypePolicies validates the policies, and returns them by entity
+// type, nil if none (i.e. the registry is disabled)
+func entityTypePolicies(policies []EntityTypePolicy) (map[string]EntityTypePolicy, error) {
+	if len(policies) == 0 {
+		return nil, nil
+	}
+
+	byEntityType := map[string]EntityTypePolicy{}
+
+	for _, policy := range policies {
+		if policy.EntityType == "" {
+			return nil, errors.New("group store: EntityTypes entity type is required")
+		}
+
+		if policy.MaxGroups < 0 {
+			return nil, errors.New("group store: EntityTypes max groups of " + policy.EntityType + " " + ERROR_NEGATIVE_NUMBER)
+		}
+
+		if _, exists := byEntityType[policy.EntityType]; exists {
+			return nil, errors.New("group store: EntityTypes entity type is duplicated: " + policy.EntityType)
+		}
+
+		byEntityType[policy.EntityType] = policy
+	}
+
+	return byEntityType, nil
+}
+
+// relationsPolicyCheck checks the new or changed relations against the
+// policies of the registered entity types
+//
+// Business logic:
+//   - the entity type must be registered, and the entity ID valid
+//   - the entities of the types, which do not allow nesting, cannot be
+//     added to a nested group, and a group having such members cannot be
+//     nested in another group
+//   - the groups of an entity, existing and new, cannot exceed MaxGroups,
+//     only the active relations are counted
+func (store *store) relationsPolicyCheck(ctx context.Context, relations []RelationInterface) error {
+	if store.entityTypes == nil || len(relations) == 0 {
+		return nil
+	}
+
+	for _, relation := range relations {
+		policy, registered := store.entityTypes[relation.EntityType()]
+
+		if !registered {
+			return relationPolicyError(ErrEntityTypeUnknown, relation, nil)
+		}
+
+		if policy.IDValidator == nil {
+			continue
+		}
+
+		if err := policy.IDValidator(relation.EntityID()); err != nil {
+			return relationPolicyError(ErrEntityIDInvalid, relation, err)
+		}
+	}
+
+	if err := store.relationsNestingCheck(ctx, relations); err != nil {
+		return err
+	}
+
+	return store.relationsMaxGroupsCheck(ctx, lo.Filter(relations, relationIsActive))
+}
+
+// relationsNestingCheck checks the nesting policies of the relations
+func (store *store) relationsNestingCheck(ctx context.Context, relations []RelationInterface) error {
+	notNestable := lo.FilterMap(lo.Values(store.entityTypes), func(policy EntityTypePolicy, _ int) (string, bool) {
+		return policy.EntityType, !policy.NestedGroupsAllowed
+	})
+
+	if len(notNestable) == 0 {
+		return nil
+	}
+
+	slices.Sort(notNestable)
+
+	isNested := map[string]bool{}
+
+	for _, relation := range relations {
+		if !store.entityTypes[relation.EntityType()].NestedGroupsAllowed {
+			nested, checked := isNested[relation.GroupID()]
+
+			if !checked {
+				count, err := store.RelationCount(ctx, NewRelationQuery().
+					SetEntityType(ENTITY_TYPE_GROUP).
+					SetEntityID(relation.GroupID()))
+
+				if err != nil {
+					return err
+				}
+
+				nested = count > 0
+				isNested[relation.GroupID()] = nested
+			}
+
+			if nested {
+				return relationPolicyError(ErrEntityNestedGroupNotAllowed, relation, nil)
+			}
+		}
+
+		if relation.EntityType() != ENTITY_TYPE_GROUP {
+			continue
+		}
+
+		// the group nested by the relation
+		for _, entityType := range notNestable {
+			count, err := store.RelationCount(ctx, NewRelationQuery().
+				SetEntityType(entityType).
+				SetGroupID(relation.EntityID()))
+
+			if err != nil {
+				return err
+			}
+
+			if count > 0 {
+				return &EntityPolicyError{
+					Err:        ErrEntityNestedGroupNotAllowed,
+					EntityType: entityType,
+					GroupID:    relation.EntityID(),
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// relationsMaxGroupsCheck checks the entities of the relations do not
+// exceed the maximum number of groups of their types
+func (store *store) relationsMaxGroupsCheck(ctx context.Context, relations []RelationInterface) error {
+	for _, policy := range store.entityTypes {
+		if policy.MaxGroups == 0 {
+			continue
+		}
+
+		quota := MembershipQuota{EntityType: policy.EntityType, MaxGroups: policy.MaxGroups}
+
+		if err := store.relationsQuotaCheck(ctx, relations, quota, ErrEntityMaxGroupsExceeded); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// == HELPERS =================================================================
+
+func relationPolicyError(violation error, relation RelationInterface, cause error) *EntityPolicyError {
+	return &EntityPolicyError{
+		Err:        violation,
+		EntityType: relation.EntityType(),
+		EntityID:   relation.EntityID(),
+		GroupID:    relation.GroupID(),
+		Cause:      cause,
+	}
+}
@@ -0,0 +1,262 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newPolicyTestGroups(t *testing.T, store StoreInterface, handles ...string) []GroupInterface {
+	groups := []GroupInterface{}
+
+	for _, handle := range handles {
+		group := NewGroup().SetHandle(handle).SetTitle(handle)
+
+		if err := store.GroupCreate(context.Background(), group); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+func TestNewStore_EntityTypesInvalid(t *testing.T) {
+	invalid := map[string][]EntityTypePolicy{
+		"empty entity type":     {{EntityType: ""}},
+		"negative max groups":   {{EntityType: "user", MaxGroups: -1}},
+		"duplicate entity type": {{EntityType: "user"}, {EntityType: "user"}},
+	}
+
+	for name, policies := range invalid {
+		if _, err := initStoreWithOptions(":memory:", func(options *NewStoreOptions) {
+			options.EntityTypes = policies
+		}); err == nil {
+			t.Fatal("expected error for", name)
+		}
+	}
+}
+
+func TestStoreRelationCreate_EntityTypeUnknown(t *testing.T) {
+	store, err := initStoreWithOptions(":memory:", func(options *NewStoreOptions) {
+		options.EntityTypes = []EntityTypePolicy{{EntityType: "user", NestedGroupsAllowed: true}}
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	groups := newPolicyTestGroups(t, store, "admins")
+
+	err = store.RelationCreate(ctx, NewRelation().
+		SetEntityType("usr").
+		SetEntityID("USER_01").
+		SetGroupID(groups[0].ID()))
+
+	if !errors.Is(err, ErrEntityTypeUnknown) {
+		t.Fatal("expected ErrEntityTypeUnknown, found:", err)
+	}
+
+	var policyErr *EntityPolicyError
+
+	if !errors.As(err, &policyErr) || policyErr.EntityType != "usr" || policyErr.GroupID != groups[0].ID() {
+		t.Fatal("expected EntityPolicyError for usr, found:", err)
+	}
+
+	if _, err := store.RelationEnsure(ctx, "usr", "USER_01", groups[0].ID()); !errors.Is(err, ErrEntityTypeUnknown) {
+		t.Fatal("expected ErrEntityTypeUnknown, found:", err)
+	}
+
+	err = store.RelationBulkCreate(ctx, []RelationInterface{
+		NewRelation().SetEntityType("user").SetEntityID("USER_01").SetGroupID(groups[0].ID()),
+		NewRelation().SetEntityType("usr").SetEntityID("USER_02").SetGroupID(groups[0].ID()),
+	})
+
+	if !errors.Is(err, ErrEntityTypeUnknown) {
+		t.Fatal("expected ErrEntityTypeUnknown, found:", err)
+	}
+
+	// the bulk insert is all or nothing
+	count, err := store.RelationCount(ctx, NewRelationQuery())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 0 {
+		t.Fatal("expected no relations, found:", count)
+	}
+
+	if _, err := store.RelationEnsure(ctx, "user", "USER_01", groups[0].ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+}
+
+func TestStoreRelationCreate_EntityIDInvalid(t *testing.T) {
+	errNotNumeric := errors.New("not numeric")
+
+	store, err := initStoreWithOptions(":memory:", func(options *NewStoreOptions) {
+		options.EntityTypes = []EntityTypePolicy{{
+			EntityType:          "user",
+			NestedGroupsAllowed: true,
+			IDValidator: func(entityID string) error {
+				if strings.Trim(entityID, "0123456789") != "" {
+					return errNotNumeric
+				}
+
+				return nil
+			},
+		}}
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	groups := newPolicyTestGroups(t, store, "admins")
+
+	_, err = store.RelationEnsure(ctx, "user", "USER_01", groups[0].ID())
+
+	if !errors.Is(err, ErrEntityIDInvalid) || !errors.Is(err, errNotNumeric) {
+		t.Fatal("expected ErrEntityIDInvalid wrapping the validator error, found:", err)
+	}
+
+	relation, err := store.RelationEnsure(ctx, "user", "123456", groups[0].ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	relation.SetEntityID("ABC")
+
+	if err := store.RelationUpdate(ctx, relation); !errors.Is(err, ErrEntityIDInvalid) {
+		t.Fatal("expected ErrEntityIDInvalid on update, found:", err)
+	}
+}
+
+func TestStoreRelationCreate_MaxGroups(t *testing.T) {
+	store, err := initStoreWithOptions(":memory:", func(options *NewStoreOptions) {
+		options.EntityTypes = []EntityTypePolicy{{EntityType: "user", MaxGroups: 2, NestedGroupsAllowed: true}}
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	groups := newPolicyTestGroups(t, store, "admins", "editors", "viewers")
+
+	for _, group := range groups[:2] {
+		if _, err := store.RelationEnsure(ctx, "user", "USER_01", group.ID()); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	// ensuring an existing membership is not a new group
+	if _, err := store.RelationEnsure(ctx, "user", "USER_01", groups[0].ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	_, err = store.RelationEnsure(ctx, "user", "USER_01", groups[2].ID())
+
+	if !errors.Is(err, ErrEntityMaxGroupsExceeded) {
+		t.Fatal("expected ErrEntityMaxGroupsExceeded, found:", err)
+	}
+
+	err = store.RelationBulkCreate(ctx, []RelationInterface{
+		NewRelation().SetEntityType("user").SetEntityID("USER_02").SetGroupID(groups[0].ID()),
+		NewRelation().SetEntityType("user").SetEntityID("USER_02").SetGroupID(groups[1].ID()),
+		NewRelation().SetEntityType("user").SetEntityID("USER_02").SetGroupID(groups[2].ID()),
+	})
+
+	if !errors.Is(err, ErrEntityMaxGroupsExceeded) {
+		t.Fatal("expected ErrEntityMaxGroupsExceeded for bulk create, found:", err)
+	}
+
+	// moving a relation to another group keeps the number of groups
+	relation, err := store.RelationFindByEntityAndGroup(ctx, "user", "USER_01", groups[1].ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	relation.SetGroupID(groups[2].ID())
+
+	if err := store.RelationUpdate(ctx, relation); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+}
+
+func TestStoreRelationCreate_NestedGroupNotAllowed(t *testing.T) {
+	store, err := initStoreWithOptions(":memory:", func(options *NewStoreOptions) {
+		options.EntityTypes = []EntityTypePolicy{
+			{EntityType: ENTITY_TYPE_GROUP, NestedGroupsAllowed: true},
+			{EntityType: "user", NestedGroupsAllowed: true},
+			{EntityType: "device"},
+		}
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	groups := newPolicyTestGroups(t, store, "sales", "sales-eu", "fleet", "all")
+	sales, salesEU, fleet, all := groups[0], groups[1], groups[2], groups[3]
+
+	if _, err := store.RelationEnsure(ctx, ENTITY_TYPE_GROUP, salesEU.ID(), sales.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := store.RelationEnsure(ctx, "user", "USER_01", salesEU.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// a device cannot be added to the nested group
+	_, err = store.RelationEnsure(ctx, "device", "DEVICE_01", salesEU.ID())
+
+	if !errors.Is(err, ErrEntityNestedGroupNotAllowed) {
+		t.Fatal("expected ErrEntityNestedGroupNotAllowed, found:", err)
+	}
+
+	if _, err := store.RelationEnsure(ctx, "device", "DEVICE_01", fleet.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// nor can the group of a device be nested
+	_, err = store.RelationEnsure(ctx, ENTITY_TYPE_GROUP, fleet.ID(), all.ID())
+
+	var policyErr *EntityPolicyError
+
+	if !errors.As(err, &policyErr) || policyErr.Err != ErrEntityNestedGroupNotAllowed || policyErr.EntityType != "device" {
+		t.Fatal("expected ErrEntityNestedGroupNotAllowed for device, found:", err)
+	}
+}
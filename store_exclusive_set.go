@@ -0,0 +1,215 @@
+package groupstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/samber/lo"
+)
+
+// ErrEntityExclusiveSetViolated is the violation of an exclusive set,
+// wrapped in an EntityPolicyError
+var ErrEntityExclusiveSetViolated = errors.New("entity belongs to another group of the exclusive set")
+
+// SwitchGroup moves the entity to the group, within the exclusive set of
+// the group, and returns the relation to the group
+//
+// Business logic:
+//   - the group must belong to the exclusive set
+//   - the relations of the entity to the other groups of the set are
+//     soft deleted, and the relation to the group is activated, in a
+//     single serializable transaction
+//   - the pending, invited and rejected relations to the group are
+//     activated, as by RelationActivate
+//   - an entity banned from the group cannot switch to it, ErrRelationBanned,
+//     and keeps its current group
+//   - switching to the group the entity already belongs to is a no-op
+func (store *store) SwitchGroup(ctx context.Context, entityType string, entityID string, exclusiveSet string, toGroupID string) (RelationInterface, error) {
+	if entityType == "" {
+		return nil, errors.New("at switch group > entity type is empty")
+	}
+
+	if entityID == "" {
+		return nil, errors.New("at switch group > entity ID is empty")
+	}
+
+	if exclusiveSet == "" {
+		return nil, errors.New("at switch group > exclusive set is empty")
+	}
+
+	if toGroupID == "" {
+		return nil, errors.New("at switch group > group ID is empty")
+	}
+
+	var relation RelationInterface
+
+	txOptions := &sql.TxOptions{Isolation: sql.LevelSerializable}
+
+	err := store.withTransactionOptions(ctx, txOptions, func(txCtx context.Context) error {
+		group, err := store.GroupFindByID(txCtx, toGroupID)
+
+		if err != nil {
+			return err
+		}
+
+		if group == nil {
+			return errors.New("at switch group > group not found: " + toGroupID)
+		}
+
+		if group.ExclusiveSet() != exclusiveSet {
+			return errors.New("at switch group > group " + toGroupID + " does not belong to the exclusive set " + exclusiveSet)
+		}
+
+		setGroups, err := store.GroupList(txCtx, NewGroupQuery().
+			SetExclusiveSet(exclusiveSet).
+			SetColumns([]string{COLUMN_ID}))
+
+		if err != nil {
+			return err
+		}
+
+		setGroupIDs := lo.SliceToMap(setGroups, func(group GroupInterface) (string, bool) {
+			return group.ID(), true
+		})
+
+		// the target relation is checked before leaving the current group
+		target, err := store.RelationFindByEntityAndGroup(txCtx, entityType, entityID, toGroupID)
+
+		if err != nil {
+			return err
+		}
+
+		if target != nil && target.Status() == RELATION_STATUS_BANNED {
+			return ErrRelationBanned
+		}
+
+		existing, err := store.RelationList(txCtx, NewRelationQuery().
+			SetEntityType(entityType).
+			SetEntityID(entityID))
+
+		if err != nil {
+			return err
+		}
+
+		for _, existingRelation := range existing {
+			if !setGroupIDs[existingRelation.GroupID()] || existingRelation.GroupID() == toGroupID {
+				continue
+			}
+
+			if err := store.RelationSoftDelete(txCtx, existingRelation); err != nil {
+				return err
+			}
+		}
+
+		if target == nil {
+			relation, err = store.RelationEnsure(txCtx, entityType, entityID, toGroupID)
+			return err
+		}
+
+		relation = target
+
+		return store.relationActivate(txCtx, target)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return relation, nil
+}
+
+// == PRIVATE METHODS =========================================================
+
+// groupExclusiveSets returns the exclusive sets of the groups, which
+// belong to one, by group ID
+func (store *store) groupExclusiveSets(ctx context.Context, groupIDs []string) (map[string]string, error) {
+	exclusiveSets := map[string]string{}
+
+	if len(groupIDs) == 0 {
+		return exclusiveSets, nil
+	}
+
+	groups, err := store.GroupList(ctx, NewGroupQuery().
+		SetIDIn(groupIDs).
+		SetColumns([]string{COLUMN_ID, COLUMN_EXCLUSIVE_SET}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		if group.ExclusiveSet() != "" {
+			exclusiveSets[group.ID()] = group.ExclusiveSet()
+		}
+	}
+
+	return exclusiveSets, nil
+}
+
+// relationsExclusiveSetCheck checks the entities of the relations belong
+// to at most one group of each exclusive set
+//
+// Business logic:
+//   - the existing relations of an entity, other than the ones checked,
+//     occupy the exclusive sets of their groups
+//   - a relation to another group of an occupied set is a violation, a
+//     relation to the same group is not (i.e. an existing membership)
+func (store *store) relationsExclusiveSetCheck(ctx context.Context, relations []RelationInterface) error {
+	relationIDs := lo.SliceToMap(relations, func(relation RelationInterface) (string, bool) {
+		return relation.ID(), true
+	})
+
+	entityRelations := lo.GroupBy(relations, func(relation RelationInterface) string {
+		return relation.EntityType() + "\x00" + relation.EntityID()
+	})
+
+	for _, newRelations := range entityRelations {
+		existing, err := store.RelationList(ctx, NewRelationQuery().
+			SetEntityType(newRelations[0].EntityType()).
+			SetEntityID(newRelations[0].EntityID()))
+
+		if err != nil {
+			return err
+		}
+
+		existing = lo.Filter(existing, func(relation RelationInterface, _ int) bool {
+			return !relationIDs[relation.ID()]
+		})
+
+		all := append(existing, newRelations...)
+		groupIDs := lo.Uniq(lo.Map(all, func(relation RelationInterface, _ int) string { return relation.GroupID() }))
+
+		exclusiveSets, err := store.groupExclusiveSets(ctx, groupIDs)
+
+		if err != nil {
+			return err
+		}
+
+		occupied := map[string]string{}
+
+		for _, relation := range existing {
+			if exclusiveSet := exclusiveSets[relation.GroupID()]; exclusiveSet != "" {
+				occupied[exclusiveSet] = relation.GroupID()
+			}
+		}
+
+		for _, relation := range newRelations {
+			exclusiveSet := exclusiveSets[relation.GroupID()]
+
+			if exclusiveSet == "" {
+				continue
+			}
+
+			if groupID, found := occupied[exclusiveSet]; found && groupID != relation.GroupID() {
+				policyErr := relationPolicyError(ErrEntityExclusiveSetViolated, relation, nil)
+				policyErr.ExclusiveSet = exclusiveSet
+				return policyErr
+			}
+
+			occupied[exclusiveSet] = relation.GroupID()
+		}
+	}
+
+	return nil
+}
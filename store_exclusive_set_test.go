@@ -0,0 +1,269 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func newExclusiveSetTestGroups(t *testing.T, store StoreInterface) (free, pro, team GroupInterface) {
+	free = NewGroup().SetHandle("plan-free").SetTitle("Free").SetExclusiveSet("plan")
+	pro = NewGroup().SetHandle("plan-pro").SetTitle("Pro").SetExclusiveSet("plan")
+	team = NewGroup().SetHandle("team").SetTitle("Team")
+
+	for _, group := range []GroupInterface{free, pro, team} {
+		if err := store.GroupCreate(context.Background(), group); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	return free, pro, team
+}
+
+func TestStoreRelationCreate_ExclusiveSet(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	free, pro, team := newExclusiveSetTestGroups(t, store)
+
+	plans, err := store.GroupList(ctx, NewGroupQuery().SetExclusiveSet("plan"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(plans) != 2 {
+		t.Fatal("expected 2 groups in the plan set, found:", len(plans))
+	}
+
+	if _, err := store.RelationEnsure(ctx, "user", "USER_01", free.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// the groups outside of the set are not limited
+	if _, err := store.RelationEnsure(ctx, "user", "USER_01", team.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// ensuring the existing membership is not a violation
+	if _, err := store.RelationEnsure(ctx, "user", "USER_01", free.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err = store.RelationCreate(ctx, NewRelation().
+		SetEntityType("user").
+		SetEntityID("USER_01").
+		SetGroupID(pro.ID()))
+
+	if !errors.Is(err, ErrEntityExclusiveSetViolated) {
+		t.Fatal("expected ErrEntityExclusiveSetViolated, found:", err)
+	}
+
+	var policyErr *EntityPolicyError
+
+	if !errors.As(err, &policyErr) || policyErr.ExclusiveSet != "plan" || policyErr.GroupID != pro.ID() {
+		t.Fatal("expected EntityPolicyError for the plan set, found:", err)
+	}
+
+	err = store.RelationBulkCreate(ctx, []RelationInterface{
+		NewRelation().SetEntityType("user").SetEntityID("USER_02").SetGroupID(free.ID()),
+		NewRelation().SetEntityType("user").SetEntityID("USER_02").SetGroupID(pro.ID()),
+	})
+
+	if !errors.Is(err, ErrEntityExclusiveSetViolated) {
+		t.Fatal("expected ErrEntityExclusiveSetViolated for bulk create, found:", err)
+	}
+
+	// moving the relation within the set keeps a single group
+	relation, err := store.RelationFindByEntityAndGroup(ctx, "user", "USER_01", free.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	relation.SetGroupID(pro.ID())
+
+	if err := store.RelationUpdate(ctx, relation); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+}
+
+func TestStoreSwitchGroup(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	free, pro, team := newExclusiveSetTestGroups(t, store)
+
+	if _, err := store.SwitchGroup(ctx, "user", "USER_01", "plan", team.ID()); err == nil {
+		t.Fatal("expected error for a group outside of the set")
+	}
+
+	// joins the set, when not a member yet
+	if _, err := store.SwitchGroup(ctx, "user", "USER_01", "plan", free.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := store.RelationEnsure(ctx, "user", "USER_01", team.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	relation, err := store.SwitchGroup(ctx, "user", "USER_01", "plan", pro.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if relation == nil || relation.GroupID() != pro.ID() {
+		t.Fatal("expected the relation to the pro plan, found:", relation)
+	}
+
+	// switching to the current group is a no-op
+	if _, err := store.SwitchGroup(ctx, "user", "USER_01", "plan", pro.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for groupID, expected := range map[string]bool{free.ID(): false, pro.ID(): true, team.ID(): true} {
+		isMember, err := store.IsMember(ctx, "user", "USER_01", groupID)
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if isMember != expected {
+			t.Fatal("unexpected membership of group", groupID, "expected:", expected)
+		}
+	}
+
+	// and back
+	if _, err := store.SwitchGroup(ctx, "user", "USER_01", "plan", free.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	count, err := store.RelationCount(ctx, NewRelationQuery().SetEntityType("user").SetEntityID("USER_01"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 2 {
+		t.Fatal("expected 2 relations, found:", count)
+	}
+}
+
+func TestStoreSwitchGroup_Banned(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	free, pro, _ := newExclusiveSetTestGroups(t, store)
+
+	if _, err := store.SwitchGroup(ctx, "user", "USER_01", "plan", free.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	invited, err := store.RelationInvite(ctx, "user", "USER_01", pro.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.RelationBan(ctx, invited.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := store.SwitchGroup(ctx, "user", "USER_01", "plan", pro.ID()); !errors.Is(err, ErrRelationBanned) {
+		t.Fatal("expected ErrRelationBanned, found:", err)
+	}
+
+	// the banned entity keeps its current group
+	isMember, err := store.IsMember(ctx, "user", "USER_01", free.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !isMember {
+		t.Fatal("expected the user to stay on the free plan")
+	}
+}
+
+func TestStoreSwitchGroup_PendingAndInvited(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	free, pro, _ := newExclusiveSetTestGroups(t, store)
+
+	for entityID, join := range map[string]func(context.Context, string, string, string) (RelationInterface, error){
+		"USER_01": store.RelationRequest,
+		"USER_02": store.RelationInvite,
+	} {
+		if _, err := store.SwitchGroup(ctx, "user", entityID, "plan", free.ID()); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		joined, err := join(ctx, "user", entityID, pro.ID())
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		relation, err := store.SwitchGroup(ctx, "user", entityID, "plan", pro.ID())
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if relation.ID() != joined.ID() || !relation.IsActive() {
+			t.Fatal("expected the relation to the pro plan to be activated, found:", relation.Status())
+		}
+
+		isMember, err := store.IsMember(ctx, "user", entityID, free.ID())
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if isMember {
+			t.Fatal("expected the user to leave the free plan")
+		}
+	}
+}
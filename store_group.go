@@ -3,6 +3,7 @@ package groupstore
 import (
 	"context"
 	"errors"
+	"iter"
 	"strconv"
 	"strings"
 
@@ -51,11 +52,40 @@ func (store *store) GroupCount(ctx context.Context, options GroupQueryInterface)
 	return i, nil
 }
 
+// GroupCreate creates the group
+//
+// When the handle generation is enabled, an empty handle is generated from
+// the title, and made unique with a numeric suffix, a given handle, which
+// is taken, is rejected with ErrGroupHandleUnavailable
 func (store *store) GroupCreate(ctx context.Context, group GroupInterface) error {
 	if group == nil {
 		return errors.New("group is nil")
 	}
 
+	if err := store.groupHandleGenerate(ctx, group); err != nil {
+		return err
+	}
+
+	return store.groupCreate(ctx, group)
+}
+
+// groupCreate creates the group with its handle as is, a live group
+// without a position is positioned after the last
+func (store *store) groupCreate(ctx context.Context, group GroupInterface) error {
+	if err := store.groupStatusCheck(group.Status()); err != nil {
+		return err
+	}
+
+	if group.Position() == 0 && !group.IsSoftDeleted() {
+		next, err := store.groupPositionNext(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		group.SetPosition(next)
+	}
+
 	group.SetCreatedAt(carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC))
 	group.SetUpdatedAt(carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC))
 
@@ -77,7 +107,7 @@ func (store *store) GroupCreate(ctx context.Context, group GroupInterface) error
 		return errors.New("groupstore: database is nil")
 	}
 
-	_, err := database.Execute(store.toQuerableContext(ctx), sqlStr, params...)
+	err := store.executeAudited(ctx, groupAuditRecord(AUDIT_ACTION_CREATED, group.ID(), nil), sqlStr, params...)
 
 	if err != nil {
 		return err
@@ -96,6 +126,7 @@ func (store *store) GroupDelete(ctx context.Context, group GroupInterface) error
 	return store.GroupDeleteByID(ctx, group.ID())
 }
 
+// GroupDeleteByID deletes the group, with its handle history
 func (store *store) GroupDeleteByID(ctx context.Context, id string) error {
 	if id == "" {
 		return errors.New("group id is empty")
@@ -113,12 +144,46 @@ func (store *store) GroupDeleteByID(ctx context.Context, id string) error {
 
 	store.logSql("delete", sqlStr, params...)
 
-	_, err := database.Execute(store.toQuerableContext(ctx), sqlStr, params...)
+	if store.groupHandleHistoryTableName == "" {
+		return store.executeAudited(ctx, groupAuditRecord(AUDIT_ACTION_DELETED, id, nil), sqlStr, params...)
+	}
 
-	return err
+	return store.withTransaction(ctx, func(txCtx context.Context) error {
+		if _, err := database.Execute(store.toQuerableContext(txCtx), sqlStr, params...); err != nil {
+			return err
+		}
+
+		if err := store.groupHandleHistoryDelete(txCtx, goqu.Ex{COLUMN_GROUP_ID: id}); err != nil {
+			return err
+		}
+
+		return store.auditRecord(txCtx, groupAuditRecord(AUDIT_ACTION_DELETED, id, nil))
+	})
 }
 
+// GroupFindByHandle finds the group by its handle
+//
+// When the handle history is enabled, and no group has the handle, the
+// group which had it most recently is returned, its current handle being
+// different (i.e. to redirect the old URLs)
 func (store *store) GroupFindByHandle(ctx context.Context, handle string) (group GroupInterface, err error) {
+	group, err = store.groupFindByHandle(ctx, handle)
+
+	if err != nil || group != nil {
+		return group, err
+	}
+
+	groupID, err := store.groupHandleHistoryFind(ctx, handle)
+
+	if err != nil || groupID == "" {
+		return nil, err
+	}
+
+	return store.GroupFindByID(ctx, groupID)
+}
+
+// groupFindByHandle finds the group by its current handle only
+func (store *store) groupFindByHandle(ctx context.Context, handle string) (group GroupInterface, err error) {
 	if handle == "" {
 		return nil, errors.New("group handle is empty")
 	}
@@ -158,6 +223,42 @@ func (store *store) GroupFindByID(ctx context.Context, id string) (group GroupIn
 	return nil, nil
 }
 
+func (store *store) GroupIter(ctx context.Context, query GroupQueryInterface) iter.Seq2[GroupInterface, error] {
+	return func(yield func(GroupInterface, error) bool) {
+		if query == nil {
+			yield(nil, errors.New("at group iter > group query is nil"))
+			return
+		}
+
+		q, columns, err := store.groupSelectQuery(query)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		sqlStr, sqlParams, errSql := q.Prepared(true).Select(columns...).ToSQL()
+
+		if errSql != nil {
+			yield(nil, errSql)
+			return
+		}
+
+		store.logSql("select", sqlStr, sqlParams...)
+
+		for modelMap, err := range store.selectIter(ctx, sqlStr, sqlParams...) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(NewGroupFromExistingData(modelMap), nil) {
+				return
+			}
+		}
+	}
+}
+
 func (store *store) GroupList(ctx context.Context, query GroupQueryInterface) ([]GroupInterface, error) {
 	if query == nil {
 		return []GroupInterface{}, errors.New("at group list > group query is nil")
@@ -213,11 +314,86 @@ func (store *store) GroupSoftDeleteByID(ctx context.Context, id string) error {
 	return store.GroupSoftDelete(ctx, group)
 }
 
+// GroupUpdate updates the changed columns of the group
+//
+// Business logic:
+//   - a changed status is checked against the status transitions, from
+//     the status stored
+//   - a changed handle, taken by another group, including the soft deleted
+//     ones, is rejected with ErrGroupHandleUnavailable, the previous
+//     handles of the other groups may be taken over
+//   - a changed handle is recorded in the handle history, when enabled,
+//     in the same transaction as the update
+//   - the positions of the other groups are closed up, when the group is
+//     soft deleted, a restored group is positioned after the last
 func (store *store) GroupUpdate(ctx context.Context, group GroupInterface) error {
 	if group == nil {
 		return errors.New("at group update > group is nil")
 	}
 
+	statusChanged := lo.HasKey(group.DataChanged(), COLUMN_STATUS)
+	handleChanged := lo.HasKey(group.DataChanged(), COLUMN_HANDLE) && group.Handle() != ""
+	softDeletedChanged := lo.HasKey(group.DataChanged(), COLUMN_SOFT_DELETED_AT)
+
+	if !statusChanged && !handleChanged && !softDeletedChanged {
+		return store.groupUpdate(ctx, group)
+	}
+
+	return store.withTransaction(ctx, func(txCtx context.Context) error {
+		list, err := store.GroupList(txCtx, NewGroupQuery().
+			SetID(group.ID()).
+			SetSoftDeletedIncluded(true).
+			SetColumns([]string{COLUMN_ID, COLUMN_STATUS, COLUMN_HANDLE, COLUMN_POSITION, COLUMN_SOFT_DELETED_AT}).
+			SetLimit(1))
+
+		if err != nil {
+			return err
+		}
+
+		stored := lo.FirstOr(list, nil)
+
+		if statusChanged {
+			from := ""
+
+			if stored != nil {
+				from = stored.Status()
+			}
+
+			if err := store.groupStatusTransitionCheck(from, group.Status()); err != nil {
+				return err
+			}
+		}
+
+		if handleChanged {
+			taken, err := store.groupHandleTaken(txCtx, group.Handle(), group.ID(), false)
+
+			if err != nil {
+				return err
+			}
+
+			if taken {
+				return ErrGroupHandleUnavailable
+			}
+		}
+
+		if handleChanged && stored != nil {
+			if err := store.groupHandleHistoryRecord(txCtx, group.ID(), stored.Handle(), group.Handle()); err != nil {
+				return err
+			}
+		}
+
+		if softDeletedChanged && stored != nil {
+			if err := store.groupPositionMove(txCtx, stored, group); err != nil {
+				return err
+			}
+		}
+
+		return store.groupUpdate(txCtx, group)
+	})
+}
+
+// groupUpdate updates the changed columns of the group, without checks
+func (store *store) groupUpdate(ctx context.Context, group GroupInterface) error {
 	group.SetUpdatedAt(carbon.Now(carbon.UTC).ToDateTimeString())
 
 	dataChanged := group.DataChanged()
@@ -245,13 +421,164 @@ func (store *store) GroupUpdate(ctx context.Context, group GroupInterface) error
 		return errors.New("groupstore: database is nil")
 	}
 
-	_, err := database.Execute(store.toQuerableContext(ctx), sqlStr, params...)
+	record := groupAuditRecord(auditUpdateAction(dataChanged), group.ID(), auditUpdateDetails(dataChanged))
+
+	err := store.executeAudited(ctx, record, sqlStr, params...)
 
 	group.MarkAsNotDirty()
 
 	return err
 }
 
+// GroupUpsertByHandle creates the group, or updates the live group with
+// the same handle, if one exists
+//
+// Business logic:
+//   - the handle is required
+//   - when a group with the handle exists, its row is overwritten with the
+//     data of the given group, keeping its ID and creation date, which are
+//     copied to the given group
+//   - otherwise the group is created
+//   - a group without a position keeps the position of the existing
+//     group, or is positioned after the last, when created
+//   - the write is a native upsert (ON CONFLICT DO UPDATE, ON DUPLICATE KEY
+//     UPDATE on MySQL) on the unique index of the handles of the live
+//     groups, so that concurrent upserts never create duplicates
+//   - the status transition is checked against the group found before the
+//     write, a group created concurrently in between is overwritten
+//     unchecked
+func (store *store) GroupUpsertByHandle(ctx context.Context, group GroupInterface) error {
+	if group == nil {
+		return errors.New("at group upsert by handle > group is nil")
+	}
+
+	if group.Handle() == "" {
+		return errors.New("at group upsert by handle > group handle is empty")
+	}
+
+	return store.withTransaction(ctx, func(txCtx context.Context) error {
+		existing, err := store.groupFindByHandle(txCtx, group.Handle())
+
+		if err != nil {
+			return err
+		}
+
+		if existing != nil {
+			if err := store.groupStatusTransitionCheck(existing.Status(), group.Status()); err != nil {
+				return err
+			}
+		} else if err := store.groupStatusCheck(group.Status()); err != nil {
+			return err
+		}
+
+		if store.dbDriverName == sb.DIALECT_MSSQL {
+			// no native conflict handling, and no unique index
+			return store.groupUpsertByID(txCtx, group, existing)
+		}
+
+		createdID := group.ID()
+
+		now := carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)
+
+		group.SetCreatedAt(now)
+		group.SetUpdatedAt(now)
+
+		if existing != nil {
+			group.SetID(existing.ID())
+			group.SetPosition(lo.Ternary(group.Position() == 0, existing.Position(), group.Position()))
+		} else if group.Position() == 0 {
+			next, err := store.groupPositionNext(txCtx)
+
+			if err != nil {
+				return err
+			}
+
+			group.SetPosition(next)
+		}
+
+		data := group.Data()
+
+		updated := goqu.Record{}
+
+		for column := range lo.OmitByKeys(data, []string{COLUMN_ID, COLUMN_CREATED_AT}) {
+			excluded := lo.Ternary(store.dbDriverName == sb.DIALECT_MYSQL, "VALUES(?)", "excluded.?")
+			updated[column] = goqu.L(excluded, goqu.I(column))
+		}
+
+		sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+			Insert(store.groupTableName).
+			Prepared(true).
+			Rows(data).
+			OnConflict(goqu.DoUpdate(store.sqlLiveConflictTarget(store.groupHandleUniqueIndex()), updated)).
+			ToSQL()
+
+		if errSql != nil {
+			return errSql
+		}
+
+		store.logSql("upsert", sqlStr, params...)
+
+		if _, err := database.Execute(store.toQuerableContext(txCtx), sqlStr, params...); err != nil {
+			return err
+		}
+
+		upserted, err := store.groupFindByHandle(txCtx, group.Handle())
+
+		if err != nil {
+			return err
+		}
+
+		if upserted == nil {
+			return errors.New("at group upsert by handle > group not found after upsert")
+		}
+
+		// the row inserted has the new ID, the row updated its own
+		action := lo.Ternary(upserted.ID() == createdID && existing == nil, AUDIT_ACTION_CREATED, AUDIT_ACTION_UPDATED)
+
+		group.SetID(upserted.ID())
+		group.SetCreatedAt(upserted.CreatedAtCarbon().ToDateTimeString(carbon.UTC))
+		group.MarkAsNotDirty()
+
+		return store.auditRecord(txCtx, groupAuditRecord(action, group.ID(), nil))
+	})
+}
+
+// groupUpsertByID creates the group, or overwrites the existing group,
+// keeping its ID and creation date
+func (store *store) groupUpsertByID(ctx context.Context, group GroupInterface, existing GroupInterface) error {
+	if existing == nil {
+		return store.groupCreate(ctx, group)
+	}
+
+	group.SetID(existing.ID())
+	group.SetPosition(lo.Ternary(group.Position() == 0, existing.Position(), group.Position()))
+	group.SetCreatedAt(existing.CreatedAtCarbon().ToDateTimeString(carbon.UTC))
+	group.SetUpdatedAt(carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC))
+
+	data := lo.OmitByKeys(group.Data(), []string{COLUMN_ID}) // ID is not updateable
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		Update(store.groupTableName).
+		Prepared(true).
+		Set(data).
+		Where(goqu.C(COLUMN_ID).Eq(existing.ID())).
+		ToSQL()
+
+	if errSql != nil {
+		return errSql
+	}
+
+	store.logSql("update", sqlStr, params...)
+
+	if err := store.executeAudited(ctx, groupAuditRecord(AUDIT_ACTION_UPDATED, group.ID(), nil), sqlStr, params...); err != nil {
+		return err
+	}
+
+	group.MarkAsNotDirty()
+
+	return nil
+}
+
 func (store *store) groupSelectQuery(options GroupQueryInterface) (selectDataset *goqu.SelectDataset, columns []any, err error) {
 	if options == nil {
 		return nil, nil, errors.New("group options is nil")
@@ -283,6 +610,22 @@ func (store *store) groupSelectQuery(options GroupQueryInterface) (selectDataset
 		q = q.Where(goqu.C(COLUMN_HANDLE).Eq(options.Handle()))
 	}
 
+	if options.HasExclusiveSet() {
+		q = q.Where(goqu.C(COLUMN_EXCLUSIVE_SET).Eq(options.ExclusiveSet()))
+	}
+
+	if options.HasExternalID() {
+		q = q.Where(goqu.C(COLUMN_EXTERNAL_ID).Eq(options.ExternalID()))
+	}
+
+	if options.HasActivateAtLte() {
+		q = q.Where(goqu.C(COLUMN_ACTIVATE_AT).Lte(options.ActivateAtLte()))
+	}
+
+	if options.HasDeactivateAtLte() {
+		q = q.Where(goqu.C(COLUMN_DEACTIVATE_AT).Lte(options.DeactivateAtLte()))
+	}
+
 	if options.HasTitleLike() {
 		q = q.Where(goqu.C(COLUMN_TITLE).ILike(`%` + options.TitleLike() + `%`))
 	}
@@ -310,11 +653,7 @@ func (store *store) groupSelectQuery(options GroupQueryInterface) (selectDataset
 
 	if options.HasOrderBy() {
 		sort := lo.Ternary(options.HasSortDirection(), options.SortDirection(), sb.DESC)
-		if strings.EqualFold(sort, sb.ASC) {
-			q = q.Order(goqu.I(options.OrderBy()).Asc())
-		} else {
-			q = q.Order(goqu.I(options.OrderBy()).Desc())
-		}
+		q = q.Order(orderExpressions(options.OrderBy(), strings.EqualFold(sort, sb.ASC))...)
 	}
 
 	columns = []any{}
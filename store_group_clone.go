@@ -0,0 +1,404 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gouniverse/sb"
+	"github.com/gouniverse/uid"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// GroupCloneOptions define the options for cloning a group
+type GroupCloneOptions struct {
+	// Title is the title of the clone, defaults to the title of the group
+	Title string
+
+	// Handle is the handle of the clone, defaults to the handle of the
+	// group with the HandleSuffix, or is empty, when the group has none
+	Handle string
+
+	// HandleSuffix is appended to the handles of the cloned groups, the
+	// empty handles excepted, defaults to "-copy"
+	HandleSuffix string
+
+	// ChildrenIncluded clones the nested groups too, recursively
+	ChildrenIncluded bool
+
+	// RelationsIncluded copies the relations of the entities, other than
+	// the nested groups, to the clones
+	RelationsIncluded bool
+}
+
+// GroupTemplate is a reusable structure of groups, created by
+// GroupTemplateInstantiate, with the {{name}} variables substituted
+//
+// Example (YAML):
+//
+//	handle: "{{customer}}"
+//	title: "{{customer_name}}"
+//	metas:
+//	  plan: trial
+//	members:
+//	  user: ["{{owner_id}}"]
+//	children:
+//	  - handle: "{{customer}}-admins"
+//	    title: "{{customer_name}} Administrators"
+//	    members:
+//	      user: ["{{owner_id}}"]
+type GroupTemplate struct {
+	Handle string            `json:"handle" yaml:"handle"`
+	Title  string            `json:"title" yaml:"title"`
+	Status string            `json:"status,omitempty" yaml:"status,omitempty"`
+	Memo   string            `json:"memo,omitempty" yaml:"memo,omitempty"`
+	Metas  map[string]string `json:"metas,omitempty" yaml:"metas,omitempty"`
+
+	// Members are the entity IDs by entity type
+	Members map[string][]string `json:"members,omitempty" yaml:"members,omitempty"`
+
+	// Children are the groups nested in the group
+	Children []GroupTemplate `json:"children,omitempty" yaml:"children,omitempty"`
+}
+
+// groupCloneMetasOmitted are the metas of the group, which are not cloned
+var groupCloneMetasOmitted = []string{
+	GROUP_META_MERGED_AT,
+	GROUP_META_MERGED_INTO,
+	GROUP_META_SPLIT_AT,
+	GROUP_META_SPLIT_FROM,
+}
+
+// groupTemplateVariable matches the variables of the templates
+var groupTemplateVariable = regexp.MustCompile(`{{\s*([A-Za-z0-9_]+)\s*}}`)
+
+// GroupClone clones the group, and returns the clone
+//
+// Business logic:
+//   - the clone has the columns and the metas of the group, with a new ID,
+//     the title and the handle of the options
+//   - the clone is not in the exclusive set, has no external ID and no
+//     schedule, and does not keep the merge and split metas, these being
+//     bound to the original group
+//   - a handle taken by another group, including the soft deleted ones and
+//     the previous handles, is rejected with ErrGroupHandleUnavailable
+//   - the nested groups are cloned, when the children are included, each
+//     once, and nested in the clones of their parents, their handles with
+//     the handle suffix
+//   - the relations of the other entities, with their status and metas,
+//     are copied, when the relations are included, except for the dynamic
+//     groups, their relations being computed
+//   - the clones are created in one transaction
+func (store *store) GroupClone(ctx context.Context, groupID string, options GroupCloneOptions) (GroupInterface, error) {
+	if groupID == "" {
+		return nil, errors.New("at group clone > group ID is empty")
+	}
+
+	if options.HandleSuffix == "" {
+		options.HandleSuffix = "-copy"
+	}
+
+	var clone GroupInterface
+
+	err := store.withTransaction(ctx, func(txCtx context.Context) error {
+		group, err := store.GroupFindByID(txCtx, groupID)
+
+		if err != nil {
+			return err
+		}
+
+		if group == nil {
+			return errors.New("at group clone > group not found: " + groupID)
+		}
+
+		handle := lo.CoalesceOrEmpty(options.Handle, groupCloneHandle(group.Handle(), options.HandleSuffix))
+		title := lo.CoalesceOrEmpty(options.Title, group.Title())
+
+		clone, err = store.groupCloneTree(txCtx, group, handle, title, options, map[string]GroupInterface{}, map[string]bool{})
+
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return clone, nil
+}
+
+// GroupTemplateInstantiate creates the groups of the template, with the
+// variables substituted, and returns the top group
+//
+// Business logic:
+//   - the {{name}} variables are substituted in all the values of the
+//     template (titles, handles, memos, metas and member IDs), an unknown
+//     variable is an error
+//   - the groups are active, unless a status is set
+//   - the children are nested in their parents
+//   - the groups are created in one transaction
+func (store *store) GroupTemplateInstantiate(ctx context.Context, template GroupTemplate, variables map[string]string) (GroupInterface, error) {
+	resolved, err := groupTemplateSubstitute(template, variables)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var group GroupInterface
+
+	err = store.withTransaction(ctx, func(txCtx context.Context) error {
+		group, err = store.groupTemplateCreate(txCtx, resolved)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// ParseGroupTemplate reads a group template written in YAML or JSON (JSON
+// being valid YAML), unknown fields are rejected
+func ParseGroupTemplate(r io.Reader) (GroupTemplate, error) {
+	template := GroupTemplate{}
+
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&template); err != nil && err != io.EOF {
+		return GroupTemplate{}, errors.New("at group template parse > " + err.Error())
+	}
+
+	return template, nil
+}
+
+// == PRIVATE METHODS =========================================================
+
+// groupCloneTree clones the group, and its children when included
+//
+// The cloned groups are kept by the ID of the original, so that a group
+// nested more than once is cloned once, the groups being cloned detect
+// the cycles
+func (store *store) groupCloneTree(ctx context.Context, group GroupInterface, handle string, title string, options GroupCloneOptions, cloned map[string]GroupInterface, cloning map[string]bool) (GroupInterface, error) {
+	if cloning[group.ID()] {
+		return nil, errors.New("at group clone > group is nested in itself: " + group.ID())
+	}
+
+	cloning[group.ID()] = true
+	defer delete(cloning, group.ID())
+
+	if handle != "" {
+		taken, err := store.groupHandleTaken(ctx, handle, "", true)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if taken {
+			return nil, errors.Join(ErrGroupHandleUnavailable, errors.New("at group clone > handle is taken: "+handle))
+		}
+	}
+
+	clone := NewGroupFromExistingData(maps.Clone(group.Data())).
+		SetID(uid.HumanUid()).
+		SetHandle(handle).
+		SetTitle(title).
+		SetPosition(0).
+		SetExclusiveSet("").
+		SetExternalID("").
+		SetActivateAt(sb.MAX_DATETIME).
+		SetDeactivateAt(sb.MAX_DATETIME).
+		SetSoftDeletedAt(sb.MAX_DATETIME)
+
+	metas, err := clone.Metas()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := clone.SetMetas(lo.OmitByKeys(metas, groupCloneMetasOmitted)); err != nil {
+		return nil, err
+	}
+
+	if err := store.GroupCreate(ctx, clone); err != nil {
+		return nil, err
+	}
+
+	cloned[group.ID()] = clone
+
+	relations, err := store.RelationList(ctx, NewRelationQuery().
+		SetGroupID(group.ID()).
+		SetStatusIn(RELATION_STATUSES))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, relation := range relations {
+		entityID := relation.EntityID()
+
+		switch {
+		case relation.EntityType() == ENTITY_TYPE_GROUP && !options.ChildrenIncluded:
+			continue
+		case relation.EntityType() == ENTITY_TYPE_GROUP:
+			child, err := store.groupCloneChild(ctx, entityID, options, cloned, cloning)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if child == nil {
+				continue // the nested group is deleted
+			}
+
+			entityID = child.ID()
+		case !options.RelationsIncluded || group.IsDynamic():
+			continue
+		}
+
+		copied := NewGroupEntityRelationFromExistingData(maps.Clone(relation.Data())).
+			SetID(uid.HumanUid()).
+			SetGroupID(clone.ID()).
+			SetEntityID(entityID)
+
+		if err := store.RelationCreate(ctx, copied); err != nil {
+			return nil, err
+		}
+	}
+
+	return clone, nil
+}
+
+// groupCloneChild returns the clone of the nested group, cloning it when
+// not cloned yet, or nil when the nested group does not exist
+func (store *store) groupCloneChild(ctx context.Context, childID string, options GroupCloneOptions, cloned map[string]GroupInterface, cloning map[string]bool) (GroupInterface, error) {
+	if clone, exists := cloned[childID]; exists {
+		return clone, nil
+	}
+
+	child, err := store.GroupFindByID(ctx, childID)
+
+	if err != nil || child == nil {
+		return nil, err
+	}
+
+	return store.groupCloneTree(ctx, child, groupCloneHandle(child.Handle(), options.HandleSuffix), child.Title(), options, cloned, cloning)
+}
+
+// groupTemplateCreate creates the group of the template, with its members
+// and children
+func (store *store) groupTemplateCreate(ctx context.Context, template GroupTemplate) (GroupInterface, error) {
+	status := lo.CoalesceOrEmpty(template.Status, GROUP_STATUS_ACTIVE)
+
+	group := NewGroup().
+		SetHandle(template.Handle).
+		SetTitle(template.Title).
+		SetStatus(status).
+		SetMemo(template.Memo)
+
+	if err := group.SetMetas(lo.Assign(template.Metas)); err != nil {
+		return nil, err
+	}
+
+	if err := store.GroupCreate(ctx, group); err != nil {
+		return nil, err
+	}
+
+	for _, entityType := range slices.Sorted(maps.Keys(template.Members)) {
+		for _, entityID := range lo.Uniq(template.Members[entityType]) {
+			if _, err := store.RelationEnsure(ctx, entityType, entityID, group.ID()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, childTemplate := range template.Children {
+		child, err := store.groupTemplateCreate(ctx, childTemplate)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := store.RelationEnsure(ctx, ENTITY_TYPE_GROUP, child.ID(), group.ID()); err != nil {
+			return nil, err
+		}
+	}
+
+	return group, nil
+}
+
+// == HELPERS =================================================================
+
+// groupCloneHandle returns the handle of the clone of a group, the handle
+// of the group with the suffix, or an empty handle, when it has none
+func groupCloneHandle(handle string, suffix string) string {
+	if handle == "" {
+		return ""
+	}
+
+	return handle + suffix
+}
+
+// groupTemplateSubstitute returns the template, with the variables
+// substituted in all its values
+func groupTemplateSubstitute(template GroupTemplate, variables map[string]string) (GroupTemplate, error) {
+	var errUnknown error
+
+	substitute := func(value string) string {
+		return groupTemplateVariable.ReplaceAllStringFunc(value, func(match string) string {
+			name := groupTemplateVariable.FindStringSubmatch(match)[1]
+
+			if replacement, exists := variables[name]; exists {
+				return replacement
+			}
+
+			if errUnknown == nil {
+				errUnknown = errors.New("at group template > variable is not defined: " + name)
+			}
+
+			return match
+		})
+	}
+
+	resolved := GroupTemplate{
+		Handle:  substitute(template.Handle),
+		Title:   substitute(template.Title),
+		Status:  substitute(template.Status),
+		Memo:    substitute(template.Memo),
+		Metas:   map[string]string{},
+		Members: map[string][]string{},
+	}
+
+	for name, value := range template.Metas {
+		resolved.Metas[name] = substitute(value)
+	}
+
+	for entityType, entityIDs := range template.Members {
+		resolved.Members[entityType] = lo.Map(entityIDs, func(entityID string, _ int) string { return substitute(entityID) })
+	}
+
+	for _, child := range template.Children {
+		resolvedChild, err := groupTemplateSubstitute(child, variables)
+
+		if err != nil {
+			return GroupTemplate{}, err
+		}
+
+		resolved.Children = append(resolved.Children, resolvedChild)
+	}
+
+	if errUnknown != nil {
+		return GroupTemplate{}, errUnknown
+	}
+
+	if strings.TrimSpace(resolved.Title) == "" {
+		return GroupTemplate{}, errors.New("at group template > group title is empty: " + resolved.Handle)
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,310 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStoreGroupClone(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	company := NewGroup().SetHandle("acme").SetTitle("Acme").SetCapacity(10)
+	admins := NewGroup().SetHandle("acme-admins").SetTitle("Acme Admins")
+
+	if err := company.SetMetas(map[string]string{"plan": "trial"}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for _, group := range []GroupInterface{company, admins} {
+		if err := store.GroupCreate(ctx, group); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	for _, relation := range []RelationInterface{
+		NewRelation().SetEntityType(ENTITY_TYPE_GROUP).SetEntityID(admins.ID()).SetGroupID(company.ID()),
+		NewRelation().SetEntityType("user").SetEntityID("USER_01").SetGroupID(admins.ID()),
+		NewRelation().SetEntityType("user").SetEntityID("USER_02").SetGroupID(company.ID()).SetStatus(RELATION_STATUS_PENDING),
+	} {
+		if err := store.RelationCreate(ctx, relation); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	// a shallow clone
+	shallow, err := store.GroupClone(ctx, company.ID(), GroupCloneOptions{})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if shallow.ID() == company.ID() || shallow.Handle() != "acme-copy" || shallow.Title() != "Acme" {
+		t.Fatal("expected a new group with the suffixed handle, found:", shallow.Handle())
+	}
+
+	if shallow.Meta("plan") != "trial" || shallow.Capacity() != 10 {
+		t.Fatal("expected the metas and the capacity to be copied")
+	}
+
+	count, err := store.RelationCount(ctx, NewRelationQuery().SetGroupID(shallow.ID()).SetStatusIn(RELATION_STATUSES))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 0 {
+		t.Fatal("expected no relations, found:", count)
+	}
+
+	// a deep clone
+	deep, err := store.GroupClone(ctx, company.ID(), GroupCloneOptions{
+		Handle:            "globex",
+		Title:             "Globex",
+		HandleSuffix:      "-globex",
+		ChildrenIncluded:  true,
+		RelationsIncluded: true,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if deep.Handle() != "globex" || deep.Title() != "Globex" {
+		t.Fatal("expected the handle and the title of the options, found:", deep.Handle())
+	}
+
+	pending, err := store.RelationList(ctx, NewRelationQuery().SetGroupID(deep.ID()).SetStatus(RELATION_STATUS_PENDING))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(pending) != 1 || pending[0].EntityID() != "USER_02" {
+		t.Fatal("expected the pending relation to be copied")
+	}
+
+	child, err := store.GroupFindByHandle(ctx, "acme-admins-globex")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if child == nil {
+		t.Fatal("expected the nested group to be cloned")
+	}
+
+	isMember, err := store.IsMember(ctx, ENTITY_TYPE_GROUP, child.ID(), deep.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !isMember {
+		t.Fatal("expected the cloned child to be nested in the clone")
+	}
+
+	isMember, err = store.IsMember(ctx, "user", "USER_01", child.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !isMember {
+		t.Fatal("expected the relations of the child to be copied")
+	}
+
+	// a taken handle is rejected
+	_, err = store.GroupClone(ctx, company.ID(), GroupCloneOptions{})
+
+	if !errors.Is(err, ErrGroupHandleUnavailable) {
+		t.Fatal("expected ErrGroupHandleUnavailable, found:", err)
+	}
+
+	// a group without a handle is cloned without a handle
+	untitled := NewGroup().SetTitle("Untitled").SetHandle("")
+
+	if err := store.GroupCreate(ctx, untitled); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	clone, err := store.GroupClone(ctx, untitled.ID(), GroupCloneOptions{})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if clone.Handle() != "" {
+		t.Fatal("expected no handle, found:", clone.Handle())
+	}
+}
+
+func TestStoreGroupClone_BoundColumns(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	pro := NewGroup().SetHandle("plan-pro").SetTitle("Pro").
+		SetExclusiveSet("plan").
+		SetExternalID("ext-pro").
+		SetDeactivateAt("2099-01-01 00:00:00")
+
+	if err := pro.SetMetas(map[string]string{"tier": "2", GROUP_META_SPLIT_FROM: "GROUP_00"}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.GroupCreate(ctx, pro); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := store.RelationEnsure(ctx, "user", "USER_01", pro.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// the members of the exclusive set group are copied, the clone being
+	// outside of the set
+	clone, err := store.GroupClone(ctx, pro.ID(), GroupCloneOptions{RelationsIncluded: true})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if clone.ExclusiveSet() != "" || clone.ExternalID() != "" || !strings.HasPrefix(clone.DeactivateAt(), "9999") {
+		t.Fatal("expected no exclusive set, external ID and schedule, found:", clone.ExclusiveSet(), clone.ExternalID(), clone.DeactivateAt())
+	}
+
+	if clone.Meta("tier") != "2" || clone.Meta(GROUP_META_SPLIT_FROM) != "" {
+		t.Fatal("expected the metas without the split meta, found:", clone.Meta("tier"), clone.Meta(GROUP_META_SPLIT_FROM))
+	}
+
+	isMember, err := store.IsMember(ctx, "user", "USER_01", clone.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !isMember {
+		t.Fatal("expected the relations to be copied")
+	}
+
+	// the external ID still finds the original only
+	groups, err := store.GroupList(ctx, NewGroupQuery().SetExternalID("ext-pro"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(groups) != 1 || groups[0].ID() != pro.ID() {
+		t.Fatal("expected the original group only, found:", len(groups))
+	}
+}
+
+func TestStoreGroupTemplateInstantiate(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+
+	template, err := ParseGroupTemplate(strings.NewReader(`
+handle: "{{customer}}"
+title: "{{ customer_name }}"
+metas:
+  plan: trial
+  customer: "{{customer}}"
+members:
+  user: ["{{owner_id}}"]
+children:
+  - handle: "{{customer}}-admins"
+    title: "{{customer_name}} Administrators"
+    members:
+      user: ["{{owner_id}}"]
+`))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	_, err = store.GroupTemplateInstantiate(ctx, template, map[string]string{"customer": "acme"})
+
+	if err == nil || !strings.Contains(err.Error(), "customer_name") {
+		t.Fatal("expected an error for the undefined variable, found:", err)
+	}
+
+	group, err := store.GroupTemplateInstantiate(ctx, template, map[string]string{
+		"customer":      "acme",
+		"customer_name": "Acme",
+		"owner_id":      "USER_01",
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if group.Handle() != "acme" || group.Title() != "Acme" || !group.IsActive() {
+		t.Fatal("expected the variables to be substituted, found:", group.Handle(), group.Title())
+	}
+
+	if group.Meta("customer") != "acme" || group.Meta("plan") != "trial" {
+		t.Fatal("expected the metas of the template")
+	}
+
+	admins, err := store.GroupFindByHandle(ctx, "acme-admins")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if admins == nil || admins.Title() != "Acme Administrators" {
+		t.Fatal("expected the child group to be created")
+	}
+
+	isMember, err := store.IsMember(ctx, ENTITY_TYPE_GROUP, admins.ID(), group.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !isMember {
+		t.Fatal("expected the child group to be nested")
+	}
+
+	isMember, err = store.IsMember(ctx, "user", "USER_01", "acme-admins")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !isMember {
+		t.Fatal("expected the seed member")
+	}
+}
@@ -0,0 +1,281 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/base/database"
+	"github.com/gouniverse/uid"
+	"github.com/gouniverse/utils"
+	"github.com/samber/lo"
+)
+
+// groupHandleMaxLength is the length of the handle column
+const groupHandleMaxLength = 50
+
+// groupHandleSuffixMax is the largest numeric suffix tried, when making a
+// generated handle unique
+const groupHandleSuffixMax = 10000
+
+// ErrGroupHandleUnavailable is returned when the handle of the group is
+// taken by another group, or no unique handle could be generated for it
+var ErrGroupHandleUnavailable = errors.New("groupstore > group handle is not available")
+
+// == PRIVATE METHODS =========================================================
+
+// groupHandleGenerate sets a unique handle to the group, when the handle
+// generation is enabled
+//
+// Business logic:
+//   - an empty handle is generated from the title (i.e. "Book Club" is
+//     "book-club"), or is "group", when the title has no letters, nor digits
+//   - the generated handle is made unique with a numeric suffix (i.e.
+//     "book-club-2"), the handles of the soft deleted groups and the
+//     previous handles of the groups are taken too, so that the old URLs
+//     keep resolving
+//   - a given handle is kept as is, ErrGroupHandleUnavailable is returned,
+//     when it is taken
+//   - the handles taken are read once, a group created concurrently with
+//     the same handle is rejected by the unique index of the handles of
+//     the live groups
+func (store *store) groupHandleGenerate(ctx context.Context, group GroupInterface) error {
+	if !store.groupHandleGenerationEnabled {
+		return nil
+	}
+
+	if group.Handle() != "" {
+		taken, err := store.groupHandleTaken(ctx, group.Handle(), group.ID(), true)
+
+		if err != nil {
+			return err
+		}
+
+		if taken {
+			return ErrGroupHandleUnavailable
+		}
+
+		return nil
+	}
+
+	base := utils.StrSlugify(group.Title(), '-')
+
+	if base == "" {
+		base = "group"
+	}
+
+	// the prefix all the handles tried start with, the base shortened for
+	// the longest suffix
+	prefix := groupHandleWithSuffix(base, groupHandleSuffixMax)
+	prefix = strings.TrimSuffix(prefix, "-"+strconv.Itoa(groupHandleSuffixMax))
+
+	handles, err := store.groupHandlesTakenWithPrefix(ctx, prefix)
+
+	if err != nil {
+		return err
+	}
+
+	taken := lo.Keyify(handles)
+
+	for suffix := 1; suffix <= groupHandleSuffixMax; suffix++ {
+		handle := groupHandleWithSuffix(base, suffix)
+
+		if !lo.HasKey(taken, handle) {
+			group.SetHandle(handle)
+			return nil
+		}
+	}
+
+	return ErrGroupHandleUnavailable
+}
+
+// groupHandleTaken returns true, if another group, including the soft
+// deleted ones, has the handle, or had it, when historyIncluded is true
+func (store *store) groupHandleTaken(ctx context.Context, handle string, groupID string, historyIncluded bool) (bool, error) {
+	list, err := store.GroupList(ctx, NewGroupQuery().
+		SetHandle(handle).
+		SetSoftDeletedIncluded(true).
+		SetColumns([]string{COLUMN_ID}))
+
+	if err != nil {
+		return false, err
+	}
+
+	if lo.ContainsBy(list, func(group GroupInterface) bool { return group.ID() != groupID }) {
+		return true, nil
+	}
+
+	if !historyIncluded {
+		return false, nil
+	}
+
+	previousGroupID, err := store.groupHandleHistoryFind(ctx, handle)
+
+	if err != nil {
+		return false, err
+	}
+
+	return previousGroupID != "" && previousGroupID != groupID, nil
+}
+
+// groupHandlesTakenWithPrefix returns the handles starting with the prefix,
+// of the groups, including the soft deleted ones, and of the handle history
+func (store *store) groupHandlesTakenWithPrefix(ctx context.Context, prefix string) ([]string, error) {
+	tableNames := []string{store.groupTableName}
+
+	if store.groupHandleHistoryTableName != "" {
+		tableNames = append(tableNames, store.groupHandleHistoryTableName)
+	}
+
+	handles := []string{}
+
+	for _, tableName := range tableNames {
+		sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+			From(tableName).
+			Prepared(true).
+			Select(COLUMN_HANDLE).
+			Where(goqu.C(COLUMN_HANDLE).Like(prefix + "%")).
+			ToSQL()
+
+		if errSql != nil {
+			return nil, errSql
+		}
+
+		store.logSql("select", sqlStr, params...)
+
+		rows, err := database.SelectToMapString(store.toQuerableContext(ctx), sqlStr, params...)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			handles = append(handles, row[COLUMN_HANDLE])
+		}
+	}
+
+	return handles, nil
+}
+
+// groupHandleHistoryRecord records the previous handle of the group, when
+// the handle history is enabled
+//
+// The handle the group moves to is removed from its history, as it
+// resolves directly again
+func (store *store) groupHandleHistoryRecord(ctx context.Context, groupID string, previousHandle string, handle string) error {
+	if store.groupHandleHistoryTableName == "" {
+		return nil
+	}
+
+	if err := store.groupHandleHistoryDelete(ctx, goqu.Ex{COLUMN_GROUP_ID: groupID, COLUMN_HANDLE: handle}); err != nil {
+		return err
+	}
+
+	if previousHandle == "" || previousHandle == handle {
+		return nil
+	}
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		Insert(store.groupHandleHistoryTableName).
+		Prepared(true).
+		Rows(map[string]any{
+			COLUMN_ID:         uid.HumanUid(),
+			COLUMN_GROUP_ID:   groupID,
+			COLUMN_HANDLE:     previousHandle,
+			COLUMN_CREATED_AT: carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC),
+		}).
+		ToSQL()
+
+	if errSql != nil {
+		return errSql
+	}
+
+	store.logSql("insert", sqlStr, params...)
+
+	_, err := database.Execute(store.toQuerableContext(ctx), sqlStr, params...)
+
+	return err
+}
+
+// groupHandleHistoryFind returns the ID of the group, which had the handle
+// most recently, or an empty string, when none had it
+func (store *store) groupHandleHistoryFind(ctx context.Context, handle string) (string, error) {
+	if store.groupHandleHistoryTableName == "" {
+		return "", nil
+	}
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		From(store.groupHandleHistoryTableName).
+		Prepared(true).
+		Select(COLUMN_GROUP_ID).
+		Where(goqu.C(COLUMN_HANDLE).Eq(handle)).
+		Order(goqu.C(COLUMN_CREATED_AT).Desc()).
+		Limit(1).
+		ToSQL()
+
+	if errSql != nil {
+		return "", errSql
+	}
+
+	store.logSql("select", sqlStr, params...)
+
+	rows, err := database.SelectToMapString(store.toQuerableContext(ctx), sqlStr, params...)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(rows) < 1 {
+		return "", nil
+	}
+
+	return rows[0][COLUMN_GROUP_ID], nil
+}
+
+// groupHandleHistoryDelete deletes the handle history entries matching
+// the condition, when the handle history is enabled
+func (store *store) groupHandleHistoryDelete(ctx context.Context, condition goqu.Ex) error {
+	if store.groupHandleHistoryTableName == "" {
+		return nil
+	}
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		Delete(store.groupHandleHistoryTableName).
+		Prepared(true).
+		Where(condition).
+		ToSQL()
+
+	if errSql != nil {
+		return errSql
+	}
+
+	store.logSql("delete", sqlStr, params...)
+
+	_, err := database.Execute(store.toQuerableContext(ctx), sqlStr, params...)
+
+	return err
+}
+
+// == HELPERS =================================================================
+
+// groupHandleWithSuffix returns the handle with the numeric suffix, none
+// for the first one, shortened on a character boundary to fit the handle
+// column
+func groupHandleWithSuffix(base string, suffix int) string {
+	ending := ""
+
+	if suffix > 1 {
+		ending = "-" + strconv.Itoa(suffix)
+	}
+
+	runes := []rune(base)
+
+	if len(runes)+len(ending) > groupHandleMaxLength {
+		base = strings.TrimRight(string(runes[:groupHandleMaxLength-len(ending)]), "-")
+	}
+
+	return base + ending
+}
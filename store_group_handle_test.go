@@ -0,0 +1,196 @@
+package groupstore
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestStoreGroupHandleGeneration(t *testing.T) {
+	store, err := initStoreWithOptions(":memory:", func(options *NewStoreOptions) {
+		options.GroupHandleHistoryTableName = "groups_group_handle_history_table"
+		options.GroupHandleGenerationEnabled = true
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+
+	first := NewGroup().SetTitle("Book Club!")
+	second := NewGroup().SetTitle("Book  club")
+	given := NewGroup().SetTitle("Another").SetHandle("readers")
+	untitled := NewGroup().SetTitle("!!!")
+
+	for _, group := range []GroupInterface{first, second, given, untitled} {
+		if err := store.GroupCreate(ctx, group); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	if first.Handle() != "book-club" {
+		t.Fatal("expected book-club, found:", first.Handle())
+	}
+
+	if second.Handle() != "book-club-2" {
+		t.Fatal("expected book-club-2, found:", second.Handle())
+	}
+
+	if given.Handle() != "readers" {
+		t.Fatal("expected readers, found:", given.Handle())
+	}
+
+	// a given handle, which is taken, is rejected, not renamed
+	err = store.GroupCreate(ctx, NewGroup().SetTitle("Another").SetHandle("book-club"))
+
+	if err != ErrGroupHandleUnavailable {
+		t.Fatal("expected ErrGroupHandleUnavailable, found:", err)
+	}
+
+	if err := store.GroupSoftDelete(ctx, given); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err = store.GroupCreate(ctx, NewGroup().SetTitle("Another").SetHandle("readers"))
+
+	if err != ErrGroupHandleUnavailable {
+		t.Fatal("expected ErrGroupHandleUnavailable for the handle of a soft deleted group, found:", err)
+	}
+
+	// a changed handle, which is taken, is rejected
+	err = store.GroupUpdate(ctx, second.SetHandle("book-club"))
+
+	if err != ErrGroupHandleUnavailable {
+		t.Fatal("expected ErrGroupHandleUnavailable, found:", err)
+	}
+
+	if untitled.Handle() != "group" {
+		t.Fatal("expected group, found:", untitled.Handle())
+	}
+
+	long := NewGroup().SetTitle(strings.Repeat("a", 60))
+
+	if err := store.GroupCreate(ctx, long); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(long.Handle()) != groupHandleMaxLength {
+		t.Fatal("expected the handle to be shortened, found:", long.Handle())
+	}
+}
+
+func TestGroupHandleWithSuffix(t *testing.T) {
+	base := strings.Repeat("ж", 60)
+
+	handle := groupHandleWithSuffix(base, 2)
+
+	if !utf8.ValidString(handle) {
+		t.Fatal("expected the handle to be shortened on a character boundary, found:", handle)
+	}
+
+	if handle != strings.Repeat("ж", groupHandleMaxLength-2)+"-2" {
+		t.Fatal("unexpected handle:", handle)
+	}
+}
+
+func TestStoreGroupHandleHistory(t *testing.T) {
+	store, err := initStoreWithOptions(":memory:", func(options *NewStoreOptions) {
+		options.GroupHandleHistoryTableName = "groups_group_handle_history_table"
+		options.GroupHandleGenerationEnabled = true
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	group := NewGroup().SetTitle("Club").SetHandle("club")
+
+	if err := store.GroupCreate(ctx, group); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.GroupUpdate(ctx, group.SetHandle("chess-club")); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	found, err := store.GroupFindByHandle(ctx, "club")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found == nil || found.ID() != group.ID() {
+		t.Fatal("expected the previous handle to resolve the group")
+	}
+
+	if found.Handle() != "chess-club" {
+		t.Fatal("expected the current handle, found:", found.Handle())
+	}
+
+	// the previous handles are not reused by the generated handles
+	other := NewGroup().SetTitle("Club")
+
+	if err := store.GroupCreate(ctx, other); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if other.Handle() != "club-2" {
+		t.Fatal("expected club-2, found:", other.Handle())
+	}
+
+	// the current handles take precedence over the previous ones
+	if err := store.GroupUpdate(ctx, other.SetHandle("club")); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	found, err = store.GroupFindByHandle(ctx, "club")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found == nil || found.ID() != other.ID() {
+		t.Fatal("expected the group with the current handle")
+	}
+
+	// the history is deleted with the group
+	if err := store.GroupDelete(ctx, group); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	found, err = store.GroupFindByHandle(ctx, "chess-club")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found != nil {
+		t.Fatal("expected no group, found:", found.ID())
+	}
+
+	// the handles of the deleted group are available again
+	chess := NewGroup().SetTitle("Chess Club")
+
+	if err := store.GroupCreate(ctx, chess); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if chess.Handle() != "chess-club" {
+		t.Fatal("expected chess-club, found:", chess.Handle())
+	}
+}
@@ -0,0 +1,484 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dromara/carbon/v2"
+	"github.com/samber/lo"
+)
+
+// ErrGroupMergeMetasConflict is returned by GroupMerge, with the
+// GROUP_MERGE_METAS_FAIL strategy, when a source has a meta of the target
+// with another value
+var ErrGroupMergeMetasConflict = errors.New("groupstore > group merge metas conflict")
+
+// GroupMergeOptions define the options for merging groups
+type GroupMergeOptions struct {
+	// MetasConflict is the strategy for the metas of the sources, which the
+	// target has with another value, one of the GROUP_MERGE_METAS_*
+	// constants, defaults to GROUP_MERGE_METAS_KEEP_TARGET
+	MetasConflict string
+}
+
+// GroupMergeResult is the record of the changes made by a merge
+type GroupMergeResult struct {
+	TargetID  string
+	SourceIDs []string
+
+	// Moved are the relations moved from the sources to the target
+	Moved []RelationInterface
+
+	// Removed are the relations soft deleted, as their entity was the
+	// target, or had a relation with a stronger status kept
+	Removed []RelationInterface
+
+	// Repointed are the relations of the sources, as entities of other
+	// groups (i.e. nested in them), which relate the target instead
+	Repointed []RelationInterface
+
+	// MetasConflicts are the names of the metas, which the sources had
+	// with another value than the target
+	MetasConflicts []string
+
+	MergedAt string
+}
+
+// GroupSplitResult is the record of the changes made by a split
+type GroupSplitResult struct {
+	SourceID string
+	GroupID  string
+
+	// Moved are the relations moved from the source to the new group
+	Moved []RelationInterface
+
+	SplitAt string
+}
+
+// GroupMerge merges the source groups into the target group
+//
+// Business logic:
+//   - the relations of the sources, of any status, are moved to the
+//     target, checked as any new relation of the target (i.e. against its
+//     capacity)
+//   - of the relations of an entity, the one with the strongest status is
+//     kept, the others are soft deleted (see relationStatusRank), the
+//     relation of the target, else of the first source, on a tie
+//   - the relations of the sources, as entities of other groups, are
+//     re-pointed to the target, the ones of the target itself soft deleted
+//   - the metas of the sources are added to the target, in the order of
+//     the sources, the conflicts resolved by the options.MetasConflict
+//   - the sources are soft deleted, with the GROUP_META_MERGED_INTO and
+//     GROUP_META_MERGED_AT metas
+//   - the dynamic groups cannot be merged, their relations being computed
+//   - the merge is recorded in the audit trail, for the target and for
+//     each source, when enabled
+//   - the changes are made in one transaction, and returned
+func (store *store) GroupMerge(ctx context.Context, sourceIDs []string, targetID string, options GroupMergeOptions) (GroupMergeResult, error) {
+	if targetID == "" {
+		return GroupMergeResult{}, errors.New("at group merge > target ID is empty")
+	}
+
+	if len(sourceIDs) == 0 {
+		return GroupMergeResult{}, errors.New("at group merge > source IDs are empty")
+	}
+
+	if slices.Contains(sourceIDs, "") || len(lo.Uniq(sourceIDs)) != len(sourceIDs) {
+		return GroupMergeResult{}, errors.New("at group merge > source IDs must be unique and not empty")
+	}
+
+	if slices.Contains(sourceIDs, targetID) {
+		return GroupMergeResult{}, errors.New("at group merge > target cannot be a source")
+	}
+
+	if options.MetasConflict == "" {
+		options.MetasConflict = GROUP_MERGE_METAS_KEEP_TARGET
+	}
+
+	if !slices.Contains([]string{GROUP_MERGE_METAS_KEEP_TARGET, GROUP_MERGE_METAS_KEEP_SOURCE, GROUP_MERGE_METAS_FAIL}, options.MetasConflict) {
+		return GroupMergeResult{}, errors.New("at group merge > metas conflict strategy is not valid: " + options.MetasConflict)
+	}
+
+	result := GroupMergeResult{
+		TargetID:  targetID,
+		SourceIDs: sourceIDs,
+		MergedAt:  carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC),
+	}
+
+	err := store.withTransaction(ctx, func(txCtx context.Context) error {
+		target, err := store.groupFindMergeable(txCtx, targetID)
+
+		if err != nil {
+			return err
+		}
+
+		targetMetas, err := target.Metas()
+
+		if err != nil {
+			return err
+		}
+
+		related, err := store.relationKeys(txCtx, targetID)
+
+		if err != nil {
+			return err
+		}
+
+		for _, sourceID := range sourceIDs {
+			source, err := store.groupFindMergeable(txCtx, sourceID)
+
+			if err != nil {
+				return err
+			}
+
+			sourceMetas, err := source.Metas()
+
+			if err != nil {
+				return err
+			}
+
+			for name, value := range sourceMetas {
+				current, exists := targetMetas[name]
+
+				if exists && current != value {
+					result.MetasConflicts = append(result.MetasConflicts, name)
+
+					if options.MetasConflict == GROUP_MERGE_METAS_FAIL {
+						return errors.Join(ErrGroupMergeMetasConflict, errors.New("meta "+name+" of group "+sourceID))
+					}
+
+					if options.MetasConflict == GROUP_MERGE_METAS_KEEP_TARGET {
+						continue
+					}
+				}
+
+				targetMetas[name] = value
+			}
+
+			relations, err := store.RelationList(txCtx, NewRelationQuery().
+				SetGroupID(sourceID).
+				SetStatusIn(RELATION_STATUSES))
+
+			if err != nil {
+				return err
+			}
+
+			for _, relation := range relations {
+				if relation.EntityType() == ENTITY_TYPE_GROUP && relation.EntityID() == targetID {
+					if err := store.RelationSoftDelete(txCtx, relation); err != nil {
+						return err
+					}
+
+					result.Removed = append(result.Removed, relation)
+					continue
+				}
+
+				key := relation.EntityType() + "\x00" + relation.EntityID()
+
+				kept, err := store.relationMerge(txCtx, &result, related[key], relation.SetGroupID(targetID))
+
+				if err != nil {
+					return err
+				}
+
+				if kept {
+					related[key] = relation
+					result.Moved = append(result.Moved, relation)
+				}
+			}
+
+			if err := store.relationsRepoint(txCtx, &result, sourceID, targetID); err != nil {
+				return err
+			}
+
+			if err := source.SetMeta(GROUP_META_MERGED_INTO, targetID); err != nil {
+				return err
+			}
+
+			if err := source.SetMeta(GROUP_META_MERGED_AT, result.MergedAt); err != nil {
+				return err
+			}
+
+			if err := store.GroupSoftDelete(txCtx, source); err != nil {
+				return err
+			}
+
+			if err := store.auditRecord(txCtx, groupAuditRecord(AUDIT_ACTION_MERGED, sourceID, map[string]string{
+				GROUP_META_MERGED_INTO: targetID,
+			})); err != nil {
+				return err
+			}
+		}
+
+		if err := target.SetMetas(targetMetas); err != nil {
+			return err
+		}
+
+		if err := store.GroupUpdate(txCtx, target); err != nil {
+			return err
+		}
+
+		return store.auditRecord(txCtx, groupAuditRecord(AUDIT_ACTION_MERGED, targetID, map[string]string{
+			"sources": strings.Join(sourceIDs, ","),
+			"moved":   strconv.Itoa(len(result.Moved)),
+			"removed": strconv.Itoa(len(result.Removed)),
+		}))
+	})
+
+	if err != nil {
+		return GroupMergeResult{}, err
+	}
+
+	result.MetasConflicts = lo.Uniq(result.MetasConflicts)
+
+	return result, nil
+}
+
+// GroupSplit creates the new group, and moves to it the relations of the
+// group, which match the predicate
+//
+// Business logic:
+//   - the relations, of any status, are moved, checked as any new
+//     relation of the new group (i.e. against its capacity)
+//   - the new group has the GROUP_META_SPLIT_FROM and GROUP_META_SPLIT_AT
+//     metas
+//   - the dynamic groups cannot be split, their relations being computed
+//   - the split is recorded in the audit trail, for the group and for the
+//     new group, when enabled
+//   - the changes are made in one transaction, and returned
+func (store *store) GroupSplit(ctx context.Context, groupID string, newGroup GroupInterface, predicate func(relation RelationInterface) bool) (GroupSplitResult, error) {
+	if groupID == "" {
+		return GroupSplitResult{}, errors.New("at group split > group ID is empty")
+	}
+
+	if newGroup == nil {
+		return GroupSplitResult{}, errors.New("at group split > new group is nil")
+	}
+
+	if predicate == nil {
+		return GroupSplitResult{}, errors.New("at group split > predicate is nil")
+	}
+
+	if newGroup.IsDynamic() {
+		return GroupSplitResult{}, errors.New("at group split > new group cannot be dynamic")
+	}
+
+	result := GroupSplitResult{
+		SourceID: groupID,
+		GroupID:  newGroup.ID(),
+		SplitAt:  carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC),
+	}
+
+	err := store.withTransaction(ctx, func(txCtx context.Context) error {
+		if _, err := store.groupFindMergeable(txCtx, groupID); err != nil {
+			return err
+		}
+
+		if err := newGroup.SetMeta(GROUP_META_SPLIT_FROM, groupID); err != nil {
+			return err
+		}
+
+		if err := newGroup.SetMeta(GROUP_META_SPLIT_AT, result.SplitAt); err != nil {
+			return err
+		}
+
+		if err := store.GroupCreate(txCtx, newGroup); err != nil {
+			return err
+		}
+
+		relations, err := store.RelationList(txCtx, NewRelationQuery().
+			SetGroupID(groupID).
+			SetStatusIn(RELATION_STATUSES))
+
+		if err != nil {
+			return err
+		}
+
+		for _, relation := range lo.Filter(relations, func(relation RelationInterface, _ int) bool { return predicate(relation) }) {
+			if err := store.RelationUpdate(txCtx, relation.SetGroupID(newGroup.ID())); err != nil {
+				return err
+			}
+
+			result.Moved = append(result.Moved, relation)
+		}
+
+		if err := store.auditRecord(txCtx, groupAuditRecord(AUDIT_ACTION_SPLIT, groupID, map[string]string{
+			"group": newGroup.ID(),
+			"moved": strconv.Itoa(len(result.Moved)),
+		})); err != nil {
+			return err
+		}
+
+		return store.auditRecord(txCtx, groupAuditRecord(AUDIT_ACTION_SPLIT, newGroup.ID(), map[string]string{
+			GROUP_META_SPLIT_FROM: groupID,
+		}))
+	})
+
+	if err != nil {
+		return GroupSplitResult{}, err
+	}
+
+	return result, nil
+}
+
+// == PRIVATE METHODS =========================================================
+
+// groupFindMergeable returns the group, which must exist and not be dynamic
+func (store *store) groupFindMergeable(ctx context.Context, groupID string) (GroupInterface, error) {
+	group, err := store.GroupFindByID(ctx, groupID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if group == nil {
+		return nil, errors.New("at group merge > group not found: " + groupID)
+	}
+
+	if group.IsDynamic() {
+		return nil, errors.New("at group merge > dynamic group cannot be merged, nor split: " + groupID)
+	}
+
+	return group, nil
+}
+
+// relationKeys returns the relations of the group, with any status, by
+// entity type and ID
+func (store *store) relationKeys(ctx context.Context, groupID string) (map[string]RelationInterface, error) {
+	relations, err := store.RelationList(ctx, NewRelationQuery().
+		SetGroupID(groupID).
+		SetStatusIn(RELATION_STATUSES))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.SliceToMap(relations, func(relation RelationInterface) (string, RelationInterface) {
+		return relation.EntityType() + "\x00" + relation.EntityID(), relation
+	}), nil
+}
+
+// relationMerge saves the changed relation, unless the existing relation,
+// which it duplicates, has a stronger status, in which case the relation
+// is soft deleted. The existing relation, with a weaker status, is soft
+// deleted instead. Returns true, if the relation is kept
+func (store *store) relationMerge(ctx context.Context, result *GroupMergeResult, existing RelationInterface, relation RelationInterface) (bool, error) {
+	if existing != nil && relationStatusRank(existing) >= relationStatusRank(relation) {
+		// the relation is soft deleted where it is, not where it would be moved
+		relation.MarkAsNotDirty()
+
+		stored, err := store.RelationFindByID(ctx, relation.ID())
+
+		if err != nil {
+			return false, err
+		}
+
+		if stored == nil {
+			return false, errors.New("at group merge > relation not found: " + relation.ID())
+		}
+
+		if err := store.RelationSoftDelete(ctx, stored); err != nil {
+			return false, err
+		}
+
+		result.Removed = append(result.Removed, stored)
+
+		return false, nil
+	}
+
+	if existing != nil {
+		if err := store.RelationSoftDelete(ctx, existing); err != nil {
+			return false, err
+		}
+
+		result.Moved = relationsWithout(result.Moved, existing)
+		result.Repointed = relationsWithout(result.Repointed, existing)
+		result.Removed = append(result.Removed, existing)
+	}
+
+	if err := store.RelationUpdate(ctx, relation); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// relationsRepoint re-points the relations of the source, as the entity
+// of other groups, to the target, the relations of the target itself, as
+// its own entity, being soft deleted
+func (store *store) relationsRepoint(ctx context.Context, result *GroupMergeResult, sourceID string, targetID string) error {
+	relations, err := store.RelationList(ctx, NewRelationQuery().
+		SetEntityType(ENTITY_TYPE_GROUP).
+		SetEntityID(sourceID).
+		SetStatusIn(RELATION_STATUSES))
+
+	if err != nil {
+		return err
+	}
+
+	for _, relation := range relations {
+		if relation.GroupID() == targetID {
+			if err := store.RelationSoftDelete(ctx, relation); err != nil {
+				return err
+			}
+
+			result.Moved = relationsWithout(result.Moved, relation)
+			result.Removed = append(result.Removed, relation)
+			continue
+		}
+
+		existing, err := store.RelationList(ctx, NewRelationQuery().
+			SetEntityType(ENTITY_TYPE_GROUP).
+			SetEntityID(targetID).
+			SetGroupID(relation.GroupID()).
+			SetStatusIn(RELATION_STATUSES).
+			SetLimit(1))
+
+		if err != nil {
+			return err
+		}
+
+		kept, err := store.relationMerge(ctx, result, lo.FirstOr(existing, nil), relation.SetEntityID(targetID))
+
+		if err != nil {
+			return err
+		}
+
+		if kept {
+			result.Repointed = append(result.Repointed, relation)
+		}
+	}
+
+	return nil
+}
+
+// == HELPERS =================================================================
+
+// relationStatusRank returns the precedence of the status of the relation,
+// when the relations of an entity are merged: the ban, the membership, the
+// invitation, the request, then the rejection
+func relationStatusRank(relation RelationInterface) int {
+	switch {
+	case relation.IsBanned():
+		return 4
+	case relation.IsActive():
+		return 3
+	case relation.IsInvited():
+		return 2
+	case relation.IsPending():
+		return 1
+	default:
+		return 0
+	}
+}
+
+// relationsWithout returns the relations, without the one with the ID of
+// the relation
+func relationsWithout(relations []RelationInterface, relation RelationInterface) []RelationInterface {
+	return lo.Reject(relations, func(item RelationInterface, _ int) bool {
+		return item.ID() == relation.ID()
+	})
+}
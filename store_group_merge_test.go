@@ -0,0 +1,348 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStoreGroupMerge(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+
+	sales := NewGroup().SetHandle("sales").SetTitle("Sales")
+	salesEU := NewGroup().SetHandle("sales-eu").SetTitle("Sales EU")
+	salesUK := NewGroup().SetHandle("sales-uk").SetTitle("Sales UK")
+
+	if err := sales.SetMetas(map[string]string{"region": "global"}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := salesEU.SetMetas(map[string]string{"region": "eu", "manager": "USER_01"}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for _, group := range []GroupInterface{sales, salesEU, salesUK} {
+		if err := store.GroupCreate(ctx, group); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	for _, relation := range []RelationInterface{
+		NewRelation().SetEntityType("user").SetEntityID("USER_01").SetGroupID(sales.ID()),
+		NewRelation().SetEntityType("user").SetEntityID("USER_01").SetGroupID(salesEU.ID()),
+		NewRelation().SetEntityType("user").SetEntityID("USER_02").SetGroupID(salesEU.ID()),
+		NewRelation().SetEntityType("user").SetEntityID("USER_02").SetGroupID(salesUK.ID()),
+		NewRelation().SetEntityType("user").SetEntityID("USER_03").SetGroupID(salesUK.ID()).SetStatus(RELATION_STATUS_PENDING),
+	} {
+		if err := store.RelationCreate(ctx, relation); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	_, err = store.GroupMerge(ctx, []string{salesEU.ID()}, sales.ID(), GroupMergeOptions{MetasConflict: GROUP_MERGE_METAS_FAIL})
+
+	if !errors.Is(err, ErrGroupMergeMetasConflict) {
+		t.Fatal("expected ErrGroupMergeMetasConflict, found:", err)
+	}
+
+	result, err := store.GroupMerge(ctx, []string{salesEU.ID(), salesUK.ID()}, sales.ID(), GroupMergeOptions{})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(result.Moved) != 2 {
+		t.Fatal("expected 2 moved relations, found:", len(result.Moved))
+	}
+
+	if len(result.Removed) != 2 {
+		t.Fatal("expected 2 removed relations, found:", len(result.Removed))
+	}
+
+	if len(result.MetasConflicts) != 1 || result.MetasConflicts[0] != "region" {
+		t.Fatal("expected the region conflict, found:", result.MetasConflicts)
+	}
+
+	members, err := store.RelationList(ctx, NewRelationQuery().
+		SetGroupID(sales.ID()).
+		SetStatusIn(RELATION_STATUSES))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(members) != 3 {
+		t.Fatal("expected 3 relations, found:", len(members))
+	}
+
+	merged, err := store.GroupFindByID(ctx, sales.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if merged.Meta("region") != "global" || merged.Meta("manager") != "USER_01" {
+		t.Fatal("expected the metas of the target kept, and of the source added")
+	}
+
+	source, err := store.GroupFindByID(ctx, salesEU.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if source != nil {
+		t.Fatal("expected the source to be soft deleted")
+	}
+
+	sources, err := store.GroupList(ctx, NewGroupQuery().
+		SetID(salesUK.ID()).
+		SetSoftDeletedIncluded(true))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(sources) != 1 || sources[0].Meta(GROUP_META_MERGED_INTO) != sales.ID() {
+		t.Fatal("expected the source to record the merge")
+	}
+}
+
+func TestStoreGroupMergeCapacity(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	target := NewGroup().SetHandle("target").SetTitle("Target").SetCapacity(1)
+	source := NewGroup().SetHandle("source").SetTitle("Source")
+
+	for _, group := range []GroupInterface{target, source} {
+		if err := store.GroupCreate(ctx, group); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	for _, entityID := range []string{"USER_01", "USER_02"} {
+		if _, err := store.RelationEnsure(ctx, "user", entityID, source.ID()); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	_, err = store.GroupMerge(ctx, []string{source.ID()}, target.ID(), GroupMergeOptions{})
+
+	if !errors.Is(err, ErrGroupCapacityExceeded) {
+		t.Fatal("expected ErrGroupCapacityExceeded, found:", err)
+	}
+
+	// the merge is rolled back
+	count, err := store.RelationCount(ctx, NewRelationQuery().SetGroupID(source.ID()))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 2 {
+		t.Fatal("expected the relations to stay in the source, found:", count)
+	}
+}
+
+func TestStoreGroupMergeStatusesAndNesting(t *testing.T) {
+	store, err := initStoreWithOptions(":memory:", func(options *NewStoreOptions) {
+		options.AuditTableName = "groups_audit_table"
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	target := NewGroup().SetHandle("target").SetTitle("Target")
+	source := NewGroup().SetHandle("source").SetTitle("Source")
+	parent := NewGroup().SetHandle("parent").SetTitle("Parent")
+
+	for _, group := range []GroupInterface{target, source, parent} {
+		if err := store.GroupCreate(ctx, group); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	for _, relation := range []RelationInterface{
+		NewRelation().SetEntityType("user").SetEntityID("USER_01").SetGroupID(target.ID()).SetStatus(RELATION_STATUS_PENDING),
+		NewRelation().SetEntityType("user").SetEntityID("USER_01").SetGroupID(source.ID()),
+		NewRelation().SetEntityType("user").SetEntityID("USER_02").SetGroupID(target.ID()),
+		NewRelation().SetEntityType("user").SetEntityID("USER_02").SetGroupID(source.ID()).SetStatus(RELATION_STATUS_INVITED),
+		NewRelation().SetEntityType(ENTITY_TYPE_GROUP).SetEntityID(source.ID()).SetGroupID(parent.ID()),
+		NewRelation().SetEntityType(ENTITY_TYPE_GROUP).SetEntityID(source.ID()).SetGroupID(target.ID()),
+	} {
+		if err := store.RelationCreate(ctx, relation); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	result, err := store.GroupMerge(ctx, []string{source.ID()}, target.ID(), GroupMergeOptions{})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(result.Moved) != 1 || len(result.Removed) != 3 || len(result.Repointed) != 1 {
+		t.Fatal("unexpected result:", len(result.Moved), len(result.Removed), len(result.Repointed))
+	}
+
+	// the active membership of the source is kept over the pending request
+	// of the target, the membership of the target over the invitation
+	for entityID, status := range map[string]string{"USER_01": RELATION_STATUS_ACTIVE, "USER_02": RELATION_STATUS_ACTIVE} {
+		relations, err := store.RelationList(ctx, NewRelationQuery().
+			SetEntityType("user").
+			SetEntityID(entityID).
+			SetGroupID(target.ID()).
+			SetStatusIn(RELATION_STATUSES))
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if len(relations) != 1 || relations[0].Status() != status {
+			t.Fatal("expected one relation with the status", status, "of", entityID, "found:", relations)
+		}
+	}
+
+	// the nesting of the source in the parent is moved to the target, the
+	// one in the target removed
+	isMember, err := store.IsMember(ctx, ENTITY_TYPE_GROUP, target.ID(), parent.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !isMember {
+		t.Fatal("expected the target to be nested in the parent")
+	}
+
+	count, err := store.RelationCount(ctx, NewRelationQuery().
+		SetEntityType(ENTITY_TYPE_GROUP).
+		SetEntityID(source.ID()).
+		SetStatusIn(RELATION_STATUSES))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 0 {
+		t.Fatal("expected no relations of the source as entity, found:", count)
+	}
+
+	for _, groupID := range []string{target.ID(), source.ID()} {
+		records, err := store.AuditList(ctx, AuditListOptions{GroupID: groupID, Action: AUDIT_ACTION_MERGED})
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if len(records) != 1 {
+			t.Fatal("expected the merge to be recorded for", groupID, "found:", len(records))
+		}
+	}
+}
+
+func TestStoreGroupSplit(t *testing.T) {
+	store, err := initStoreWithOptions(":memory:", func(options *NewStoreOptions) {
+		options.AuditTableName = "groups_audit_table"
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	sales := NewGroup().SetHandle("sales").SetTitle("Sales")
+
+	if err := store.GroupCreate(ctx, sales); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for _, entityID := range []string{"UK_01", "UK_02", "EU_01"} {
+		if _, err := store.RelationEnsure(ctx, "user", entityID, sales.ID()); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	salesUK := NewGroup().SetHandle("sales-uk").SetTitle("Sales UK")
+
+	result, err := store.GroupSplit(ctx, sales.ID(), salesUK, func(relation RelationInterface) bool {
+		return relation.EntityID()[:2] == "UK"
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(result.Moved) != 2 {
+		t.Fatal("expected 2 moved relations, found:", len(result.Moved))
+	}
+
+	created, err := store.GroupFindByID(ctx, salesUK.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if created == nil || created.Meta(GROUP_META_SPLIT_FROM) != sales.ID() {
+		t.Fatal("expected the new group to record the split")
+	}
+
+	count, err := store.RelationCount(ctx, NewRelationQuery().SetGroupID(sales.ID()))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 1 {
+		t.Fatal("expected 1 relation left, found:", count)
+	}
+
+	for _, groupID := range []string{sales.ID(), salesUK.ID()} {
+		records, err := store.AuditList(ctx, AuditListOptions{GroupID: groupID, Action: AUDIT_ACTION_SPLIT})
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if len(records) != 1 {
+			t.Fatal("expected the split to be recorded for", groupID, "found:", len(records))
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package groupstore
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+// The group status violations
+var (
+	ErrGroupStatusInvalid    = errors.New("groupstore > group status is not valid")
+	ErrGroupStatusTransition = errors.New("groupstore > group status transition is not allowed")
+)
+
+// GroupScheduleSweepResult lists the groups changed by a schedule sweep
+type GroupScheduleSweepResult struct {
+	Activated   []GroupInterface
+	Deactivated []GroupInterface
+
+	// Skipped are the groups, which were due, but their status could not
+	// move to the scheduled one, their schedule is cleared
+	Skipped []GroupInterface
+}
+
+// DefaultGroupStatusTransitions returns the default statuses of the
+// groups, with the statuses each one can move to
+//
+// Example, a custom state machine, where an archived group stays archived:
+//
+//	transitions := groupstore.DefaultGroupStatusTransitions()
+//	transitions[groupstore.GROUP_STATUS_ARCHIVED] = []string{}
+func DefaultGroupStatusTransitions() map[string][]string {
+	return map[string][]string{
+		GROUP_STATUS_ACTIVE:   {GROUP_STATUS_INACTIVE, GROUP_STATUS_ARCHIVED, GROUP_STATUS_DELETED},
+		GROUP_STATUS_INACTIVE: {GROUP_STATUS_ACTIVE, GROUP_STATUS_ARCHIVED, GROUP_STATUS_DELETED},
+		GROUP_STATUS_ARCHIVED: {GROUP_STATUS_ACTIVE, GROUP_STATUS_INACTIVE},
+		GROUP_STATUS_DELETED:  {GROUP_STATUS_ACTIVE, GROUP_STATUS_INACTIVE, GROUP_STATUS_ARCHIVED},
+	}
+}
+
+// GroupScheduleSweep activates and deactivates the groups, which are due
+// according to their ActivateAt and DeactivateAt times
+//
+// Business logic:
+//   - a due activation moves the group to active, if the transition is
+//     allowed
+//   - a due deactivation moves an active group to inactive, the groups
+//     with another status (i.e. archived) are left unchanged
+//   - when both are due, they are applied in the order of their times
+//   - the processed schedules are cleared, so that the sweep can run
+//     periodically (i.e. from a cron job)
+func (store *store) GroupScheduleSweep(ctx context.Context) (GroupScheduleSweepResult, error) {
+	result := GroupScheduleSweepResult{}
+	now := carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)
+
+	activationsDue, err := store.GroupList(ctx, NewGroupQuery().SetActivateAtLte(now))
+
+	if err != nil {
+		return result, err
+	}
+
+	deactivationsDue, err := store.GroupList(ctx, NewGroupQuery().SetDeactivateAtLte(now))
+
+	if err != nil {
+		return result, err
+	}
+
+	isActivationDue := lo.SliceToMap(activationsDue, func(group GroupInterface) (string, bool) {
+		return group.ID(), true
+	})
+
+	isDeactivationDue := lo.SliceToMap(deactivationsDue, func(group GroupInterface) (string, bool) {
+		return group.ID(), true
+	})
+
+	due := lo.UniqBy(append(activationsDue, deactivationsDue...), func(group GroupInterface) string {
+		return group.ID()
+	})
+
+	for _, group := range due {
+		original := group.Status()
+		skipped := false
+
+		type scheduled struct {
+			at     int64
+			status string
+		}
+
+		events := []scheduled{}
+
+		if isActivationDue[group.ID()] {
+			events = append(events, scheduled{at: group.ActivateAtCarbon().Timestamp(), status: GROUP_STATUS_ACTIVE})
+			group.SetActivateAt(sb.MAX_DATETIME)
+		}
+
+		if isDeactivationDue[group.ID()] {
+			events = append(events, scheduled{at: group.DeactivateAtCarbon().Timestamp(), status: GROUP_STATUS_INACTIVE})
+			group.SetDeactivateAt(sb.MAX_DATETIME)
+		}
+
+		slices.SortStableFunc(events, func(a, b scheduled) int {
+			return cmp.Compare(a.at, b.at)
+		})
+
+		for _, event := range events {
+			if event.status == GROUP_STATUS_INACTIVE && !group.IsActive() {
+				continue // only the active groups are deactivated
+			}
+
+			if store.groupStatusTransitionCheck(group.Status(), event.status) != nil {
+				skipped = true
+				continue
+			}
+
+			group.SetStatus(event.status)
+		}
+
+		if err := store.groupUpdate(ctx, group); err != nil {
+			return result, err
+		}
+
+		switch {
+		case group.Status() != original && group.IsActive():
+			result.Activated = append(result.Activated, group)
+		case group.Status() != original:
+			result.Deactivated = append(result.Deactivated, group)
+		case skipped:
+			result.Skipped = append(result.Skipped, group)
+		}
+	}
+
+	return result, nil
+}
+
+// == PRIVATE METHODS =========================================================
+
+// groupStatusTransitionsValidate validates the group status transitions,
+// each status moved to must be a status of the transitions
+func groupStatusTransitionsValidate(transitions map[string][]string) error {
+	for status, targets := range transitions {
+		if status == "" {
+			return errors.New("group store: GroupStatusTransitions status is required")
+		}
+
+		for _, target := range targets {
+			if !lo.HasKey(transitions, target) {
+				return errors.New("group store: GroupStatusTransitions status " + status + " moves to an unknown status: " + target)
+			}
+		}
+	}
+
+	return nil
+}
+
+// groupStatusCheck checks the status is one of the group statuses
+func (store *store) groupStatusCheck(status string) error {
+	if !lo.HasKey(store.groupStatusTransitions, status) {
+		return ErrGroupStatusInvalid
+	}
+
+	return nil
+}
+
+// groupStatusTransitionCheck checks the group can move from the status to
+// the other
+//
+// Business logic:
+//   - the status must be valid
+//   - keeping the same status is always allowed
+//   - a group without a status, or with an unknown one (i.e. stored before
+//     the transitions were changed), can move to any valid status
+func (store *store) groupStatusTransitionCheck(from string, to string) error {
+	if err := store.groupStatusCheck(to); err != nil {
+		return err
+	}
+
+	if from == to {
+		return nil
+	}
+
+	targets, known := store.groupStatusTransitions[from]
+
+	if known && !slices.Contains(targets, to) {
+		return ErrGroupStatusTransition
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/sb"
+)
+
+func TestStoreGroupStatusTransitions(t *testing.T) {
+	transitions := DefaultGroupStatusTransitions()
+	transitions[GROUP_STATUS_ARCHIVED] = []string{}
+
+	store, err := initStoreWithOptions(":memory:", func(options *NewStoreOptions) {
+		options.GroupStatusTransitions = transitions
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+
+	if err := store.GroupCreate(ctx, NewGroup().SetHandle("unknown").SetTitle("Unknown").SetStatus("unknown")); !errors.Is(err, ErrGroupStatusInvalid) {
+		t.Fatal("expected ErrGroupStatusInvalid, found:", err)
+	}
+
+	group := NewGroup().SetHandle("club").SetTitle("Club")
+
+	if err := store.GroupCreate(ctx, group); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.GroupUpdate(ctx, group.SetStatus(GROUP_STATUS_ARCHIVED)); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	archived, err := store.GroupFindByID(ctx, group.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if archived == nil || !archived.IsArchived() {
+		t.Fatal("expected an archived group")
+	}
+
+	// an archived group stays archived with these transitions
+	if err := store.GroupUpdate(ctx, archived.SetStatus(GROUP_STATUS_ACTIVE)); !errors.Is(err, ErrGroupStatusTransition) {
+		t.Fatal("expected ErrGroupStatusTransition, found:", err)
+	}
+
+	// the other changes are still allowed
+	archived.SetStatus(GROUP_STATUS_ARCHIVED).SetTitle("Old Club")
+
+	if err := store.GroupUpdate(ctx, archived); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+}
+
+func TestStoreGroupStatusTransitionsValidation(t *testing.T) {
+	_, err := initStoreWithOptions(":memory:", func(options *NewStoreOptions) {
+		options.GroupStatusTransitions = map[string][]string{
+			GROUP_STATUS_ACTIVE: {"unknown"},
+		}
+	})
+
+	if err == nil {
+		t.Fatal("expected an error for a transition to an unknown status")
+	}
+}
+
+func TestStoreGroupScheduleSweep(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	past := carbon.Now(carbon.UTC).SubHour().ToDateTimeString(carbon.UTC)
+	future := carbon.Now(carbon.UTC).AddDay().ToDateTimeString(carbon.UTC)
+
+	launch := NewGroup().SetHandle("launch").SetTitle("Launch").
+		SetStatus(GROUP_STATUS_INACTIVE).
+		SetActivateAt(past)
+
+	closing := NewGroup().SetHandle("closing").SetTitle("Closing").
+		SetStatus(GROUP_STATUS_ACTIVE).
+		SetDeactivateAt(past)
+
+	later := NewGroup().SetHandle("later").SetTitle("Later").
+		SetStatus(GROUP_STATUS_INACTIVE).
+		SetActivateAt(future)
+
+	for _, group := range []GroupInterface{launch, closing, later} {
+		if err := store.GroupCreate(ctx, group); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	result, err := store.GroupScheduleSweep(ctx)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(result.Activated) != 1 || result.Activated[0].ID() != launch.ID() {
+		t.Fatal("expected the launch group to be activated, found:", len(result.Activated))
+	}
+
+	if len(result.Deactivated) != 1 || result.Deactivated[0].ID() != closing.ID() {
+		t.Fatal("expected the closing group to be deactivated, found:", len(result.Deactivated))
+	}
+
+	activated, err := store.GroupFindByID(ctx, launch.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !activated.IsActive() {
+		t.Fatal("expected an active group, found:", activated.Status())
+	}
+
+	if !strings.Contains(activated.ActivateAt(), sb.MAX_DATETIME) {
+		t.Fatal("expected the schedule to be cleared, found:", activated.ActivateAt())
+	}
+
+	notDue, err := store.GroupFindByID(ctx, later.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !notDue.IsInactive() {
+		t.Fatal("expected the group, which is not due, to be unchanged, found:", notDue.Status())
+	}
+
+	// the processed schedules are not applied again
+	result, err = store.GroupScheduleSweep(ctx)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(result.Activated)+len(result.Deactivated)+len(result.Skipped) != 0 {
+		t.Fatal("expected nothing to sweep")
+	}
+}
@@ -54,7 +54,7 @@ func TestStoreGroupCount(t *testing.T) {
 
 	err = store.GroupCreate(context.Background(), NewGroup().
 		SetStatus(GROUP_STATUS_ACTIVE).
-		SetHandle("GROUP_HANDLE").
+		SetHandle("GROUP_HANDLE_2").
 		SetTitle("GROUP_TITLE"))
 
 	if err != nil {
@@ -536,3 +536,167 @@ func TestStoreGroupSoftDeleteByID(t *testing.T) {
 		t.Fatal("Group MUST be soft deleted")
 	}
 }
+
+func TestStoreGroupIter(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	for _, handle := range []string{"GROUP_01", "GROUP_02", "GROUP_03"} {
+		group := NewGroup().
+			SetStatus(GROUP_STATUS_ACTIVE).
+			SetHandle(handle).
+			SetTitle(handle)
+
+		if err := store.GroupCreate(context.Background(), group); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	handles := []string{}
+
+	for group, err := range store.GroupIter(context.Background(), NewGroupQuery().SetOrderBy(COLUMN_HANDLE).SetSortDirection(sb.ASC)) {
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		handles = append(handles, group.Handle())
+	}
+
+	if strings.Join(handles, ",") != "GROUP_01,GROUP_02,GROUP_03" {
+		t.Fatal("unexpected handles:", handles)
+	}
+
+	count := 0
+
+	for _, err := range store.GroupIter(context.Background(), NewGroupQuery()) {
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		count++
+
+		if count == 2 {
+			break
+		}
+	}
+
+	if count != 2 {
+		t.Fatal("unexpected count:", count)
+	}
+
+	for _, err := range store.GroupIter(context.Background(), NewGroupQuery().SetLimit(-1)) {
+		if err == nil {
+			t.Fatal("must return error as limit is invalid")
+		}
+	}
+}
+
+func TestStoreGroupUpsertByHandle(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	group := NewGroup().
+		SetStatus(GROUP_STATUS_ACTIVE).
+		SetHandle("GROUP_HANDLE").
+		SetTitle("GROUP_TITLE")
+
+	err = store.GroupUpsertByHandle(context.Background(), group)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	upserted := NewGroup().
+		SetStatus(GROUP_STATUS_INACTIVE).
+		SetHandle("GROUP_HANDLE").
+		SetTitle("GROUP_TITLE_2")
+
+	err = store.GroupUpsertByHandle(context.Background(), upserted)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if upserted.ID() != group.ID() {
+		t.Fatal("Group ID MUST be the ID of the existing group, got:", upserted.ID())
+	}
+
+	count, err := store.GroupCount(context.Background(), NewGroupQuery())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 1 {
+		t.Fatal("unexpected count:", count)
+	}
+
+	groupFound, err := store.GroupFindByHandle(context.Background(), "GROUP_HANDLE")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if groupFound.Title() != "GROUP_TITLE_2" {
+		t.Fatal("Group title MUST be GROUP_TITLE_2, found:", groupFound.Title())
+	}
+
+	if groupFound.Status() != GROUP_STATUS_INACTIVE {
+		t.Fatal("Group status MUST be inactive, found:", groupFound.Status())
+	}
+
+	if groupFound.CreatedAtCarbon().ToDateTimeString() != group.CreatedAtCarbon().ToDateTimeString() {
+		t.Fatal("Group created at MUST be kept, found:", groupFound.CreatedAt())
+	}
+
+	// a soft deleted group does not hold its handle
+	if err := store.GroupSoftDelete(context.Background(), groupFound); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	recreated := NewGroup().
+		SetStatus(GROUP_STATUS_ACTIVE).
+		SetHandle("GROUP_HANDLE").
+		SetTitle("GROUP_TITLE_3")
+
+	if err := store.GroupUpsertByHandle(context.Background(), recreated); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if recreated.ID() == group.ID() {
+		t.Fatal("Group MUST be a new one, as the existing one is soft deleted")
+	}
+
+	// the handles of the live groups are unique
+	err = store.GroupCreate(context.Background(), NewGroup().
+		SetHandle("GROUP_HANDLE").
+		SetTitle("GROUP_TITLE_4"))
+
+	if err == nil {
+		t.Fatal("must return error as the handle is taken")
+	}
+
+	err = store.GroupUpsertByHandle(context.Background(), NewGroup())
+
+	if err == nil {
+		t.Fatal("must return error as handle is empty")
+	}
+}
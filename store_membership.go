@@ -0,0 +1,240 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/base/database"
+	"github.com/samber/lo"
+)
+
+// membershipBatchSize is the maximum number of membership checks
+// sent to the database in a single query (keeps the number of
+// parameters well below the limits of the supported databases)
+const membershipBatchSize = 500
+
+// MembershipCheck identifies an entity and a group, the group can be
+// given either by its ID or by its handle
+type MembershipCheck struct {
+	EntityType      string
+	EntityID        string
+	GroupIDOrHandle string
+}
+
+// IsMember checks if the entity is a member of the group,
+// the group can be given either by its ID or by its handle
+//
+// With an entity attribute resolver, the membership of a dynamic group is
+// evaluated against its rule, which is selected in the same query as the
+// relation
+func (store *store) IsMember(ctx context.Context, entityType string, entityID string, groupIDOrHandle string) (bool, error) {
+	if entityType == "" {
+		return false, errors.New("is member > entityType is empty")
+	}
+
+	if entityID == "" {
+		return false, errors.New("is member > entityID is empty")
+	}
+
+	if groupIDOrHandle == "" {
+		return false, errors.New("is member > groupIDOrHandle is empty")
+	}
+
+	now := carbon.Now(carbon.UTC).ToDateTimeString()
+
+	groupIDsByHandle := goqu.Dialect(store.dbDriverName).
+		From(store.groupTableName).
+		Select(goqu.C(COLUMN_ID)).
+		Where(goqu.C(COLUMN_HANDLE).Eq(groupIDOrHandle)).
+		Where(goqu.C(COLUMN_SOFT_DELETED_AT).Gt(now))
+
+	relationQuery := goqu.Dialect(store.dbDriverName).
+		From(store.groupEntityRelationTableName).
+		Select(goqu.C(COLUMN_ID)).
+		Where(goqu.C(COLUMN_ENTITY_TYPE).Eq(entityType)).
+		Where(goqu.C(COLUMN_ENTITY_ID).Eq(entityID)).
+		Where(goqu.Or(
+			goqu.C(COLUMN_GROUP_ID).Eq(groupIDOrHandle),
+			goqu.C(COLUMN_GROUP_ID).In(groupIDsByHandle),
+		)).
+		Where(goqu.C(COLUMN_SOFT_DELETED_AT).Gt(now)).
+		Where(relationStatusExpression(COLUMN_STATUS, []string{RELATION_STATUS_ACTIVE})).
+		Limit(1)
+
+	query := relationQuery
+
+	if store.entityAttributeResolver != nil {
+		// the rule of the group is selected with the relation, in one
+		// query, a dynamic group being checked against its rule instead
+		ruleQuery := goqu.Dialect(store.dbDriverName).
+			From(store.groupTableName).
+			Select(goqu.C(COLUMN_RULE)).
+			Where(goqu.Or(
+				goqu.C(COLUMN_ID).Eq(groupIDOrHandle),
+				goqu.C(COLUMN_HANDLE).Eq(groupIDOrHandle),
+			)).
+			Where(goqu.C(COLUMN_SOFT_DELETED_AT).Gt(now)).
+			Limit(1)
+
+		query = goqu.Dialect(store.dbDriverName).
+			Select(ruleQuery.As(COLUMN_RULE), relationQuery.As(COLUMN_ID))
+	}
+
+	sqlStr, params, errSql := query.Prepared(true).ToSQL()
+
+	if errSql != nil {
+		return false, errSql
+	}
+
+	store.logSql("select", sqlStr, params...)
+
+	if store.db == nil {
+		return false, errors.New("groupstore: database is nil")
+	}
+
+	mapped, err := database.SelectToMapString(store.toQuerableContext(ctx), sqlStr, params...)
+
+	if err != nil {
+		return false, err
+	}
+
+	if len(mapped) == 0 {
+		return false, nil
+	}
+
+	rule, err := NewGroupFromExistingData(mapped[0]).Rule()
+
+	if err != nil {
+		return false, err
+	}
+
+	if rule != nil && rule.EntityType == entityType {
+		members, err := store.dynamicMembers(ctx, *rule, []string{entityID})
+
+		if err != nil {
+			return false, err
+		}
+
+		return members[entityID], nil
+	}
+
+	return mapped[0][COLUMN_ID] != "", nil
+}
+
+// BatchIsMember checks the memberships of many entity-group pairs at once
+//
+// Business logic:
+//   - the result has the same length and order as the checks
+//   - the groups can be given either by ID or by handle
+//   - the checks are sent in one query (in chunks for very large batches)
+//   - with an entity attribute resolver, the checks of the dynamic groups
+//     are evaluated against their rules, one resolver call per entity type
+func (store *store) BatchIsMember(ctx context.Context, checks []MembershipCheck) ([]bool, error) {
+	results := make([]bool, len(checks))
+
+	for _, check := range checks {
+		if check.EntityType == "" || check.EntityID == "" || check.GroupIDOrHandle == "" {
+			return []bool{}, errors.New("batch is member > entityType, entityID and groupIDOrHandle are required")
+		}
+	}
+
+	staticChecks, dynamicFound, err := store.dynamicMembershipChecks(ctx, checks)
+
+	if err != nil {
+		return []bool{}, err
+	}
+
+	found := map[string]bool{}
+
+	for _, chunk := range lo.Chunk(staticChecks, membershipBatchSize) {
+		keys, err := store.membershipKeys(ctx, chunk)
+
+		if err != nil {
+			return []bool{}, err
+		}
+
+		for _, key := range keys {
+			found[key] = true
+		}
+	}
+
+	for key, isMember := range dynamicFound {
+		found[key] = isMember
+	}
+
+	for i, check := range checks {
+		results[i] = found[membershipKey(check.EntityType, check.EntityID, check.GroupIDOrHandle)]
+	}
+
+	return results, nil
+}
+
+// membershipKeys returns the membership keys (entity type, entity ID and
+// group ID or handle) of the existing relations matching the checks
+func (store *store) membershipKeys(ctx context.Context, checks []MembershipCheck) ([]string, error) {
+	now := carbon.Now(carbon.UTC).ToDateTimeString()
+
+	conditions := lo.Map(checks, func(check MembershipCheck, _ int) goqu.Expression {
+		return goqu.And(
+			goqu.I("r."+COLUMN_ENTITY_TYPE).Eq(check.EntityType),
+			goqu.I("r."+COLUMN_ENTITY_ID).Eq(check.EntityID),
+			goqu.Or(
+				goqu.I("r."+COLUMN_GROUP_ID).Eq(check.GroupIDOrHandle),
+				goqu.I("g."+COLUMN_HANDLE).Eq(check.GroupIDOrHandle),
+			),
+		)
+	})
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		From(goqu.T(store.groupEntityRelationTableName).As("r")).
+		Prepared(true).
+		LeftJoin(goqu.T(store.groupTableName).As("g"), goqu.On(
+			goqu.I("g."+COLUMN_ID).Eq(goqu.I("r."+COLUMN_GROUP_ID)),
+			goqu.I("g."+COLUMN_SOFT_DELETED_AT).Gt(now),
+		)).
+		Select(
+			goqu.I("r."+COLUMN_ENTITY_TYPE).As(COLUMN_ENTITY_TYPE),
+			goqu.I("r."+COLUMN_ENTITY_ID).As(COLUMN_ENTITY_ID),
+			goqu.I("r."+COLUMN_GROUP_ID).As(COLUMN_GROUP_ID),
+			goqu.I("g."+COLUMN_HANDLE).As(COLUMN_HANDLE),
+		).
+		Where(goqu.I("r." + COLUMN_SOFT_DELETED_AT).Gt(now)).
+		Where(relationStatusExpression("r."+COLUMN_STATUS, []string{RELATION_STATUS_ACTIVE})).
+		Where(goqu.Or(conditions...)).
+		ToSQL()
+
+	if errSql != nil {
+		return nil, errSql
+	}
+
+	store.logSql("select", sqlStr, params...)
+
+	if store.db == nil {
+		return nil, errors.New("groupstore: database is nil")
+	}
+
+	mapped, err := database.SelectToMapString(store.toQuerableContext(ctx), sqlStr, params...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+
+	for _, row := range mapped {
+		keys = append(keys, membershipKey(row[COLUMN_ENTITY_TYPE], row[COLUMN_ENTITY_ID], row[COLUMN_GROUP_ID]))
+
+		if row[COLUMN_HANDLE] != "" {
+			keys = append(keys, membershipKey(row[COLUMN_ENTITY_TYPE], row[COLUMN_ENTITY_ID], row[COLUMN_HANDLE]))
+		}
+	}
+
+	return keys, nil
+}
+
+// membershipKey returns a key uniquely identifying an entity-group pair
+func membershipKey(entityType string, entityID string, groupIDOrHandle string) string {
+	return entityType + "\x00" + entityID + "\x00" + groupIDOrHandle
+}
@@ -0,0 +1,157 @@
+package groupstore
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestStoreIsMember(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	group := NewGroup().
+		SetStatus(GROUP_STATUS_ACTIVE).
+		SetHandle("ADMINS").
+		SetTitle("Administrators")
+
+	err = store.GroupCreate(context.Background(), group)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	relation := NewRelation().
+		SetEntityType("USER").
+		SetEntityID("USER_01").
+		SetGroupID(group.ID())
+
+	err = store.RelationCreate(context.Background(), relation)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	isMember, err := store.IsMember(context.Background(), "USER", "USER_01", group.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !isMember {
+		t.Fatal("USER_01 MUST be member by group ID")
+	}
+
+	isMember, err = store.IsMember(context.Background(), "USER", "USER_01", "ADMINS")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !isMember {
+		t.Fatal("USER_01 MUST be member by group handle")
+	}
+
+	isMember, err = store.IsMember(context.Background(), "USER", "USER_02", "ADMINS")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if isMember {
+		t.Fatal("USER_02 MUST NOT be member")
+	}
+
+	err = store.RelationSoftDelete(context.Background(), relation)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	isMember, err = store.IsMember(context.Background(), "USER", "USER_01", "ADMINS")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if isMember {
+		t.Fatal("USER_01 MUST NOT be member after soft delete")
+	}
+
+	_, err = store.IsMember(context.Background(), "USER", "", "ADMINS")
+
+	if err == nil {
+		t.Fatal("must return error as entity ID is empty")
+	}
+}
+
+func TestStoreBatchIsMember(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	group := NewGroup().
+		SetStatus(GROUP_STATUS_ACTIVE).
+		SetHandle("EDITORS").
+		SetTitle("Editors")
+
+	err = store.GroupCreate(context.Background(), group)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	relations := []RelationInterface{
+		NewRelation().SetEntityType("USER").SetEntityID("USER_01").SetGroupID(group.ID()),
+		NewRelation().SetEntityType("USER").SetEntityID("USER_02").SetGroupID("GROUP_WITHOUT_ROW"),
+	}
+
+	for _, relation := range relations {
+		if err := store.RelationCreate(context.Background(), relation); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	results, err := store.BatchIsMember(context.Background(), []MembershipCheck{
+		{EntityType: "USER", EntityID: "USER_01", GroupIDOrHandle: "EDITORS"},
+		{EntityType: "USER", EntityID: "USER_01", GroupIDOrHandle: group.ID()},
+		{EntityType: "USER", EntityID: "USER_02", GroupIDOrHandle: "EDITORS"},
+		{EntityType: "USER", EntityID: "USER_02", GroupIDOrHandle: "GROUP_WITHOUT_ROW"},
+		{EntityType: "PRODUCT", EntityID: "USER_01", GroupIDOrHandle: "EDITORS"},
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !slices.Equal(results, []bool{true, true, false, true, false}) {
+		t.Fatal("unexpected results:", results)
+	}
+
+	results, err = store.BatchIsMember(context.Background(), []MembershipCheck{})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(results) != 0 {
+		t.Fatal("unexpected results:", results)
+	}
+}
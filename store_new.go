@@ -16,6 +16,21 @@ type NewStoreOptions struct {
 	// GroupEntityRelationTableName is the name of the entity to group relation table
 	GroupEntityRelationTableName string
 
+	// GroupHandleHistoryTableName is the name of the group handle history
+	// table, optional. When set, the previous handles of the groups are
+	// recorded, and still resolved by GroupFindByHandle
+	GroupHandleHistoryTableName string
+
+	// AuditTableName is the name of the audit table, optional. When set,
+	// the changes of the groups and of the relations are recorded in it,
+	// in the same transactions, and listed by AuditList
+	AuditTableName string
+
+	// GroupHandleGenerationEnabled enables the generation of the handles,
+	// GroupCreate generates the empty handles from the titles, and makes
+	// the handles unique with numeric suffixes
+	GroupHandleGenerationEnabled bool
+
 	// DB is the underlying database connection
 	DB *sql.DB
 
@@ -30,6 +45,25 @@ type NewStoreOptions struct {
 
 	// SqlLogger is the sql statement logger when debug mode is enabled, defaults to the default logger
 	SqlLogger *slog.Logger
+
+	// EntityAttributeResolver provides the entity attributes for evaluating
+	// the rules of the dynamic groups, optional. Without it the membership
+	// checks of the dynamic groups use their materialised relations
+	EntityAttributeResolver EntityAttributeResolver
+
+	// EntityTypes registers the entity types allowed in the relations, with
+	// their policies, optional. When empty any entity type is allowed. The
+	// nesting of groups requires ENTITY_TYPE_GROUP to be registered
+	EntityTypes []EntityTypePolicy
+
+	// MembershipQuotas limit the number of groups, of a kind, the entities
+	// of a type can belong to, optional
+	MembershipQuotas []MembershipQuota
+
+	// GroupStatusTransitions are the statuses of the groups, with the
+	// statuses each one can move to, optional. Defaults to
+	// DefaultGroupStatusTransitions()
+	GroupStatusTransitions map[string][]string
 }
 
 // NewStore creates a new block store
@@ -54,14 +88,39 @@ func NewStore(opts NewStoreOptions) (StoreInterface, error) {
 		opts.SqlLogger = slog.Default()
 	}
 
+	entityTypes, err := entityTypePolicies(opts.EntityTypes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := membershipQuotasValidate(opts.MembershipQuotas); err != nil {
+		return nil, err
+	}
+
+	if opts.GroupStatusTransitions == nil {
+		opts.GroupStatusTransitions = DefaultGroupStatusTransitions()
+	}
+
+	if err := groupStatusTransitionsValidate(opts.GroupStatusTransitions); err != nil {
+		return nil, err
+	}
+
 	store := &store{
 		groupTableName:               opts.GroupTableName,
 		groupEntityRelationTableName: opts.GroupEntityRelationTableName,
+		groupHandleHistoryTableName:  opts.GroupHandleHistoryTableName,
+		groupHandleGenerationEnabled: opts.GroupHandleGenerationEnabled,
+		auditTableName:               opts.AuditTableName,
 		automigrateEnabled:           opts.AutomigrateEnabled,
 		db:                           opts.DB,
 		dbDriverName:                 opts.DbDriverName,
 		debugEnabled:                 opts.DebugEnabled,
 		sqlLogger:                    opts.SqlLogger,
+		entityAttributeResolver:      opts.EntityAttributeResolver,
+		entityTypes:                  entityTypes,
+		membershipQuotas:             opts.MembershipQuotas,
+		groupStatusTransitions:       opts.GroupStatusTransitions,
 	}
 
 	if store.automigrateEnabled {
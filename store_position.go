@@ -0,0 +1,449 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/base/database"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+	"github.com/spf13/cast"
+)
+
+// GroupReorder positions the groups of the scope in the given order
+//
+// Business logic:
+//   - the scope is the list of the groups (i.e. the sub-categories of a
+//     category, NewGroupQuery().SetIDIn(categoryIDs)), nil for all the
+//     groups, the soft deleted groups are never in it
+//   - the given groups, which must be in the scope, come first, the other
+//     groups of the scope follow, in their current order
+//   - the groups take the positions held by the groups of the scope, the
+//     unpositioned ones positions after the last, so the positions of all
+//     the groups stay unique and dense
+//   - only the changed positions are updated, in one transaction
+func (store *store) GroupReorder(ctx context.Context, scope GroupQueryInterface, groupIDs []string) error {
+	if err := positionIDsValidate(groupIDs); err != nil {
+		return errors.New("at group reorder > " + err.Error())
+	}
+
+	if scope == nil {
+		scope = NewGroupQuery()
+	}
+
+	return store.withTransaction(ctx, func(txCtx context.Context) error {
+		ordered, err := store.GroupList(txCtx, scope.
+			SetSoftDeletedIncluded(false).
+			SetColumns([]string{COLUMN_ID, COLUMN_POSITION}).
+			SetOrderBy(COLUMN_POSITION).
+			SetSortDirection(sb.ASC))
+
+		if err != nil {
+			return err
+		}
+
+		next, err := store.groupPositionNext(txCtx)
+
+		if err != nil {
+			return err
+		}
+
+		// the unpositioned groups are last, so the positions stay ascending
+		positions := lo.Map(ordered, func(group GroupInterface, _ int) int {
+			if group.Position() > 0 {
+				return group.Position()
+			}
+
+			next++
+
+			return next - 1
+		})
+
+		byID := lo.KeyBy(ordered, func(group GroupInterface) string { return group.ID() })
+
+		reordered := []GroupInterface{}
+
+		for _, groupID := range groupIDs {
+			group, exists := byID[groupID]
+
+			if !exists {
+				return errors.New("at group reorder > group not found in scope: " + groupID)
+			}
+
+			reordered = append(reordered, group)
+		}
+
+		reordered = append(reordered, lo.Filter(ordered, func(group GroupInterface, _ int) bool {
+			return !slices.Contains(groupIDs, group.ID())
+		})...)
+
+		for index, group := range reordered {
+			if group.Position() == positions[index] {
+				continue
+			}
+
+			if err := store.groupUpdate(txCtx, group.SetPosition(positions[index])); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// RelationMoveAfter moves the relation after the other relation of the
+// same group, the positions of the group are renumbered from 1
+func (store *store) RelationMoveAfter(ctx context.Context, relationID string, afterRelationID string) error {
+	return store.relationMove(ctx, relationID, afterRelationID, 1)
+}
+
+// RelationMoveBefore moves the relation before the other relation of the
+// same group, the positions of the group are renumbered from 1
+func (store *store) RelationMoveBefore(ctx context.Context, relationID string, beforeRelationID string) error {
+	return store.relationMove(ctx, relationID, beforeRelationID, 0)
+}
+
+// RelationReorder positions the relations of the group in the given order
+//
+// Business logic:
+//   - the given relations, which must belong to the group, come first
+//   - the other relations of the group follow, in their current order
+//   - the positions of the group are renumbered from 1, so they stay dense
+//   - only the changed positions are updated, in one transaction
+func (store *store) RelationReorder(ctx context.Context, groupID string, relationIDs []string) error {
+	if groupID == "" {
+		return errors.New("at relation reorder > group ID is empty")
+	}
+
+	if err := positionIDsValidate(relationIDs); err != nil {
+		return errors.New("at relation reorder > " + err.Error())
+	}
+
+	return store.withTransaction(ctx, func(txCtx context.Context) error {
+		ordered, err := store.relationsOrdered(txCtx, groupID)
+
+		if err != nil {
+			return err
+		}
+
+		byID := lo.KeyBy(ordered, func(relation RelationInterface) string { return relation.ID() })
+
+		reordered := []RelationInterface{}
+
+		for _, relationID := range relationIDs {
+			relation, exists := byID[relationID]
+
+			if !exists {
+				return errors.New("at relation reorder > relation not found in group: " + relationID)
+			}
+
+			reordered = append(reordered, relation)
+		}
+
+		reordered = append(reordered, lo.Filter(ordered, func(relation RelationInterface, _ int) bool {
+			return !slices.Contains(relationIDs, relation.ID())
+		})...)
+
+		return store.relationsPositionsUpdate(txCtx, reordered)
+	})
+}
+
+// == PRIVATE METHODS =========================================================
+
+// relationMove moves the relation next to the other relation, before it
+// with the offset 0, after it with the offset 1
+func (store *store) relationMove(ctx context.Context, relationID string, otherRelationID string, offset int) error {
+	if relationID == "" || otherRelationID == "" {
+		return errors.New("at relation move > relation ID is empty")
+	}
+
+	if relationID == otherRelationID {
+		return errors.New("at relation move > relation cannot be moved next to itself")
+	}
+
+	return store.withTransaction(ctx, func(txCtx context.Context) error {
+		relation, err := store.RelationFindByID(txCtx, relationID)
+
+		if err != nil {
+			return err
+		}
+
+		other, err := store.RelationFindByID(txCtx, otherRelationID)
+
+		if err != nil {
+			return err
+		}
+
+		if relation == nil || other == nil {
+			return errors.New("at relation move > relation not found")
+		}
+
+		if relation.GroupID() != other.GroupID() {
+			return errors.New("at relation move > relations are not in the same group")
+		}
+
+		ordered, err := store.relationsOrdered(txCtx, relation.GroupID())
+
+		if err != nil {
+			return err
+		}
+
+		ordered = lo.Reject(ordered, func(item RelationInterface, _ int) bool { return item.ID() == relationID })
+
+		index := slices.IndexFunc(ordered, func(item RelationInterface) bool { return item.ID() == otherRelationID })
+
+		return store.relationsPositionsUpdate(txCtx, slices.Insert(ordered, index+offset, relation))
+	})
+}
+
+// relationsOrdered returns the relations of the group, with any status,
+// in the order of their positions
+func (store *store) relationsOrdered(ctx context.Context, groupID string) ([]RelationInterface, error) {
+	return store.RelationList(ctx, NewRelationQuery().
+		SetGroupID(groupID).
+		SetStatusIn(RELATION_STATUSES).
+		SetOrderBy(COLUMN_POSITION).
+		SetSortDirection(sb.ASC))
+}
+
+// relationsPositionsUpdate positions the relations in their order, from
+// 1, updating only the changed positions
+func (store *store) relationsPositionsUpdate(ctx context.Context, relations []RelationInterface) error {
+	for index, relation := range relations {
+		if relation.Position() == index+1 {
+			continue
+		}
+
+		if err := store.RelationUpdate(ctx, relation.SetPosition(index+1)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// groupPositionMove keeps the positions of the live groups dense, when the
+// group is soft deleted, or restored: the gap left by the group is closed,
+// the restored group is positioned after the last, unless its position is
+// changed too
+func (store *store) groupPositionMove(ctx context.Context, stored GroupInterface, group GroupInterface) error {
+	switch {
+	case !stored.IsSoftDeleted() && group.IsSoftDeleted() && stored.Position() > 0:
+		return store.positionsClose(ctx, store.groupTableName, stored.Position())
+	case stored.IsSoftDeleted() && !group.IsSoftDeleted() && !lo.HasKey(group.DataChanged(), COLUMN_POSITION):
+		next, err := store.groupPositionNext(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		group.SetPosition(next)
+	}
+
+	return nil
+}
+
+// groupPositionNext returns the position after the last of the live groups
+func (store *store) groupPositionNext(ctx context.Context) (int, error) {
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		From(store.groupTableName).
+		Prepared(true).
+		Select(goqu.MAX(COLUMN_POSITION).As(COLUMN_POSITION)).
+		Where(goqu.C(COLUMN_SOFT_DELETED_AT).Gt(carbon.Now(carbon.UTC).ToDateTimeString())).
+		ToSQL()
+
+	if errSql != nil {
+		return 0, errSql
+	}
+
+	store.logSql("select", sqlStr, params...)
+
+	rows, err := database.SelectToMapString(store.toQuerableContext(ctx), sqlStr, params...)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if len(rows) < 1 {
+		return 1, nil
+	}
+
+	return cast.ToInt(rows[0][COLUMN_POSITION]) + 1, nil
+}
+
+// relationPositionMove keeps the positions of the groups dense, when the
+// relation leaves its group, moved or soft deleted, or joins a group,
+// moved or restored: the gap left by the relation is closed, the relation
+// joining is positioned after the last, unless its position is changed too
+func (store *store) relationPositionMove(ctx context.Context, relation RelationInterface) error {
+	list, err := store.RelationList(ctx, NewRelationQuery().
+		SetID(relation.ID()).
+		SetStatusIn(RELATION_STATUSES).
+		SetSoftDeletedIncluded(true).
+		SetColumns([]string{COLUMN_ID, COLUMN_GROUP_ID, COLUMN_POSITION, COLUMN_SOFT_DELETED_AT}).
+		SetLimit(1))
+
+	if err != nil {
+		return err
+	}
+
+	stored := lo.FirstOr(list, nil)
+
+	if stored == nil {
+		return nil
+	}
+
+	moved := stored.GroupID() != relation.GroupID()
+	softDeleted := lo.Ternary(lo.HasKey(relation.DataChanged(), COLUMN_SOFT_DELETED_AT), relation.IsSoftDeleted(), stored.IsSoftDeleted())
+	left := !stored.IsSoftDeleted() && (moved || softDeleted)
+	joined := !softDeleted && (moved || stored.IsSoftDeleted())
+
+	if left && stored.Position() > 0 {
+		if err := store.positionsClose(ctx, store.groupEntityRelationTableName, stored.Position(), goqu.C(COLUMN_GROUP_ID).Eq(stored.GroupID())); err != nil {
+			return err
+		}
+	}
+
+	if joined && !lo.HasKey(relation.DataChanged(), COLUMN_POSITION) {
+		relation.SetPosition(0)
+		return store.relationsPositionsAppend(ctx, []RelationInterface{relation})
+	}
+
+	return nil
+}
+
+// relationsPositionsAppend positions the live relations without a
+// position after the last relations of their groups, in their order
+func (store *store) relationsPositionsAppend(ctx context.Context, relations []RelationInterface) error {
+	unpositioned := lo.Filter(relations, func(relation RelationInterface, _ int) bool {
+		return relation.Position() == 0 && !relation.IsSoftDeleted()
+	})
+
+	if len(unpositioned) == 0 {
+		return nil
+	}
+
+	groupIDs := lo.Uniq(lo.Map(unpositioned, func(relation RelationInterface, _ int) string { return relation.GroupID() }))
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		From(store.groupEntityRelationTableName).
+		Prepared(true).
+		Select(goqu.C(COLUMN_GROUP_ID), goqu.MAX(COLUMN_POSITION).As(COLUMN_POSITION)).
+		Where(
+			goqu.C(COLUMN_GROUP_ID).In(groupIDs),
+			goqu.C(COLUMN_SOFT_DELETED_AT).Gt(carbon.Now(carbon.UTC).ToDateTimeString()),
+		).
+		GroupBy(goqu.C(COLUMN_GROUP_ID)).
+		ToSQL()
+
+	if errSql != nil {
+		return errSql
+	}
+
+	store.logSql("select", sqlStr, params...)
+
+	rows, err := database.SelectToMapString(store.toQuerableContext(ctx), sqlStr, params...)
+
+	if err != nil {
+		return err
+	}
+
+	last := lo.SliceToMap(rows, func(row map[string]string) (string, int) {
+		return row[COLUMN_GROUP_ID], cast.ToInt(row[COLUMN_POSITION])
+	})
+
+	for _, relation := range unpositioned {
+		last[relation.GroupID()]++
+		relation.SetPosition(last[relation.GroupID()])
+	}
+
+	return nil
+}
+
+// relationsPositionsRenumber renumbers the positions of the groups from 1
+func (store *store) relationsPositionsRenumber(ctx context.Context, groupIDs []string) error {
+	for _, groupID := range groupIDs {
+		ordered, err := store.relationsOrdered(ctx, groupID)
+
+		if err != nil {
+			return err
+		}
+
+		if err := store.relationsPositionsUpdate(ctx, ordered); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// positionsClose closes the gap left at the position, in the live rows of
+// the table matching the conditions, the positions after it moving back
+// by one
+func (store *store) positionsClose(ctx context.Context, tableName string, position int, conditions ...exp.Expression) error {
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		Update(tableName).
+		Prepared(true).
+		Set(goqu.Record{COLUMN_POSITION: goqu.L("? - 1", goqu.I(COLUMN_POSITION))}).
+		Where(append(conditions,
+			goqu.C(COLUMN_POSITION).Gt(position),
+			goqu.C(COLUMN_SOFT_DELETED_AT).Gt(carbon.Now(carbon.UTC).ToDateTimeString()),
+		)...).
+		ToSQL()
+
+	if errSql != nil {
+		return errSql
+	}
+
+	store.logSql("update", sqlStr, params...)
+
+	_, err := database.Execute(store.toQuerableContext(ctx), sqlStr, params...)
+
+	return err
+}
+
+// == HELPERS =================================================================
+
+// positionIDsValidate checks the IDs to position are set, and unique
+func positionIDsValidate(ids []string) error {
+	if len(ids) == 0 {
+		return errors.New("IDs are empty")
+	}
+
+	if slices.Contains(ids, "") || len(lo.Uniq(ids)) != len(ids) {
+		return errors.New("IDs must be unique and not empty")
+	}
+
+	return nil
+}
+
+// orderExpressions returns the order of the column
+//
+// The order of the positions puts the records without a position (0 or
+// NULL, i.e. created before the positions), after the positioned ones, by
+// their creation, in both directions
+func orderExpressions(column string, ascending bool) []exp.OrderedExpression {
+	orderables := []exp.Orderable{goqu.I(column)}
+
+	if column == COLUMN_POSITION {
+		orderables = []exp.Orderable{goqu.I(COLUMN_POSITION), goqu.I(COLUMN_CREATED_AT), goqu.I(COLUMN_ID)}
+	}
+
+	ordered := lo.Map(orderables, func(orderable exp.Orderable, _ int) exp.OrderedExpression {
+		return lo.Ternary(ascending, orderable.Asc(), orderable.Desc())
+	})
+
+	if column != COLUMN_POSITION {
+		return ordered
+	}
+
+	unpositioned := goqu.Case().
+		When(goqu.Or(goqu.I(COLUMN_POSITION).IsNull(), goqu.I(COLUMN_POSITION).Eq(0)), 1).
+		Else(0)
+
+	return append([]exp.OrderedExpression{unpositioned.Asc()}, ordered...)
+}
@@ -0,0 +1,242 @@
+package groupstore
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+func TestStoreGroupReorder(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	groups := []GroupInterface{
+		NewGroup().SetHandle("books").SetTitle("Books"),
+		NewGroup().SetHandle("music").SetTitle("Music"),
+		NewGroup().SetHandle("films").SetTitle("Films"),
+	}
+
+	for _, group := range groups {
+		if err := store.GroupCreate(ctx, group); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	if err := store.GroupReorder(ctx, nil, []string{groups[2].ID(), groups[0].ID(), groups[1].ID()}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	ordered, err := store.GroupList(ctx, NewGroupQuery().
+		SetOrderBy(COLUMN_POSITION).
+		SetSortDirection(sb.ASC))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	handles := lo.Map(ordered, func(group GroupInterface, _ int) string { return group.Handle() })
+
+	if strings.Join(handles, ",") != "films,books,music" {
+		t.Fatal("expected films,books,music, found:", handles)
+	}
+
+	if ordered[0].Position() != 1 || ordered[2].Position() != 3 {
+		t.Fatal("expected the positions from 1")
+	}
+
+	if err := store.GroupReorder(ctx, nil, []string{groups[0].ID(), "UNKNOWN"}); err == nil {
+		t.Fatal("expected an error for an unknown group")
+	}
+
+	// the new groups come last
+	sports := NewGroup().SetHandle("sports").SetTitle("Sports")
+
+	if err := store.GroupCreate(ctx, sports); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if sports.Position() != 4 {
+		t.Fatal("expected the position 4, found:", sports.Position())
+	}
+
+	// the scope takes the positions it holds, the others are unchanged
+	scope := NewGroupQuery().SetIDIn([]string{groups[2].ID(), sports.ID()})
+
+	if err := store.GroupReorder(ctx, scope, []string{sports.ID()}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.GroupReorder(ctx, scope, []string{groups[0].ID()}); err == nil {
+		t.Fatal("expected an error for a group not in the scope")
+	}
+
+	// the gap of a soft deleted group is closed
+	if err := store.GroupSoftDelete(ctx, groups[0]); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for direction, expected := range map[string]string{sb.ASC: "sports,music,films", sb.DESC: "films,music,sports"} {
+		ordered, err = store.GroupList(ctx, NewGroupQuery().
+			SetOrderBy(COLUMN_POSITION).
+			SetSortDirection(direction))
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		handles = lo.Map(ordered, func(group GroupInterface, _ int) string { return group.Handle() })
+
+		if strings.Join(handles, ",") != expected {
+			t.Fatal("expected", expected, "found:", handles)
+		}
+	}
+
+	positions := lo.SliceToMap(ordered, func(group GroupInterface) (string, int) {
+		return group.Handle(), group.Position()
+	})
+
+	if positions["sports"] != 1 || positions["films"] != 3 {
+		t.Fatal("expected the positions from 1, found:", positions)
+	}
+}
+
+func TestOrderExpressions_UnpositionedLast(t *testing.T) {
+	for _, ascending := range []bool{true, false} {
+		sqlStr, _, err := goqu.From("groups").Order(orderExpressions(COLUMN_POSITION, ascending)...).ToSQL()
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if !strings.Contains(sqlStr, "THEN 1 ELSE 0 END ASC") {
+			t.Fatal("expected the unpositioned records last, found:", sqlStr)
+		}
+	}
+}
+
+func TestStoreRelationReorder(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	playlist := NewGroup().SetHandle("playlist").SetTitle("Playlist")
+
+	if err := store.GroupCreate(ctx, playlist); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	songs := map[string]RelationInterface{}
+
+	for _, songID := range []string{"A", "B", "C", "D"} {
+		relation, err := store.RelationEnsure(ctx, "song", songID, playlist.ID())
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		songs[songID] = relation
+	}
+
+	order := func() string {
+		relations, err := store.RelationList(ctx, NewRelationQuery().
+			SetGroupID(playlist.ID()).
+			SetOrderBy(COLUMN_POSITION).
+			SetSortDirection(sb.ASC))
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		positions := lo.Map(relations, func(relation RelationInterface, index int) string {
+			if relation.Position() != index+1 {
+				t.Fatal("expected dense positions, found:", relation.Position(), "at", index+1)
+			}
+
+			return relation.EntityID()
+		})
+
+		return strings.Join(positions, "")
+	}
+
+	if err := store.RelationReorder(ctx, playlist.ID(), []string{songs["C"].ID(), songs["A"].ID()}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found := order(); found != "CABD" {
+		t.Fatal("expected CABD, found:", found)
+	}
+
+	if err := store.RelationMoveBefore(ctx, songs["D"].ID(), songs["C"].ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found := order(); found != "DCAB" {
+		t.Fatal("expected DCAB, found:", found)
+	}
+
+	if err := store.RelationMoveAfter(ctx, songs["D"].ID(), songs["B"].ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found := order(); found != "CABD" {
+		t.Fatal("expected CABD, found:", found)
+	}
+
+	// a new relation, without a position, comes last
+	if _, err := store.RelationEnsure(ctx, "song", "E", playlist.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.RelationMoveAfter(ctx, songs["A"].ID(), songs["B"].ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found := order(); found != "CBADE" {
+		t.Fatal("expected CBADE, found:", found)
+	}
+
+	// the gap of a soft deleted relation is closed
+	if err := store.RelationSoftDelete(ctx, songs["B"]); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found := order(); found != "CADE" {
+		t.Fatal("expected CADE, found:", found)
+	}
+
+	// the relations created in bulk come last, the existing ones skipped
+	if err := store.RelationBulkCreate(ctx, []RelationInterface{
+		NewRelation().SetEntityType("song").SetEntityID("F").SetGroupID(playlist.ID()),
+		NewRelation().SetEntityType("song").SetEntityID("C").SetGroupID(playlist.ID()),
+		NewRelation().SetEntityType("song").SetEntityID("G").SetGroupID(playlist.ID()),
+	}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found := order(); found != "CADEFG" {
+		t.Fatal("expected CADEFG, found:", found)
+	}
+}
@@ -0,0 +1,411 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest declares the groups, and their static memberships, as kept
+// under version control
+//
+// Example (YAML):
+//
+//	groups:
+//	  - handle: admins
+//	    title: Administrators
+//	    status: active
+//	    metas:
+//	      color: red
+//	    members:
+//	      user: ["123456", "654321"]
+type Manifest struct {
+	Groups []ManifestGroup `json:"groups" yaml:"groups"`
+}
+
+// ManifestGroup declares a group, matched to the stored groups by handle
+//
+// The empty fields are not managed, i.e. an empty title leaves the stored
+// title as it is. The members are managed only when set, then the
+// relations of the entity types listed, which are missing from the
+// manifest, are removed from the group. The relations of the other entity
+// types (i.e. the nested groups of a manifest listing users only) are kept.
+type ManifestGroup struct {
+	Handle string            `json:"handle" yaml:"handle"`
+	Title  string            `json:"title,omitempty" yaml:"title,omitempty"`
+	Status string            `json:"status,omitempty" yaml:"status,omitempty"`
+	Memo   string            `json:"memo,omitempty" yaml:"memo,omitempty"`
+	Metas  map[string]string `json:"metas,omitempty" yaml:"metas,omitempty"`
+
+	// Members are the entity IDs by entity type
+	Members map[string][]string `json:"members,omitempty" yaml:"members,omitempty"`
+}
+
+// ReconcileOptions define the options for reconciling a manifest
+type ReconcileOptions struct {
+	// PruneGroups soft deletes the groups, which are not in the manifest
+	PruneGroups bool
+
+	// DryRun only returns the plan, without any change
+	DryRun bool
+}
+
+// ReconcilePlan holds the changes needed to bring the store in line with
+// a manifest
+type ReconcilePlan struct {
+	Changes []ReconcileChange `json:"changes"`
+}
+
+// ReconcileChange is a single change of a reconcile plan
+type ReconcileChange struct {
+	// Action is one of the RECONCILE_ACTION_* constants
+	Action      string   `json:"action"`
+	GroupHandle string   `json:"group_handle"`
+	GroupID     string   `json:"group_id"`
+	EntityType  string   `json:"entity_type,omitempty"`
+	EntityID    string   `json:"entity_id,omitempty"`
+	Fields      []string `json:"fields,omitempty"` // the updated group fields
+
+	group    GroupInterface
+	relation RelationInterface
+}
+
+// IsEmpty returns true if the store is in line with the manifest
+func (plan ReconcilePlan) IsEmpty() bool {
+	return len(plan.Changes) == 0
+}
+
+// String returns the plan as text, one change per line
+func (plan ReconcilePlan) String() string {
+	lines := lo.Map(plan.Changes, func(change ReconcileChange, _ int) string {
+		switch change.Action {
+		case RECONCILE_ACTION_GROUP_CREATE:
+			return "+ group " + change.GroupHandle
+		case RECONCILE_ACTION_GROUP_UPDATE:
+			return "~ group " + change.GroupHandle + " (" + strings.Join(change.Fields, ", ") + ")"
+		case RECONCILE_ACTION_GROUP_DELETE:
+			return "- group " + change.GroupHandle
+		case RECONCILE_ACTION_RELATION_CREATE:
+			return "+ member " + change.GroupHandle + " " + change.EntityType + " " + change.EntityID
+		default:
+			return "- member " + change.GroupHandle + " " + change.EntityType + " " + change.EntityID
+		}
+	})
+
+	return strings.Join(lines, "\n")
+}
+
+// ParseManifest reads a manifest written in YAML or JSON (JSON being
+// valid YAML), unknown fields are rejected
+func ParseManifest(r io.Reader) (Manifest, error) {
+	manifest := Manifest{}
+
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&manifest); err != nil && err != io.EOF {
+		return Manifest{}, errors.New("at manifest parse > " + err.Error())
+	}
+
+	return manifest, nil
+}
+
+// Reconcile compares the manifest to the store, and returns the plan of
+// the changes, which are applied in a single transaction unless in dry-run
+//
+// Business logic:
+//   - the groups are matched by handle, the missing ones are created
+//     (active, unless another status is declared)
+//   - the declared title, status, memo and metas are updated
+//   - the declared members are added, and the relations of the group,
+//     which are not declared, are soft deleted
+//   - with PruneGroups, the groups not in the manifest are soft deleted
+func (store *store) Reconcile(ctx context.Context, manifest Manifest, options ReconcileOptions) (ReconcilePlan, error) {
+	if err := manifestValidate(manifest, lo.Keys(store.groupStatusTransitions)); err != nil {
+		return ReconcilePlan{}, err
+	}
+
+	plan, err := store.reconcilePlan(ctx, manifest, options)
+
+	if err != nil {
+		return ReconcilePlan{}, err
+	}
+
+	if options.DryRun || plan.IsEmpty() {
+		return plan, nil
+	}
+
+	err = store.withTransaction(ctx, func(txCtx context.Context) error {
+		relationsCreated := []RelationInterface{}
+
+		for _, change := range plan.Changes {
+			var err error
+
+			switch change.Action {
+			case RECONCILE_ACTION_GROUP_CREATE:
+				err = store.GroupCreate(txCtx, change.group)
+			case RECONCILE_ACTION_GROUP_UPDATE:
+				err = store.GroupUpdate(txCtx, change.group)
+			case RECONCILE_ACTION_GROUP_DELETE:
+				err = store.GroupSoftDelete(txCtx, change.group)
+			case RECONCILE_ACTION_RELATION_CREATE:
+				relationsCreated = append(relationsCreated, change.relation)
+			case RECONCILE_ACTION_RELATION_DELETE:
+				err = store.RelationSoftDelete(txCtx, change.relation)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return store.RelationBulkCreate(txCtx, relationsCreated)
+	})
+
+	if err != nil {
+		return ReconcilePlan{}, err
+	}
+
+	return plan, nil
+}
+
+// reconcilePlan builds the plan of the changes, without applying them
+func (store *store) reconcilePlan(ctx context.Context, manifest Manifest, options ReconcileOptions) (ReconcilePlan, error) {
+	plan := ReconcilePlan{}
+
+	for _, declared := range manifest.Groups {
+		group, err := store.groupFindByHandle(ctx, declared.Handle)
+
+		if err != nil {
+			return ReconcilePlan{}, err
+		}
+
+		relations := []RelationInterface{}
+
+		if group == nil {
+			group = NewGroup().
+				SetHandle(declared.Handle).
+				SetTitle(lo.CoalesceOrEmpty(declared.Title, declared.Handle)).
+				SetStatus(lo.CoalesceOrEmpty(declared.Status, GROUP_STATUS_ACTIVE)).
+				SetMemo(declared.Memo)
+
+			if declared.Metas != nil {
+				if err := group.SetMetas(declared.Metas); err != nil {
+					return ReconcilePlan{}, err
+				}
+			}
+
+			plan.Changes = append(plan.Changes, reconcileGroupChange(RECONCILE_ACTION_GROUP_CREATE, group))
+		} else {
+			fields, err := reconcileGroupUpdate(group, declared)
+
+			if err != nil {
+				return ReconcilePlan{}, err
+			}
+
+			if len(fields) > 0 {
+				change := reconcileGroupChange(RECONCILE_ACTION_GROUP_UPDATE, group)
+				change.Fields = fields
+				plan.Changes = append(plan.Changes, change)
+			}
+
+			if declared.Members != nil {
+				relations, err = store.RelationList(ctx, NewRelationQuery().
+					SetGroupID(group.ID()).
+					SetStatusIn(RELATION_STATUSES).
+					SetOrderBy(COLUMN_CREATED_AT).
+					SetSortDirection(sb.ASC))
+
+				if err != nil {
+					return ReconcilePlan{}, err
+				}
+			}
+		}
+
+		if declared.Members == nil {
+			continue
+		}
+
+		plan.Changes = append(plan.Changes, reconcileRelationChanges(group, declared.Members, relations)...)
+	}
+
+	if !options.PruneGroups {
+		return plan, nil
+	}
+
+	declaredHandles := lo.Map(manifest.Groups, func(declared ManifestGroup, _ int) string { return declared.Handle })
+
+	groups, err := store.GroupList(ctx, NewGroupQuery().
+		SetOrderBy(COLUMN_HANDLE).
+		SetSortDirection(sb.ASC))
+
+	if err != nil {
+		return ReconcilePlan{}, err
+	}
+
+	for _, group := range groups {
+		if !lo.Contains(declaredHandles, group.Handle()) {
+			plan.Changes = append(plan.Changes, reconcileGroupChange(RECONCILE_ACTION_GROUP_DELETE, group))
+		}
+	}
+
+	return plan, nil
+}
+
+// reconcileGroupUpdate sets the declared fields, which differ, on the
+// group, and returns their names
+func reconcileGroupUpdate(group GroupInterface, declared ManifestGroup) ([]string, error) {
+	fields := []string{}
+
+	if declared.Title != "" && declared.Title != group.Title() {
+		group.SetTitle(declared.Title)
+		fields = append(fields, COLUMN_TITLE)
+	}
+
+	if declared.Status != "" && declared.Status != group.Status() {
+		group.SetStatus(declared.Status)
+		fields = append(fields, COLUMN_STATUS)
+	}
+
+	if declared.Memo != "" && declared.Memo != group.Memo() {
+		group.SetMemo(declared.Memo)
+		fields = append(fields, COLUMN_MEMO)
+	}
+
+	if declared.Metas != nil {
+		metas, err := group.Metas()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !maps.Equal(metas, declared.Metas) {
+			if err := group.SetMetas(declared.Metas); err != nil {
+				return nil, err
+			}
+
+			fields = append(fields, COLUMN_METAS)
+		}
+	}
+
+	return fields, nil
+}
+
+// reconcileRelationChanges returns the changes adding the declared members
+// missing from the relations, and removing the active relations not
+// declared, of the entity types declared
+//
+// The relations, which are not active (i.e. pending or banned), are left
+// as they are, neither created again nor removed
+func reconcileRelationChanges(group GroupInterface, members map[string][]string, relations []RelationInterface) []ReconcileChange {
+	changes := []ReconcileChange{}
+
+	relationKey := func(entityType, entityID string) string {
+		return entityType + "\x00" + entityID
+	}
+
+	existing := map[string]bool{}
+
+	for _, relation := range relations {
+		existing[relationKey(relation.EntityType(), relation.EntityID())] = true
+	}
+
+	declared := map[string]bool{}
+
+	for _, entityType := range slices.Sorted(maps.Keys(members)) {
+		for _, entityID := range members[entityType] {
+			key := relationKey(entityType, entityID)
+
+			if declared[key] {
+				continue
+			}
+
+			declared[key] = true
+
+			if existing[key] {
+				continue
+			}
+
+			relation := NewRelation().
+				SetEntityType(entityType).
+				SetEntityID(entityID).
+				SetGroupID(group.ID())
+
+			changes = append(changes, reconcileRelationChange(RECONCILE_ACTION_RELATION_CREATE, group, relation))
+		}
+	}
+
+	for _, relation := range relations {
+		if !relation.IsActive() || !lo.HasKey(members, relation.EntityType()) {
+			continue
+		}
+
+		if !declared[relationKey(relation.EntityType(), relation.EntityID())] {
+			changes = append(changes, reconcileRelationChange(RECONCILE_ACTION_RELATION_DELETE, group, relation))
+		}
+	}
+
+	return changes
+}
+
+func reconcileGroupChange(action string, group GroupInterface) ReconcileChange {
+	return ReconcileChange{
+		Action:      action,
+		GroupHandle: group.Handle(),
+		GroupID:     group.ID(),
+		group:       group,
+	}
+}
+
+func reconcileRelationChange(action string, group GroupInterface, relation RelationInterface) ReconcileChange {
+	return ReconcileChange{
+		Action:      action,
+		GroupHandle: group.Handle(),
+		GroupID:     group.ID(),
+		EntityType:  relation.EntityType(),
+		EntityID:    relation.EntityID(),
+		relation:    relation,
+	}
+}
+
+// manifestValidate checks the manifest declares unique handles, known
+// statuses and non-empty members
+func manifestValidate(manifest Manifest, statuses []string) error {
+	handles := map[string]bool{}
+
+	for index, declared := range manifest.Groups {
+		if declared.Handle == "" {
+			return errors.New("at reconcile > group handle is empty, group: " + strconv.Itoa(index+1))
+		}
+
+		if handles[declared.Handle] {
+			return errors.New("at reconcile > group handle declared more than once: " + declared.Handle)
+		}
+
+		handles[declared.Handle] = true
+
+		if declared.Status != "" && !lo.Contains(statuses, declared.Status) {
+			return errors.New("at reconcile > invalid status of group " + declared.Handle + ": " + declared.Status)
+		}
+
+		for entityType, entityIDs := range declared.Members {
+			if entityType == "" {
+				return errors.New("at reconcile > empty entity type in members of group " + declared.Handle)
+			}
+
+			if lo.Contains(entityIDs, "") {
+				return errors.New("at reconcile > empty " + entityType + " ID in members of group " + declared.Handle)
+			}
+		}
+	}
+
+	return nil
+}
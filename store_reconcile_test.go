@@ -0,0 +1,234 @@
+package groupstore
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const testManifest = `
+groups:
+  - handle: admins
+    title: Administrators
+    metas:
+      color: red
+    members:
+      user: ["USER_01", "USER_03"]
+  - handle: billing
+    title: Billing
+    status: inactive
+    members:
+      user: ["USER_02"]
+`
+
+func TestParseManifest(t *testing.T) {
+	manifest, err := ParseManifest(strings.NewReader(testManifest))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(manifest.Groups) != 2 {
+		t.Fatal("groups must be 2, found:", len(manifest.Groups))
+	}
+
+	if manifest.Groups[0].Metas["color"] != "red" || len(manifest.Groups[0].Members["user"]) != 2 {
+		t.Fatal("unexpected group:", manifest.Groups[0])
+	}
+
+	// JSON is valid YAML
+	manifest, err = ParseManifest(strings.NewReader(`{"groups": [{"handle": "admins", "members": {"user": ["USER_01"]}}]}`))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(manifest.Groups) != 1 || manifest.Groups[0].Members["user"][0] != "USER_01" {
+		t.Fatal("unexpected manifest:", manifest)
+	}
+
+	_, err = ParseManifest(strings.NewReader("groups:\n  - handle: admins\n    titel: Administrators\n"))
+
+	if err == nil {
+		t.Fatal("must return error as the field is unknown")
+	}
+}
+
+func TestStoreReconcile(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	admins := NewGroup().SetHandle("admins").SetTitle("Admins").SetStatus(GROUP_STATUS_ACTIVE)
+	legacy := NewGroup().SetHandle("legacy").SetTitle("Legacy").SetStatus(GROUP_STATUS_ACTIVE)
+
+	for _, group := range []GroupInterface{admins, legacy} {
+		if err := store.GroupCreate(context.Background(), group); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	for _, entityID := range []string{"USER_01", "USER_02"} {
+		if _, err := store.RelationEnsure(context.Background(), "user", entityID, admins.ID()); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	// the manifest lists users only, the relations of the other entity
+	// types, and the banned users, are kept
+	kept := []RelationInterface{
+		NewRelation().SetEntityType("service").SetEntityID("SERVICE_01").SetGroupID(admins.ID()),
+		NewRelation().SetEntityType("user").SetEntityID("USER_04").SetGroupID(admins.ID()).SetStatus(RELATION_STATUS_BANNED),
+	}
+
+	for _, relation := range kept {
+		if err := store.RelationCreate(context.Background(), relation); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	manifest, err := ParseManifest(strings.NewReader(testManifest))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	options := ReconcileOptions{PruneGroups: true, DryRun: true}
+
+	plan, err := store.Reconcile(context.Background(), manifest, options)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := strings.Join([]string{
+		"~ group admins (title, metas)",
+		"+ member admins user USER_03",
+		"- member admins user USER_02",
+		"+ group billing",
+		"+ member billing user USER_02",
+		"- group legacy",
+	}, "\n")
+
+	if plan.String() != expected {
+		t.Fatal("unexpected plan:", plan.String())
+	}
+
+	found, err := store.GroupFindByHandle(context.Background(), "billing")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found != nil {
+		t.Fatal("dry run must not create groups")
+	}
+
+	// apply
+	options.DryRun = false
+
+	_, err = store.Reconcile(context.Background(), manifest, options)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	billing, err := store.GroupFindByHandle(context.Background(), "billing")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if billing == nil || billing.Status() != GROUP_STATUS_INACTIVE {
+		t.Fatal("billing group must be created as inactive")
+	}
+
+	found, err = store.GroupFindByID(context.Background(), legacy.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found != nil {
+		t.Fatal("legacy group must be pruned")
+	}
+
+	for _, check := range []struct {
+		entityID string
+		group    string
+		isMember bool
+	}{
+		{"USER_01", "admins", true},
+		{"USER_02", "admins", false},
+		{"USER_03", "admins", true},
+		{"USER_02", "billing", true},
+	} {
+		isMember, err := store.IsMember(context.Background(), "user", check.entityID, check.group)
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if isMember != check.isMember {
+			t.Fatal("unexpected membership of", check.entityID, "in", check.group, ":", isMember)
+		}
+	}
+
+	for _, relation := range kept {
+		found, err := store.RelationFindByID(context.Background(), relation.ID())
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if found == nil || found.IsSoftDeleted() {
+			t.Fatal("relation must be kept:", relation.EntityType(), relation.EntityID())
+		}
+	}
+
+	// the store is now in line with the manifest
+	plan, err = store.Reconcile(context.Background(), manifest, options)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !plan.IsEmpty() {
+		t.Fatal("plan must be empty, found:", plan.String())
+	}
+}
+
+func TestStoreReconcile_InvalidManifest(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	manifests := []Manifest{
+		{Groups: []ManifestGroup{{Title: "No handle"}}},
+		{Groups: []ManifestGroup{{Handle: "admins"}, {Handle: "admins"}}},
+		{Groups: []ManifestGroup{{Handle: "admins", Status: "enabled"}}},
+		{Groups: []ManifestGroup{{Handle: "admins", Members: map[string][]string{"user": {""}}}}},
+	}
+
+	for _, manifest := range manifests {
+		if _, err := store.Reconcile(context.Background(), manifest, ReconcileOptions{}); err == nil {
+			t.Fatal("must return error for invalid manifest:", manifest)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package groupstore
 import (
 	"context"
 	"errors"
+	"iter"
 	"strconv"
 	"strings"
 
@@ -14,6 +15,124 @@ import (
 	"github.com/spf13/cast"
 )
 
+// relationBulkChunkSize is the number of relations inserted by a single
+// statement (keeps the number of parameters below the MSSQL limit of 2100)
+const relationBulkChunkSize = 200
+
+// ErrRelationExists is returned by RelationCreate, when the entity is
+// already related to the group, with any status
+var ErrRelationExists = errors.New("groupstore > relation with the same entityType-entityID-groupID combination already exists")
+
+// RelationBulkCreate creates the relations with multi-row inserts
+//
+// Business logic:
+//   - the relations, which exist already, are skipped
+//   - the relations are checked against the policies, the capacities and
+//     the quotas, all or none are inserted
+//   - the relations are inserted in chunks, in a single transaction
+//   - the relations without a position are positioned after the last of
+//     their groups, in their order
+func (store *store) RelationBulkCreate(ctx context.Context, relations []RelationInterface) error {
+	for _, relation := range relations {
+		if relation == nil {
+			return errors.New("groupstore > RelationBulkCreate. relation is nil")
+		}
+
+		if relation.GroupID() == "" || relation.EntityID() == "" || relation.EntityType() == "" {
+			return errors.New("groupstore > RelationBulkCreate. relation groupID, entityID and entityType are required")
+		}
+	}
+
+	if len(relations) == 0 {
+		return nil
+	}
+
+	if store.dbDriverName == sb.DIALECT_MSSQL {
+		// no native conflict handling, and no unique index
+		return store.withRelationsChecked(ctx, relations, func(checkedCtx context.Context) error {
+			return store.withTransaction(checkedCtx, func(txCtx context.Context) error {
+				for _, relation := range relations {
+					existing, err := store.RelationFindByEntityAndGroup(txCtx, relation.EntityType(), relation.EntityID(), relation.GroupID())
+
+					if err != nil {
+						return err
+					}
+
+					if existing != nil {
+						continue
+					}
+
+					if err := store.relationInsert(txCtx, relation); err != nil {
+						return err
+					}
+				}
+
+				return nil
+			})
+		})
+	}
+
+	now := carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)
+
+	return store.withRelationsChecked(ctx, relations, func(checkedCtx context.Context) error {
+		return store.withTransaction(checkedCtx, func(txCtx context.Context) error {
+			if err := store.relationsPositionsAppend(txCtx, relations); err != nil {
+				return err
+			}
+
+			skipped := false
+
+			for _, chunk := range lo.Chunk(relations, relationBulkChunkSize) {
+				rows := lo.Map(chunk, func(relation RelationInterface, _ int) any {
+					relation.SetCreatedAt(now)
+					relation.SetUpdatedAt(now)
+					return relation.Data()
+				})
+
+				sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+					Insert(store.groupEntityRelationTableName).
+					Prepared(true).
+					Rows(rows...).
+					OnConflict(goqu.DoNothing()).
+					ToSQL()
+
+				if errSql != nil {
+					return errSql
+				}
+
+				store.logSql("insert", sqlStr, params...)
+
+				if store.db == nil {
+					return errors.New("groupstore: database is nil")
+				}
+
+				result, err := database.Execute(store.toQuerableContext(txCtx), sqlStr, params...)
+
+				if err != nil {
+					return err
+				}
+
+				if inserted, err := result.RowsAffected(); err != nil || inserted < int64(len(chunk)) {
+					skipped = true
+				}
+
+				lo.ForEach(chunk, func(relation RelationInterface, _ int) { relation.MarkAsNotDirty() })
+			}
+
+			if skipped {
+				// the existing relations were skipped, the gaps left are closed
+				groupIDs := lo.Uniq(lo.Map(relations, func(relation RelationInterface, _ int) string { return relation.GroupID() }))
+
+				if err := store.relationsPositionsRenumber(txCtx, groupIDs); err != nil {
+					return err
+				}
+			}
+
+			return store.relationsAuditCreated(txCtx, relations)
+		})
+	})
+}
+
 func (store *store) RelationCount(ctx context.Context, options RelationQueryInterface) (int64, error) {
 	options.SetCountOnly(true)
 
@@ -80,7 +199,32 @@ func (store *store) RelationCreate(ctx context.Context, relation RelationInterfa
 	}
 
 	if relationExists != nil {
-		return errors.New("groupstore > RelationCreate. relation with the same entityType-entityID-groupID combination already exists")
+		return ErrRelationExists
+	}
+
+	err = store.withRelationsChecked(ctx, []RelationInterface{relation}, func(txCtx context.Context) error {
+		return store.relationInsert(txCtx, relation)
+	})
+
+	if err == nil {
+		return nil
+	}
+
+	// a relation created concurrently is rejected by the unique index
+	concurrent, errFind := store.RelationFindByEntityAndGroup(ctx, relation.EntityType(), relation.EntityID(), relation.GroupID())
+
+	if errFind == nil && concurrent != nil {
+		return ErrRelationExists
+	}
+
+	return err
+}
+
+// relationInsert inserts the relation, without any checks, a live
+// relation without a position is positioned after the last of its group
+func (store *store) relationInsert(ctx context.Context, relation RelationInterface) error {
+	if err := store.relationsPositionsAppend(ctx, []RelationInterface{relation}); err != nil {
+		return err
 	}
 
 	relation.SetCreatedAt(carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC))
@@ -104,7 +248,7 @@ func (store *store) RelationCreate(ctx context.Context, relation RelationInterfa
 		return errors.New("entityGroupstore: database is nil")
 	}
 
-	_, err = database.Execute(store.toQuerableContext(ctx), sqlStr, params...)
+	err := store.executeAudited(ctx, relationAuditRecord(AUDIT_ACTION_CREATED, relation, nil), sqlStr, params...)
 
 	if err != nil {
 		return err
@@ -140,9 +284,122 @@ func (store *store) RelationDeleteByID(ctx context.Context, id string) error {
 
 	store.logSql("delete", sqlStr, params...)
 
-	_, err := database.Execute(store.toQuerableContext(ctx), sqlStr, params...)
+	return store.withAuditTransaction(ctx, func(txCtx context.Context) error {
+		var relation RelationInterface
 
-	return err
+		if store.auditTableName != "" {
+			list, err := store.RelationList(txCtx, NewRelationQuery().
+				SetID(id).
+				SetStatusIn(RELATION_STATUSES).
+				SetSoftDeletedIncluded(true).
+				SetLimit(1))
+
+			if err != nil {
+				return err
+			}
+
+			relation = lo.FirstOr(list, nil)
+		}
+
+		if _, err := database.Execute(store.toQuerableContext(txCtx), sqlStr, params...); err != nil {
+			return err
+		}
+
+		if relation == nil {
+			return nil // not found, or the audit trail is disabled
+		}
+
+		return store.auditRecord(txCtx, relationAuditRecord(AUDIT_ACTION_DELETED, relation, nil))
+	})
+}
+
+// RelationEnsure makes sure the entity is related to the group, and returns
+// the relation, the existing one or the newly created
+//
+// The existing relation is returned whatever its status, a pending or
+// invited relation must be approved with RelationApprove.
+//
+// It is idempotent and safe for concurrent use. The insert relies on the
+// unique index on the relation table and the native conflict handling of
+// the database (ON CONFLICT DO NOTHING, INSERT IGNORE on MySQL), so that
+// concurrent calls never create duplicates.
+func (store *store) RelationEnsure(ctx context.Context, entityType string, entityID string, groupID string) (RelationInterface, error) {
+	if entityType == "" {
+		return nil, errors.New("groupstore > RelationEnsure. entityType is empty")
+	}
+
+	if entityID == "" {
+		return nil, errors.New("groupstore > RelationEnsure. entityID is empty")
+	}
+
+	if groupID == "" {
+		return nil, errors.New("groupstore > RelationEnsure. groupID is empty")
+	}
+
+	existing, err := store.RelationFindByEntityAndGroup(ctx, entityType, entityID, groupID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		return existing, nil
+	}
+
+	relation := NewRelation().
+		SetEntityType(entityType).
+		SetEntityID(entityID).
+		SetGroupID(groupID)
+
+	if store.dbDriverName == sb.DIALECT_MSSQL {
+		// no native conflict handling, and no unique index
+		return relation, store.RelationCreate(ctx, relation)
+	}
+
+	err = store.withRelationsChecked(ctx, []RelationInterface{relation}, func(txCtx context.Context) error {
+		if err := store.relationsPositionsAppend(txCtx, []RelationInterface{relation}); err != nil {
+			return err
+		}
+
+		sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+			Insert(store.groupEntityRelationTableName).
+			Prepared(true).
+			Rows(relation.Data()).
+			OnConflict(goqu.DoNothing()).
+			ToSQL()
+
+		if errSql != nil {
+			return errSql
+		}
+
+		store.logSql("insert", sqlStr, params...)
+
+		if store.db == nil {
+			return errors.New("entityGroupstore: database is nil")
+		}
+
+		if _, err := database.Execute(store.toQuerableContext(txCtx), sqlStr, params...); err != nil {
+			return err
+		}
+
+		return store.relationsAuditCreated(txCtx, []RelationInterface{relation})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	ensured, err := store.RelationFindByEntityAndGroup(ctx, entityType, entityID, groupID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if ensured == nil {
+		return nil, errors.New("groupstore > RelationEnsure. relation not found after insert")
+	}
+
+	return ensured, nil
 }
 
 func (store *store) RelationFindByEntityAndGroup(
@@ -167,6 +424,7 @@ func (store *store) RelationFindByEntityAndGroup(
 		SetEntityType(entityType).
 		SetEntityID(entityID).
 		SetGroupID(groupID).
+		SetStatusIn(RELATION_STATUSES).
 		SetLimit(1)
 
 	list, err := store.RelationList(ctx, query)
@@ -187,7 +445,7 @@ func (store *store) RelationFindByID(ctx context.Context, id string) (relation R
 		return nil, errors.New("relation id is empty")
 	}
 
-	query := NewRelationQuery().SetID(id).SetLimit(1)
+	query := NewRelationQuery().SetID(id).SetStatusIn(RELATION_STATUSES).SetLimit(1)
 
 	list, err := store.RelationList(ctx, query)
 
@@ -202,6 +460,42 @@ func (store *store) RelationFindByID(ctx context.Context, id string) (relation R
 	return nil, nil
 }
 
+func (store *store) RelationIter(ctx context.Context, query RelationQueryInterface) iter.Seq2[RelationInterface, error] {
+	return func(yield func(RelationInterface, error) bool) {
+		if query == nil {
+			yield(nil, errors.New("at relation iter > relation query is nil"))
+			return
+		}
+
+		q, columns, err := store.relationSelectQuery(query)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		sqlStr, sqlParams, errSql := q.Prepared(true).Select(columns...).ToSQL()
+
+		if errSql != nil {
+			yield(nil, errSql)
+			return
+		}
+
+		store.logSql("select", sqlStr, sqlParams...)
+
+		for modelMap, err := range store.selectIter(ctx, sqlStr, sqlParams...) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(NewGroupEntityRelationFromExistingData(modelMap), nil) {
+				return
+			}
+		}
+	}
+}
+
 func (store *store) RelationList(ctx context.Context, query RelationQueryInterface) ([]RelationInterface, error) {
 	if query == nil {
 		return []RelationInterface{}, errors.New("at relation list > relation query is nil")
@@ -257,11 +551,34 @@ func (store *store) RelationSoftDeleteByID(ctx context.Context, id string) error
 	return store.RelationSoftDelete(ctx, relation)
 }
 
+// RelationUpdate updates the changed columns of the relation
+//
+// Business logic:
+//   - a relation moved to another group, or restored, is positioned after
+//     the last of its group, unless its position is changed too
+//   - the positions of the other relations of the group are closed up,
+//     when the relation is moved to another group, or soft deleted
 func (store *store) RelationUpdate(ctx context.Context, relation RelationInterface) error {
 	if relation == nil {
 		return errors.New("at relation update > relation is nil")
 	}
 
+	if !lo.HasKey(relation.DataChanged(), COLUMN_GROUP_ID) && !lo.HasKey(relation.DataChanged(), COLUMN_SOFT_DELETED_AT) {
+		return store.relationUpdate(ctx, relation)
+	}
+
+	return store.withTransaction(ctx, func(txCtx context.Context) error {
+		if err := store.relationPositionMove(txCtx, relation); err != nil {
+			return err
+		}
+
+		return store.relationUpdate(txCtx, relation)
+	})
+}
+
+// relationUpdate updates the changed columns of the relation, checked
+// when its key changes, or when it is activated
+func (store *store) relationUpdate(ctx context.Context, relation RelationInterface) error {
 	relation.SetUpdatedAt(carbon.Now(carbon.UTC).ToDateTimeString())
 
 	dataChanged := relation.DataChanged()
@@ -272,28 +589,47 @@ func (store *store) RelationUpdate(ctx context.Context, relation RelationInterfa
 		return nil
 	}
 
-	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
-		Update(store.groupEntityRelationTableName).
-		Prepared(true).
-		Set(dataChanged).
-		Where(goqu.C(COLUMN_ID).Eq(relation.ID())).
-		ToSQL()
+	update := func(ctx context.Context) error {
+		sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+			Update(store.groupEntityRelationTableName).
+			Prepared(true).
+			Set(dataChanged).
+			Where(goqu.C(COLUMN_ID).Eq(relation.ID())).
+			ToSQL()
 
-	if errSql != nil {
-		return errSql
+		if errSql != nil {
+			return errSql
+		}
+
+		store.logSql("update", sqlStr, params...)
+
+		if store.db == nil {
+			return errors.New("entityGroupstore: database is nil")
+		}
+
+		record := relationAuditRecord(auditUpdateAction(dataChanged), relation, auditUpdateDetails(dataChanged))
+
+		return store.executeAudited(ctx, record, sqlStr, params...)
 	}
 
-	store.logSql("update", sqlStr, params...)
+	var err error
 
-	if store.db == nil {
-		return errors.New("entityGroupstore: database is nil")
+	keyChanged := lo.HasKey(dataChanged, COLUMN_ENTITY_TYPE) || lo.HasKey(dataChanged, COLUMN_ENTITY_ID) || lo.HasKey(dataChanged, COLUMN_GROUP_ID)
+	activated := lo.HasKey(dataChanged, COLUMN_STATUS) && relation.IsActive()
+
+	if keyChanged || activated {
+		err = store.withRelationsChecked(ctx, []RelationInterface{relation}, update)
+	} else {
+		err = update(ctx)
 	}
 
-	_, err := database.Execute(store.toQuerableContext(ctx), sqlStr, params...)
+	if err != nil {
+		return err
+	}
 
 	relation.MarkAsNotDirty()
 
-	return err
+	return nil
 }
 
 func (store *store) relationSelectQuery(options RelationQueryInterface) (selectDataset *goqu.SelectDataset, columns []any, err error) {
@@ -327,6 +663,18 @@ func (store *store) relationSelectQuery(options RelationQueryInterface) (selectD
 		q = q.Where(goqu.C(COLUMN_GROUP_ID).Eq(options.GroupID()))
 	}
 
+	statuses := []string{}
+
+	if options.HasStatus() {
+		statuses = append(statuses, options.Status())
+	}
+
+	if options.HasStatusIn() {
+		statuses = append(statuses, options.StatusIn()...)
+	}
+
+	q = q.Where(relationStatusExpression(COLUMN_STATUS, lo.CoalesceSliceOrEmpty(statuses, []string{RELATION_STATUS_ACTIVE})))
+
 	if options.HasCreatedAtGte() && options.HasCreatedAtLte() {
 		q = q.Where(
 			goqu.C(COLUMN_CREATED_AT).Gte(options.CreatedAtGte()),
@@ -350,11 +698,7 @@ func (store *store) relationSelectQuery(options RelationQueryInterface) (selectD
 
 	if options.HasOrderBy() {
 		sort := lo.Ternary(options.HasSortDirection(), options.SortDirection(), sb.DESC)
-		if strings.EqualFold(sort, sb.ASC) {
-			q = q.Order(goqu.I(options.OrderBy()).Asc())
-		} else {
-			q = q.Order(goqu.I(options.OrderBy()).Desc())
-		}
+		q = q.Order(orderExpressions(options.OrderBy(), strings.EqualFold(sort, sb.ASC))...)
 	}
 
 	columns = []any{}
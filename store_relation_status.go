@@ -0,0 +1,260 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+// The relation status violations
+var (
+	ErrRelationBanned           = errors.New("groupstore > entity is banned from the group")
+	ErrRelationStatusTransition = errors.New("groupstore > relation status transition is not allowed")
+)
+
+// relationStatusTransitions are the statuses a relation can move to, by
+// its current status, a banned relation must be deleted to lift the ban
+var relationStatusTransitions = map[string][]string{
+	RELATION_STATUS_ACTIVE:   {RELATION_STATUS_BANNED},
+	RELATION_STATUS_BANNED:   {},
+	RELATION_STATUS_INVITED:  {RELATION_STATUS_ACTIVE, RELATION_STATUS_REJECTED, RELATION_STATUS_BANNED},
+	RELATION_STATUS_PENDING:  {RELATION_STATUS_ACTIVE, RELATION_STATUS_REJECTED, RELATION_STATUS_BANNED},
+	RELATION_STATUS_REJECTED: {RELATION_STATUS_PENDING, RELATION_STATUS_INVITED, RELATION_STATUS_BANNED},
+}
+
+// RelationRequest records the request of the entity to join the group,
+// and returns the relation
+//
+// Business logic:
+//   - a new relation is pending, until approved or rejected
+//   - a rejected request can be made again, it is pending again
+//   - requesting to join a group the entity is invited to accepts the
+//     invitation, the relation is active
+//   - the pending and active relations are returned unchanged
+//   - a banned entity cannot request to join, ErrRelationBanned
+func (store *store) RelationRequest(ctx context.Context, entityType string, entityID string, groupID string) (RelationInterface, error) {
+	return store.relationStatusEnsure(ctx, entityType, entityID, groupID, RELATION_STATUS_PENDING, RELATION_STATUS_INVITED)
+}
+
+// RelationInvite records the invitation of the entity to join the group,
+// and returns the relation
+//
+// Business logic:
+//   - a new relation is invited, until approved (accepted) or rejected
+//     (declined)
+//   - a rejected entity can be invited again
+//   - inviting an entity, which requested to join, approves its request,
+//     the relation is active
+//   - the invited and active relations are returned unchanged
+//   - a banned entity cannot be invited, ErrRelationBanned
+func (store *store) RelationInvite(ctx context.Context, entityType string, entityID string, groupID string) (RelationInterface, error) {
+	return store.relationStatusEnsure(ctx, entityType, entityID, groupID, RELATION_STATUS_INVITED, RELATION_STATUS_PENDING)
+}
+
+// RelationActivate makes sure the entity is an active member of the group,
+// and returns the relation
+//
+// Business logic:
+//   - a new relation is active
+//   - the pending and invited relations are approved
+//   - a rejected relation is requested again, and approved
+//   - the active relations are returned unchanged
+//   - a banned entity cannot be activated, ErrRelationBanned
+//   - the group capacities, the membership quotas and the exclusive sets
+//     are checked, as when creating an active relation
+func (store *store) RelationActivate(ctx context.Context, entityType string, entityID string, groupID string) (RelationInterface, error) {
+	if entityType == "" {
+		return nil, errors.New("at relation status > entity type is empty")
+	}
+
+	if entityID == "" {
+		return nil, errors.New("at relation status > entity ID is empty")
+	}
+
+	if groupID == "" {
+		return nil, errors.New("at relation status > group ID is empty")
+	}
+
+	var relation RelationInterface
+
+	err := store.withTransaction(ctx, func(txCtx context.Context) error {
+		existing, err := store.RelationFindByEntityAndGroup(txCtx, entityType, entityID, groupID)
+
+		if err != nil {
+			return err
+		}
+
+		if existing == nil {
+			relation, err = store.RelationEnsure(txCtx, entityType, entityID, groupID)
+			return err
+		}
+
+		relation = existing
+
+		return store.relationActivate(txCtx, existing)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return relation, nil
+}
+
+// RelationApprove approves the pending request, or accepts the invitation,
+// the relation becomes active
+//
+// The group capacities, the membership quotas and the exclusive sets are
+// checked, as when creating an active relation
+func (store *store) RelationApprove(ctx context.Context, relationID string) error {
+	return store.relationStatusTransition(ctx, relationID, RELATION_STATUS_ACTIVE)
+}
+
+// RelationReject rejects the pending request, or declines the invitation
+func (store *store) RelationReject(ctx context.Context, relationID string) error {
+	return store.relationStatusTransition(ctx, relationID, RELATION_STATUS_REJECTED)
+}
+
+// RelationBan bans the entity from the group, the relation is kept as
+// banned, so that the entity cannot request to join again
+func (store *store) RelationBan(ctx context.Context, relationID string) error {
+	return store.relationStatusTransition(ctx, relationID, RELATION_STATUS_BANNED)
+}
+
+// == PRIVATE METHODS =========================================================
+
+// relationStatusEnsure creates the relation with the status, unless it
+// exists, and returns it
+//
+// The existing relation with the accepting status is approved, the
+// rejected one moves to the status, the others are returned unchanged
+func (store *store) relationStatusEnsure(ctx context.Context, entityType string, entityID string, groupID string, status string, acceptingStatus string) (RelationInterface, error) {
+	if entityType == "" {
+		return nil, errors.New("at relation status > entity type is empty")
+	}
+
+	if entityID == "" {
+		return nil, errors.New("at relation status > entity ID is empty")
+	}
+
+	if groupID == "" {
+		return nil, errors.New("at relation status > group ID is empty")
+	}
+
+	var relation RelationInterface
+
+	err := store.withTransaction(ctx, func(txCtx context.Context) error {
+		existing, err := store.RelationFindByEntityAndGroup(txCtx, entityType, entityID, groupID)
+
+		if err != nil {
+			return err
+		}
+
+		if existing == nil {
+			relation = NewRelation().
+				SetEntityType(entityType).
+				SetEntityID(entityID).
+				SetGroupID(groupID).
+				SetStatus(status)
+
+			return store.RelationCreate(txCtx, relation)
+		}
+
+		relation = existing
+
+		switch existing.Status() {
+		case RELATION_STATUS_BANNED:
+			return ErrRelationBanned
+		case acceptingStatus:
+			return store.relationStatusSet(txCtx, existing, RELATION_STATUS_ACTIVE)
+		case RELATION_STATUS_REJECTED:
+			return store.relationStatusSet(txCtx, existing, status)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return relation, nil
+}
+
+// relationActivate moves the existing relation to active, through the
+// allowed transitions, so that the activation is checked
+func (store *store) relationActivate(ctx context.Context, relation RelationInterface) error {
+	switch relation.Status() {
+	case RELATION_STATUS_BANNED:
+		return ErrRelationBanned
+	case RELATION_STATUS_PENDING, RELATION_STATUS_INVITED:
+		return store.relationStatusSet(ctx, relation, RELATION_STATUS_ACTIVE)
+	case RELATION_STATUS_REJECTED:
+		if err := store.relationStatusSet(ctx, relation, RELATION_STATUS_PENDING); err != nil {
+			return err
+		}
+
+		return store.relationStatusSet(ctx, relation, RELATION_STATUS_ACTIVE)
+	}
+
+	return nil
+}
+
+// relationStatusTransition moves the relation to the status
+func (store *store) relationStatusTransition(ctx context.Context, relationID string, status string) error {
+	if relationID == "" {
+		return errors.New("at relation status > relation ID is empty")
+	}
+
+	return store.withTransaction(ctx, func(txCtx context.Context) error {
+		relation, err := store.RelationFindByID(txCtx, relationID)
+
+		if err != nil {
+			return err
+		}
+
+		if relation == nil {
+			return errors.New("at relation status > relation not found: " + relationID)
+		}
+
+		return store.relationStatusSet(txCtx, relation, status)
+	})
+}
+
+// relationStatusSet updates the status of the relation, if the transition
+// is allowed, the relations without a status being active
+func (store *store) relationStatusSet(ctx context.Context, relation RelationInterface, status string) error {
+	current := relation.Status()
+
+	if current == "" {
+		current = RELATION_STATUS_ACTIVE
+	}
+
+	if !slices.Contains(relationStatusTransitions[current], status) {
+		return ErrRelationStatusTransition
+	}
+
+	return store.RelationUpdate(ctx, relation.SetStatus(status))
+}
+
+// == HELPERS =================================================================
+
+// relationStatusExpression returns the condition selecting the relations
+// with the statuses, including the relations without a status as active
+func relationStatusExpression(column string, statuses []string) exp.Expression {
+	condition := goqu.I(column).In(statuses)
+
+	if !slices.Contains(statuses, RELATION_STATUS_ACTIVE) {
+		return condition
+	}
+
+	return goqu.Or(condition, goqu.I(column).IsNull())
+}
+
+// relationIsActive is the filter of the active relations
+func relationIsActive(relation RelationInterface, _ int) bool {
+	return relation.IsActive()
+}
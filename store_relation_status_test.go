@@ -0,0 +1,248 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStoreRelationRequest(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	club := NewGroup().SetHandle("club").SetTitle("Club")
+
+	if err := store.GroupCreate(ctx, club); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	relation, err := store.RelationRequest(ctx, "user", "USER_01", club.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !relation.IsPending() {
+		t.Fatal("expected a pending relation, found:", relation.Status())
+	}
+
+	// the pending relations are not members
+	isMember, err := store.IsMember(ctx, "user", "USER_01", "club")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if isMember {
+		t.Fatal("a pending relation must not be a member")
+	}
+
+	count, err := store.RelationCount(ctx, NewRelationQuery().SetGroupID(club.ID()))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 0 {
+		t.Fatal("expected no active relations, found:", count)
+	}
+
+	pending, err := store.RelationList(ctx, NewRelationQuery().
+		SetGroupID(club.ID()).
+		SetStatus(RELATION_STATUS_PENDING))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(pending) != 1 || pending[0].ID() != relation.ID() {
+		t.Fatal("expected the pending relation to be listed, found:", len(pending))
+	}
+
+	if err := store.RelationApprove(ctx, relation.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	isMember, err = store.IsMember(ctx, "user", "USER_01", "club")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !isMember {
+		t.Fatal("an approved relation must be a member")
+	}
+
+	// an active relation cannot be approved, nor rejected
+	if err := store.RelationReject(ctx, relation.ID()); !errors.Is(err, ErrRelationStatusTransition) {
+		t.Fatal("expected ErrRelationStatusTransition, found:", err)
+	}
+
+	if err := store.RelationBan(ctx, relation.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := store.RelationRequest(ctx, "user", "USER_01", club.ID()); !errors.Is(err, ErrRelationBanned) {
+		t.Fatal("expected ErrRelationBanned, found:", err)
+	}
+}
+
+func TestStoreRelationInvite(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	club := NewGroup().SetHandle("club").SetTitle("Club").SetCapacity(1)
+
+	if err := store.GroupCreate(ctx, club); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	invited, err := store.RelationInvite(ctx, "user", "USER_01", club.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !invited.IsInvited() {
+		t.Fatal("expected an invited relation, found:", invited.Status())
+	}
+
+	if err := store.RelationReject(ctx, invited.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// a rejected entity can request again
+	requested, err := store.RelationRequest(ctx, "user", "USER_01", club.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if requested.ID() != invited.ID() || !requested.IsPending() {
+		t.Fatal("expected the rejected relation to be pending again, found:", requested.Status())
+	}
+
+	// the pending relations do not take a seat
+	if _, err := store.RelationEnsure(ctx, "user", "USER_02", club.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// inviting the entity, which requested to join, approves it, if a seat is left
+	if _, err := store.RelationInvite(ctx, "user", "USER_01", club.ID()); !errors.Is(err, ErrGroupCapacityExceeded) {
+		t.Fatal("expected ErrGroupCapacityExceeded, found:", err)
+	}
+
+	club.SetCapacity(2)
+
+	if err := store.GroupUpdate(ctx, club); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	approved, err := store.RelationInvite(ctx, "user", "USER_01", club.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !approved.IsActive() {
+		t.Fatal("expected an active relation, found:", approved.Status())
+	}
+}
+
+func TestStoreRelationActivate(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	club := NewGroup().SetHandle("club").SetTitle("Club").SetCapacity(3)
+
+	if err := store.GroupCreate(ctx, club); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	created, err := store.RelationActivate(ctx, "user", "USER_01", club.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !created.IsActive() {
+		t.Fatal("expected an active relation, found:", created.Status())
+	}
+
+	pending, err := store.RelationRequest(ctx, "user", "USER_02", club.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	rejected, err := store.RelationInvite(ctx, "user", "USER_03", club.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.RelationReject(ctx, rejected.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for _, existing := range []RelationInterface{pending, rejected} {
+		activated, err := store.RelationActivate(ctx, "user", existing.EntityID(), club.ID())
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if activated.ID() != existing.ID() || !activated.IsActive() {
+			t.Fatal("expected the relation to be activated, found:", activated.Status())
+		}
+	}
+
+	// the activation is checked, as when creating an active relation
+	full, err := store.RelationRequest(ctx, "user", "USER_04", club.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := store.RelationActivate(ctx, "user", "USER_04", club.ID()); !errors.Is(err, ErrGroupCapacityExceeded) {
+		t.Fatal("expected ErrGroupCapacityExceeded, found:", err)
+	}
+
+	if err := store.RelationBan(ctx, full.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := store.RelationActivate(ctx, "user", "USER_04", club.ID()); !errors.Is(err, ErrRelationBanned) {
+		t.Fatal("expected ErrRelationBanned, found:", err)
+	}
+}
@@ -2,6 +2,8 @@ package groupstore
 
 import (
 	"context"
+	"errors"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -126,8 +128,8 @@ func TestStoreRelationCreate_Duplicate(t *testing.T) {
 
 	err = store.RelationCreate(context.Background(), entityGroup)
 
-	if err == nil {
-		t.Fatal("must return error as duplicated entity to group relationship")
+	if !errors.Is(err, ErrRelationExists) {
+		t.Fatal("must return ErrRelationExists as duplicated entity to group relationship, found:", err)
 	}
 }
 
@@ -570,3 +572,300 @@ func TestStoreEntityGroupSoftDeleteByID(t *testing.T) {
 		t.Fatal("EntityGroup MUST be soft deleted")
 	}
 }
+
+func TestStoreRelationIter(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	for _, entityID := range []string{"USER_01", "USER_02", "USER_03"} {
+		relation := NewRelation().
+			SetEntityType("USER").
+			SetEntityID(entityID).
+			SetGroupID("PERMISSION_01")
+
+		if err := store.RelationCreate(context.Background(), relation); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	entityIDs := []string{}
+
+	for relation, err := range store.RelationIter(context.Background(), NewRelationQuery().
+		SetGroupID("PERMISSION_01").
+		SetOrderBy(COLUMN_ENTITY_ID).
+		SetSortDirection(sb.ASC)) {
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		entityIDs = append(entityIDs, relation.EntityID())
+	}
+
+	if strings.Join(entityIDs, ",") != "USER_01,USER_02,USER_03" {
+		t.Fatal("unexpected entity ids:", entityIDs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	errorCount := 0
+
+	for relation, err := range store.RelationIter(ctx, NewRelationQuery()) {
+		if err == nil {
+			t.Fatal("unexpected relation:", relation)
+		}
+
+		errorCount++
+	}
+
+	if errorCount != 1 {
+		t.Fatal("must return one error as context is cancelled, got:", errorCount)
+	}
+}
+
+func TestStoreRelationEnsure(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	relation, err := store.RelationEnsure(context.Background(), "USER", "USER_01", "PERMISSION_01")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if relation == nil {
+		t.Fatal("Relation MUST NOT be nil")
+	}
+
+	ensured, err := store.RelationEnsure(context.Background(), "USER", "USER_01", "PERMISSION_01")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if ensured.ID() != relation.ID() {
+		t.Fatal("Relation MUST be the existing one, found:", ensured.ID())
+	}
+
+	count, err := store.RelationCount(context.Background(), NewRelationQuery())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 1 {
+		t.Fatal("unexpected count:", count)
+	}
+
+	err = store.RelationSoftDelete(context.Background(), relation)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	recreated, err := store.RelationEnsure(context.Background(), "USER", "USER_01", "PERMISSION_01")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if recreated.ID() == relation.ID() {
+		t.Fatal("Relation MUST be a new one, as the existing one is soft deleted")
+	}
+
+	_, err = store.RelationEnsure(context.Background(), "USER", "", "PERMISSION_01")
+
+	if err == nil {
+		t.Fatal("must return error as entity ID is empty")
+	}
+}
+
+func TestStoreRelationUniqueIndex(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	relation := NewRelation().
+		SetEntityType("USER").
+		SetEntityID("USER_01").
+		SetGroupID("PERMISSION_01")
+
+	err = store.RelationCreate(context.Background(), relation)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// bypasses the duplicate check of RelationCreate
+	_, err = store.DB().Exec(
+		`INSERT INTO groups_group_entity_relation_table (id, entity_type, entity_id, group_id, soft_deleted_at) VALUES (?, ?, ?, ?, ?)`,
+		"DUPLICATE_ID", "USER", "USER_01", "PERMISSION_01", sb.MAX_DATETIME,
+	)
+
+	if err == nil {
+		t.Fatal("must return error as the unique index is violated")
+	}
+
+	// migrating again must not fail, as the index exists already
+	err = store.AutoMigrate()
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// the soft deleted relations are not covered by the index, even when
+	// soft deleted in the same second
+	for i := 0; i < 2; i++ {
+		ensured, err := store.RelationEnsure(context.Background(), "USER", "USER_02", "PERMISSION_01")
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if err := store.RelationSoftDelete(context.Background(), ensured); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+}
+
+func TestStoreRelationUniqueIndexMigrate(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// a table of an older version, with duplicate live relations
+	_, err = db.Exec(`CREATE TABLE groups_group_entity_relation_table (id TEXT PRIMARY KEY, entity_type TEXT, entity_id TEXT, group_id TEXT, metas TEXT, memo TEXT, created_at DATETIME, updated_at DATETIME, soft_deleted_at DATETIME)`)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for _, row := range [][]string{
+		{"ID_01", "2024-01-01 00:00:00"},
+		{"ID_02", "2024-01-02 00:00:00"},
+	} {
+		_, err = db.Exec(
+			`INSERT INTO groups_group_entity_relation_table (id, entity_type, entity_id, group_id, created_at, updated_at, soft_deleted_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			row[0], "USER", "USER_01", "PERMISSION_01", row[1], row[1], sb.MAX_DATETIME,
+		)
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                           db,
+		GroupTableName:               "groups_group_table",
+		GroupEntityRelationTableName: "groups_group_entity_relation_table",
+		AutomigrateEnabled:           true,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	relations, err := store.RelationList(context.Background(), NewRelationQuery().
+		SetEntityType("USER").
+		SetEntityID("USER_01").
+		SetStatusIn(RELATION_STATUSES))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(relations) != 1 || relations[0].ID() != "ID_01" {
+		t.Fatal("expected the oldest relation to be kept, found:", len(relations))
+	}
+}
+
+func TestStoreRelationBulkCreate(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	_, err = store.RelationEnsure(context.Background(), "USER", "USER_01", "PERMISSION_01")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	relations := []RelationInterface{}
+
+	for i := range 450 {
+		relations = append(relations, NewRelation().
+			SetEntityType("USER").
+			SetEntityID("USER_"+strconv.Itoa(i)).
+			SetGroupID("PERMISSION_01"))
+	}
+
+	// USER_01 exists already, and must be skipped
+	relations = append(relations, NewRelation().
+		SetEntityType("USER").
+		SetEntityID("USER_01").
+		SetGroupID("PERMISSION_01"))
+
+	err = store.RelationBulkCreate(context.Background(), relations)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	count, err := store.RelationCount(context.Background(), NewRelationQuery().SetGroupID("PERMISSION_01"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 451 {
+		t.Fatal("relations count must be 451, found:", count)
+	}
+
+	err = store.RelationBulkCreate(context.Background(), []RelationInterface{NewRelation().SetEntityType("USER")})
+
+	if err == nil {
+		t.Fatal("must return error as the relation has no entity ID and group ID")
+	}
+}
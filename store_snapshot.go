@@ -0,0 +1,607 @@
+package groupstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/base/database"
+	"github.com/gouniverse/sb"
+	"github.com/gouniverse/uid"
+	"github.com/samber/lo"
+)
+
+// ExportOptions define the options for exporting a snapshot
+type ExportOptions struct {
+	// SoftDeletedIncluded includes the soft deleted groups and relations
+	SoftDeletedIncluded bool
+}
+
+// ImportOptions define the options for importing a snapshot
+type ImportOptions struct {
+	// MatchBy is how the imported groups are matched to the existing ones,
+	// IMPORT_MATCH_BY_ID (default) preserves the IDs, IMPORT_MATCH_BY_HANDLE
+	// matches the groups by handle and gives the new records new IDs
+	MatchBy string
+
+	// OnConflict is what happens to the existing groups and relations,
+	// IMPORT_CONFLICT_FAIL (default), IMPORT_CONFLICT_OVERWRITE or IMPORT_CONFLICT_SKIP
+	OnConflict string
+}
+
+// ImportResult holds the number of the imported groups and relations
+type ImportResult struct {
+	GroupsCreated    int
+	GroupsUpdated    int
+	GroupsSkipped    int
+	RelationsCreated int
+	RelationsUpdated int
+	RelationsSkipped int
+}
+
+// snapshotDocument is the versioned JSON document of a snapshot
+type snapshotDocument struct {
+	Version    int                `json:"version"`
+	ExportedAt string             `json:"exported_at"`
+	Groups     []snapshotGroup    `json:"groups"`
+	Relations  []snapshotRelation `json:"relations"`
+}
+
+type snapshotGroup struct {
+	ID            string            `json:"id"`
+	Status        string            `json:"status"`
+	Handle        string            `json:"handle"`
+	Title         string            `json:"title"`
+	Memo          string            `json:"memo"`
+	Metas         map[string]string `json:"metas"`
+	Rule          string            `json:"rule,omitempty"`
+	Capacity      int               `json:"capacity,omitempty"`
+	ExclusiveSet  string            `json:"exclusive_set,omitempty"`
+	ExternalID    string            `json:"external_id,omitempty"`
+	ActivateAt    string            `json:"activate_at,omitempty"`
+	DeactivateAt  string            `json:"deactivate_at,omitempty"`
+	Position      int               `json:"position,omitempty"`
+	CreatedAt     string            `json:"created_at"`
+	UpdatedAt     string            `json:"updated_at"`
+	SoftDeletedAt string            `json:"soft_deleted_at,omitempty"`
+}
+
+type snapshotRelation struct {
+	ID            string            `json:"id"`
+	EntityType    string            `json:"entity_type"`
+	EntityID      string            `json:"entity_id"`
+	GroupID       string            `json:"group_id"`
+	GroupHandle   string            `json:"group_handle,omitempty"`
+	Status        string            `json:"status,omitempty"`
+	Position      int               `json:"position,omitempty"`
+	Memo          string            `json:"memo"`
+	Metas         map[string]string `json:"metas"`
+	CreatedAt     string            `json:"created_at"`
+	UpdatedAt     string            `json:"updated_at"`
+	SoftDeletedAt string            `json:"soft_deleted_at,omitempty"`
+}
+
+// Export writes a snapshot of the groups and relations as a versioned
+// JSON document
+//
+// Business logic:
+//   - the records are streamed, the store is not loaded in memory
+//   - the export stops at the first error of the writer
+//   - the relations carry the handle of their group, so they can be
+//     imported by handle into another store
+func (store *store) Export(ctx context.Context, w io.Writer, options ExportOptions) error {
+	out := &snapshotWriter{w: w}
+
+	err := out.write(`{"version":` + strconv.Itoa(SNAPSHOT_VERSION) +
+		`,"exported_at":` + string(lo.Must(json.Marshal(carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)))) +
+		`,"groups":[`)
+
+	if err != nil {
+		return err
+	}
+
+	groupHandles := map[string]string{}
+	first := true
+
+	groups := store.GroupIter(ctx, NewGroupQuery().
+		SetOrderBy(COLUMN_CREATED_AT).
+		SetSortDirection(sb.ASC).
+		SetSoftDeletedIncluded(options.SoftDeletedIncluded))
+
+	for group, err := range groups {
+		if err != nil {
+			return err
+		}
+
+		metas, err := group.Metas()
+
+		if err != nil {
+			return err
+		}
+
+		groupHandles[group.ID()] = group.Handle()
+
+		if err := out.writeItem(&first, snapshotGroup{
+			ID:            group.ID(),
+			Status:        group.Status(),
+			Handle:        group.Handle(),
+			Title:         group.Title(),
+			Memo:          group.Memo(),
+			Metas:         metas,
+			Rule:          group.RuleJSON(),
+			Capacity:      group.Capacity(),
+			ExclusiveSet:  group.ExclusiveSet(),
+			ExternalID:    group.ExternalID(),
+			ActivateAt:    snapshotSchedule(group.ActivateAtCarbon()),
+			DeactivateAt:  snapshotSchedule(group.DeactivateAtCarbon()),
+			Position:      group.Position(),
+			CreatedAt:     snapshotDateTime(group.CreatedAtCarbon()),
+			UpdatedAt:     snapshotDateTime(group.UpdatedAtCarbon()),
+			SoftDeletedAt: lo.Ternary(group.IsSoftDeleted(), snapshotDateTime(group.SoftDeletedAtCarbon()), ""),
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := out.write(`],"relations":[`); err != nil {
+		return err
+	}
+
+	first = true
+
+	relations := store.RelationIter(ctx, NewRelationQuery().
+		SetOrderBy(COLUMN_CREATED_AT).
+		SetSortDirection(sb.ASC).
+		SetSoftDeletedIncluded(options.SoftDeletedIncluded).
+		SetStatusIn(RELATION_STATUSES))
+
+	for relation, err := range relations {
+		if err != nil {
+			return err
+		}
+
+		metas, err := relation.Metas()
+
+		if err != nil {
+			return err
+		}
+
+		if err := out.writeItem(&first, snapshotRelation{
+			ID:            relation.ID(),
+			EntityType:    relation.EntityType(),
+			EntityID:      relation.EntityID(),
+			GroupID:       relation.GroupID(),
+			GroupHandle:   groupHandles[relation.GroupID()],
+			Status:        relation.Status(),
+			Position:      relation.Position(),
+			Memo:          relation.Memo(),
+			Metas:         metas,
+			CreatedAt:     snapshotDateTime(relation.CreatedAtCarbon()),
+			UpdatedAt:     snapshotDateTime(relation.UpdatedAtCarbon()),
+			SoftDeletedAt: lo.Ternary(relation.IsSoftDeleted(), snapshotDateTime(relation.SoftDeletedAtCarbon()), ""),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return out.write("]}\n")
+}
+
+// Import reads a snapshot written by Export, and creates or updates the
+// groups and relations in a single transaction
+//
+// Business logic:
+//   - matching by ID, the groups and relations keep their IDs
+//   - matching by handle, the groups are matched by handle, and the
+//     relations by entity and group, the new records get new IDs
+//   - the relations follow their groups, when a group is matched to an
+//     existing group with another ID
+//   - a conflict is an existing record matching an imported one, it is
+//     either skipped, overwritten, or fails the whole import
+//   - the live relations are checked against the entity type policies,
+//     the capacities, the quotas and the exclusive sets, as by
+//     RelationCreate, a failed check failing the whole import
+//   - the groups and relations keep their timestamps, and are recorded
+//     as created in the audit trail
+//   - the positions of the relations in the imported groups are
+//     renumbered from 1, keeping the imported order
+func (store *store) Import(ctx context.Context, r io.Reader, options ImportOptions) (ImportResult, error) {
+	options.MatchBy = lo.CoalesceOrEmpty(options.MatchBy, IMPORT_MATCH_BY_ID)
+	options.OnConflict = lo.CoalesceOrEmpty(options.OnConflict, IMPORT_CONFLICT_FAIL)
+
+	if !lo.Contains([]string{IMPORT_MATCH_BY_ID, IMPORT_MATCH_BY_HANDLE}, options.MatchBy) {
+		return ImportResult{}, errors.New("at import > invalid match by: " + options.MatchBy)
+	}
+
+	if !lo.Contains([]string{IMPORT_CONFLICT_FAIL, IMPORT_CONFLICT_OVERWRITE, IMPORT_CONFLICT_SKIP}, options.OnConflict) {
+		return ImportResult{}, errors.New("at import > invalid on conflict: " + options.OnConflict)
+	}
+
+	document := snapshotDocument{}
+
+	if err := json.NewDecoder(r).Decode(&document); err != nil {
+		return ImportResult{}, errors.New("at import > invalid document: " + err.Error())
+	}
+
+	if document.Version != SNAPSHOT_VERSION {
+		return ImportResult{}, errors.New("at import > unsupported document version: " + strconv.Itoa(document.Version))
+	}
+
+	result := ImportResult{}
+
+	err := store.withTransaction(ctx, func(txCtx context.Context) error {
+		// the IDs of the imported groups mapped to the IDs in the store
+		groupIDs := map[string]string{}
+
+		for _, imported := range document.Groups {
+			groupID, err := store.importGroup(txCtx, imported, options, &result)
+
+			if err != nil {
+				return err
+			}
+
+			groupIDs[imported.ID] = groupID
+		}
+
+		// the IDs of the groups, whose relations were imported
+		relationGroupIDs := []string{}
+
+		for _, imported := range document.Relations {
+			importedGroupIDs, err := store.importRelation(txCtx, imported, groupIDs, options, &result)
+
+			if err != nil {
+				return err
+			}
+
+			relationGroupIDs = append(relationGroupIDs, importedGroupIDs...)
+		}
+
+		// the imported positions may collide, or leave gaps, with the
+		// positions of the relations already in the groups
+		return store.relationsPositionsRenumber(txCtx, lo.Uniq(relationGroupIDs))
+	})
+
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	return result, nil
+}
+
+// importGroup imports the group, and returns its ID in the store
+func (store *store) importGroup(ctx context.Context, imported snapshotGroup, options ImportOptions, result *ImportResult) (string, error) {
+	if imported.ID == "" {
+		return "", errors.New("at import > group ID is empty")
+	}
+
+	var existing GroupInterface
+	var err error
+
+	if options.MatchBy == IMPORT_MATCH_BY_HANDLE {
+		if imported.Handle == "" {
+			return "", errors.New("at import > group " + imported.ID + " has no handle to match by")
+		}
+
+		existing, err = store.groupFindByHandle(ctx, imported.Handle)
+	} else {
+		existing, err = store.groupFindIncludingSoftDeleted(ctx, imported.ID)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if existing == nil {
+		if err := store.groupStatusCheck(imported.Status); err != nil {
+			return "", errors.New("at import > group " + imported.ID + " has an invalid status: " + imported.Status)
+		}
+
+		id := lo.Ternary(options.MatchBy == IMPORT_MATCH_BY_HANDLE, uid.HumanUid(), imported.ID)
+
+		if err := store.snapshotInsert(ctx, store.groupTableName, map[string]string{
+			COLUMN_ID:              id,
+			COLUMN_STATUS:          imported.Status,
+			COLUMN_HANDLE:          imported.Handle,
+			COLUMN_TITLE:           imported.Title,
+			COLUMN_MEMO:            imported.Memo,
+			COLUMN_METAS:           snapshotMetas(imported.Metas),
+			COLUMN_RULE:            imported.Rule,
+			COLUMN_CAPACITY:        strconv.Itoa(imported.Capacity),
+			COLUMN_EXCLUSIVE_SET:   imported.ExclusiveSet,
+			COLUMN_EXTERNAL_ID:     imported.ExternalID,
+			COLUMN_ACTIVATE_AT:     lo.CoalesceOrEmpty(imported.ActivateAt, sb.MAX_DATETIME),
+			COLUMN_DEACTIVATE_AT:   lo.CoalesceOrEmpty(imported.DeactivateAt, sb.MAX_DATETIME),
+			COLUMN_POSITION:        strconv.Itoa(imported.Position),
+			COLUMN_CREATED_AT:      lo.CoalesceOrEmpty(imported.CreatedAt, carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)),
+			COLUMN_UPDATED_AT:      carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC),
+			COLUMN_SOFT_DELETED_AT: lo.CoalesceOrEmpty(imported.SoftDeletedAt, sb.MAX_DATETIME),
+		}); err != nil {
+			return "", err
+		}
+
+		if err := store.auditRecord(ctx, groupAuditRecord(AUDIT_ACTION_CREATED, id, nil)); err != nil {
+			return "", err
+		}
+
+		result.GroupsCreated++
+
+		return id, nil
+	}
+
+	switch options.OnConflict {
+	case IMPORT_CONFLICT_SKIP:
+		result.GroupsSkipped++
+		return existing.ID(), nil
+	case IMPORT_CONFLICT_FAIL:
+		return "", errors.New("at import > group " + imported.ID + " conflicts with the existing group " + existing.ID())
+	}
+
+	existing.SetStatus(imported.Status).
+		SetHandle(imported.Handle).
+		SetTitle(imported.Title).
+		SetMemo(imported.Memo).
+		SetRuleJSON(imported.Rule).
+		SetCapacity(imported.Capacity).
+		SetExclusiveSet(imported.ExclusiveSet).
+		SetExternalID(imported.ExternalID).
+		SetActivateAt(lo.CoalesceOrEmpty(imported.ActivateAt, sb.MAX_DATETIME)).
+		SetDeactivateAt(lo.CoalesceOrEmpty(imported.DeactivateAt, sb.MAX_DATETIME)).
+		SetPosition(imported.Position).
+		SetSoftDeletedAt(lo.CoalesceOrEmpty(imported.SoftDeletedAt, sb.MAX_DATETIME))
+
+	if err := existing.SetMetas(lo.CoalesceMapOrEmpty(imported.Metas)); err != nil {
+		return "", err
+	}
+
+	if err := store.GroupUpdate(ctx, existing); err != nil {
+		return "", err
+	}
+
+	result.GroupsUpdated++
+
+	return existing.ID(), nil
+}
+
+// importRelation imports the relation into the group it is mapped to, and
+// returns the IDs of the groups, whose relations changed
+func (store *store) importRelation(ctx context.Context, imported snapshotRelation, groupIDs map[string]string, options ImportOptions, result *ImportResult) ([]string, error) {
+	if imported.ID == "" || imported.EntityType == "" || imported.EntityID == "" || imported.GroupID == "" {
+		return nil, errors.New("at import > relation " + imported.ID + " must have an ID, entity type, entity ID and group ID")
+	}
+
+	status := lo.CoalesceOrEmpty(imported.Status, RELATION_STATUS_ACTIVE)
+
+	if !lo.Contains(RELATION_STATUSES, status) {
+		return nil, errors.New("at import > relation " + imported.ID + " has an invalid status: " + status)
+	}
+
+	groupID, mapped := groupIDs[imported.GroupID]
+
+	if !mapped && options.MatchBy == IMPORT_MATCH_BY_HANDLE {
+		if imported.GroupHandle == "" {
+			return nil, errors.New("at import > relation " + imported.ID + " has a group, which is not in the document, and no group handle")
+		}
+
+		group, err := store.GroupFindByHandle(ctx, imported.GroupHandle)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if group == nil {
+			return nil, errors.New("at import > relation " + imported.ID + " has a group, which does not exist: " + imported.GroupHandle)
+		}
+
+		groupID = group.ID()
+	} else if !mapped {
+		groupID = imported.GroupID
+	}
+
+	var existing RelationInterface
+	var err error
+
+	if options.MatchBy == IMPORT_MATCH_BY_ID {
+		existing, err = store.relationFindIncludingSoftDeleted(ctx, imported.ID)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if existing == nil {
+		existing, err = store.RelationFindByEntityAndGroup(ctx, imported.EntityType, imported.EntityID, groupID)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	softDeletedAt := lo.CoalesceOrEmpty(imported.SoftDeletedAt, sb.MAX_DATETIME)
+
+	if existing == nil {
+		row := map[string]string{
+			COLUMN_ID:              lo.Ternary(options.MatchBy == IMPORT_MATCH_BY_HANDLE, uid.HumanUid(), imported.ID),
+			COLUMN_ENTITY_TYPE:     imported.EntityType,
+			COLUMN_ENTITY_ID:       imported.EntityID,
+			COLUMN_GROUP_ID:        groupID,
+			COLUMN_STATUS:          status,
+			COLUMN_POSITION:        strconv.Itoa(imported.Position),
+			COLUMN_MEMO:            imported.Memo,
+			COLUMN_METAS:           snapshotMetas(imported.Metas),
+			COLUMN_CREATED_AT:      lo.CoalesceOrEmpty(imported.CreatedAt, carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)),
+			COLUMN_UPDATED_AT:      carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC),
+			COLUMN_SOFT_DELETED_AT: softDeletedAt,
+		}
+
+		relation := NewGroupEntityRelationFromExistingData(row)
+
+		insert := func(txCtx context.Context) error {
+			if err := store.snapshotInsert(txCtx, store.groupEntityRelationTableName, row); err != nil {
+				return err
+			}
+
+			return store.auditRecord(txCtx, relationAuditRecord(AUDIT_ACTION_CREATED, relation, nil))
+		}
+
+		// the soft deleted relations are no memberships, to be checked
+		if relation.IsSoftDeleted() {
+			err = insert(ctx)
+		} else {
+			err = store.withRelationsChecked(ctx, []RelationInterface{relation}, insert)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		result.RelationsCreated++
+
+		return []string{groupID}, nil
+	}
+
+	switch options.OnConflict {
+	case IMPORT_CONFLICT_SKIP:
+		result.RelationsSkipped++
+		return nil, nil
+	case IMPORT_CONFLICT_FAIL:
+		return nil, errors.New("at import > relation " + imported.ID + " conflicts with the existing relation " + existing.ID())
+	}
+
+	// the relation may move from another group
+	changedGroupIDs := lo.Uniq([]string{existing.GroupID(), groupID})
+
+	existing.SetEntityType(imported.EntityType).
+		SetEntityID(imported.EntityID).
+		SetGroupID(groupID).
+		SetStatus(status).
+		SetPosition(imported.Position).
+		SetMemo(imported.Memo).
+		SetSoftDeletedAt(softDeletedAt)
+
+	if err := existing.SetMetas(lo.CoalesceMapOrEmpty(imported.Metas)); err != nil {
+		return nil, err
+	}
+
+	if err := store.RelationUpdate(ctx, existing); err != nil {
+		return nil, err
+	}
+
+	result.RelationsUpdated++
+
+	return changedGroupIDs, nil
+}
+
+// groupFindIncludingSoftDeleted returns the group with the ID, even if soft deleted
+func (store *store) groupFindIncludingSoftDeleted(ctx context.Context, id string) (GroupInterface, error) {
+	list, err := store.GroupList(ctx, NewGroupQuery().
+		SetID(id).
+		SetSoftDeletedIncluded(true).
+		SetLimit(1))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	return list[0], nil
+}
+
+// relationFindIncludingSoftDeleted returns the relation with the ID, even if soft deleted
+func (store *store) relationFindIncludingSoftDeleted(ctx context.Context, id string) (RelationInterface, error) {
+	list, err := store.RelationList(ctx, NewRelationQuery().
+		SetID(id).
+		SetSoftDeletedIncluded(true).
+		SetStatusIn(RELATION_STATUSES).
+		SetLimit(1))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	return list[0], nil
+}
+
+// snapshotInsert inserts the row as is, unlike GroupCreate and
+// RelationCreate, which reset the timestamps
+func (store *store) snapshotInsert(ctx context.Context, tableName string, row map[string]string) error {
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		Insert(tableName).
+		Prepared(true).
+		Rows(row).
+		ToSQL()
+
+	if errSql != nil {
+		return errSql
+	}
+
+	store.logSql("insert", sqlStr, params...)
+
+	if store.db == nil {
+		return errors.New("groupstore: database is nil")
+	}
+
+	_, err := database.Execute(store.toQuerableContext(ctx), sqlStr, params...)
+
+	return err
+}
+
+// == HELPERS =================================================================
+
+// snapshotWriter writes the document
+type snapshotWriter struct {
+	w io.Writer
+}
+
+func (s *snapshotWriter) write(text string) error {
+	_, err := io.WriteString(s.w, text)
+	return err
+}
+
+// writeItem writes an array item on its own line, preceded by a comma
+// unless it is the first one
+func (s *snapshotWriter) writeItem(first *bool, item any) error {
+	encoded, err := json.Marshal(item)
+
+	if err != nil {
+		return err
+	}
+
+	if err := s.write(lo.Ternary(*first, "\n", ",\n") + string(encoded)); err != nil {
+		return err
+	}
+
+	*first = false
+
+	return nil
+}
+
+// snapshotDateTime formats the datetime in UTC
+func snapshotDateTime(datetime *carbon.Carbon) string {
+	return datetime.SetTimezone(carbon.UTC).ToDateTimeString(carbon.UTC)
+}
+
+// snapshotSchedule formats the scheduled datetime in UTC, empty when not
+// scheduled
+func snapshotSchedule(datetime *carbon.Carbon) string {
+	if datetime.IsInvalid() || datetime.IsZero() || datetime.Gte(carbon.Parse(sb.MAX_DATETIME, carbon.UTC)) {
+		return ""
+	}
+
+	return snapshotDateTime(datetime)
+}
+
+// snapshotMetas encodes the metas, as stored in the metas column
+func snapshotMetas(metas map[string]string) string {
+	return string(lo.Must(json.Marshal(lo.CoalesceMapOrEmpty(metas))))
+}
@@ -0,0 +1,436 @@
+package groupstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func seedSnapshot(t *testing.T, store StoreInterface) (GroupInterface, GroupInterface) {
+	admins := NewGroup().SetTitle("Admins").SetHandle("admins").SetStatus(GROUP_STATUS_ACTIVE)
+
+	if err := admins.SetMetas(map[string]string{"color": "red"}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	archived := NewGroup().SetTitle("Archived").SetHandle("archived")
+
+	for _, group := range []GroupInterface{admins, archived} {
+		if err := store.GroupCreate(context.Background(), group); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	if err := store.GroupSoftDelete(context.Background(), archived); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for _, entityID := range []string{"USER_01", "USER_02"} {
+		if _, err := store.RelationEnsure(context.Background(), "user", entityID, admins.ID()); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	return admins, archived
+}
+
+func exportSnapshot(t *testing.T, store StoreInterface, options ExportOptions) []byte {
+	buffer := &bytes.Buffer{}
+
+	if err := store.Export(context.Background(), buffer, options); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	return buffer.Bytes()
+}
+
+func TestStoreExport(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	admins, _ := seedSnapshot(t, store)
+
+	document := snapshotDocument{}
+
+	if err := json.Unmarshal(exportSnapshot(t, store, ExportOptions{}), &document); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if document.Version != SNAPSHOT_VERSION {
+		t.Fatal("unexpected version:", document.Version)
+	}
+
+	if len(document.Groups) != 1 || document.Groups[0].ID != admins.ID() || document.Groups[0].Metas["color"] != "red" {
+		t.Fatal("unexpected groups:", document.Groups)
+	}
+
+	if len(document.Relations) != 2 || document.Relations[0].GroupHandle != "admins" {
+		t.Fatal("unexpected relations:", document.Relations)
+	}
+
+	if err := json.Unmarshal(exportSnapshot(t, store, ExportOptions{SoftDeletedIncluded: true}), &document); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(document.Groups) != 2 || document.Groups[1].SoftDeletedAt == "" {
+		t.Fatal("soft deleted groups must be exported on request:", document.Groups)
+	}
+}
+
+func TestStoreImportByID(t *testing.T) {
+	source, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := source.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	admins, archived := seedSnapshot(t, source)
+	snapshot := exportSnapshot(t, source, ExportOptions{SoftDeletedIncluded: true})
+
+	target, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := target.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	result, err := target.Import(context.Background(), bytes.NewReader(snapshot), ImportOptions{})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if result.GroupsCreated != 2 || result.RelationsCreated != 2 {
+		t.Fatal("unexpected result:", result)
+	}
+
+	imported, err := target.GroupFindByID(context.Background(), admins.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if imported == nil || imported.Meta("color") != "red" {
+		t.Fatal("group must be imported with its ID and metas:", imported)
+	}
+
+	if !imported.CreatedAtCarbon().Eq(admins.CreatedAtCarbon()) {
+		t.Fatal("created at must be preserved:", imported.CreatedAt(), admins.CreatedAt())
+	}
+
+	if found, _ := target.GroupFindByID(context.Background(), archived.ID()); found != nil {
+		t.Fatal("soft deleted group must stay soft deleted")
+	}
+
+	// importing again conflicts, the default is to fail
+	_, err = target.Import(context.Background(), bytes.NewReader(snapshot), ImportOptions{})
+
+	if err == nil || !strings.Contains(err.Error(), "conflicts") {
+		t.Fatal("conflicting import must fail:", err)
+	}
+
+	result, err = target.Import(context.Background(), bytes.NewReader(snapshot), ImportOptions{OnConflict: IMPORT_CONFLICT_SKIP})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if result.GroupsSkipped != 2 || result.RelationsSkipped != 2 || result.GroupsCreated != 0 {
+		t.Fatal("unexpected result:", result)
+	}
+
+	imported.SetTitle("Changed")
+
+	if err := target.GroupUpdate(context.Background(), imported); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	result, err = target.Import(context.Background(), bytes.NewReader(snapshot), ImportOptions{OnConflict: IMPORT_CONFLICT_OVERWRITE})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if result.GroupsUpdated != 2 || result.RelationsUpdated != 2 {
+		t.Fatal("unexpected result:", result)
+	}
+
+	imported, err = target.GroupFindByID(context.Background(), admins.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if imported.Title() != "Admins" {
+		t.Fatal("group must be overwritten, title:", imported.Title())
+	}
+}
+
+func TestStoreImportByHandle(t *testing.T) {
+	source, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := source.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	admins, _ := seedSnapshot(t, source)
+	snapshot := exportSnapshot(t, source, ExportOptions{})
+
+	target, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := target.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	existing := NewGroup().SetTitle("Administrators").SetHandle("admins")
+
+	if err := target.GroupCreate(context.Background(), existing); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	result, err := target.Import(context.Background(), bytes.NewReader(snapshot), ImportOptions{
+		MatchBy:    IMPORT_MATCH_BY_HANDLE,
+		OnConflict: IMPORT_CONFLICT_SKIP,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if result.GroupsSkipped != 1 || result.RelationsCreated != 2 {
+		t.Fatal("unexpected result:", result)
+	}
+
+	isMember, err := target.IsMember(context.Background(), "user", "USER_01", existing.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !isMember {
+		t.Fatal("relations must be remapped to the group with the same handle")
+	}
+
+	if found, _ := target.GroupFindByID(context.Background(), admins.ID()); found != nil {
+		t.Fatal("the source group ID must not be used")
+	}
+}
+
+func TestStoreImportInvalid(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	_, err = store.Import(context.Background(), strings.NewReader(`{"version": 99}`), ImportOptions{})
+
+	if err == nil || !strings.Contains(err.Error(), "unsupported document version") {
+		t.Fatal("unsupported version must fail:", err)
+	}
+
+	_, err = store.Import(context.Background(), strings.NewReader(`{"version": 1}`), ImportOptions{OnConflict: "merge"})
+
+	if err == nil {
+		t.Fatal("invalid conflict strategy must fail")
+	}
+
+	// a failing import leaves nothing behind
+	_, err = store.Import(context.Background(), strings.NewReader(`{"version": 1,
+		"groups": [{"id": "GROUP_01", "title": "One", "handle": "one", "status": "active"}],
+		"relations": [{"id": "REL_01", "entity_type": "user", "group_id": "GROUP_01"}]}`), ImportOptions{})
+
+	if err == nil {
+		t.Fatal("relation without entity ID must fail")
+	}
+
+	count, err := store.GroupCount(context.Background(), NewGroupQuery().SetSoftDeletedIncluded(true))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 0 {
+		t.Fatal("failed import must be rolled back, groups:", count)
+	}
+
+	_, err = store.Import(context.Background(), strings.NewReader(`{"version": 1,
+		"groups": [{"id": "GROUP_01", "title": "One", "handle": "one", "status": "active"}],
+		"relations": [{"id": "REL_01", "entity_type": "user", "entity_id": "USER_01", "group_id": "GROUP_01", "status": "approved"}]}`), ImportOptions{})
+
+	if err == nil || !strings.Contains(err.Error(), "invalid status") {
+		t.Fatal("relation with an invalid status must fail:", err)
+	}
+}
+
+func TestStoreImportRenumbersPositions(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	admins, _ := seedSnapshot(t, store)
+
+	// the imported positions collide with, and leave a gap after, the
+	// positions of USER_01 and USER_02
+	_, err = store.Import(context.Background(), strings.NewReader(`{"version": 1,
+		"relations": [
+			{"id": "REL_03", "entity_type": "user", "entity_id": "USER_03", "group_id": "`+admins.ID()+`", "position": 1},
+			{"id": "REL_04", "entity_type": "user", "entity_id": "USER_04", "group_id": "`+admins.ID()+`", "position": 10}
+		]}`), ImportOptions{})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	relations, err := store.RelationList(context.Background(), NewRelationQuery().
+		SetGroupID(admins.ID()).
+		SetOrderBy(COLUMN_POSITION).
+		SetSortDirection("asc"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for index, relation := range relations {
+		if relation.Position() != index+1 {
+			t.Fatal("positions must be dense after the import:", index+1, relation.Position())
+		}
+	}
+
+	if len(relations) != 4 || relations[3].EntityID() != "USER_04" {
+		t.Fatal("unexpected relations:", len(relations))
+	}
+}
+
+func TestStoreImportChecksRelations(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	document := `{"version": 1,
+		"groups": [{"id": "GROUP_01", "title": "One", "handle": "one", "status": "active", "capacity": 1}],
+		"relations": [
+			{"id": "REL_01", "entity_type": "user", "entity_id": "USER_01", "group_id": "GROUP_01"},
+			{"id": "REL_02", "entity_type": "user", "entity_id": "USER_02", "group_id": "GROUP_01", "soft_deleted_at": "2020-01-01 00:00:00"},
+			{"id": "REL_03", "entity_type": "user", "entity_id": "USER_03", "group_id": "GROUP_01"}
+		]}`
+
+	_, err = store.Import(context.Background(), strings.NewReader(document), ImportOptions{})
+
+	if !errors.Is(err, ErrGroupCapacityExceeded) {
+		t.Fatal("import over the capacity must fail, found:", err)
+	}
+
+	// the soft deleted relations do not count against the capacity
+	document = strings.Replace(document, `,
+			{"id": "REL_03", "entity_type": "user", "entity_id": "USER_03", "group_id": "GROUP_01"}`, "", 1)
+
+	result, err := store.Import(context.Background(), strings.NewReader(document), ImportOptions{})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if result.GroupsCreated != 1 || result.RelationsCreated != 2 {
+		t.Fatal("unexpected result:", result)
+	}
+}
+
+// failingWriter fails the writes after the first n
+type failingWriter struct {
+	n      int
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+
+	if w.writes > w.n {
+		return 0, errors.New("disk full")
+	}
+
+	return len(p), nil
+}
+
+func TestStoreExportStopsAtWriteError(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	seedSnapshot(t, store)
+
+	writer := &failingWriter{n: 1}
+
+	if err := store.Export(context.Background(), writer, ExportOptions{}); err == nil || err.Error() != "disk full" {
+		t.Fatal("write error must be returned, found:", err)
+	}
+
+	if writer.writes != 2 {
+		t.Fatal("export must stop at the first write error, writes:", writer.writes)
+	}
+}
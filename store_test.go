@@ -33,20 +33,32 @@ func initDB(filepath string) (*sql.DB, error) {
 }
 
 func initStore(filepath string) (StoreInterface, error) {
+	return initStoreWithOptions(filepath, nil)
+}
+
+// initStoreWithOptions is initStore with the options changed by configure
+// (i.e. a policy or a feature enabled), nil for the defaults
+func initStoreWithOptions(filepath string, configure func(options *NewStoreOptions)) (StoreInterface, error) {
 	db, err := initDB(filepath)
 
 	if err != nil {
 		return nil, err
 	}
 
-	store, err := NewStore(NewStoreOptions{
+	options := NewStoreOptions{
 		DB:                           db,
 		GroupTableName:               "groups_group_table",
 		GroupEntityRelationTableName: "groups_group_entity_relation_table",
 		AutomigrateEnabled:           true,
 		DebugEnabled:                 true,
 		SqlLogger:                    slog.New(slog.NewTextHandler(os.Stdout, nil)),
-	})
+	}
+
+	if configure != nil {
+		configure(&options)
+	}
+
+	store, err := NewStore(options)
 
 	if err != nil {
 		return nil, err
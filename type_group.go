@@ -1,12 +1,16 @@
 package groupstore
 
 import (
+	"encoding/json"
+	"strconv"
+
 	"github.com/dromara/carbon/v2"
 	"github.com/gouniverse/dataobject"
 	"github.com/gouniverse/maputils"
 	"github.com/gouniverse/sb"
 	"github.com/gouniverse/uid"
 	"github.com/gouniverse/utils"
+	"github.com/spf13/cast"
 )
 
 // == CLASS ===================================================================
@@ -23,7 +27,14 @@ func NewGroup() GroupInterface {
 	o := (&group{}).
 		SetID(uid.HumanUid()).
 		SetStatus(GROUP_STATUS_INACTIVE).
+		SetCapacity(0).
+		SetPosition(0).
+		SetExclusiveSet("").
+		SetExternalID("").
+		SetActivateAt(sb.MAX_DATETIME).
+		SetDeactivateAt(sb.MAX_DATETIME).
 		SetMemo("").
+		SetRuleJSON("").
 		SetCreatedAt(carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)).
 		SetUpdatedAt(carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)).
 		SetSoftDeletedAt(sb.MAX_DATETIME)
@@ -45,6 +56,10 @@ func NewGroupFromExistingData(data map[string]string) GroupInterface {
 
 // == METHODS =================================================================
 
+func (o *group) IsArchived() bool {
+	return o.Status() == GROUP_STATUS_ARCHIVED
+}
+
 func (o *group) IsActive() bool {
 	return o.Status() == GROUP_STATUS_ACTIVE
 }
@@ -53,12 +68,44 @@ func (o *group) IsSoftDeleted() bool {
 	return o.SoftDeletedAtCarbon().Compare("<", carbon.Now(carbon.UTC))
 }
 
+// IsDynamic returns true if the members of the group are computed from
+// its rule, instead of being added as relations
+func (o *group) IsDynamic() bool {
+	return o.RuleJSON() != ""
+}
+
 func (o *group) IsInactive() bool {
 	return o.Status() == GROUP_STATUS_INACTIVE
 }
 
 // == SETTERS AND GETTERS =====================================================
 
+// ActivateAt returns the time the group is scheduled to be activated at,
+// sb.MAX_DATETIME when not scheduled
+func (o *group) ActivateAt() string {
+	return o.Get(COLUMN_ACTIVATE_AT)
+}
+
+func (o *group) ActivateAtCarbon() *carbon.Carbon {
+	return carbon.Parse(o.ActivateAt(), carbon.UTC)
+}
+
+func (o *group) SetActivateAt(activateAt string) GroupInterface {
+	o.Set(COLUMN_ACTIVATE_AT, activateAt)
+	return o
+}
+
+// Capacity returns the maximum number of members of the group,
+// 0 for unlimited
+func (o *group) Capacity() int {
+	return cast.ToInt(o.Get(COLUMN_CAPACITY))
+}
+
+func (o *group) SetCapacity(capacity int) GroupInterface {
+	o.Set(COLUMN_CAPACITY, strconv.Itoa(capacity))
+	return o
+}
+
 func (o *group) CreatedAt() string {
 	return o.Get(COLUMN_CREATED_AT)
 }
@@ -72,6 +119,43 @@ func (o *group) SetCreatedAt(createdAt string) GroupInterface {
 	return o
 }
 
+// DeactivateAt returns the time the group is scheduled to be deactivated
+// at, sb.MAX_DATETIME when not scheduled
+func (o *group) DeactivateAt() string {
+	return o.Get(COLUMN_DEACTIVATE_AT)
+}
+
+func (o *group) DeactivateAtCarbon() *carbon.Carbon {
+	return carbon.Parse(o.DeactivateAt(), carbon.UTC)
+}
+
+func (o *group) SetDeactivateAt(deactivateAt string) GroupInterface {
+	o.Set(COLUMN_DEACTIVATE_AT, deactivateAt)
+	return o
+}
+
+// ExclusiveSet returns the name of the exclusive set of the group, an
+// entity can belong to at most one group of the set
+func (o *group) ExclusiveSet() string {
+	return o.Get(COLUMN_EXCLUSIVE_SET)
+}
+
+func (o *group) SetExclusiveSet(exclusiveSet string) GroupInterface {
+	o.Set(COLUMN_EXCLUSIVE_SET, exclusiveSet)
+	return o
+}
+
+// ExternalID returns the ID of the group in an external system (i.e. the
+// SCIM externalId of an identity provider)
+func (o *group) ExternalID() string {
+	return o.Get(COLUMN_EXTERNAL_ID)
+}
+
+func (o *group) SetExternalID(externalID string) GroupInterface {
+	o.Set(COLUMN_EXTERNAL_ID, externalID)
+	return o
+}
+
 func (o *group) Handle() string {
 	return o.Get(COLUMN_HANDLE)
 }
@@ -157,6 +241,64 @@ func (o *group) UpsertMetas(metas map[string]string) error {
 	return o.SetMetas(currentMetas)
 }
 
+// Position returns the position of the group in its list, 0 when the
+// group is not positioned
+func (o *group) Position() int {
+	return cast.ToInt(o.Get(COLUMN_POSITION))
+}
+
+func (o *group) SetPosition(position int) GroupInterface {
+	o.Set(COLUMN_POSITION, strconv.Itoa(position))
+	return o
+}
+
+// Rule returns the rule of a dynamic group, or nil for a static group
+func (o *group) Rule() (*GroupRule, error) {
+	if o.RuleJSON() == "" {
+		return nil, nil
+	}
+
+	rule := GroupRule{}
+
+	if err := json.Unmarshal([]byte(o.RuleJSON()), &rule); err != nil {
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+// SetRule validates and stores the rule as json string, making the group
+// dynamic, a nil rule makes the group static
+func (o *group) SetRule(rule *GroupRule) error {
+	if rule == nil {
+		o.SetRuleJSON("")
+		return nil
+	}
+
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	ruleJSON, err := json.Marshal(rule)
+
+	if err != nil {
+		return err
+	}
+
+	o.SetRuleJSON(string(ruleJSON))
+
+	return nil
+}
+
+func (o *group) RuleJSON() string {
+	return o.Get(COLUMN_RULE)
+}
+
+func (o *group) SetRuleJSON(ruleJSON string) GroupInterface {
+	o.Set(COLUMN_RULE, ruleJSON)
+	return o
+}
+
 func (o *group) SoftDeletedAt() string {
 	return o.Get(COLUMN_SOFT_DELETED_AT)
 }
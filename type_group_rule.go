@@ -0,0 +1,122 @@
+package groupstore
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// GroupRule is the rule of a dynamic group, its members are the entities
+// of the entity type, whose attributes match the conditions
+//
+// Example, all users with country=GB:
+//
+//	&GroupRule{
+//		EntityType: "user",
+//		Conditions: []GroupRuleCondition{
+//			{Attribute: "country", Operator: RULE_OPERATOR_EQ, Values: []string{"GB"}},
+//		},
+//	}
+type GroupRule struct {
+	// EntityType is the entity type of the members
+	EntityType string `json:"entity_type"`
+
+	// Match is RULE_MATCH_ALL (default), all the conditions must match,
+	// or RULE_MATCH_ANY, at least one condition must match
+	Match string `json:"match,omitempty"`
+
+	Conditions []GroupRuleCondition `json:"conditions"`
+}
+
+// GroupRuleCondition is a condition on an attribute of the entities
+//
+// The attributes may have several values (i.e. tags), the condition
+// matches when one of them matches (eq, in, contains), or none of them
+// matches (ne, not_in).
+type GroupRuleCondition struct {
+	Attribute string `json:"attribute"`
+
+	// Operator is one of the RULE_OPERATOR_* constants
+	Operator string `json:"operator"`
+
+	Values []string `json:"values,omitempty"`
+}
+
+// Validate checks the rule has an entity type, and valid conditions
+func (rule GroupRule) Validate() error {
+	if rule.EntityType == "" {
+		return errors.New("group rule > entity type is empty")
+	}
+
+	if rule.Match != "" && rule.Match != RULE_MATCH_ALL && rule.Match != RULE_MATCH_ANY {
+		return errors.New("group rule > invalid match: " + rule.Match)
+	}
+
+	if len(rule.Conditions) == 0 {
+		return errors.New("group rule > conditions are empty")
+	}
+
+	for _, condition := range rule.Conditions {
+		if condition.Attribute == "" {
+			return errors.New("group rule > condition attribute is empty")
+		}
+
+		valuesCount := len(condition.Values)
+
+		switch condition.Operator {
+		case RULE_OPERATOR_EQ, RULE_OPERATOR_NE, RULE_OPERATOR_CONTAINS:
+			if valuesCount != 1 {
+				return errors.New("group rule > operator " + condition.Operator + " requires one value")
+			}
+		case RULE_OPERATOR_IN, RULE_OPERATOR_NOT_IN:
+			if valuesCount == 0 {
+				return errors.New("group rule > operator " + condition.Operator + " requires values")
+			}
+		case RULE_OPERATOR_EXISTS, RULE_OPERATOR_NOT_EXISTS:
+			if valuesCount != 0 {
+				return errors.New("group rule > operator " + condition.Operator + " takes no values")
+			}
+		default:
+			return errors.New("group rule > invalid operator: " + condition.Operator)
+		}
+	}
+
+	return nil
+}
+
+// Matches returns true if the attributes of an entity match the rule
+func (rule GroupRule) Matches(attributes map[string][]string) bool {
+	matches := func(condition GroupRuleCondition) bool {
+		return condition.matches(attributes[condition.Attribute])
+	}
+
+	if rule.Match == RULE_MATCH_ANY {
+		return lo.SomeBy(rule.Conditions, matches)
+	}
+
+	return lo.EveryBy(rule.Conditions, matches)
+}
+
+// matches returns true if the values of the attribute match the condition
+func (condition GroupRuleCondition) matches(values []string) bool {
+	switch condition.Operator {
+	case RULE_OPERATOR_EQ:
+		return slices.Contains(values, condition.Values[0])
+	case RULE_OPERATOR_NE:
+		return !slices.Contains(values, condition.Values[0])
+	case RULE_OPERATOR_IN:
+		return lo.Some(values, condition.Values)
+	case RULE_OPERATOR_NOT_IN:
+		return !lo.Some(values, condition.Values)
+	case RULE_OPERATOR_CONTAINS:
+		return lo.SomeBy(values, func(value string) bool { return strings.Contains(value, condition.Values[0]) })
+	case RULE_OPERATOR_EXISTS:
+		return len(values) > 0
+	case RULE_OPERATOR_NOT_EXISTS:
+		return len(values) == 0
+	}
+
+	return false
+}
@@ -1,12 +1,15 @@
 package groupstore
 
 import (
+	"strconv"
+
 	"github.com/dromara/carbon/v2"
 	"github.com/gouniverse/dataobject"
 	"github.com/gouniverse/maputils"
 	"github.com/gouniverse/sb"
 	"github.com/gouniverse/uid"
 	"github.com/gouniverse/utils"
+	"github.com/spf13/cast"
 )
 
 // == CLASS ===================================================================
@@ -23,6 +26,8 @@ func NewRelation() RelationInterface {
 	o := (&relation{}).
 		SetID(uid.HumanUid()).
 		SetMemo("").
+		SetStatus(RELATION_STATUS_ACTIVE).
+		SetPosition(0).
 		SetCreatedAt(carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)).
 		SetUpdatedAt(carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)).
 		SetSoftDeletedAt(sb.MAX_DATETIME)
@@ -44,6 +49,28 @@ func NewGroupEntityRelationFromExistingData(data map[string]string) RelationInte
 
 // == METHODS =================================================================
 
+// IsActive returns true if the relation makes the entity a member of the
+// group, the relations without a status are active
+func (o *relation) IsActive() bool {
+	return o.Status() == RELATION_STATUS_ACTIVE || o.Status() == ""
+}
+
+func (o *relation) IsBanned() bool {
+	return o.Status() == RELATION_STATUS_BANNED
+}
+
+func (o *relation) IsInvited() bool {
+	return o.Status() == RELATION_STATUS_INVITED
+}
+
+func (o *relation) IsPending() bool {
+	return o.Status() == RELATION_STATUS_PENDING
+}
+
+func (o *relation) IsRejected() bool {
+	return o.Status() == RELATION_STATUS_REJECTED
+}
+
 func (o *relation) IsSoftDeleted() bool {
 	return o.SoftDeletedAtCarbon().Compare("<", carbon.Now(carbon.UTC))
 }
@@ -178,6 +205,26 @@ func (o *relation) SetGroupID(roleID string) RelationInterface {
 	return o
 }
 
+// Position returns the position of the relation in its group, 0 when the
+// relation is not positioned
+func (o *relation) Position() int {
+	return cast.ToInt(o.Get(COLUMN_POSITION))
+}
+
+func (o *relation) SetPosition(position int) RelationInterface {
+	o.Set(COLUMN_POSITION, strconv.Itoa(position))
+	return o
+}
+
+func (o *relation) Status() string {
+	return o.Get(COLUMN_STATUS)
+}
+
+func (o *relation) SetStatus(status string) RelationInterface {
+	o.Set(COLUMN_STATUS, status)
+	return o
+}
+
 func (o *relation) UpdatedAt() string {
 	return o.Get(COLUMN_UPDATED_AT)
 }
@@ -0,0 +1,202 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+// NewUserMembershipOptions define the options for creating a new user
+// membership facade
+type NewUserMembershipOptions struct {
+	// Store is the group store, required
+	Store StoreInterface
+
+	// UserLookup returns the users with the IDs, the users not found are
+	// left out, required. It is called with at most LookupBatchSize IDs
+	UserLookup func(ctx context.Context, userIDs []string) ([]UserInterface, error)
+
+	// LookupBatchSize is the maximum number of user IDs per lookup,
+	// defaults to 100
+	LookupBatchSize int
+}
+
+// UserMembershipInterface manages the memberships of the users of a user
+// store, related to the groups with the ENTITY_TYPE_USER entity type
+type UserMembershipInterface interface {
+	// AddUser adds the user to the group, and returns the relation,
+	// the existing one or the newly created. The pending, invited and
+	// rejected relations are approved, a banned user cannot be added
+	// (ErrRelationBanned)
+	AddUser(ctx context.Context, user UserInterface, groupID string) (RelationInterface, error)
+
+	// GroupUsers returns the users of the group, in the order they were added
+	GroupUsers(ctx context.Context, groupID string) ([]UserInterface, error)
+
+	// RemoveUser removes the user from the group, if a member or invited.
+	// The pending, rejected and banned relations are kept, so that a
+	// banned user stays banned
+	RemoveUser(ctx context.Context, user UserInterface, groupID string) error
+
+	// UserGroups returns the groups of the user, ordered by handle
+	UserGroups(ctx context.Context, user UserInterface) ([]GroupInterface, error)
+}
+
+// == TYPE ====================================================================
+
+// userMembership is a facade over the store for the users of a user store
+//
+// The memberships are read from the relations, the dynamic groups are
+// included once materialised.
+type userMembership struct {
+	store           StoreInterface
+	userLookup      func(ctx context.Context, userIDs []string) ([]UserInterface, error)
+	lookupBatchSize int
+}
+
+var _ UserMembershipInterface = (*userMembership)(nil) // verify it implements the interface
+
+// == CONSTRUCTOR =============================================================
+
+// NewUserMembership creates a new user membership facade
+func NewUserMembership(opts NewUserMembershipOptions) (UserMembershipInterface, error) {
+	if opts.Store == nil {
+		return nil, errors.New("user membership: Store is required")
+	}
+
+	if opts.UserLookup == nil {
+		return nil, errors.New("user membership: UserLookup is required")
+	}
+
+	if opts.LookupBatchSize <= 0 {
+		opts.LookupBatchSize = 100
+	}
+
+	return &userMembership{
+		store:           opts.Store,
+		userLookup:      opts.UserLookup,
+		lookupBatchSize: opts.LookupBatchSize,
+	}, nil
+}
+
+// == PUBLIC METHODS ==========================================================
+
+func (m *userMembership) AddUser(ctx context.Context, user UserInterface, groupID string) (RelationInterface, error) {
+	if err := userValidate(user); err != nil {
+		return nil, errors.New("at add user > " + err.Error())
+	}
+
+	return m.store.RelationActivate(ctx, ENTITY_TYPE_USER, user.ID(), groupID)
+}
+
+// GroupUsers returns the users of the group
+//
+// Business logic:
+//   - the users are looked up in batches of LookupBatchSize IDs
+//   - the users no longer found in the user store are left out
+func (m *userMembership) GroupUsers(ctx context.Context, groupID string) ([]UserInterface, error) {
+	if groupID == "" {
+		return nil, errors.New("at group users > group ID is empty")
+	}
+
+	relations, err := m.store.RelationList(ctx, NewRelationQuery().
+		SetGroupID(groupID).
+		SetEntityType(ENTITY_TYPE_USER).
+		SetOrderBy(COLUMN_CREATED_AT).
+		SetSortDirection(sb.ASC))
+
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := lo.Uniq(lo.Map(relations, func(relation RelationInterface, _ int) string {
+		return relation.EntityID()
+	}))
+
+	usersByID := map[string]UserInterface{}
+
+	for _, chunk := range lo.Chunk(userIDs, m.lookupBatchSize) {
+		users, err := m.userLookup(ctx, chunk)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, user := range users {
+			if user != nil {
+				usersByID[user.ID()] = user
+			}
+		}
+	}
+
+	users := make([]UserInterface, 0, len(userIDs))
+
+	for _, userID := range userIDs {
+		if user, found := usersByID[userID]; found {
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
+
+func (m *userMembership) RemoveUser(ctx context.Context, user UserInterface, groupID string) error {
+	if err := userValidate(user); err != nil {
+		return errors.New("at remove user > " + err.Error())
+	}
+
+	relation, err := m.store.RelationFindByEntityAndGroup(ctx, ENTITY_TYPE_USER, user.ID(), groupID)
+
+	if err != nil {
+		return err
+	}
+
+	if relation == nil || !(relation.IsActive() || relation.IsInvited()) {
+		return nil // not a member, or banned, pending or rejected
+	}
+
+	return m.store.RelationSoftDelete(ctx, relation)
+}
+
+func (m *userMembership) UserGroups(ctx context.Context, user UserInterface) ([]GroupInterface, error) {
+	if err := userValidate(user); err != nil {
+		return nil, errors.New("at user groups > " + err.Error())
+	}
+
+	relations, err := m.store.RelationList(ctx, NewRelationQuery().
+		SetEntityType(ENTITY_TYPE_USER).
+		SetEntityID(user.ID()))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(relations) == 0 {
+		return []GroupInterface{}, nil
+	}
+
+	groupIDs := lo.Uniq(lo.Map(relations, func(relation RelationInterface, _ int) string {
+		return relation.GroupID()
+	}))
+
+	return m.store.GroupList(ctx, NewGroupQuery().
+		SetIDIn(groupIDs).
+		SetOrderBy(COLUMN_HANDLE).
+		SetSortDirection(sb.ASC))
+}
+
+// == HELPERS =================================================================
+
+func userValidate(user UserInterface) error {
+	if user == nil {
+		return errors.New("user is nil")
+	}
+
+	if user.ID() == "" {
+		return errors.New("user ID is empty")
+	}
+
+	return nil
+}
@@ -0,0 +1,278 @@
+package groupstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// testUser implements the ID of UserInterface, the other methods are
+// not used by the user membership facade
+type testUser struct {
+	UserInterface
+	id string
+}
+
+func (u *testUser) ID() string {
+	return u.id
+}
+
+func initUserMembership(t *testing.T, store StoreInterface, users map[string]UserInterface, lookups *[][]string) UserMembershipInterface {
+	membership, err := NewUserMembership(NewUserMembershipOptions{
+		Store: store,
+		UserLookup: func(ctx context.Context, userIDs []string) ([]UserInterface, error) {
+			*lookups = append(*lookups, userIDs)
+
+			found := []UserInterface{}
+
+			for _, userID := range userIDs {
+				if user, ok := users[userID]; ok {
+					found = append(found, user)
+				}
+			}
+
+			return found, nil
+		},
+		LookupBatchSize: 2,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	return membership
+}
+
+func TestNewUserMembership(t *testing.T) {
+	if _, err := NewUserMembership(NewUserMembershipOptions{}); err == nil {
+		t.Fatal("expected error for missing store")
+	}
+
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if _, err := NewUserMembership(NewUserMembershipOptions{Store: store}); err == nil {
+		t.Fatal("expected error for missing user lookup")
+	}
+}
+
+func TestUserMembership(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+
+	users := map[string]UserInterface{
+		"USER_01": &testUser{id: "USER_01"},
+		"USER_02": &testUser{id: "USER_02"},
+		"USER_03": &testUser{id: "USER_03"},
+	}
+
+	lookups := [][]string{}
+	membership := initUserMembership(t, store, users, &lookups)
+
+	admins := NewGroup().SetHandle("admins").SetTitle("Admins")
+	editors := NewGroup().SetHandle("editors").SetTitle("Editors")
+
+	for _, group := range []GroupInterface{admins, editors} {
+		if err := store.GroupCreate(ctx, group); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	for _, userID := range []string{"USER_01", "USER_02", "USER_03"} {
+		if _, err := membership.AddUser(ctx, users[userID], admins.ID()); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	// a user deleted from the user store
+	if _, err := membership.AddUser(ctx, &testUser{id: "USER_04"}, admins.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// adding again is a no-op
+	if _, err := membership.AddUser(ctx, users["USER_01"], admins.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := membership.AddUser(ctx, users["USER_01"], editors.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// other entity types are not users
+	if _, err := store.RelationEnsure(ctx, "service", "USER_02", editors.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	groupUsers, err := membership.GroupUsers(ctx, admins.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(groupUsers) != 3 {
+		t.Fatal("expected 3 users, found:", len(groupUsers))
+	}
+
+	if len(lookups) != 2 {
+		t.Fatal("expected 2 batched lookups, found:", len(lookups))
+	}
+
+	editorUsers, err := membership.GroupUsers(ctx, editors.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(editorUsers) != 1 || editorUsers[0].ID() != "USER_01" {
+		t.Fatal("expected USER_01 only, found:", len(editorUsers))
+	}
+
+	userGroups, err := membership.UserGroups(ctx, users["USER_01"])
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(userGroups) != 2 || userGroups[0].Handle() != "admins" || userGroups[1].Handle() != "editors" {
+		t.Fatal("expected admins and editors, found:", len(userGroups))
+	}
+
+	if err := membership.RemoveUser(ctx, users["USER_01"], admins.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// removing a non member is a no-op
+	if err := membership.RemoveUser(ctx, users["USER_01"], admins.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	userGroups, err = membership.UserGroups(ctx, users["USER_01"])
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(userGroups) != 1 || userGroups[0].Handle() != "editors" {
+		t.Fatal("expected editors only, found:", len(userGroups))
+	}
+
+	// a banned user stays banned
+	banned := NewRelation().
+		SetEntityType(ENTITY_TYPE_USER).
+		SetEntityID(users["USER_01"].ID()).
+		SetGroupID(admins.ID()).
+		SetStatus(RELATION_STATUS_BANNED)
+
+	if err := store.RelationCreate(ctx, banned); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := membership.RemoveUser(ctx, users["USER_01"], admins.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found, err := store.RelationFindByEntityAndGroup(ctx, ENTITY_TYPE_USER, users["USER_01"].ID(), admins.ID()); err != nil || found == nil || !found.IsBanned() {
+		t.Fatal("the ban must be kept:", found, err)
+	}
+
+	userGroups, err = membership.UserGroups(ctx, users["USER_03"])
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(userGroups) != 1 {
+		t.Fatal("expected 1 group, found:", len(userGroups))
+	}
+
+	if _, err := membership.UserGroups(ctx, nil); err == nil {
+		t.Fatal("expected error for nil user")
+	}
+
+	if _, err := membership.AddUser(ctx, &testUser{}, admins.ID()); err == nil {
+		t.Fatal("expected error for user without ID")
+	}
+}
+
+func TestUserMembershipAddUser_Statuses(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	defer func() {
+		if err := store.DB().Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	ctx := context.Background()
+	lookups := [][]string{}
+	membership := initUserMembership(t, store, map[string]UserInterface{}, &lookups)
+
+	club := NewGroup().SetHandle("club").SetTitle("Club")
+
+	if err := store.GroupCreate(ctx, club); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := store.RelationRequest(ctx, ENTITY_TYPE_USER, "USER_01", club.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := store.RelationInvite(ctx, ENTITY_TYPE_USER, "USER_02", club.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	rejected, err := store.RelationRequest(ctx, ENTITY_TYPE_USER, "USER_03", club.ID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.RelationReject(ctx, rejected.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// the pending, invited and rejected users are approved
+	for _, userID := range []string{"USER_01", "USER_02", "USER_03"} {
+		relation, err := membership.AddUser(ctx, &testUser{id: userID}, club.ID())
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if !relation.IsActive() {
+			t.Fatal("expected an active relation for", userID, "found:", relation.Status())
+		}
+	}
+
+	if err := store.RelationBan(ctx, rejected.ID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := membership.AddUser(ctx, &testUser{id: "USER_03"}, club.ID()); !errors.Is(err, ErrRelationBanned) {
+		t.Fatal("expected ErrRelationBanned, found:", err)
+	}
+}